@@ -6,7 +6,7 @@ import (
 )
 
 func main() {
-	resp, err := http.Get("http://localhost:8080/api/v1/health")
+	resp, err := http.Get("http://localhost:8080/__health")
 	if err != nil || resp.StatusCode != http.StatusOK {
 		os.Exit(1)
 	}
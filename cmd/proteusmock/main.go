@@ -5,8 +5,11 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/sophialabs/proteusmock/internal/app"
+	inboundhttp "github.com/sophialabs/proteusmock/internal/infrastructure/inbound/http"
 )
 
 func main() {
@@ -14,8 +17,54 @@ func main() {
 	flag.StringVar(&cfg.RootDir, "root", cfg.RootDir, "root directory for mock scenarios")
 	flag.IntVar(&cfg.Port, "port", cfg.Port, "HTTP server port")
 	flag.IntVar(&cfg.TraceSize, "trace-size", cfg.TraceSize, "number of trace entries to keep")
+	flag.IntVar(&cfg.MaxTraceCandidates, "max-trace-candidates", cfg.MaxTraceCandidates, "max candidate results recorded per trace entry (0 = unlimited)")
 	flag.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level (debug, info, warn, error)")
-	flag.StringVar(&cfg.DefaultEngine, "default-engine", cfg.DefaultEngine, "default template engine for all scenarios (expr, jinja2)")
+	flag.StringVar(&cfg.DefaultEngine, "default-engine", cfg.DefaultEngine, "default template engine for all scenarios (expr, jinja2, go)")
+	flag.Func("profiles", "comma-separated active scenario profiles (scenarios without profiles always load)", commaList(&cfg.ActiveProfiles))
+	flag.StringVar(&cfg.OverridesDir, "overrides", cfg.OverridesDir, "directory of scenarios deep-merged onto base scenarios with the same ID")
+	flag.IntVar(&cfg.HistoryLimit, "history-limit", cfg.HistoryLimit, "previous versions kept per scenario saved via the admin API (0 = disable history)")
+	flag.IntVar(&cfg.GlobalMaxPageSize, "max-page-size", cfg.GlobalMaxPageSize, "global cap on pagination page size across all scenarios (0 = unlimited)")
+	flag.BoolVar(&cfg.StrictLoad, "strict", cfg.StrictLoad, "fail startup and reloads if any scenario fails to compile or a .json file does not parse")
+	flag.BoolVar(&cfg.DevMode, "dev", cfg.DevMode, "include template render errors in 500 response bodies (development only)")
+	flag.BoolVar(&cfg.SelfTest, "self-test", cfg.SelfTest, "check every scenario's example_request at startup and fail if any does not match or render")
+	flag.BoolVar(&cfg.MethodNotAllowed, "method-not-allowed", cfg.MethodNotAllowed, "answer 405 with Allow when a path is mocked only for other methods (false = 404)")
+	flag.BoolVar(&cfg.CaptureEcho, "capture-echo", cfg.CaptureEcho, "answer unmatched requests with 200 and a JSON echo of the request instead of 404")
+	flag.BoolVar(&cfg.InitSample, "init-sample", cfg.InitSample, "create <root>/scenarios/hello.yaml (GET /hello) when the root directory is missing or empty")
+	flag.BoolVar(&cfg.DisableAdmin, "disable-admin", cfg.DisableAdmin, "do not serve the /__admin API (/__health stays available)")
+	flag.BoolVar(&cfg.DisableDashboard, "disable-dashboard", cfg.DisableDashboard, "do not serve the /__ui dashboard")
+	flag.StringVar(&cfg.AdminUser, "admin-user", cfg.AdminUser, "require HTTP basic auth with this user on /__admin and /__ui (with --admin-password)")
+	flag.StringVar(&cfg.AdminPassword, "admin-password", cfg.AdminPassword, "password for --admin-user")
+	flag.Func("cors-origins", "comma-separated origins allowed by CORS (* = any); enables CORS headers and automatic preflights", commaList(&cfg.CORSOrigins))
+	flag.Func("cors-methods", "comma-separated methods advertised on CORS preflight (default: the methods the path is mocked for)", commaList(&cfg.CORSMethods))
+	flag.Func("cors-headers", "comma-separated request headers allowed on CORS preflight (default: those requested)", commaList(&cfg.CORSHeaders))
+	flag.BoolVar(&cfg.CORSCredentials, "cors-credentials", cfg.CORSCredentials, "send Access-Control-Allow-Credentials: true for allowed origins")
+	flag.StringVar(&cfg.TrailingSlash, "trailing-slash", cfg.TrailingSlash, "treat /path/ vs /path: strip, redirect or equivalent (default: distinct routes)")
+	flag.IntVar(&cfg.MaxDynamicScenarios, "max-dynamic-scenarios", cfg.MaxDynamicScenarios, "keep at most N generated scenarios (e.g. proxy recordings), deleting the least recently matched on reload (0 = unlimited)")
+	flag.Uint64Var(&cfg.RandomSeed, "seed", cfg.RandomSeed, "seed random template helpers, generated bodies, jitter and fault rates for reproducible runs (0 = unseeded)")
+	flag.Func("middleware", "add a global middleware, name[:key=value,...] (repeatable; first is outermost)", func(v string) error {
+		spec, err := inboundhttp.ParseMiddlewareSpec(v)
+		if err != nil {
+			return err
+		}
+		cfg.Middlewares = append(cfg.Middlewares, spec)
+		return nil
+	})
+	flag.Func("status-body", "default body template for bodiless responses with a status, STATUS=FILE (repeatable)", func(v string) error {
+		status, path, ok := strings.Cut(v, "=")
+		code, err := strconv.Atoi(status)
+		if !ok || err != nil || code < 100 || code > 599 {
+			return fmt.Errorf("expected STATUS=FILE, got %q", v)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if cfg.StatusBodies == nil {
+			cfg.StatusBodies = map[int]string{}
+		}
+		cfg.StatusBodies[code] = string(data)
+		return nil
+	})
 	flag.Parse()
 
 	a, err := app.New(cfg)
@@ -35,3 +84,16 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// commaList returns a flag.Func that appends the comma-separated values of
+// each occurrence to dst, skipping blanks.
+func commaList(dst *[]string) func(string) error {
+	return func(v string) error {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*dst = append(*dst, item)
+			}
+		}
+		return nil
+	}
+}
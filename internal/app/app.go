@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"syscall"
 
+	inboundhttp "github.com/sophialabs/proteusmock/internal/infrastructure/inbound/http"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/filesystem"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/logging"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/wiring"
@@ -30,12 +31,51 @@ func New(cfg Config) (*App, error) {
 		Level: level,
 	})))
 
+	if (cfg.AdminUser == "") != (cfg.AdminPassword == "") {
+		return nil, errors.New("admin user and password must be set together")
+	}
+
+	if cfg.InitSample {
+		path, err := seedSample(cfg.RootDir)
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			logger.Info("created sample scenario", "file", path)
+		}
+	}
+
 	container, err := wiring.New(wiring.Params{
-		RootDir:        cfg.RootDir,
-		TraceSize:      cfg.TraceSize,
-		RateLimiterTTL: cfg.RateLimiterTTL,
-		Logger:         logger,
-		DefaultEngine:  cfg.DefaultEngine,
+		RootDir:            cfg.RootDir,
+		TraceSize:          cfg.TraceSize,
+		RateLimiterTTL:     cfg.RateLimiterTTL,
+		Logger:             logger,
+		DefaultEngine:      cfg.DefaultEngine,
+		MaxTraceCandidates: cfg.MaxTraceCandidates,
+		ActiveProfiles:     cfg.ActiveProfiles,
+		OverridesDir:       cfg.OverridesDir,
+		HistoryLimit:       cfg.HistoryLimit,
+		GlobalMaxPageSize:  cfg.GlobalMaxPageSize,
+		StrictLoad:         cfg.StrictLoad,
+		TemplateFuncs:      cfg.TemplateFuncs,
+		MethodNotAllowed:   cfg.MethodNotAllowed,
+		CaptureEcho:        cfg.CaptureEcho,
+		DevMode:            cfg.DevMode,
+		StatusBodies:       cfg.StatusBodies,
+		DisableAdmin:       cfg.DisableAdmin,
+		DisableDashboard:   cfg.DisableDashboard,
+		AdminUser:          cfg.AdminUser,
+		AdminPassword:      cfg.AdminPassword,
+		CORS: inboundhttp.CORSPolicy{
+			AllowedOrigins:   cfg.CORSOrigins,
+			AllowedMethods:   cfg.CORSMethods,
+			AllowedHeaders:   cfg.CORSHeaders,
+			AllowCredentials: cfg.CORSCredentials,
+		},
+		MaxDynamicScenarios: cfg.MaxDynamicScenarios,
+		RandomSeed:          cfg.RandomSeed,
+		TrailingSlash:       cfg.TrailingSlash,
+		Middlewares:         cfg.Middlewares,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to wire infrastructure: %w", err)
@@ -43,12 +83,15 @@ func New(cfg Config) (*App, error) {
 
 	addr := fmt.Sprintf(":%d", cfg.Port)
 	httpServer := &http.Server{
-		Addr:         addr,
-		Handler:      container.Server(),
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
-		IdleTimeout:  cfg.IdleTimeout,
+		Addr:              addr,
+		Handler:           container.Server(),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
+	httpServer.SetKeepAlivesEnabled(!cfg.DisableKeepAlives)
 
 	return &App{
 		cfg:        cfg,
@@ -58,7 +101,8 @@ func New(cfg Config) (*App, error) {
 }
 
 // Run executes the full application lifecycle: load scenarios, start watcher,
-// serve HTTP, and handle graceful shutdown on SIGINT/SIGTERM or context cancellation.
+// serve HTTP, reload on SIGHUP, and handle graceful shutdown on SIGINT/SIGTERM
+// or context cancellation.
 func (a *App) Run(ctx context.Context) error {
 	defer a.container.Close()
 
@@ -72,9 +116,24 @@ func (a *App) Run(ctx context.Context) error {
 	}
 	server.Rebuild(idx)
 
+	if a.cfg.SelfTest {
+		tested, failures := server.SelfTest()
+		for _, f := range failures {
+			logger.Error("self-test failed", "scenario", f.ScenarioID, "error", f.Err)
+		}
+		if len(failures) > 0 {
+			return fmt.Errorf("self-test failed for %d of %d scenarios", len(failures), tested)
+		}
+		logger.Info("self-test passed", "scenarios", tested)
+	}
+
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
 	watcher := a.setupWatcher()
 	if watcher != nil {
 		defer watcher.Stop()
@@ -87,11 +146,17 @@ func (a *App) Run(ctx context.Context) error {
 		}
 	}()
 
-	select {
-	case err := <-serverErr:
-		return fmt.Errorf("server error: %w", err)
-	case <-ctx.Done():
-		logger.Info("shutting down server...")
+waitLoop:
+	for {
+		select {
+		case err := <-serverErr:
+			return fmt.Errorf("server error: %w", err)
+		case <-hup:
+			a.reload(ctx, "SIGHUP reload")
+		case <-ctx.Done():
+			logger.Info("shutting down server...")
+			break waitLoop
+		}
 	}
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.ShutdownTimeout)
@@ -107,17 +172,9 @@ func (a *App) Run(ctx context.Context) error {
 
 func (a *App) setupWatcher() *filesystem.Watcher {
 	logger := a.container.Logger()
-	server := a.container.Server()
-	loadUC := a.container.LoadScenariosUseCase()
 
 	watcher, err := filesystem.NewWatcher(a.cfg.RootDir, a.cfg.WatcherDebounce, logger, func() {
-		newIdx, err := loadUC.Execute(context.Background())
-		if err != nil {
-			logger.Error("hot reload failed", "error", err)
-			return
-		}
-		server.Rebuild(newIdx)
-		logger.Info("hot reload complete")
+		a.reload(context.Background(), "hot reload")
 	})
 	if err != nil {
 		logger.Warn("file watcher not available", "error", err)
@@ -129,6 +186,20 @@ func (a *App) setupWatcher() *filesystem.Watcher {
 	return watcher
 }
 
+// reload re-runs scenario loading and swaps the router. Failures are logged and
+// the previous scenarios stay active.
+func (a *App) reload(ctx context.Context, kind string) {
+	logger := a.container.Logger()
+
+	newIdx, err := a.container.LoadScenariosUseCase().Execute(ctx)
+	if err != nil {
+		logger.Error(kind+" failed", "error", err)
+		return
+	}
+	a.container.Server().Rebuild(newIdx)
+	logger.Info(kind + " complete")
+}
+
 func parseLogLevel(s string) slog.Level {
 	switch s {
 	case "debug":
@@ -0,0 +1,91 @@
+//go:build integration && unix
+
+package app_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sophialabs/proteusmock/internal/app"
+)
+
+func TestRun_ReloadsOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	writeTestScenario(t, dir)
+
+	port := freePort(t)
+	cfg := app.DefaultConfig()
+	cfg.RootDir = dir
+	cfg.Port = port
+	cfg.LogLevel = "error"
+	// Keep the file watcher from reloading so only SIGHUP can pick up changes.
+	cfg.WatcherDebounce = time.Hour
+
+	a, err := app.New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- a.Run(ctx)
+	}()
+
+	base := fmt.Sprintf("http://localhost:%d", port)
+	waitForServer(t, base+"/api/health", 3*time.Second)
+
+	if code := getStatus(t, base+"/api/added"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 before reload, got %d", code)
+	}
+
+	added := `id: added
+name: Added
+when:
+  method: GET
+  path: /api/added
+response:
+  status: 200
+`
+	if err := os.WriteFile(filepath.Join(dir, "scenarios", "added.yaml"), []byte(added), 0o644); err != nil {
+		t.Fatalf("failed to write scenario file: %v", err)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("failed to send SIGHUP: %v", err)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for getStatus(t, base+"/api/added") != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("scenario not reloaded after SIGHUP")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+}
+
+func getStatus(t *testing.T, url string) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
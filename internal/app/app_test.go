@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -94,6 +95,44 @@ func TestRun_FailsOnInvalidScenarios(t *testing.T) {
 	}
 }
 
+func TestRun_StrictLoadFailsOnCompileError(t *testing.T) {
+	dir := t.TempDir()
+	scenarioDir := filepath.Join(dir, "scenarios")
+	if err := os.MkdirAll(scenarioDir, 0o755); err != nil {
+		t.Fatalf("failed to create scenario dir: %v", err)
+	}
+	yaml := `id: bad-regex
+name: Bad Regex
+when:
+  method: GET
+  path: /bad
+  headers:
+    X-Bad: "[invalid"
+response:
+  status: 200
+`
+	if err := os.WriteFile(filepath.Join(scenarioDir, "bad.yaml"), []byte(yaml), 0o644); err != nil {
+		t.Fatalf("failed to write scenario file: %v", err)
+	}
+
+	cfg := app.DefaultConfig()
+	cfg.RootDir = dir
+	cfg.Port = freePort(t)
+	cfg.StrictLoad = true
+
+	a, err := app.New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := a.Run(ctx); err == nil {
+		t.Error("expected startup to fail in strict mode")
+	}
+}
+
 func TestRun_ListensOnPort(t *testing.T) {
 	dir := t.TempDir()
 	writeTestScenario(t, dir)
@@ -141,6 +180,97 @@ func TestRun_ListensOnPort(t *testing.T) {
 	}
 }
 
+func TestRun_RejectsOversizedHeaders(t *testing.T) {
+	dir := t.TempDir()
+	writeTestScenario(t, dir)
+
+	port := freePort(t)
+	cfg := app.DefaultConfig()
+	cfg.RootDir = dir
+	cfg.Port = port
+	cfg.MaxHeaderBytes = 1024
+
+	a, err := app.New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = a.Run(ctx) }()
+
+	url := fmt.Sprintf("http://localhost:%d/api/health", port)
+	waitForServer(t, url, 3*time.Second)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	// net/http allows 4096 bytes of slack on top of MaxHeaderBytes.
+	req.Header.Set("X-Padding", strings.Repeat("a", 16*1024))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("expected 431, got %d", resp.StatusCode)
+	}
+}
+
+func TestRun_InitSampleServesHello(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mock")
+
+	port := freePort(t)
+	cfg := app.DefaultConfig()
+	cfg.RootDir = dir
+	cfg.Port = port
+	cfg.InitSample = true
+
+	a, err := app.New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = a.Run(ctx) }()
+
+	url := fmt.Sprintf("http://localhost:%d/hello", port)
+	waitForServer(t, url, 3*time.Second)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "scenarios", "hello.yaml")); err != nil {
+		t.Errorf("expected sample scenario file: %v", err)
+	}
+}
+
+func TestNew_InitSampleLeavesPopulatedRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeTestScenario(t, dir)
+
+	cfg := app.DefaultConfig()
+	cfg.RootDir = dir
+	cfg.InitSample = true
+
+	if _, err := app.New(cfg); err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "scenarios", "hello.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected no sample in a populated root, got err=%v", err)
+	}
+}
+
 func freePort(t *testing.T) int {
 	t.Helper()
 	l, err := net.Listen("tcp", ":0")
@@ -1,6 +1,11 @@
 package app
 
-import "time"
+import (
+	"time"
+
+	inboundhttp "github.com/sophialabs/proteusmock/internal/infrastructure/inbound/http"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/template"
+)
 
 // Config holds all configurable parameters for the application.
 type Config struct {
@@ -9,15 +14,108 @@ type Config struct {
 	TraceSize int
 	LogLevel  string
 
+	MaxTraceCandidates int // 0 = record every candidate
+
 	RateLimiterTTL  time.Duration
 	WatcherDebounce time.Duration
 
-	ReadTimeout     time.Duration
-	WriteTimeout    time.Duration
-	IdleTimeout     time.Duration
-	ShutdownTimeout time.Duration
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+
+	MaxHeaderBytes    int  // request header size limit, in bytes
+	DisableKeepAlives bool // close connections after each response
+
+	DefaultEngine string // "" = static, "expr", "jinja2", "go"
+
+	// ActiveProfiles selects which profile-restricted scenarios load.
+	// Scenarios without profiles always load.
+	ActiveProfiles []string
+
+	// OverridesDir, when set, holds scenarios that are deep-merged onto the
+	// base scenarios with the same ID (e.g. per-environment tweaks).
+	OverridesDir string
+
+	// HistoryLimit is how many previous versions are kept per scenario when
+	// saving through the admin API (under <root>/.history). 0 disables history.
+	HistoryLimit int
+
+	// GlobalMaxPageSize caps the pagination page size of every scenario,
+	// whatever its own max_size. 0 = unlimited.
+	GlobalMaxPageSize int
+
+	// StrictLoad refuses to start (and rejects reloads) when any scenario
+	// fails to compile or a .json file doesn't parse. The default skips
+	// broken scenarios and files with a warning.
+	StrictLoad bool
+
+	// SelfTest runs each scenario's example_request through matching,
+	// rendering and pagination at startup, and refuses to start if any fails.
+	SelfTest bool
+
+	// DevMode answers template render failures with the error message and
+	// template name instead of an opaque 500 body.
+	DevMode bool
+
+	// StatusBodies maps a response status to a default body template, used
+	// by scenarios that answer with that status but declare no body. The
+	// templates use DefaultEngine, or Expr when it is unset.
+	StatusBodies map[int]string
 
-	DefaultEngine string // "" = static, "expr", "jinja2"
+	// TemplateFuncs registers custom helpers with every template engine.
+	// Names may not collide with built-in helpers.
+	TemplateFuncs map[string]template.TemplateFunc
+
+	// MethodNotAllowed answers 405 with an Allow header when a path has
+	// scenarios only for other methods. Off by default, which answers 404.
+	MethodNotAllowed bool
+
+	// CaptureEcho answers unmatched requests with 200 and the request echoed
+	// as JSON instead of 404, while still tracing the miss. Meant for
+	// building mocks against a live client.
+	CaptureEcho bool
+
+	// InitSample writes a sample scenario to <root>/scenarios/hello.yaml on
+	// startup when the root directory is missing or empty.
+	InitSample bool
+
+	// DisableAdmin and DisableDashboard leave the /__admin API and the /__ui
+	// dashboard unrouted, e.g. for shared deployments. /__health stays up.
+	DisableAdmin     bool
+	DisableDashboard bool
+
+	// AdminUser and AdminPassword, when set, require HTTP basic auth on the
+	// admin API and dashboard. Mock routes stay open.
+	AdminUser     string
+	AdminPassword string
+
+	// CORSOrigins enables CORS for the listed origins ("*" = any). The other
+	// CORS fields only apply when it is set; empty methods and headers
+	// mirror what the mocked path and the preflight ask for.
+	CORSOrigins     []string
+	CORSMethods     []string
+	CORSHeaders     []string
+	CORSCredentials bool
+
+	// MaxDynamicScenarios caps generated scenarios (e.g. proxy recordings).
+	// Reloads delete the least recently matched beyond the cap; file-authored
+	// scenarios are never evicted. 0 = unlimited.
+	MaxDynamicScenarios int
+
+	// RandomSeed seeds every random draw (template helpers, generated bodies,
+	// jitter, fault rates and load balancing) for reproducible runs.
+	// 0 = unseeded.
+	RandomSeed uint64
+
+	// TrailingSlash relates mock paths with and without a trailing slash:
+	// "" keeps them distinct, "strip", "redirect" or "equivalent".
+	TrailingSlash string
+
+	// Middlewares is an ordered pipeline of built-in transforms applied to
+	// every mock request and response; the first entry is the outermost.
+	Middlewares []inboundhttp.MiddlewareSpec
 }
 
 // DefaultConfig returns a Config with sensible production defaults.
@@ -31,9 +129,14 @@ func DefaultConfig() Config {
 		RateLimiterTTL:  10 * time.Minute,
 		WatcherDebounce: 500 * time.Millisecond,
 
-		ReadTimeout:     30 * time.Second,
-		WriteTimeout:    30 * time.Second,
-		IdleTimeout:     60 * time.Second,
-		ShutdownTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		ShutdownTimeout:   10 * time.Second,
+
+		MaxHeaderBytes: 1 << 20,
+
+		HistoryLimit: 20,
 	}
 }
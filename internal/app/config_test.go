@@ -90,6 +90,19 @@ func TestNew_InvalidRootDir(t *testing.T) {
 	}
 }
 
+func TestNew_AdminPasswordWithoutUser(t *testing.T) {
+	dir := t.TempDir()
+	writeTestScenario(t, dir)
+
+	cfg := app.DefaultConfig()
+	cfg.RootDir = dir
+	cfg.AdminPassword = "s3cret"
+
+	if _, err := app.New(cfg); err == nil {
+		t.Error("expected error when only the admin password is set")
+	}
+}
+
 func TestNew_WithDefaultEngine(t *testing.T) {
 	dir := t.TempDir()
 	writeTestScenario(t, dir)
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// sampleScenario is written to <root>/scenarios/hello.yaml on first run.
+const sampleScenario = `# Sample scenario created by --init-sample. Edit or delete it freely;
+# files under the root directory are reloaded on change.
+id: hello
+name: Hello
+when:
+  method: GET
+  path: /hello
+response:
+  status: 200
+  content_type: application/json
+  body: '{"message": "Hello from ProteusMock"}'
+`
+
+// seedSample writes a sample scenario when root is missing or empty, so a
+// first run serves something. It reports the file written, or "" when root
+// already had content.
+func seedSample(root string) (string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to read root directory: %w", err)
+	}
+	if len(entries) > 0 {
+		return "", nil
+	}
+
+	dir := filepath.Join(root, "scenarios")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create scenarios directory: %w", err)
+	}
+	path := filepath.Join(dir, "hello.yaml")
+	if err := os.WriteFile(path, []byte(sampleScenario), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write sample scenario: %w", err)
+	}
+	return path, nil
+}
@@ -1,39 +1,74 @@
 package match
 
 import (
+	"encoding/json"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sophialabs/proteusmock/internal/domain/trace"
 )
 
 // IncomingRequest represents an HTTP request in domain terms, free of net/http.
 type IncomingRequest struct {
-	Method  string
-	Path    string
+	Method string
+	Path   string
+	// Host is the lower-cased request host without port, exposed as "host".
+	Host    string
 	Headers map[string]string
+	// Query holds every value of each query parameter, in request order.
+	Query map[string][]string
+	// Cookies holds the decoded value of each request cookie, by name.
+	Cookies map[string]string
 	Body    []byte
+	// ContentLength is the declared Content-Length; -1 means unknown.
+	ContentLength int64
+	// Now is the time the request is evaluated at, exposed as the "now" field.
+	Now time.Time
+	// Secure is set for requests received over TLS, exposed as "secure".
+	Secure bool
 }
 
 // EvalResult holds the outcome of evaluating candidates against a request.
 type EvalResult struct {
-	Matched    *CompiledScenario
+	Matched *CompiledScenario
+	// Matches lists every matching candidate in evaluation order (Matched is the first).
+	Matches    []*CompiledScenario
 	Candidates []trace.CandidateResult
+	// CandidatesOmitted counts candidates evaluated but not recorded in Candidates.
+	CandidatesOmitted int
+	// UnsupportedMediaType is set when at least one candidate was rejected
+	// because the request Content-Type is not in its RequireContentType.
+	UnsupportedMediaType bool
 }
 
 // Evaluator evaluates incoming requests against compiled scenarios.
-type Evaluator struct{}
+type Evaluator struct {
+	maxCandidates int // 0 = record every candidate
+}
 
 // NewEvaluator creates a new Evaluator.
 func NewEvaluator() *Evaluator {
 	return &Evaluator{}
 }
 
+// SetMaxCandidates caps how many candidate results are recorded per request.
+// The first n candidates are kept, plus the matched one if it falls beyond the
+// cap. Zero or a negative value disables the cap.
+func (e *Evaluator) SetMaxCandidates(n int) {
+	e.maxCandidates = max(n, 0)
+}
+
 // Evaluate runs all candidates against the request and returns the best match.
 // Candidates are assumed to be pre-sorted by priority descending, then ID ascending
 // (as done by ScenarioIndex.Build).
 func (e *Evaluator) Evaluate(req *IncomingRequest, candidates []*CompiledScenario) EvalResult {
+	capacity := len(candidates)
+	if e.maxCandidates > 0 {
+		capacity = min(capacity, e.maxCandidates+1)
+	}
 	result := EvalResult{
-		Candidates: make([]trace.CandidateResult, 0, len(candidates)),
+		Candidates: make([]trace.CandidateResult, 0, capacity),
 	}
 
 	// Build field value map for predicate evaluation.
@@ -48,20 +83,48 @@ func (e *Evaluator) Evaluate(req *IncomingRequest, candidates []*CompiledScenari
 			Matched:      true,
 		}
 
-		for _, fp := range cs.Predicates {
+		predicates := cs.Predicates
+		if cs.Disabled() {
+			// A scenario disabled at runtime never matches; its predicates
+			// are not evaluated.
+			cr.Matched = false
+			cr.FailedField = "disabled"
+			cr.FailedReason = "scenario disabled at runtime"
+			predicates = nil
+		} else if contentType := req.Headers["Content-Type"]; !cs.AcceptsContentType(contentType) {
+			// Content-Type requirements are checked before any predicate so a
+			// wrong media type is reported as such rather than as a body mismatch.
+			cr.Matched = false
+			cr.FailedField = "content_type"
+			cr.FailedReason = "unsupported content type: " + contentType
+			result.UnsupportedMediaType = true
+			predicates = nil
+		}
+
+		for _, fp := range predicates {
 			val := resolveFieldValue(fp.Field, fieldValues, bodyStr)
 			if !fp.Predicate(val) {
 				cr.Matched = false
 				cr.FailedField = fp.Field
 				cr.FailedReason = "value did not match: " + val
+				if val == Missing {
+					cr.FailedReason = "field not present"
+				}
 				break
 			}
 		}
 
-		result.Candidates = append(result.Candidates, cr)
+		if e.maxCandidates == 0 || len(result.Candidates) < e.maxCandidates || (cr.Matched && result.Matched == nil) {
+			result.Candidates = append(result.Candidates, cr)
+		} else {
+			result.CandidatesOmitted++
+		}
 
-		if cr.Matched && result.Matched == nil {
-			result.Matched = cs
+		if cr.Matched {
+			if result.Matched == nil {
+				result.Matched = cs
+			}
+			result.Matches = append(result.Matches, cs)
 		}
 	}
 
@@ -75,16 +138,65 @@ func resolveFieldValue(field string, fieldValues map[string]string, body string)
 	if strings.HasPrefix(field, "body:") || field == "body" {
 		return body
 	}
-	return fieldValues[field]
+	if v, ok := fieldValues[field]; ok {
+		return v
+	}
+	if strings.HasPrefix(field, "query:") || strings.HasPrefix(field, "header:") || strings.HasPrefix(field, "cookie:") {
+		return Missing
+	}
+	return ""
+}
+
+// DeclaredContentLength is the Content-Length a request described by its
+// headers and body declares, for requests that did not arrive over HTTP
+// (explain, self-test): the Content-Length header when present, -1 for a
+// chunked transfer or an invalid header, otherwise the body size. Header
+// keys must be canonical.
+func DeclaredContentLength(headers map[string]string, body []byte) int64 {
+	if v, ok := headers["Content-Length"]; ok {
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil || n < 0 {
+			return -1
+		}
+		return n
+	}
+	if strings.EqualFold(strings.TrimSpace(headers["Transfer-Encoding"]), "chunked") {
+		return -1
+	}
+	return int64(len(body))
 }
 
 func buildFieldValues(req *IncomingRequest) map[string]string {
 	values := map[string]string{
 		"method": req.Method,
 		"path":   req.Path,
+		"host":   req.Host,
+		"secure": strconv.FormatBool(req.Secure),
+	}
+	// An unknown length (chunked transfer) is left out, so content_length
+	// matchers never match it.
+	if req.ContentLength >= 0 {
+		values["content_length"] = strconv.FormatInt(req.ContentLength, 10)
+	}
+	if !req.Now.IsZero() {
+		values["now"] = req.Now.Format(time.RFC3339)
 	}
 	for k, v := range req.Headers {
 		values["header:"+k] = v
 	}
+	for k, v := range req.Cookies {
+		values["cookie:"+k] = v
+	}
+	for k, v := range req.Query {
+		// Repeated parameters match on their first value, like headers.
+		first := ""
+		if len(v) > 0 {
+			first = v[0]
+		}
+		values["query:"+k] = first
+		// All values, as a JSON array, for query array predicates.
+		all, _ := json.Marshal(v)
+		values["query:"+k+":array"] = string(all)
+	}
 	return values
 }
@@ -1,6 +1,7 @@
 package match_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
@@ -92,6 +93,37 @@ func TestEvaluator_PriorityOrdering(t *testing.T) {
 	}
 }
 
+func TestEvaluator_DisabledScenarioSkipped(t *testing.T) {
+	eval := match.NewEvaluator()
+	req := &match.IncomingRequest{Method: "GET", Path: "/api/items"}
+
+	always := func(string) bool { return true }
+	high := &match.CompiledScenario{
+		ID:         "high-priority",
+		Priority:   20,
+		Predicates: []match.FieldPredicate{{Field: "method", Predicate: always}},
+	}
+	low := &match.CompiledScenario{
+		ID:         "low-priority",
+		Priority:   5,
+		Predicates: []match.FieldPredicate{{Field: "method", Predicate: always}},
+	}
+	high.SetDisabled(true)
+
+	result := eval.Evaluate(req, []*match.CompiledScenario{high, low})
+	if result.Matched == nil || result.Matched.ID != "low-priority" {
+		t.Fatalf("expected disabled scenario to be skipped, got %+v", result.Matched)
+	}
+	if c := result.Candidates[0]; c.Matched || c.FailedField != "disabled" {
+		t.Errorf("expected disabled candidate in trace, got %+v", c)
+	}
+
+	high.SetDisabled(false)
+	if result := eval.Evaluate(req, []*match.CompiledScenario{high, low}); result.Matched.ID != "high-priority" {
+		t.Errorf("expected re-enabled scenario to win, got %q", result.Matched.ID)
+	}
+}
+
 func TestEvaluator_FailedPredicateTrace(t *testing.T) {
 	eval := match.NewEvaluator()
 	req := &match.IncomingRequest{
@@ -188,3 +220,80 @@ func TestEvaluator_DeterministicIDOrdering(t *testing.T) {
 		t.Errorf("expected 'a-scenario' (first in pre-sorted order), got %q", result.Matched.ID)
 	}
 }
+
+func TestEvaluator_CollectsAllMatches(t *testing.T) {
+	eval := match.NewEvaluator()
+	req := &match.IncomingRequest{Method: "GET", Path: "/"}
+
+	always := func(string) bool { return true }
+	never := func(string) bool { return false }
+
+	candidates := []*match.CompiledScenario{
+		{ID: "a", Predicates: []match.FieldPredicate{{Field: "method", Predicate: always}}},
+		{ID: "b", Predicates: []match.FieldPredicate{{Field: "method", Predicate: never}}},
+		{ID: "c", Predicates: []match.FieldPredicate{{Field: "method", Predicate: always}}},
+	}
+
+	result := eval.Evaluate(req, candidates)
+	if result.Matched == nil || result.Matched.ID != "a" {
+		t.Fatalf("expected 'a' as first match, got %v", result.Matched)
+	}
+	if len(result.Matches) != 2 || result.Matches[0].ID != "a" || result.Matches[1].ID != "c" {
+		t.Errorf("expected matches [a c], got %v", result.Matches)
+	}
+}
+
+func TestEvaluator_MaxCandidates(t *testing.T) {
+	never := func(string) bool { return false }
+	always := func(string) bool { return true }
+
+	var candidates []*match.CompiledScenario
+	for i := range 20 {
+		candidates = append(candidates, &match.CompiledScenario{
+			ID:         fmt.Sprintf("miss-%02d", i),
+			Predicates: []match.FieldPredicate{{Field: "method", Predicate: never}},
+		})
+	}
+	candidates = append(candidates, &match.CompiledScenario{
+		ID:         "hit",
+		Predicates: []match.FieldPredicate{{Field: "method", Predicate: always}},
+	})
+
+	eval := match.NewEvaluator()
+	eval.SetMaxCandidates(5)
+	result := eval.Evaluate(&match.IncomingRequest{Method: "GET", Path: "/x"}, candidates)
+
+	if result.Matched == nil || result.Matched.ID != "hit" {
+		t.Fatal("expected match beyond the cap to still be selected")
+	}
+	if len(result.Candidates) != 6 {
+		t.Fatalf("expected 5 candidates plus the matched one, got %d", len(result.Candidates))
+	}
+	if result.Candidates[4].ScenarioID != "miss-04" {
+		t.Errorf("expected first 5 candidates kept, got %q at index 4", result.Candidates[4].ScenarioID)
+	}
+	if last := result.Candidates[5]; last.ScenarioID != "hit" || !last.Matched {
+		t.Errorf("expected matched candidate recorded last, got %+v", last)
+	}
+	if result.CandidatesOmitted != 15 {
+		t.Errorf("expected 15 omitted candidates, got %d", result.CandidatesOmitted)
+	}
+
+	// Without a match, only the first N are recorded.
+	result = eval.Evaluate(&match.IncomingRequest{Method: "GET", Path: "/x"}, candidates[:20])
+	if len(result.Candidates) != 5 || result.CandidatesOmitted != 15 {
+		t.Errorf("expected 5 recorded and 15 omitted, got %d and %d", len(result.Candidates), result.CandidatesOmitted)
+	}
+}
+
+func TestEvaluator_NoCandidateCapByDefault(t *testing.T) {
+	var candidates []*match.CompiledScenario
+	for i := range 50 {
+		candidates = append(candidates, &match.CompiledScenario{ID: fmt.Sprintf("s-%02d", i)})
+	}
+
+	result := match.NewEvaluator().Evaluate(&match.IncomingRequest{Method: "GET"}, candidates)
+	if len(result.Candidates) != 50 || result.CandidatesOmitted != 0 {
+		t.Errorf("expected all 50 candidates recorded, got %d (omitted %d)", len(result.Candidates), result.CandidatesOmitted)
+	}
+}
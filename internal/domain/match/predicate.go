@@ -1,5 +1,15 @@
 package match
 
+import (
+	"errors"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
 // Predicate tests a string value and returns true if it matches.
 type Predicate func(string) bool
 
@@ -52,16 +62,153 @@ type FieldPredicate struct {
 
 // CompiledScenario holds a scenario with its compiled field predicates.
 type CompiledScenario struct {
-	ID         string
-	Name       string
-	Priority   int
-	Method     string
-	PathKey    string
+	ID       string
+	Name     string
+	Priority int
+	Method   string
+	PathKey  string
+	// Methods lists every method the scenario serves when it has several;
+	// Method and PathKey then hold the first. The index registers the
+	// scenario under one key per method.
+	Methods    []string
 	Predicates []FieldPredicate
 	Response   CompiledResponse
-	Policy     *CompiledPolicy
+	// Responses is the response sequence, served one per match by
+	// NextResponse. Response holds the first entry. Empty means Response
+	// is always served.
+	Responses []CompiledResponse
+	// Cycle restarts Responses after the last entry instead of repeating it.
+	Cycle    bool
+	Policy   *CompiledPolicy
+	Profiles []string
+	// Static is non-nil for directory mounts, which bypass predicate evaluation.
+	Static *CompiledStatic
+	// Warnings lists non-fatal problems found while compiling.
+	Warnings []string
+	// HostPattern is the compiled when.host pattern; its named groups are
+	// exposed to templates as host params. Nil when no host is configured.
+	HostPattern *regexp.Regexp
+	// Deprecated responses carry Deprecation and Warning headers.
+	Deprecated         bool
+	DeprecationMessage string
+	// RequireContentType lists the accepted request media types, lower-cased
+	// and without parameters; "type/*" accepts any subtype. It is checked
+	// before Predicates. Empty means any Content-Type is accepted.
+	RequireContentType []string
+	// Quiet suppresses the info logs that follow a match.
+	Quiet bool
+	// Example is the scenario's example request for the startup self-test,
+	// or nil. Callers must copy it before evaluation.
+	Example *IncomingRequest
+
+	// served counts matches for Responses; safe for concurrent requests.
+	served atomic.Uint64
+	// disabled scenarios are skipped by the evaluator but stay indexed.
+	disabled atomic.Bool
+}
+
+// SetDisabled enables or disables the scenario for matching. It is safe to
+// call while requests are being evaluated.
+func (cs *CompiledScenario) SetDisabled(disabled bool) {
+	cs.disabled.Store(disabled)
+}
+
+// Disabled reports whether the scenario is skipped by the evaluator.
+func (cs *CompiledScenario) Disabled() bool {
+	return cs.disabled.Load()
+}
+
+// NextResponse returns the response for the current match and advances the
+// sequence when the scenario has one.
+func (cs *CompiledScenario) NextResponse() CompiledResponse {
+	if len(cs.Responses) == 0 {
+		return cs.Response
+	}
+	n := cs.served.Add(1) - 1
+	if cs.Cycle {
+		return cs.Responses[n%uint64(len(cs.Responses))]
+	}
+	return cs.Responses[min(n, uint64(len(cs.Responses)-1))]
+}
+
+// Served returns how many matches the response sequence has served.
+func (cs *CompiledScenario) Served() uint64 {
+	return cs.served.Load()
+}
+
+// SetServed moves the response sequence as if n matches had been served.
+func (cs *CompiledScenario) SetServed(n uint64) {
+	cs.served.Store(n)
+}
+
+// AcceptsContentType reports whether a request Content-Type header value
+// satisfies RequireContentType. Parameters such as charset are ignored.
+func (cs *CompiledScenario) AcceptsContentType(contentType string) bool {
+	if len(cs.RequireContentType) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, want := range cs.RequireContentType {
+		if want == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(want, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// PathKeys returns every index key the scenario is registered under: one
+// METHOD:path key per method.
+func (cs *CompiledScenario) PathKeys() []string {
+	if len(cs.Methods) <= 1 {
+		return []string{cs.PathKey}
+	}
+	path := cs.PathKey[len(cs.Method)+1:]
+	keys := make([]string, 0, len(cs.Methods))
+	for _, m := range cs.Methods {
+		keys = append(keys, m+":"+path)
+	}
+	return keys
+}
+
+// HostParams returns the named segments captured from host by HostPattern.
+func (cs *CompiledScenario) HostParams(host string) map[string]string {
+	if cs.HostPattern == nil {
+		return nil
+	}
+	m := cs.HostPattern.FindStringSubmatch(host)
+	if m == nil {
+		return nil
+	}
+	params := make(map[string]string)
+	for i, name := range cs.HostPattern.SubexpNames() {
+		if name != "" {
+			params[name] = m[i]
+		}
+	}
+	return params
+}
+
+// CompiledStatic is a resolved static directory mount.
+type CompiledStatic struct {
+	Dir        string // absolute path within the mock root
+	PathPrefix string // without trailing slash, e.g. "/cdn"
 }
 
+// Missing is the value predicates receive for an optional field that is not
+// present in the request, such as an absent query parameter or header. It lets
+// presence checks tell a missing field apart from an empty one.
+const Missing = "\x00missing"
+
+// ErrBodyFileNotFound is returned by renderers when a body_file resolved at
+// request time does not exist.
+var ErrBodyFileNotFound = errors.New("body file not found")
+
 // BodyRenderer renders a response body dynamically. Nil means static body.
 type BodyRenderer interface {
 	Render(ctx RenderContext) ([]byte, error)
@@ -74,8 +221,14 @@ type RenderContext struct {
 	Headers     map[string]string
 	QueryParams map[string]string
 	PathParams  map[string]string
+	HostParams  map[string]string
 	Body        []byte
 	Now         string // ISO-8601 timestamp
+	// RemoteIP is the client IP, taken from forwarding headers when present.
+	RemoteIP string
+	// CanonicalizeBody re-formats a JSON request body before templating:
+	// "" leaves it as received, "compact" or "pretty" sort keys and re-indent.
+	CanonicalizeBody string
 }
 
 // CompiledResponse is a resolved response ready to serve.
@@ -83,15 +236,152 @@ type CompiledResponse struct {
 	Status      int
 	Headers     map[string]string
 	Body        []byte       // used when Renderer is nil
+	BodyFile    string       // body_file path for static file bodies; served with Range support
 	Renderer    BodyRenderer // non-nil for dynamic bodies
 	ContentType string
+	// TemplateName names Renderer's source in errors: the body_file path or
+	// "inline".
+	TemplateName string
+	// Charset is the canonical charset the body is served in ("" = as-is).
+	Charset string
+	// Encoder transcodes the final UTF-8 body into Charset. Nil means no transcoding.
+	Encoder func([]byte) ([]byte, error)
+	// Raw bodies are served verbatim: no pagination, null stripping or
+	// Content-Type inference.
+	Raw bool
+	// Mirror serves the request body with the request's Content-Type
+	// instead of Body. Mirror responses are also Raw.
+	Mirror bool
+	// OmitNulls strips null-valued object keys from JSON bodies after rendering.
+	OmitNulls bool
+	// CanonicalizeBody is passed to the renderer as RenderContext.CanonicalizeBody.
+	CanonicalizeBody string
+	// Switch, when non-nil, selects a per-case response from the request body.
+	Switch *CompiledSwitch
+	// TimeSwitch, when non-nil, selects a per-case response by request time.
+	// It is resolved before Switch.
+	TimeSwitch *CompiledTimeSwitch
+	// Cache, when non-nil, sets Cache-Control and an Expires header relative
+	// to the request time.
+	Cache *CompiledCache
+	// BodyFileMissingStatus is served when a templated body_file resolves to
+	// a file that does not exist.
+	BodyFileMissingStatus int
+	// TruncateAtBytes, when positive, cuts the body after N bytes and closes
+	// the connection.
+	TruncateAtBytes int
+	// Compression lists the offered content codings in preference order,
+	// negotiated against Accept-Encoding per request.
+	Compression []string
+	// Location renders the Location header of created responses. Nil means none.
+	Location BodyRenderer
+	// Proxy, when non-nil, forwards the request upstream instead of
+	// rendering this response.
+	Proxy *CompiledProxy
+}
+
+// CompiledProxy is a resolved upstream for proxied responses.
+type CompiledProxy struct {
+	Target      *url.URL
+	StripPrefix string
+	Timeout     time.Duration
+	Record      bool
+}
+
+// CompiledCache holds a precomputed Cache-Control value and the max age used
+// to derive Expires.
+type CompiledCache struct {
+	CacheControl string
+	MaxAge       time.Duration
+}
+
+// CompiledSwitch selects a response by a value extracted from the request body.
+type CompiledSwitch struct {
+	// Extract returns the discriminator value, or false if the body has none.
+	Extract func(body []byte) (string, bool)
+	Cases   map[string]CompiledResponse
+	// Default is served when no case matches. Nil means the enclosing response.
+	Default *CompiledResponse
+}
+
+// Resolve returns the response to serve for a request body: the matching
+// switch case, the switch default, or r itself.
+func (r CompiledResponse) Resolve(body []byte) CompiledResponse {
+	if r.Switch == nil {
+		return r
+	}
+	if v, ok := r.Switch.Extract(body); ok {
+		if c, ok := r.Switch.Cases[v]; ok {
+			return c
+		}
+	}
+	if r.Switch.Default != nil {
+		return *r.Switch.Default
+	}
+	resolved := r
+	resolved.Switch = nil
+	return resolved
+}
+
+// CompiledTimeSwitch selects a response by the request time.
+type CompiledTimeSwitch struct {
+	Cases []CompiledTimeCase
+	// Default is served when no case is active. Nil means the enclosing response.
+	Default *CompiledResponse
+}
+
+// CompiledTimeCase is a response served while Active reports true.
+type CompiledTimeCase struct {
+	Active   func(now time.Time) bool
+	Response CompiledResponse
+}
+
+// ResolveTime returns the response to serve at now: the first active time
+// case, the time switch default, or r itself.
+func (r CompiledResponse) ResolveTime(now time.Time) CompiledResponse {
+	if r.TimeSwitch == nil {
+		return r
+	}
+	for _, c := range r.TimeSwitch.Cases {
+		if c.Active(now) {
+			return c.Response
+		}
+	}
+	if r.TimeSwitch.Default != nil {
+		return *r.TimeSwitch.Default
+	}
+	resolved := r
+	resolved.TimeSwitch = nil
+	return resolved
 }
 
 // CompiledPolicy holds resolved policy configuration.
 type CompiledPolicy struct {
-	RateLimit  *CompiledRateLimit
-	Latency    *CompiledLatency
-	Pagination *CompiledPagination
+	RateLimit   *CompiledRateLimit
+	Latency     *CompiledLatency
+	Pagination  *CompiledPagination
+	LoadBalance *CompiledLoadBalance
+	Fault       *CompiledFault
+}
+
+// Fault types.
+const (
+	// FaultResetConnection drops the connection without a response.
+	FaultResetConnection = "reset_connection"
+	// FaultEmptyResponse ends the exchange without writing a status or body.
+	FaultEmptyResponse = "empty_response"
+)
+
+// CompiledFault is a validated fault policy.
+type CompiledFault struct {
+	Type string
+	Rate float64 // in (0, 1]
+}
+
+// CompiledLoadBalance holds the weight used when several equally-ranked
+// load-balanced scenarios match the same request.
+type CompiledLoadBalance struct {
+	Weight int
 }
 
 // CompiledRateLimit holds rate limit parameters.
@@ -99,12 +389,15 @@ type CompiledRateLimit struct {
 	Rate  float64
 	Burst int
 	Key   string
+	// Response is served instead of the default 429 error. Nil keeps the default.
+	Response *CompiledResponse
 }
 
 // CompiledLatency holds latency simulation parameters.
 type CompiledLatency struct {
-	FixedMs  int
-	JitterMs int
+	FixedMs       int // body delay
+	JitterMs      int // added to the body delay
+	HeaderDelayMs int
 }
 
 // CompiledPagination holds resolved pagination configuration.
@@ -116,8 +409,14 @@ type CompiledPagination struct {
 	LimitParam  string
 	DefaultSize int
 	MaxSize     int
-	DataPath    string
-	Envelope    CompiledPaginationEnvelope
+	// GlobalMaxSize is the operator-wide page size ceiling (0 = unlimited).
+	GlobalMaxSize int
+	DataPath      string
+	// TotalItemsPath or TotalItems, when set, give the dataset's true size;
+	// the body is then the current page and is not sliced.
+	TotalItemsPath string
+	TotalItems     *int
+	Envelope       CompiledPaginationEnvelope
 }
 
 // CompiledPaginationEnvelope holds resolved envelope field names.
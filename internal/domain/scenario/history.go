@@ -0,0 +1,28 @@
+package scenario
+
+import (
+	"context"
+	"time"
+)
+
+// Version describes one saved snapshot of a scenario's YAML.
+type Version struct {
+	// ID is the sortable snapshot identifier (a UTC timestamp), used in URLs.
+	ID      string
+	SavedAt time.Time
+	Size    int
+}
+
+// History is the port for keeping previous versions of scenarios edited
+// through the admin API.
+type History interface {
+	// Record stores yamlContent as a new version of scenario id.
+	Record(ctx context.Context, id string, yamlContent []byte) error
+
+	// List returns the stored versions of scenario id, newest first.
+	List(ctx context.Context, id string) ([]Version, error)
+
+	// Get returns the YAML of one version.
+	// Returns ErrNotFound if the version does not exist.
+	Get(ctx context.Context, id, version string) ([]byte, error)
+}
@@ -7,7 +7,37 @@ type Scenario struct {
 	Priority int
 	When     WhenClause
 	Response Response
-	Policy   *Policy
+	// Responses, when set, replaces Response with a sequence served one per
+	// match: after the last entry the sequence restarts if Cycle is set,
+	// otherwise the last entry repeats.
+	Responses []Response
+	Cycle     bool
+	Policy    *Policy
+	// Profiles restricts loading to the listed profiles. Empty means always load.
+	Profiles []string
+	// Static, when set, mounts a directory of files instead of matching When
+	// and serving Response.
+	Static *StaticMount
+	// Kind selects a built-in behavior: "" serves Response, KindMirror
+	// answers with the request body and Content-Type.
+	Kind string
+	// Deprecated marks the mocked endpoint as deprecated: responses carry
+	// Deprecation and Warning headers, the latter with DeprecationMessage.
+	Deprecated         bool
+	DeprecationMessage string
+	// RequireContentType lists the media types the request body may carry.
+	// Requests with any other Content-Type are rejected with 415 instead of
+	// falling through to 404. Empty means any Content-Type is accepted.
+	RequireContentType []string
+	// Quiet suppresses the info logs that follow a match, e.g. for noisy
+	// health checks. Set with `log: false`.
+	Quiet bool
+	// Dynamic marks scenarios generated at runtime, such as proxy
+	// recordings. Only dynamic scenarios are subject to eviction.
+	Dynamic bool
+	// ExampleRequest is a sample request that should match this scenario.
+	// It is only used by the startup self-test.
+	ExampleRequest *ExampleRequest
 
 	// SourceFile is the absolute path to the YAML file this scenario was loaded from.
 	SourceFile string
@@ -16,37 +46,136 @@ type Scenario struct {
 	SourceIndex int
 }
 
+// ExampleRequest describes a request for the self-test. Method and Path
+// default to the scenario's own; Path is required when when.path has
+// parameters.
+type ExampleRequest struct {
+	Method  string
+	Path    string
+	Headers map[string]string
+	Query   map[string]string
+	Body    string
+}
+
+// KindMirror scenarios return the request body unchanged, with the
+// request's Content-Type.
+const KindMirror = "mirror"
+
 // WhenClause defines the conditions for matching an incoming request.
 type WhenClause struct {
-	Method  string
+	Method string
+	// Methods, when set, lets one scenario serve several methods and takes
+	// precedence over Method.
+	Methods []string
 	Path    string
+	// Host matches the request host by DNS label: "*" matches any single
+	// label and "{name}" captures one for templates, e.g. "{tenant}.example.com".
+	Host    string
 	Headers map[string]StringMatcher
-	Body    *BodyClause
+	// UserAgent and Referer are shorthands for matching those headers. They
+	// apply in addition to any Headers entry for the same header.
+	UserAgent *StringMatcher
+	Referer   *StringMatcher
+	// Query matches query parameters by name against their first value.
+	Query map[string]StringMatcher
+	// Cookies matches request cookies by name. When a name is sent twice the
+	// first cookie wins; values are URL-decoded before matching.
+	Cookies map[string]StringMatcher
+	// QueryArrays matches every value of repeated query parameters.
+	QueryArrays map[string]QueryArrayMatcher
+	Body        *BodyClause
+	// ContentLength matches the declared Content-Length of the request,
+	// which may differ from the actual body size. Requests without one
+	// (chunked uploads) never match.
+	ContentLength *NumericMatcher
+	// BodyHash matches a digest of the raw request body.
+	BodyHash *BodyHash
+	// BodyPrefix matches the leading bytes of the raw request body, e.g. a
+	// file format's magic number.
+	BodyPrefix *BodyPrefix
+	// Schedule is a five-field cron expression; the scenario only matches
+	// during minutes the expression selects.
+	Schedule string
+	// Secure, when set, matches only requests received over TLS (true) or
+	// plain HTTP (false).
+	Secure *bool
+	// Expr is a boolean Expr expression over the request body; json(path)
+	// returns typed JSONPath values so fields can be compared, e.g.
+	// "json('$.start') < json('$.end')".
+	Expr string
+}
+
+// MethodList returns the methods the clause matches: Methods when set,
+// otherwise Method alone.
+func (w WhenClause) MethodList() []string {
+	if len(w.Methods) > 0 {
+		return w.Methods
+	}
+	if w.Method == "" {
+		return nil
+	}
+	return []string{w.Method}
 }
 
 // BodyClause represents conditions on the request body.
 type BodyClause struct {
+	// Decode, when set, is a JSONPath to a base64-encoded string in the JSON
+	// body. The decoded payload replaces the body for the rest of the clause,
+	// and ContentType describes it.
+	Decode      string
 	ContentType string
-	Conditions  []BodyCondition
-	All         []BodyClause
-	Any         []BodyClause
-	Not         *BodyClause
+	// Regex matches the raw body whatever the content type.
+	Regex string
+	// KeyOrder, when set, requires the body to be a JSON object whose
+	// top-level keys appear in the listed order.
+	KeyOrder   *KeyOrder
+	Conditions []BodyCondition
+	All        []BodyClause
+	Any        []BodyClause
+	Not        *BodyClause
+}
+
+// KeyOrder lists the expected top-level keys of a JSON object body, in
+// order. By default the body must have exactly these keys; AllowExtra lets
+// other keys appear anywhere as long as the listed ones keep their order.
+type KeyOrder struct {
+	Keys       []string
+	AllowExtra bool
 }
 
 // BodyCondition represents a single body extraction + matching rule.
 type BodyCondition struct {
-	// Extractor is a JSONPath or XPath expression.
+	// Extractor is a JSONPath, JSON Pointer or XPath expression, or a
+	// dot-separated field number path for protobuf bodies.
 	Extractor string
 	// Matcher is the string matcher applied to the extracted value.
 	Matcher StringMatcher
+	// Min and Max are optional inclusive numeric bounds on the extracted value.
+	// When either is set, non-numeric values never match.
+	Min *float64
+	Max *float64
+	// AllRegex, when set, requires the JSONPath to select an array whose
+	// every element, stringified, matches the regex. It replaces Matcher.
+	// AllowEmpty makes an empty array match; by default it does not.
+	AllRegex   string
+	AllowEmpty bool
 }
 
 // StringMatcher represents a string matching rule.
 // If Exact is non-empty, it's an exact match (prefixed with "=" in YAML).
-// Otherwise, Pattern is treated as a regex.
+// Otherwise, Pattern is treated as a regex. Exists ("!exists" in YAML) only
+// requires the value to be present, whatever it is; Absent ("!absent")
+// requires it to be missing. Numeric, when set, compares the value as a
+// number (">100", ">=1.5", "<0", "<=10" or "==42" in YAML) and Pattern keeps
+// the source text; non-numeric values never match. Negated ("!=value" in YAML)
+// inverts the Exact comparison: anything but Exact matches.
 type StringMatcher struct {
 	Exact   string
 	Pattern string
+	Exists  bool
+	Absent  bool
+	Negated bool
+	Numeric *NumericMatcher
 }
 
 // IsExact returns true if this matcher uses exact comparison.
@@ -62,21 +191,155 @@ func (m StringMatcher) Value() string {
 	return m.Pattern
 }
 
+// QueryArrayMatcher matches all values of a repeated query parameter, e.g.
+// ?id=1&id=2. Contains requires each listed value to be present; Count
+// bounds the number of values (0 when the parameter is absent).
+type QueryArrayMatcher struct {
+	Contains []string
+	Count    *NumericMatcher
+}
+
+// NumericMatcher represents a numeric comparison rule.
+// All non-nil bounds must hold for the matcher to succeed.
+type NumericMatcher struct {
+	Eq  *float64
+	Gt  *float64
+	Gte *float64
+	Lt  *float64
+	Lte *float64
+}
+
+// BodyHash pins a scenario to an exact payload by digest.
+type BodyHash struct {
+	Algorithm string // "md5", "sha1", "sha256" or "sha512"
+	Expected  string // hex-encoded digest, case-insensitive
+}
+
+// BodyPrefix is a byte signature the request body must start with, given
+// as exactly one of Hex or Base64.
+type BodyPrefix struct {
+	Hex    string // whitespace is ignored, e.g. "89 50 4E 47"
+	Base64 string
+}
+
+// StaticMount serves files from Dir (relative to the mock root) under PathPrefix.
+type StaticMount struct {
+	Dir        string
+	PathPrefix string
+}
+
 // Response defines what the mock server returns.
 type Response struct {
-	Status      int
-	Headers     map[string]string
-	Body        string
+	Status  int
+	Headers map[string]string
+	Body    string
+	// BodyFile may contain ${ } expressions to pick the file per request.
 	BodyFile    string
 	ContentType string
-	Engine      string // "" = static, "expr", "jinja2"
+	Engine      string // "" = static, "expr", "jinja2", "go"
+	Charset     string // "" = UTF-8 as authored, otherwise an IANA charset name
+	OmitNulls   bool   // strip null-valued keys from JSON bodies before writing
+	// CanonicalizeBody re-formats the JSON request body seen by templates:
+	// "" = as received, "compact" or "pretty".
+	CanonicalizeBody string
+	// Raw serves Body or BodyFile byte for byte: no templating, no body
+	// post-processing and no Content-Type inference. Used to serve malformed
+	// payloads on purpose.
+	Raw bool
+	// Switch, when set, picks the response by a value in the request body.
+	Switch *ResponseSwitch
+	// TimeSwitch, when set, picks the response by the request time.
+	TimeSwitch *TimeSwitch
+	// Cache, when set, generates Cache-Control and Expires headers.
+	Cache *Cache
+	// TruncateAtBytes, when positive, sends only the first N body bytes and
+	// then drops the connection, simulating a truncated response.
+	TruncateAtBytes int
+	// Compression lists the content codings ("br", "gzip") the response may
+	// be compressed with, in preference order. Empty means never compress.
+	Compression []string
+	// Proxy, when set, forwards the request to a real upstream and serves its
+	// response instead of the fields above.
+	Proxy *Proxy
+	// Created, when set, makes the status default to 201 and adds a Location
+	// header pointing at the new resource.
+	Created *Created
+	// BodyFileMissingStatus is returned when a templated BodyFile resolves
+	// to a missing file (0 = 404).
+	BodyFileMissingStatus int
+	// Generate, when set, builds the body as a JSON array of fake objects
+	// instead of Body or BodyFile.
+	Generate *Generate
+}
+
+// Generate describes a list of generated objects: Count objects, each with
+// one value per field name, produced by the named fake type ("uuid",
+// "name", "email", ...).
+type Generate struct {
+	Count  int
+	Fields map[string]string
+}
+
+// Created describes a resource-creating response. Location may contain
+// ${ } expressions, e.g. "/orders/${uuid()}".
+type Created struct {
+	Location string
+}
+
+// Cache declares HTTP caching semantics for a response.
+type Cache struct {
+	MaxAge     int    // seconds
+	Visibility string // "", "public" or "private"
+	Immutable  bool
+}
+
+// ResponseSwitch dispatches on a JSONPath value extracted from the request
+// body. Cases are keyed by the extracted value; when none matches, Default is
+// served, or the enclosing response if Default is nil.
+type ResponseSwitch struct {
+	On      string
+	Cases   map[string]Response
+	Default *Response
+}
+
+// TimeSwitch picks a response by the request time: the first case whose
+// cron schedule covers it is served; otherwise Default, or the enclosing
+// response if Default is nil.
+type TimeSwitch struct {
+	Cases   []TimeCase
+	Default *Response
 }
 
-// Policy defines rate limiting, latency simulation, and pagination.
+// TimeCase is a response served while Schedule, a five-field cron
+// expression, covers the request time.
+type TimeCase struct {
+	Schedule string
+	Response Response
+}
+
+// Policy defines rate limiting, latency simulation, pagination, and load balancing.
 type Policy struct {
-	RateLimit  *RateLimit
-	Latency    *Latency
-	Pagination *Pagination
+	RateLimit   *RateLimit
+	Latency     *Latency
+	Pagination  *Pagination
+	LoadBalance *LoadBalance
+	Fault       *Fault
+}
+
+// Fault simulates a network failure instead of serving the response.
+type Fault struct {
+	// Type is "reset_connection" or "empty_response".
+	Type string
+	// Rate is the fraction of matching requests that fault, in (0, 1].
+	// Zero means every request.
+	Rate float64
+}
+
+// LoadBalance opts a scenario into weighted random selection among other
+// load-balanced scenarios of the same priority that match the same request.
+type LoadBalance struct {
+	// Weight is the relative selection weight. Zero defaults to the scenario priority.
+	Weight int
 }
 
 // RateLimit configures token-bucket rate limiting.
@@ -84,12 +347,29 @@ type RateLimit struct {
 	Rate  float64
 	Burst int
 	Key   string
+	// Response replaces the default 429 JSON error when the limit is hit.
+	// Its status defaults to 429.
+	Response *Response
+}
+
+// Proxy forwards matched requests to an upstream base URL.
+type Proxy struct {
+	// Target is the upstream base URL; the request path is appended to its path.
+	Target string
+	// StripPrefix is removed from the request path before it is appended.
+	StripPrefix string
+	TimeoutMs   int // 0 = 30 seconds
+	// Record saves each upstream response as a new scenario.
+	Record bool
 }
 
-// Latency configures response delay simulation.
+// Latency configures response delay simulation in two phases: HeaderDelayMs
+// elapses before the status line and headers are sent, FixedMs (plus jitter)
+// elapses between the headers and the body.
 type Latency struct {
-	FixedMs  int
-	JitterMs int
+	FixedMs       int
+	JitterMs      int
+	HeaderDelayMs int
 }
 
 // PaginationStyle determines how pagination parameters are interpreted.
@@ -110,7 +390,11 @@ type Pagination struct {
 	DefaultSize int
 	MaxSize     int
 	DataPath    string
-	Envelope    PaginationEnvelope
+	// TotalItemsSource gives the dataset's true size for bodies that are
+	// already a single page: a JSONPath into the body (e.g. "$.meta.total")
+	// or a fixed number. When set, the body is not sliced.
+	TotalItemsSource string
+	Envelope         PaginationEnvelope
 }
 
 // PaginationEnvelope configures the field names in the paginated response wrapper.
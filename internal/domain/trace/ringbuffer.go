@@ -1,6 +1,9 @@
 package trace
 
-import "sync"
+import (
+	"slices"
+	"sync"
+)
 
 // RingBuffer is a concurrent-safe fixed-size ring buffer for trace entries.
 type RingBuffer struct {
@@ -9,8 +12,16 @@ type RingBuffer struct {
 	size    int
 	head    int
 	count   int
+	lastID  uint64
+	// subscribers receive each added entry; keyed by the receive-only view
+	// handed out by Subscribe.
+	subscribers map[<-chan Entry]chan Entry
 }
 
+// subscriberBuffer is how many entries a subscriber may lag behind before
+// it starts missing them.
+const subscriberBuffer = 64
+
 // NewRingBuffer creates a ring buffer that holds up to size entries.
 func NewRingBuffer(size int) *RingBuffer {
 	if size <= 0 {
@@ -22,16 +33,53 @@ func NewRingBuffer(size int) *RingBuffer {
 	}
 }
 
-// Add appends an entry to the ring buffer, overwriting the oldest if full.
+// Add appends an entry to the ring buffer, overwriting the oldest if full,
+// and assigns it the next ID.
 func (rb *RingBuffer) Add(e Entry) {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
 
+	rb.lastID++
+	e.ID = rb.lastID
 	rb.entries[rb.head] = e
 	rb.head = (rb.head + 1) % rb.size
 	if rb.count < rb.size {
 		rb.count++
 	}
+
+	for _, ch := range rb.subscribers {
+		select {
+		case ch <- e:
+		default: // slow subscriber: drop rather than block requests
+		}
+	}
+}
+
+// Subscribe returns a channel that receives every entry added from now on.
+// A subscriber that falls behind misses entries instead of slowing Add.
+// Call Unsubscribe to release it.
+func (rb *RingBuffer) Subscribe() <-chan Entry {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	ch := make(chan Entry, subscriberBuffer)
+	if rb.subscribers == nil {
+		rb.subscribers = make(map[<-chan Entry]chan Entry)
+	}
+	rb.subscribers[ch] = ch
+	return ch
+}
+
+// Unsubscribe stops delivery to a channel returned by Subscribe and closes
+// it. Unknown channels are ignored.
+func (rb *RingBuffer) Unsubscribe(sub <-chan Entry) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if ch, ok := rb.subscribers[sub]; ok {
+		delete(rb.subscribers, sub)
+		close(ch)
+	}
 }
 
 // Last returns the last n entries in chronological order.
@@ -54,6 +102,51 @@ func (rb *RingBuffer) Last(n int) []Entry {
 	return result
 }
 
+// LastMatching returns the last n entries for which keep reports true, in
+// chronological order.
+func (rb *RingBuffer) LastMatching(n int, keep func(Entry) bool) []Entry {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+
+	if n <= 0 {
+		return nil
+	}
+
+	var result []Entry
+	for i := 1; i <= rb.count && len(result) < n; i++ {
+		e := rb.entries[(rb.head-i+rb.size)%rb.size]
+		if keep(e) {
+			result = append(result, e)
+		}
+	}
+	slices.Reverse(result)
+	return result
+}
+
+// Get returns the stored entry with the given ID, or false once it has been
+// overwritten.
+func (rb *RingBuffer) Get(id uint64) (Entry, bool) {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+
+	age := rb.lastID - id // 0 for the newest entry
+	if id == 0 || id > rb.lastID || age >= uint64(rb.count) {
+		return Entry{}, false
+	}
+	return rb.entries[(rb.head-1-int(age)+rb.size)%rb.size], true
+}
+
+// Clear drops every stored entry. IDs keep increasing, so an ID never
+// refers to two different entries.
+func (rb *RingBuffer) Clear() {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.entries = make([]Entry, rb.size)
+	rb.head = 0
+	rb.count = 0
+}
+
 // Count returns the number of entries currently stored.
 func (rb *RingBuffer) Count() int {
 	rb.mu.RLock()
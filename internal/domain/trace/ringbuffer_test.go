@@ -1,6 +1,7 @@
 package trace_test
 
 import (
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -125,3 +126,132 @@ func TestRingBuffer_Concurrency(t *testing.T) {
 		t.Errorf("expected count %d, got %d", n, rb.Count())
 	}
 }
+
+func TestRingBuffer_Subscribe(t *testing.T) {
+	rb := trace.NewRingBuffer(10)
+	rb.Add(trace.Entry{Path: "/before"})
+
+	sub := rb.Subscribe()
+	rb.Add(trace.Entry{Path: "/after"})
+
+	select {
+	case e := <-sub:
+		if e.Path != "/after" {
+			t.Errorf("expected /after, got %s", e.Path)
+		}
+	default:
+		t.Fatal("expected an entry for the subscriber")
+	}
+
+	rb.Unsubscribe(sub)
+	if _, ok := <-sub; ok {
+		t.Error("expected channel to be closed after Unsubscribe")
+	}
+	rb.Add(trace.Entry{Path: "/ignored"}) // must not panic on the closed channel
+	rb.Unsubscribe(sub)                   // unknown channels are ignored
+}
+
+func TestRingBuffer_SlowSubscriberDoesNotBlock(t *testing.T) {
+	rb := trace.NewRingBuffer(10)
+	sub := rb.Subscribe()
+	defer rb.Unsubscribe(sub)
+
+	for range 1000 {
+		rb.Add(trace.Entry{Path: "/flood"})
+	}
+	if rb.Count() != 10 {
+		t.Errorf("expected count 10, got %d", rb.Count())
+	}
+}
+
+func TestRingBuffer_Get(t *testing.T) {
+	rb := trace.NewRingBuffer(3)
+	for _, p := range []string{"/a", "/b", "/c", "/d"} {
+		rb.Add(trace.Entry{Path: p})
+	}
+
+	tests := []struct {
+		id   uint64
+		path string
+		ok   bool
+	}{
+		{0, "", false},
+		{1, "", false}, // overwritten
+		{2, "/b", true},
+		{4, "/d", true},
+		{5, "", false},
+	}
+	for _, tt := range tests {
+		e, ok := rb.Get(tt.id)
+		if ok != tt.ok || e.Path != tt.path {
+			t.Errorf("Get(%d) = %q, %v; want %q, %v", tt.id, e.Path, ok, tt.path, tt.ok)
+		}
+		if ok && e.ID != tt.id {
+			t.Errorf("Get(%d) returned entry with ID %d", tt.id, e.ID)
+		}
+	}
+}
+
+func TestRingBuffer_Clear(t *testing.T) {
+	rb := trace.NewRingBuffer(5)
+	for range 3 {
+		rb.Add(trace.Entry{Path: "/old"})
+	}
+	rb.Clear()
+
+	if rb.Count() != 0 || len(rb.Last(5)) != 0 {
+		t.Fatalf("expected empty buffer after Clear, got %d entries", rb.Count())
+	}
+	rb.Add(trace.Entry{Path: "/new"})
+	last := rb.Last(5)
+	if len(last) != 1 || last[0].Path != "/new" {
+		t.Fatalf("unexpected entries after Clear: %+v", last)
+	}
+	if last[0].ID != 4 {
+		t.Errorf("expected IDs to continue after Clear, got %d", last[0].ID)
+	}
+}
+
+func TestRingBuffer_ClearConcurrentAdd(t *testing.T) {
+	rb := trace.NewRingBuffer(10)
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				rb.Add(trace.Entry{Path: "/concurrent"})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			rb.Clear()
+			_ = rb.Last(10)
+		}()
+	}
+	wg.Wait()
+
+	if rb.Count() > 10 {
+		t.Errorf("expected at most 10 entries, got %d", rb.Count())
+	}
+}
+
+func TestRingBuffer_LastMatching(t *testing.T) {
+	rb := trace.NewRingBuffer(5)
+	for _, p := range []string{"/a1", "/b1", "/a2", "/b2", "/a3", "/a4"} {
+		rb.Add(trace.Entry{Path: p})
+	}
+	isA := func(e trace.Entry) bool { return strings.HasPrefix(e.Path, "/a") }
+
+	got := rb.LastMatching(2, isA)
+	if len(got) != 2 || got[0].Path != "/a3" || got[1].Path != "/a4" {
+		t.Errorf("unexpected last 2: %+v", got)
+	}
+	// /a1 was overwritten, so only three remain.
+	if got := rb.LastMatching(10, isA); len(got) != 3 || got[0].Path != "/a2" {
+		t.Errorf("unexpected all matching: %+v", got)
+	}
+	if got := rb.LastMatching(0, isA); got != nil {
+		t.Errorf("expected nil for n=0, got %+v", got)
+	}
+}
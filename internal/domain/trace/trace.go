@@ -4,12 +4,33 @@ import "time"
 
 // Entry represents a single match trace entry.
 type Entry struct {
-	Timestamp   time.Time         `json:"timestamp"`
-	Method      string            `json:"method"`
-	Path        string            `json:"path"`
-	MatchedID   string            `json:"matched_id"`
-	Candidates  []CandidateResult `json:"candidates"`
-	RateLimited bool              `json:"rate_limited"`
+	// ID identifies the entry within its ring buffer; assigned by Add.
+	ID                uint64            `json:"id"`
+	Timestamp         time.Time         `json:"timestamp"`
+	Method            string            `json:"method"`
+	Path              string            `json:"path"`
+	MatchedID         string            `json:"matched_id"`
+	Candidates        []CandidateResult `json:"candidates"`
+	RateLimited       bool              `json:"rate_limited"`
+	CandidatesOmitted int               `json:"candidates_omitted,omitempty"`
+	// UpstreamStatus is the status returned by the upstream of a proxied
+	// match (502 or 504 when it could not be reached).
+	UpstreamStatus int `json:"upstream_status,omitempty"`
+	// Fault is the fault injected instead of the response, if any.
+	Fault string `json:"fault,omitempty"`
+	// Request holds what matching saw, so the entry can be replayed.
+	Request *Request `json:"request,omitempty"`
+}
+
+// Request is the captured detail of a traced request.
+type Request struct {
+	Host          string              `json:"host,omitempty"`
+	Headers       map[string]string   `json:"headers,omitempty"`
+	Query         map[string][]string `json:"query,omitempty"`
+	Cookies       map[string]string   `json:"cookies,omitempty"`
+	Body          string              `json:"body,omitempty"`
+	ContentLength int64               `json:"content_length"`
+	Secure        bool                `json:"secure,omitempty"`
 }
 
 // CandidateResult records the evaluation result for a single candidate scenario.
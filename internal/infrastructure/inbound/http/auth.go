@@ -0,0 +1,38 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// SetAdminAuth protects the /__admin and /__ui routes with HTTP basic auth.
+// An empty user leaves them open. Mock routes and /__health are never
+// protected.
+func (s *Server) SetAdminAuth(user, password string) {
+	s.adminUser = user
+	s.adminPassword = password
+}
+
+// requireAdminAuth answers 401 unless the request carries the configured
+// basic-auth credentials. Both fields are compared in constant time.
+func (s *Server) requireAdminAuth(next http.Handler) http.Handler {
+	if s.adminUser == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		userOK := subtle.ConstantTimeCompare([]byte(user), []byte(s.adminUser)) == 1
+		passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(s.adminPassword)) == 1
+		if !ok || !userOK || !passwordOK {
+			w.Header().Set("WWW-Authenticate", `Basic realm="proteusmock", charset="UTF-8"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			writeJSON(w, map[string]string{
+				"error":   "unauthorized",
+				"message": "Admin credentials required",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
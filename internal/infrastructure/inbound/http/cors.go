@@ -0,0 +1,100 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSPolicy configures the cross-origin headers added to mock responses.
+type CORSPolicy struct {
+	// AllowedOrigins lists origins allowed to call the mock; "*" allows any.
+	// Empty disables CORS handling.
+	AllowedOrigins []string
+	// AllowedMethods are advertised on preflight. Empty advertises the
+	// methods the requested path is mocked for.
+	AllowedMethods []string
+	// AllowedHeaders are advertised on preflight. Empty allows whatever
+	// headers the preflight asks for.
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and auth headers. The
+	// origin is then always echoed, never "*".
+	AllowCredentials bool
+}
+
+// SetCORS enables CORS handling: allowed origins get Access-Control-*
+// headers on every mock response, and OPTIONS preflights are answered for
+// every mocked path that has no OPTIONS scenario of its own. Headers set by a
+// scenario's response still win. The /__ routes never get CORS headers, so
+// the admin API and dashboard, which expose captured requests, stay
+// same-origin.
+func (s *Server) SetCORS(policy CORSPolicy) {
+	s.cors = policy
+}
+
+func (p CORSPolicy) enabled() bool {
+	return len(p.AllowedOrigins) > 0
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or
+// "" when it is not allowed.
+func (p CORSPolicy) allowOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, o := range p.AllowedOrigins {
+		if o == "*" {
+			if p.AllowCredentials {
+				return origin
+			}
+			return "*"
+		}
+		if strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// corsMiddleware adds the allow-origin and credentials headers for allowed
+// origins before the route runs, so handlers can still override them.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		if allowed := s.cors.allowOrigin(r.Header.Get("Origin")); allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			if s.cors.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsPreflight answers OPTIONS for a mocked path whose scenarios don't
+// handle OPTIONS themselves.
+func (s *Server) corsPreflight(methods []string) http.HandlerFunc {
+	allowMethods := s.cors.AllowedMethods
+	if len(allowMethods) == 0 {
+		allowMethods = methods
+	}
+	allow := strings.Join(allowMethods, ", ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		if r.Header.Get("Access-Control-Request-Method") != "" && w.Header().Get("Access-Control-Allow-Origin") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", allow)
+			headers := strings.Join(s.cors.AllowedHeaders, ", ")
+			if headers == "" {
+				headers = r.Header.Get("Access-Control-Request-Headers")
+			}
+			if headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// corsMaxAge is how long, in seconds, browsers may cache a preflight.
+const corsMaxAge = 600
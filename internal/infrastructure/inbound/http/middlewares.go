@@ -0,0 +1,217 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// MiddlewareSpec is one step of the global transformation pipeline: the
+// name of a built-in middleware and its parameters.
+type MiddlewareSpec struct {
+	Name   string
+	Params map[string]string
+}
+
+// middlewareFactory builds a middleware from its parameters, rejecting
+// missing or invalid ones.
+type middlewareFactory func(params map[string]string) (func(http.Handler) http.Handler, error)
+
+// middlewareRegistry holds the built-in middlewares by name.
+var middlewareRegistry = map[string]middlewareFactory{
+	"request_header":  newRequestHeaderMiddleware,
+	"response_header": newResponseHeaderMiddleware,
+	"redact":          newRedactMiddleware,
+	"compress":        newCompressMiddleware,
+}
+
+// ParseMiddlewareSpec parses "name" or "name:key=value,key=value" into a
+// spec. Values may not contain commas.
+func ParseMiddlewareSpec(s string) (MiddlewareSpec, error) {
+	name, rawParams, _ := strings.Cut(s, ":")
+	spec := MiddlewareSpec{Name: strings.TrimSpace(name), Params: map[string]string{}}
+	if spec.Name == "" {
+		return MiddlewareSpec{}, fmt.Errorf("middleware %q has no name", s)
+	}
+	for _, pair := range strings.Split(rawParams, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return MiddlewareSpec{}, fmt.Errorf("middleware %q: parameter %q is not key=value", spec.Name, pair)
+		}
+		spec.Params[strings.TrimSpace(key)] = value
+	}
+	return spec, nil
+}
+
+// SetMiddlewares installs the global pipeline, in order: the first spec is
+// the outermost, seeing the request first and the response last. The
+// pipeline wraps mock routes only; admin, dashboard and health routes are
+// never affected.
+func (s *Server) SetMiddlewares(specs []MiddlewareSpec) error {
+	chain := make([]func(http.Handler) http.Handler, 0, len(specs))
+	for _, spec := range specs {
+		factory, ok := middlewareRegistry[spec.Name]
+		if !ok {
+			names := make([]string, 0, len(middlewareRegistry))
+			for name := range middlewareRegistry {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			return fmt.Errorf("unknown middleware %q (supported: %s)", spec.Name, strings.Join(names, ", "))
+		}
+		mw, err := factory(spec.Params)
+		if err != nil {
+			return fmt.Errorf("middleware %q: %w", spec.Name, err)
+		}
+		chain = append(chain, skipInternalRoutes(mw))
+	}
+	s.middlewares = chain
+	return nil
+}
+
+// skipInternalRoutes bypasses mw for /__ routes.
+func skipInternalRoutes(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/__") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+func requireParam(params map[string]string, key string) (string, error) {
+	v := params[key]
+	if v == "" {
+		return "", fmt.Errorf("missing %q parameter", key)
+	}
+	return v, nil
+}
+
+// newRequestHeaderMiddleware sets header "name" to "value" on the request
+// before it is matched.
+func newRequestHeaderMiddleware(params map[string]string) (func(http.Handler) http.Handler, error) {
+	name, err := requireParam(params, "name")
+	if err != nil {
+		return nil, err
+	}
+	value := params["value"]
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set(name, value)
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// newResponseHeaderMiddleware sets header "name" to "value" on every
+// response. Headers set by the scenario win.
+func newResponseHeaderMiddleware(params map[string]string) (func(http.Handler) http.Handler, error) {
+	name, err := requireParam(params, "name")
+	if err != nil {
+		return nil, err
+	}
+	value := params["value"]
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(name, value)
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// newRedactMiddleware replaces every match of the "pattern" regexp in the
+// response body with "replacement" (default "***"). The response is
+// buffered, so streamed responses are delivered at once. Content-encoded
+// bodies, such as per-scenario compression, and 206 partial responses are
+// passed through unchanged: their bytes can't be rewritten in place.
+func newRedactMiddleware(params map[string]string) (func(http.Handler) http.Handler, error) {
+	pattern, err := requireParam(params, "pattern")
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	replacement, ok := params["replacement"]
+	if !ok {
+		replacement = "***"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+			next.ServeHTTP(buf, r)
+
+			body := buf.body.Bytes()
+			if redactable(buf.status, w.Header()) {
+				body = re.ReplaceAllLiteral(body, []byte(replacement))
+				if w.Header().Get("Content-Length") != "" {
+					w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				}
+			}
+			w.WriteHeader(buf.status)
+			_, _ = w.Write(body)
+		})
+	}, nil
+}
+
+// redactable reports whether a response body is plain bytes that can be
+// rewritten whole: not content-encoded and not a partial range.
+func redactable(status int, header http.Header) bool {
+	if status == http.StatusPartialContent {
+		return false
+	}
+	enc := header.Get("Content-Encoding")
+	return enc == "" || strings.EqualFold(enc, "identity")
+}
+
+// newCompressMiddleware gzip/deflate-encodes responses for clients that
+// accept it, at "level" (default 5).
+func newCompressMiddleware(params map[string]string) (func(http.Handler) http.Handler, error) {
+	level := 5
+	if v := params["level"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 9 {
+			return nil, fmt.Errorf("invalid level %q (expected 1-9)", v)
+		}
+		level = n
+	}
+	return middleware.Compress(level), nil
+}
+
+// bufferedResponse holds a response back so a middleware can rewrite its
+// body. Headers go straight to the underlying writer's header map.
+type bufferedResponse struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.wroteHeader {
+		return
+	}
+	b.status = status
+	b.wroteHeader = true
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
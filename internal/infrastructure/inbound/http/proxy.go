@@ -0,0 +1,130 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/usecases"
+)
+
+// serveProxy forwards the request to the response's upstream and copies its
+// answer back, then records the trace entry with the upstream status. body
+// is the already-read request body.
+func (s *Server) serveProxy(w http.ResponseWriter, r *http.Request, body []byte, p *match.CompiledProxy, result usecases.HandleRequestResult) {
+	upstreamStatus := http.StatusBadGateway
+	defer func() { s.handleReqUC.TraceProxied(result.TraceEntry, upstreamStatus) }()
+
+	ctx, cancel := context.WithTimeout(r.Context(), p.Timeout)
+	defer cancel()
+
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if p.StripPrefix != "" {
+				pr.Out.URL.Path = ensureLeadingSlash(strings.TrimPrefix(pr.Out.URL.Path, p.StripPrefix))
+				pr.Out.URL.RawPath = ""
+			}
+			pr.SetURL(p.Target)
+			pr.SetXForwarded()
+			if p.Record {
+				// Recordings store the body as text, so ask for it uncompressed.
+				pr.Out.Header.Del("Accept-Encoding")
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			upstreamStatus = resp.StatusCode
+			if p.Record {
+				return s.recordProxied(r, result, resp)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+			upstreamStatus = http.StatusBadGateway
+			if errors.Is(err, context.DeadlineExceeded) {
+				upstreamStatus = http.StatusGatewayTimeout
+			}
+			s.logger.Warn("proxy request failed", "scenario", result.TraceEntry.MatchedID, "target", p.Target.String(), "error", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(upstreamStatus)
+			writeJSON(w, map[string]string{
+				"error":   "proxy_error",
+				"message": err.Error(),
+			})
+		},
+	}
+
+	out := r.Clone(ctx)
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	rp.ServeHTTP(w, out)
+}
+
+// recordProxied saves the first upstream response for a method and path as
+// a scenario. It buffers the body and puts it back so the response can still
+// be copied to the client; the scenario is written in the background, so
+// recording never delays or fails the proxied request.
+func (s *Server) recordProxied(r *http.Request, result usecases.HandleRequestResult, resp *http.Response) error {
+	if s.saveUC == nil {
+		s.logger.Warn("proxy recording requires scenario CRUD; skipping", "scenario", result.TraceEntry.MatchedID)
+		return nil
+	}
+	id := usecases.RecordingID(result.TraceEntry.MatchedID, r.Method, r.URL.Path)
+	if _, inFlight := s.recorded.LoadOrStore(id, struct{}{}); inFlight {
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		s.recorded.Delete(id)
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	rec := usecases.Recording{
+		SourceID:    result.TraceEntry.MatchedID,
+		Priority:    result.Priority,
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        data,
+		At:          result.TraceEntry.Timestamp,
+	}
+	ctx := context.WithoutCancel(r.Context())
+	s.recordings.Add(1)
+	go func() {
+		defer s.recordings.Done()
+		// Record itself skips saved recordings, so forget the attempt once it
+		// is done; a recording deleted later is then recorded again.
+		defer s.recorded.Delete(id)
+		_, err := s.saveUC.Record(ctx, rec)
+		switch {
+		case errors.Is(err, usecases.ErrAlreadyRecorded):
+			s.logger.Debug("proxied response already recorded", "scenario", id)
+		case err != nil:
+			s.logger.Warn("failed to record proxied response", "scenario", result.TraceEntry.MatchedID, "error", err)
+		default:
+			s.logger.Info("recorded proxied response", "scenario", id, "method", rec.Method, "path", rec.Path)
+		}
+	}()
+	return nil
+}
+
+// WaitRecordings blocks until the proxy recordings being written in the
+// background are saved. Call it after the HTTP server has shut down.
+func (s *Server) WaitRecordings() {
+	s.recordings.Wait()
+}
+
+func ensureLeadingSlash(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		return "/" + p
+	}
+	return p
+}
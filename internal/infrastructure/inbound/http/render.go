@@ -0,0 +1,142 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/services"
+)
+
+// incomingRequest converts r and its already-read body into the request the
+// evaluator matches. Header keys are canonicalized for consistent matching.
+func incomingRequest(r *http.Request, body []byte) *match.IncomingRequest {
+	headers := make(map[string]string)
+	for k := range r.Header {
+		headers[http.CanonicalHeaderKey(k)] = r.Header.Get(k)
+	}
+	return &match.IncomingRequest{
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		Host:          requestHost(r),
+		Headers:       headers,
+		Query:         r.URL.Query(),
+		Cookies:       requestCookies(r),
+		Body:          body,
+		ContentLength: r.ContentLength,
+		Secure:        r.TLS != nil,
+	}
+}
+
+// renderContext is the template context of resp answering r, which was
+// matched as incoming with hostParams captured by the scenario's host.
+func renderContext(r *http.Request, incoming *match.IncomingRequest, hostParams map[string]string, resp *match.CompiledResponse) match.RenderContext {
+	return match.RenderContext{
+		Method:           r.Method,
+		Path:             r.URL.Path,
+		Headers:          incoming.Headers,
+		QueryParams:      extractQueryParams(r),
+		PathParams:       extractPathParams(r),
+		HostParams:       hostParams,
+		Body:             incoming.Body,
+		Now:              incoming.Now.UTC().Format(time.RFC3339),
+		RemoteIP:         remoteIP(r),
+		CanonicalizeBody: resp.CanonicalizeBody,
+	}
+}
+
+// Steps of renderResponse, reported by renderError.
+const (
+	stepBody       = "body render"
+	stepLocation   = "location render"
+	stepPagination = "pagination"
+	stepOmitNulls  = "omit_nulls"
+	stepCharset    = "charset transcoding"
+)
+
+// renderError is a failed step of renderResponse.
+type renderError struct {
+	step string
+	// template names the failed template for the render steps.
+	template string
+	err      error
+}
+
+func (e *renderError) Error() string { return fmt.Sprintf("%s failed: %v", e.step, e.err) }
+
+func (e *renderError) Unwrap() error { return e.err }
+
+// renderedResponse is a response body and Location ready to be written.
+type renderedResponse struct {
+	body     []byte
+	location string
+	// skipped lists the pagination and omit_nulls steps that failed; the
+	// body is left as it was before them.
+	skipped []*renderError
+}
+
+// renderResponse renders resp for renderCtx and post-processes the body the
+// way it is served: default status bodies, mirroring, pagination, omit_nulls
+// and charset transcoding. Compression is left to the caller, which
+// negotiates it. A failed render or transcoding aborts with a *renderError.
+func (s *Server) renderResponse(resp *match.CompiledResponse, pagination *match.CompiledPagination, renderCtx match.RenderContext) (renderedResponse, error) {
+	var out renderedResponse
+
+	renderer, templateName := resp.Renderer, resp.TemplateName
+	if renderer == nil && len(resp.Body) == 0 {
+		if def, ok := s.statusBodies[resp.Status]; ok {
+			renderer, templateName = def, fmt.Sprintf("status_body_%d", resp.Status)
+		}
+	}
+	switch {
+	case resp.Mirror:
+		out.body = renderCtx.Body
+	case renderer != nil:
+		rendered, err := renderer.Render(renderCtx)
+		if err != nil {
+			return out, &renderError{step: stepBody, template: templateName, err: err}
+		}
+		out.body = rendered
+	default:
+		out.body = resp.Body
+	}
+
+	if resp.Location != nil {
+		rendered, err := resp.Location.Render(renderCtx)
+		if err != nil {
+			return out, &renderError{step: stepLocation, template: "location", err: err}
+		}
+		out.location = string(rendered)
+	}
+
+	// Pagination post-processing: slice the rendered body and wrap in envelope.
+	if pagination != nil && !resp.Raw {
+		paginated, err := services.Paginate(out.body, pagination, renderCtx.QueryParams)
+		if err != nil {
+			out.skipped = append(out.skipped, &renderError{step: stepPagination, err: err})
+		} else {
+			out.body = paginated
+		}
+	}
+
+	// Strip null-valued keys from JSON bodies.
+	if resp.OmitNulls && services.IsJSONContentType(resp.ContentType) {
+		pruned, err := services.OmitNulls(out.body)
+		if err != nil {
+			out.skipped = append(out.skipped, &renderError{step: stepOmitNulls, err: err})
+		} else {
+			out.body = pruned
+		}
+	}
+
+	// Transcode the final body into the configured charset.
+	if resp.Encoder != nil {
+		encoded, err := resp.Encoder(out.body)
+		if err != nil {
+			return out, &renderError{step: stepCharset, err: err}
+		}
+		out.body = encoded
+	}
+	return out, nil
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+)
+
+// SelfTestFailure is a scenario whose example request failed the self-test.
+type SelfTestFailure struct {
+	ScenarioID string
+	Err        error
+}
+
+// SelfTest runs the example request of every scenario that declares one
+// through matching and the rendering and post-processing of served
+// responses, and reports the scenarios that fail. Nothing is served or
+// traced, and rate limits, response sequences, delays and faults are left
+// untouched. It returns the number of scenarios tested.
+func (s *Server) SelfTest() (int, []SelfTestFailure) {
+	idx := s.index.Load()
+	if idx == nil {
+		return 0, nil
+	}
+	var (
+		tested   int
+		failures []SelfTestFailure
+	)
+	for _, cs := range idx.All() {
+		if cs.Example == nil {
+			continue
+		}
+		tested++
+		if err := s.selfTestScenario(cs); err != nil {
+			failures = append(failures, SelfTestFailure{ScenarioID: cs.ID, Err: err})
+		}
+	}
+	return tested, failures
+}
+
+func (s *Server) selfTestScenario(cs *match.CompiledScenario) error {
+	r := exampleHTTPRequest(cs.Example)
+	req := incomingRequest(r, []byte(cs.Example.Body))
+	candidates, ok := s.lookupCandidates(req.Method, req.Path)
+	if !ok {
+		return errors.New("server not ready")
+	}
+	replay := s.handleReqUC.Replay(req, candidates)
+	switch replay.MatchedID {
+	case cs.ID:
+	case "":
+		return fmt.Errorf("example request %s %s did not match", req.Method, req.Path)
+	default:
+		return fmt.Errorf("example request %s %s matched scenario %q instead", req.Method, req.Path, replay.MatchedID)
+	}
+
+	rctx := chi.NewRouteContext()
+	if router := s.router.Load(); router != nil {
+		router.Find(rctx, req.Method, req.Path)
+	}
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	var pagination *match.CompiledPagination
+	if cs.Policy != nil {
+		pagination = cs.Policy.Pagination
+	}
+	responses := cs.Responses
+	if len(responses) == 0 {
+		responses = []match.CompiledResponse{cs.Response}
+	}
+	for i, resp := range responses {
+		if resp.Proxy != nil {
+			continue
+		}
+		resp = resp.ResolveTime(req.Now).Resolve(req.Body)
+		rendered, err := s.renderResponse(&resp, pagination, renderContext(r, req, cs.HostParams(req.Host), &resp))
+		if err == nil && len(rendered.skipped) > 0 {
+			err = rendered.skipped[0]
+		}
+		if err != nil {
+			if len(responses) > 1 {
+				return fmt.Errorf("responses[%d]: %w", i, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// exampleHTTPRequest builds the request a client would send for ex, so the
+// self-test derives matching and template input the way live requests do.
+func exampleHTTPRequest(ex *match.IncomingRequest) *http.Request {
+	header := make(http.Header, len(ex.Headers))
+	for k, v := range ex.Headers {
+		header.Set(k, v)
+	}
+	host := header.Get("Host")
+	header.Del("Host")
+	return &http.Request{
+		Method:        ex.Method,
+		URL:           &url.URL{Path: ex.Path, RawQuery: url.Values(ex.Query).Encode()},
+		Header:        header,
+		Host:          host,
+		RemoteAddr:    "127.0.0.1:0",
+		ContentLength: ex.ContentLength,
+	}
+}
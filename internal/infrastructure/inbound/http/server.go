@@ -1,13 +1,19 @@
 package http
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,17 +36,53 @@ const maxBodySize = 10 << 20 // 10 MB
 
 // Server is the main HTTP server for ProteusMock.
 type Server struct {
-	router      atomic.Pointer[chi.Mux]
-	index       atomic.Pointer[services.ScenarioIndex]
-	rebuildMu   sync.Mutex
+	router    atomic.Pointer[chi.Mux]
+	index     atomic.Pointer[services.ScenarioIndex]
+	rebuildMu sync.Mutex
+	// serving is set by the first request; custom methods can no longer be
+	// registered with chi after that.
+	serving     atomic.Bool
 	handleReqUC *usecases.HandleRequestUseCase
 	loadUC      *usecases.LoadScenariosUseCase
 	saveUC      *usecases.SaveScenarioUseCase
 	deleteUC    *usecases.DeleteScenarioUseCase
+	validateUC  *usecases.ValidateScenarioUseCase
 	repo        scenario.Repository
+	history     scenario.History
 	traceBuf    *trace.RingBuffer
 	logger      ports.Logger
 	rootDir     string
+	// methodNotAllowed answers 405 instead of 404 when a path only has
+	// scenarios for other methods.
+	methodNotAllowed bool
+	// captureEcho answers unmatched requests with 200 and a JSON dump of
+	// the request instead of 404.
+	captureEcho bool
+	// devMode puts template render errors in the 500 response body.
+	devMode bool
+	// statusBodies renders the body of bodiless responses by status.
+	statusBodies map[int]match.BodyRenderer
+	// adminDisabled and dashboardDisabled leave the /__admin and /__ui
+	// routes out of the router.
+	adminDisabled     bool
+	dashboardDisabled bool
+	// adminUser and adminPassword, when set, guard the admin and dashboard
+	// routes with basic auth.
+	adminUser     string
+	adminPassword string
+	cors          CORSPolicy
+	trailingSlash string // "" = strict, see SetTrailingSlash
+	// middlewares is the global pipeline, outermost first (see SetMiddlewares).
+	middlewares []func(http.Handler) http.Handler
+	// disabledIDs holds scenarios disabled through the admin API. It is
+	// re-applied on every Rebuild, so the state survives reloads; guarded
+	// by rebuildMu.
+	disabledIDs map[string]bool
+	// recorded holds the IDs of proxy recordings being saved, so concurrent
+	// exchanges for a method and path write it once; recordings tracks the
+	// writes still running in the background.
+	recorded   sync.Map
+	recordings sync.WaitGroup
 }
 
 // NewServer creates a new Server.
@@ -68,41 +110,215 @@ func (s *Server) SetCRUDDeps(saveUC *usecases.SaveScenarioUseCase, deleteUC *use
 	s.rootDir = rootDir
 }
 
+// SetHistory enables the scenario history endpoints.
+func (s *Server) SetHistory(history scenario.History) {
+	s.history = history
+}
+
+// SetValidateUseCase injects the optional use case backing the scenario validation endpoint.
+func (s *Server) SetValidateUseCase(validateUC *usecases.ValidateScenarioUseCase) {
+	s.validateUC = validateUC
+}
+
+// SetMethodNotAllowed makes requests whose path has scenarios only for other
+// methods get 405 with an Allow header instead of 404.
+func (s *Server) SetMethodNotAllowed(enabled bool) {
+	s.methodNotAllowed = enabled
+}
+
+// SetCaptureEcho makes unmatched requests return 200 with the request echoed
+// as JSON, so clients keep going while mocks are being written. Misses are
+// still recorded in the trace.
+func (s *Server) SetCaptureEcho(enabled bool) {
+	s.captureEcho = enabled
+}
+
+// SetDevMode makes template render failures answer with the error and
+// the template's name instead of an opaque message. Meant for local
+// development only: errors may reveal template source and request data.
+func (s *Server) SetDevMode(enabled bool) {
+	s.devMode = enabled
+}
+
+// SetStatusBodies sets default body templates by status. A response whose
+// status is in the map and that has no body of its own is rendered with
+// the mapped template.
+func (s *Server) SetStatusBodies(bodies map[int]match.BodyRenderer) {
+	s.statusBodies = bodies
+}
+
+// SetAdminDisabled leaves the /__admin routes out of the router, so only
+// mock endpoints and /__health are served.
+func (s *Server) SetAdminDisabled(disabled bool) {
+	s.adminDisabled = disabled
+}
+
+// SetDashboardDisabled leaves the /__ui dashboard routes out of the router.
+func (s *Server) SetDashboardDisabled(disabled bool) {
+	s.dashboardDisabled = disabled
+}
+
+// maxCustomMethods bounds how many non-standard methods are registered with
+// chi, which supports a fixed number of method types process-wide.
+const maxCustomMethods = 32
+
+var (
+	customMethodsMu sync.Mutex
+	customMethods   = make(map[string]bool)
+)
+
+// registerMethods makes the non-standard methods (PURGE, PROPFIND, ...) of
+// idx's scenarios routable. chi keeps its method table in package state that
+// live routers read without locking, so methods are only registered before
+// the server serves its first request. A scenario whose method is first seen
+// later is not routed, chi answers it 405, until a restart.
+func (s *Server) registerMethods(idx *services.ScenarioIndex) {
+	customMethodsMu.Lock()
+	defer customMethodsMu.Unlock()
+
+	unroutable := make(map[string]bool)
+	for _, m := range idx.Methods() {
+		if m == "" || isStandardMethod(m) || customMethods[m] {
+			continue
+		}
+		if s.serving.Load() {
+			unroutable[m] = true
+			continue
+		}
+		if len(customMethods) >= maxCustomMethods {
+			s.logger.Warn("too many custom HTTP methods, scenario method not routable", "method", m)
+			continue
+		}
+		chi.RegisterMethod(m)
+		customMethods[m] = true
+	}
+	if len(unroutable) == 0 {
+		return
+	}
+	for _, cs := range idx.All() {
+		if unroutable[cs.Method] {
+			s.logger.Warn("scenario uses an HTTP method first seen after startup and is not routed; restart the server to route it",
+				"id", cs.ID, "method", cs.Method)
+		}
+	}
+}
+
+func isStandardMethod(m string) bool {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
 // BuildRouter creates a new chi.Mux with admin and mock routes for the given index.
 func (s *Server) BuildRouter(idx *services.ScenarioIndex) *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RealIP)
+	if s.cors.enabled() {
+		r.Use(skipInternalRoutes(s.corsMiddleware))
+	}
+	if s.trailingSlash != "" {
+		r.Use(s.trailingSlashMiddleware(r))
+	}
+	r.Use(s.middlewares...)
+
+	// Liveness probe, served even when the admin API is disabled.
+	r.Get("/__health", handleHealth)
+
+	if !s.adminDisabled {
+		s.mountAdmin(r.With(s.requireAdminAuth))
+	}
+
+	// Dashboard SPA (embedded). Serves files directly to avoid http.FileServer redirect loops.
+	if !s.dashboardDisabled {
+		dist, _ := fs.Sub(dashboard.DistFS, "dist")
+		serveDashboard := s.dashboardHandler(dist)
+		ui := r.With(s.requireAdminAuth)
+		ui.Get("/__ui", serveDashboard)
+		ui.Get("/__ui/*", serveDashboard)
+	}
+
+	// Static directory mounts.
+	for _, cs := range idx.Statics() {
+		h := staticHandler(cs)
+		r.Get(cs.Static.PathPrefix+"/*", h)
+		r.Head(cs.Static.PathPrefix+"/*", h)
+	}
+
+	// Dynamic mock routes from index.
+	for _, path := range idx.Paths() {
+		routePath := path
+		r.HandleFunc(routePath, s.mockHandler)
+		// Preflights go to the mock handler when a scenario mocks OPTIONS.
+		if methods := idx.AllowedMethods(routePath); s.cors.enabled() && !slices.Contains(methods, http.MethodOptions) {
+			r.Options(routePath, s.corsPreflight(methods))
+		}
+	}
 
-	// Admin routes.
+	// Catch-all for unmatched paths — returns 404 with debug info.
+	r.NotFound(s.notFoundHandler)
+
+	return r
+}
+
+// mountAdmin registers the /__admin API routes.
+func (s *Server) mountAdmin(r chi.Router) {
 	r.Route("/__admin", func(r chi.Router) {
 		r.Get("/scenarios", s.handleListScenarios)
 		r.Get("/scenarios/search", s.handleSearchScenarios)
 		r.Get("/scenarios/{scenarioID}", s.handleGetScenario)
+		r.Get("/scenarios/{scenarioID}/history", s.handleListHistory)
+		r.Get("/scenarios/{scenarioID}/history/{version}", s.handleGetHistoryVersion)
 		r.Put("/scenarios/{scenarioID}", s.handleUpdateScenario)
 		r.Post("/scenarios", s.handleCreateScenario)
+		r.Post("/scenarios/validate", s.handleValidateScenario)
 		r.Delete("/scenarios/{scenarioID}", s.handleDeleteScenario)
+		r.Post("/scenarios/{scenarioID}/disable", s.handleSetScenarioDisabled(true))
+		r.Post("/scenarios/{scenarioID}/enable", s.handleSetScenarioDisabled(false))
+		r.Get("/index", s.handleGetIndex)
 		r.Get("/files", s.handleListFiles)
 		r.Get("/trace", s.handleGetTrace)
+		r.Delete("/trace", s.handleClearTrace)
+		r.Get("/trace.csv", s.handleExportTraceCSV)
+		r.Get("/trace/stream", s.handleStreamTrace)
+		r.Post("/trace/{entryID}/replay", s.handleReplayTrace)
+		r.Post("/match", s.handleExplainMatch)
 		r.Post("/reload", s.handleReload)
+		r.Post("/latency", s.handleSetLatency)
+		r.Delete("/latency", s.handleClearLatency)
+		r.Get("/state", s.handleGetState)
+		r.Post("/state", s.handleRestoreState)
 	})
+}
 
-	// Dashboard SPA (embedded). Serves files directly to avoid http.FileServer redirect loops.
-	dist, _ := fs.Sub(dashboard.DistFS, "dist")
-	serveDashboard := s.dashboardHandler(dist)
-	r.Get("/__ui", serveDashboard)
-	r.Get("/__ui/*", serveDashboard)
+// handleHealth reports that the server is up.
+func handleHealth(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, map[string]string{"status": "ok"})
+}
 
-	// Dynamic mock routes from index.
-	for _, path := range idx.Paths() {
-		routePath := path
-		r.HandleFunc(routePath, s.mockHandler)
+// staticHandler serves files from a static mount. The directory is opened as an
+// os.Root per request so paths, including symlinks, cannot escape it.
+// http.FileServerFS handles content-type detection and range requests. A
+// disabled mount answers 404.
+func staticHandler(cs *match.CompiledScenario) http.HandlerFunc {
+	st := cs.Static
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cs.Disabled() {
+			http.NotFound(w, r)
+			return
+		}
+		root, err := os.OpenRoot(st.Dir)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer root.Close()
+		http.StripPrefix(st.PathPrefix, http.FileServerFS(root.FS())).ServeHTTP(w, r)
 	}
-
-	// Catch-all for unmatched paths — returns 404 with debug info.
-	r.NotFound(s.notFoundHandler)
-
-	return r
 }
 
 // Rebuild atomically swaps the router and index. Serialized via mutex.
@@ -110,6 +326,12 @@ func (s *Server) Rebuild(idx *services.ScenarioIndex) {
 	s.rebuildMu.Lock()
 	defer s.rebuildMu.Unlock()
 
+	s.registerMethods(idx)
+	for id := range s.disabledIDs {
+		if cs, ok := idx.ByID(id); ok {
+			cs.SetDisabled(true)
+		}
+	}
 	r := s.BuildRouter(idx)
 	s.index.Store(idx)
 	s.router.Store(r)
@@ -118,6 +340,9 @@ func (s *Server) Rebuild(idx *services.ScenarioIndex) {
 
 // ServeHTTP implements http.Handler using the atomic router.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.serving.Load() {
+		s.serving.Store(true)
+	}
 	router := s.router.Load()
 	if router == nil {
 		http.Error(w, "server not ready", http.StatusServiceUnavailable)
@@ -189,6 +414,12 @@ func (s *Server) dashboardHandler(dist fs.FS) http.HandlerFunc {
 }
 
 func (s *Server) notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	if s.captureEcho {
+		// Evaluate against no candidates so the miss reaches the trace.
+		s.mockHandler(w, r)
+		return
+	}
+
 	s.logger.Info("request received (no route)", "method", r.Method, "path", r.URL.Path, "query", r.URL.RawQuery, "remote", r.RemoteAddr)
 
 	w.Header().Set("Content-Type", "application/json")
@@ -201,6 +432,44 @@ func (s *Server) notFoundHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// requestCookies returns the request cookies by name, URL-decoded. When a
+// name repeats the first cookie wins, as with Request.Cookie.
+func requestCookies(r *http.Request) map[string]string {
+	cookies := r.Cookies()
+	if len(cookies) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(cookies))
+	for _, c := range cookies {
+		if _, ok := values[c.Name]; ok {
+			continue
+		}
+		v, err := url.PathUnescape(c.Value)
+		if err != nil {
+			v = c.Value
+		}
+		values[c.Name] = v
+	}
+	return values
+}
+
+// remoteIP returns the client IP without port. The RealIP middleware has
+// already replaced RemoteAddr with True-Client-IP, X-Real-IP or the first
+// X-Forwarded-For address when the request carries one.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// quietLogger drops info logs, for the logs following a match of a scenario
+// with `log: false`. Warnings and errors still go through.
+type quietLogger struct{ ports.Logger }
+
+func (quietLogger) Info(string, ...any) {}
+
 func (s *Server) mockHandler(w http.ResponseWriter, r *http.Request) {
 	s.logger.Info("request received", "method", r.Method, "path", r.URL.Path, "query", r.URL.RawQuery, "remote", r.RemoteAddr)
 
@@ -211,18 +480,7 @@ func (s *Server) mockHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Canonicalize header keys to http.CanonicalHeaderKey for consistent matching.
-	headers := make(map[string]string)
-	for k := range r.Header {
-		headers[http.CanonicalHeaderKey(k)] = r.Header.Get(k)
-	}
-
-	incoming := &match.IncomingRequest{
-		Method:  r.Method,
-		Path:    r.URL.Path,
-		Headers: headers,
-		Body:    body,
-	}
+	incoming := incomingRequest(r, body)
 
 	idx := s.index.Load()
 	if idx == nil {
@@ -234,15 +492,41 @@ func (s *Server) mockHandler(w http.ResponseWriter, r *http.Request) {
 	// falling back to the actual path if no pattern is available.
 	routePath := r.URL.Path
 	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
-		routePath = rctx.RoutePattern()
+		routePath = routePattern(rctx)
 	}
 	key := r.Method + ":" + routePath
 	candidates := idx.Lookup(key)
 
+	if len(candidates) == 0 && s.methodNotAllowed {
+		if allowed := idx.AllowedMethods(routePath); len(allowed) > 0 {
+			s.logger.Info("method not allowed", "method", r.Method, "path", r.URL.Path)
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			writeJSON(w, map[string]any{
+				"error":   "method_not_allowed",
+				"method":  r.Method,
+				"path":    r.URL.Path,
+				"allowed": allowed,
+				"message": "No scenario registered for this method",
+			})
+			return
+		}
+	}
+
 	result := s.handleReqUC.Execute(r.Context(), incoming, candidates)
 
+	logger := s.logger
+	if result.Quiet {
+		logger = quietLogger{s.logger}
+	}
+
 	if result.RateLimited {
-		s.logger.Info("request rate-limited", "method", r.Method, "path", r.URL.Path)
+		logger.Info("request rate-limited", "method", r.Method, "path", r.URL.Path)
+		if result.Response != nil {
+			s.writeRateLimited(w, result.TraceEntry.MatchedID, result.Response, renderContext(r, incoming, result.HostParams, result.Response))
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Retry-After", "1")
 		w.WriteHeader(http.StatusTooManyRequests)
@@ -253,8 +537,27 @@ func (s *Server) mockHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !result.Matched && result.UnsupportedMediaType {
+		logger.Info("unsupported media type", "method", r.Method, "path", r.URL.Path, "content_type", r.Header.Get("Content-Type"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		writeJSON(w, map[string]any{
+			"error":        "unsupported_media_type",
+			"method":       r.Method,
+			"path":         r.URL.Path,
+			"content_type": r.Header.Get("Content-Type"),
+			"message":      "Request Content-Type is not accepted by this endpoint",
+		})
+		return
+	}
+
 	if !result.Matched {
-		s.logger.Info("request unmatched", "method", r.Method, "path", r.URL.Path, "candidates", len(result.TraceEntry.Candidates))
+		logger.Info("request unmatched", "method", r.Method, "path", r.URL.Path, "candidates", len(result.TraceEntry.Candidates))
+		if s.captureEcho {
+			w.Header().Set("Content-Type", "application/json")
+			writeJSON(w, buildEchoResponse(incoming))
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		debugResp := buildDebugResponse(r.Method, r.URL.Path, result.TraceEntry)
@@ -262,42 +565,203 @@ func (s *Server) mockHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if result.Fault != "" {
+		if err := s.handleReqUC.Wait(r.Context(), result.HeaderDelay); err != nil {
+			s.logger.Debug("header delay cancelled", "scenario", result.TraceEntry.MatchedID, "error", err)
+			return
+		}
+		logger.Info("fault injected", "method", r.Method, "path", r.URL.Path, "scenario", result.TraceEntry.MatchedID, "fault", result.Fault)
+		if result.Fault == match.FaultResetConnection {
+			s.resetConnection(w)
+		}
+		// An empty response returns without writing anything.
+		return
+	}
+
 	resp := result.Response
 
-	// Render dynamic body if template renderer is present.
-	queryParams := extractQueryParams(r)
-	var bodyBytes []byte
-	if resp.Renderer != nil {
-		renderCtx := match.RenderContext{
-			Method:      r.Method,
-			Path:        r.URL.Path,
-			Headers:     headers,
-			QueryParams: queryParams,
-			PathParams:  extractPathParams(r),
-			Body:        body,
-			Now:         time.Now().UTC().Format(time.RFC3339),
-		}
-		rendered, renderErr := resp.Renderer.Render(renderCtx)
-		if renderErr != nil {
-			s.logger.Error("template render failed", "error", renderErr)
-			http.Error(w, "template render error", http.StatusInternalServerError)
+	if resp.Proxy != nil {
+		s.serveProxy(w, r, body, resp.Proxy, result)
+		return
+	}
+
+	rendered, renderErr := s.renderResponse(resp, result.Pagination, renderContext(r, incoming, result.HostParams, resp))
+	var failed *renderError
+	if errors.As(renderErr, &failed) {
+		switch {
+		case errors.Is(failed, match.ErrBodyFileNotFound):
+			logger.Info("body file not found", "scenario", result.TraceEntry.MatchedID, "error", failed.err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(resp.BodyFileMissingStatus)
+			writeJSON(w, map[string]string{
+				"error":   "body_file_not_found",
+				"message": "No fixture exists for this request",
+			})
+		case failed.step == stepCharset:
+			s.logger.Error("charset transcoding failed", "charset", resp.Charset, "error", failed.err)
+			http.Error(w, "charset encoding error", http.StatusInternalServerError)
+		default:
+			s.logger.Error(failed.step+" failed", "error", failed.err)
+			s.writeRenderError(w, result.TraceEntry.MatchedID, failed.template, failed.err)
+		}
+		return
+	}
+	for _, skipped := range rendered.skipped {
+		s.logger.Error(skipped.step+" failed, skipping it", "error", skipped.err)
+	}
+	bodyBytes, location := rendered.body, rendered.location
+
+	// Compress with the best content coding both sides support.
+	var contentEncoding string
+	if len(resp.Compression) > 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if enc := services.NegotiateEncoding(r.Header.Get("Accept-Encoding"), resp.Compression); enc != "" {
+			compressed, compressErr := services.Compress(bodyBytes, enc)
+			if compressErr != nil {
+				s.logger.Error("compression failed, returning body uncompressed", "encoding", enc, "error", compressErr)
+			} else {
+				bodyBytes = compressed
+				contentEncoding = enc
+			}
+		}
+	}
+
+	// Latency phase 1: delay before the status line and headers.
+	if err := s.handleReqUC.Wait(r.Context(), result.HeaderDelay); err != nil {
+		s.logger.Debug("header delay cancelled", "scenario", result.TraceEntry.MatchedID, "error", err)
+		return
+	}
+
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	contentType := resp.ContentType
+	if resp.Mirror {
+		contentType = r.Header.Get("Content-Type")
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	} else if resp.Raw {
+		// Stop net/http from sniffing a Content-Type for raw bodies.
+		w.Header()["Content-Type"] = nil
+	}
+	if location != "" {
+		w.Header().Set("Location", location)
+	}
+	if contentEncoding != "" {
+		w.Header().Set("Content-Encoding", contentEncoding)
+	}
+	if result.Deprecated {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Warning", deprecationWarning(result.DeprecationMessage))
+	}
+
+	// Static file bodies are served from a seekable reader so Range requests
+	// get 206 partial content. ServeContent writes headers and body together,
+	// so the body delay is applied up front.
+	truncate := resp.TruncateAtBytes > 0 && resp.TruncateAtBytes < len(bodyBytes)
+	if resp.BodyFile != "" && resp.Status == http.StatusOK && !truncate {
+		if err := s.handleReqUC.Wait(r.Context(), result.BodyDelay); err != nil {
+			s.logger.Debug("body delay cancelled", "scenario", result.TraceEntry.MatchedID, "error", err)
 			return
 		}
-		bodyBytes = rendered
-	} else {
-		bodyBytes = resp.Body
+		http.ServeContent(w, r, resp.BodyFile, time.Time{}, bytes.NewReader(bodyBytes))
+		logger.Info("request matched", "method", r.Method, "path", r.URL.Path, "scenario", result.TraceEntry.MatchedID, "status", resp.Status)
+		return
 	}
 
-	// Pagination post-processing: slice the rendered body and wrap in envelope.
-	if result.Pagination != nil {
-		paginated, paginateErr := services.Paginate(bodyBytes, result.Pagination, queryParams)
-		if paginateErr != nil {
-			s.logger.Error("pagination failed, returning unpaginated response", "error", paginateErr)
-		} else {
-			bodyBytes = paginated
+	if truncate {
+		// Declare the full length so clients can tell the body was cut short.
+		w.Header().Set("Content-Length", strconv.Itoa(len(bodyBytes)))
+	}
+	w.WriteHeader(resp.Status)
+
+	// Latency phase 2: flush headers, then delay before streaming the body.
+	if result.BodyDelay > 0 {
+		_ = http.NewResponseController(w).Flush()
+		if err := s.handleReqUC.Wait(r.Context(), result.BodyDelay); err != nil {
+			s.logger.Debug("body delay cancelled", "scenario", result.TraceEntry.MatchedID, "error", err)
+			return
 		}
 	}
 
+	if truncate {
+		s.writeTruncated(w, bodyBytes[:resp.TruncateAtBytes])
+		logger.Info("request matched, body truncated", "method", r.Method, "path", r.URL.Path, "scenario", result.TraceEntry.MatchedID, "bytes", resp.TruncateAtBytes)
+		return
+	}
+
+	if _, err := w.Write(bodyBytes); err != nil {
+		s.logger.Debug("failed to write response body", "error", err)
+	}
+
+	logger.Info("request matched", "method", r.Method, "path", r.URL.Path, "scenario", result.TraceEntry.MatchedID, "status", resp.Status)
+}
+
+// writeRenderError answers a failed template render with 500. Outside dev
+// mode the body stays opaque.
+func (s *Server) writeRenderError(w http.ResponseWriter, scenarioID, template string, err error) {
+	if !s.devMode {
+		http.Error(w, "template render error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	writeJSON(w, map[string]string{
+		"error":    "template_render_error",
+		"scenario": scenarioID,
+		"template": template,
+		"message":  err.Error(),
+	})
+}
+
+// writeTruncated writes part of the body, then hijacks and closes the
+// connection. Where hijacking is unsupported (e.g. HTTP/2), the short write
+// against the declared Content-Length makes the server abort the response.
+func (s *Server) writeTruncated(w http.ResponseWriter, partial []byte) {
+	if _, err := w.Write(partial); err != nil {
+		s.logger.Debug("failed to write truncated body", "error", err)
+		return
+	}
+	rc := http.NewResponseController(w)
+	_ = rc.Flush()
+	conn, _, err := rc.Hijack()
+	if err != nil {
+		s.logger.Debug("hijack unsupported, relying on short write", "error", err)
+		return
+	}
+	_ = conn.Close()
+}
+
+// resetConnection hijacks and closes the client connection, with a TCP reset
+// where possible. Where hijacking is unsupported (e.g. HTTP/2), it aborts
+// the handler so the server drops the stream.
+func (s *Server) resetConnection(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		s.logger.Debug("hijack unsupported, aborting handler", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	_ = conn.Close()
+}
+
+// writeRateLimited serves a scenario's custom rate-limit response.
+func (s *Server) writeRateLimited(w http.ResponseWriter, scenarioID string, resp *match.CompiledResponse, renderCtx match.RenderContext) {
+	bodyBytes := resp.Body
+	if resp.Renderer != nil {
+		rendered, err := resp.Renderer.Render(renderCtx)
+		if err != nil {
+			s.logger.Error("rate limit template render failed", "error", err)
+			s.writeRenderError(w, scenarioID, resp.TemplateName, err)
+			return
+		}
+		bodyBytes = rendered
+	}
+
+	w.Header().Set("Retry-After", "1")
 	for k, v := range resp.Headers {
 		w.Header().Set(k, v)
 	}
@@ -305,11 +769,24 @@ func (s *Server) mockHandler(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", resp.ContentType)
 	}
 	w.WriteHeader(resp.Status)
-	if _, err := w.Write(bodyBytes); err != nil {
-		s.logger.Debug("failed to write response body", "error", err)
-	}
+	w.Write(bodyBytes)
+}
 
-	s.logger.Info("request matched", "method", r.Method, "path", r.URL.Path, "scenario", result.TraceEntry.MatchedID, "status", resp.Status)
+// buildEchoResponse dumps an unmatched request for capture-echo mode.
+func buildEchoResponse(req *match.IncomingRequest) map[string]any {
+	query := req.Query
+	if query == nil {
+		query = map[string][]string{}
+	}
+	return map[string]any{
+		"matched": false,
+		"method":  req.Method,
+		"path":    req.Path,
+		"host":    req.Host,
+		"query":   query,
+		"headers": req.Headers,
+		"body":    string(req.Body),
+	}
 }
 
 func buildDebugResponse(method, path string, entry trace.Entry) map[string]any {
@@ -336,14 +813,18 @@ func buildDebugResponse(method, path string, entry trace.Entry) map[string]any {
 		}
 		resp["candidates"] = candidates
 	}
+	if entry.CandidatesOmitted > 0 {
+		resp["candidates_truncated"] = true
+		resp["candidates_omitted"] = entry.CandidatesOmitted
+	}
 
 	return resp
 }
 
-func (s *Server) handleListScenarios(w http.ResponseWriter, _ *http.Request) {
+func (s *Server) handleListScenarios(w http.ResponseWriter, r *http.Request) {
 	idx := s.index.Load()
 	if idx == nil {
-		writeJSON(w, []any{})
+		writeAdminJSON(w, r, []any{})
 		return
 	}
 
@@ -351,113 +832,616 @@ func (s *Server) handleListScenarios(w http.ResponseWriter, _ *http.Request) {
 	scenarios := make([]map[string]any, 0, len(all))
 	for _, cs := range all {
 		scenarios = append(scenarios, map[string]any{
-			"id":       cs.ID,
-			"name":     cs.Name,
-			"priority": cs.Priority,
-			"method":   cs.Method,
-			"path_key": cs.PathKey,
+			"id":         cs.ID,
+			"name":       cs.Name,
+			"priority":   cs.Priority,
+			"method":     cs.Method,
+			"methods":    methodsJSON(cs),
+			"path_key":   cs.PathKey,
+			"profiles":   profilesJSON(cs.Profiles),
+			"deprecated": cs.Deprecated,
+			"disabled":   cs.Disabled(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeAdminJSON(w, r, scenarios)
+}
+
+// handleSetScenarioDisabled returns a handler that disables or re-enables a
+// loaded scenario. Disabled scenarios stay listed but never match, and stay
+// disabled across reloads until enabled again; the state is not persisted.
+func (s *Server) handleSetScenarioDisabled(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "scenarioID")
+
+		s.rebuildMu.Lock()
+		defer s.rebuildMu.Unlock()
+
+		idx := s.index.Load()
+		var cs *match.CompiledScenario
+		if idx != nil {
+			cs, _ = idx.ByID(id)
+		}
+		if cs == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			writeAdminJSON(w, r, map[string]string{"error": "not_found", "message": "scenario not found: " + id})
+			return
+		}
+
+		cs.SetDisabled(disabled)
+		if disabled {
+			if s.disabledIDs == nil {
+				s.disabledIDs = make(map[string]bool)
+			}
+			s.disabledIDs[id] = true
+		} else {
+			delete(s.disabledIDs, id)
+		}
+		s.logger.Info("scenario toggled", "id", id, "disabled", disabled)
+
+		w.Header().Set("Content-Type", "application/json")
+		writeAdminJSON(w, r, map[string]any{"id": id, "disabled": disabled})
+	}
+}
+
+// deprecationWarning formats msg as a Warning header with the 299
+// (miscellaneous persistent warning) code, quoting it as RFC 9110 requires.
+func deprecationWarning(msg string) string {
+	msg = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(msg)
+	return `299 - "` + msg + `"`
+}
+
+// methodsJSON lists every method a scenario serves.
+func methodsJSON(cs *match.CompiledScenario) []string {
+	if len(cs.Methods) > 0 {
+		return cs.Methods
+	}
+	return []string{cs.Method}
+}
+
+// profilesJSON renders profiles as a JSON array, never null.
+func profilesJSON(profiles []string) []string {
+	if profiles == nil {
+		return []string{}
+	}
+	return profiles
+}
+
+func (s *Server) handleSearchScenarios(w http.ResponseWriter, r *http.Request) {
+	q := strings.ToLower(r.URL.Query().Get("q"))
+	idx := s.index.Load()
+	if idx == nil {
+		w.Header().Set("Content-Type", "application/json")
+		writeAdminJSON(w, r, []any{})
+		return
+	}
+
+	var results []map[string]any
+	for _, cs := range idx.All() {
+		if q == "" ||
+			strings.Contains(strings.ToLower(cs.ID), q) ||
+			strings.Contains(strings.ToLower(cs.Name), q) ||
+			strings.Contains(strings.ToLower(cs.PathKey), q) {
+			results = append(results, map[string]any{
+				"id":         cs.ID,
+				"name":       cs.Name,
+				"priority":   cs.Priority,
+				"method":     cs.Method,
+				"methods":    methodsJSON(cs),
+				"path_key":   cs.PathKey,
+				"profiles":   profilesJSON(cs.Profiles),
+				"deprecated": cs.Deprecated,
+				"disabled":   cs.Disabled(),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeAdminJSON(w, r, results)
+}
+
+func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	if s.rootDir == "" {
+		w.Header().Set("Content-Type", "application/json")
+		writeAdminJSON(w, r, []string{})
+		return
+	}
+
+	var files []string
+	err := filepath.WalkDir(s.rootDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip inaccessible entries
+		}
+		if d.IsDir() {
+			// Hidden directories (e.g. .history snapshots) are not scenario files.
+			if path != s.rootDir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, relErr := filepath.Rel(s.rootDir, path)
+		if relErr != nil {
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("failed to list files", "error", err)
+	}
+
+	if files == nil {
+		files = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeAdminJSON(w, r, files)
+}
+
+// handleGetIndex returns the routing table: every METHOD:path key with its
+// candidates in evaluation order, plus static directory mounts.
+func (s *Server) handleGetIndex(w http.ResponseWriter, r *http.Request) {
+	routes := []map[string]any{}
+	statics := []map[string]any{}
+
+	if idx := s.index.Load(); idx != nil {
+		for _, key := range idx.Keys() {
+			candidates := idx.Lookup(key)
+			entries := make([]map[string]any, 0, len(candidates))
+			for i, cs := range candidates {
+				fields := make([]string, 0, len(cs.Predicates))
+				for _, p := range cs.Predicates {
+					fields = append(fields, p.Field)
+				}
+				entries = append(entries, map[string]any{
+					"position":         i + 1,
+					"id":               cs.ID,
+					"name":             cs.Name,
+					"priority":         cs.Priority,
+					"specificity":      len(cs.Predicates),
+					"predicate_fields": fields,
+				})
+			}
+
+			method, path, _ := strings.Cut(key, ":")
+			routes = append(routes, map[string]any{
+				"key":        key,
+				"method":     method,
+				"path":       path,
+				"candidates": entries,
+			})
+		}
+
+		for _, cs := range idx.Statics() {
+			statics = append(statics, map[string]any{
+				"id":          cs.ID,
+				"name":        cs.Name,
+				"path_prefix": cs.Static.PathPrefix,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeAdminJSON(w, r, map[string]any{
+		"routes":  routes,
+		"statics": statics,
+	})
+}
+
+func (s *Server) handleGetTrace(w http.ResponseWriter, r *http.Request) {
+	n := 10
+	if lastParam := r.URL.Query().Get("last"); lastParam != "" {
+		if parsed, err := strconv.Atoi(lastParam); err == nil && parsed > 0 {
+			n = parsed
+		}
+	}
+
+	q := r.URL.Query()
+	method := strings.ToUpper(q.Get("method"))
+	pathPrefix := q.Get("path")
+	var matched *bool
+	if v := q.Get("matched"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			writeAdminJSON(w, r, map[string]string{"error": "invalid_request", "message": "matched must be true or false"})
+			return
+		}
+		matched = &b
+	}
+
+	var entries []trace.Entry
+	if method == "" && pathPrefix == "" && matched == nil {
+		entries = s.traceBuf.Last(n)
+	} else {
+		// Filter before truncating, so n counts matching entries.
+		entries = s.traceBuf.LastMatching(n, func(e trace.Entry) bool {
+			return (method == "" || e.Method == method) &&
+				strings.HasPrefix(e.Path, pathPrefix) &&
+				(matched == nil || (e.MatchedID != "") == *matched)
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeAdminJSON(w, r, entries)
+}
+
+// handleClearTrace drops every trace entry, e.g. between test cases.
+func (s *Server) handleClearTrace(w http.ResponseWriter, _ *http.Request) {
+	s.traceBuf.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleExportTraceCSV writes every buffered trace entry as CSV, oldest
+// first, for analysis in a spreadsheet.
+func (s *Server) handleExportTraceCSV(w http.ResponseWriter, _ *http.Request) {
+	entries := s.traceBuf.Last(s.traceBuf.Count())
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="trace.csv"`)
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"timestamp", "method", "path", "matched_id", "rate_limited", "candidates"})
+	for _, e := range entries {
+		_ = cw.Write([]string{
+			e.Timestamp.Format(time.RFC3339Nano),
+			e.Method,
+			e.Path,
+			e.MatchedID,
+			strconv.FormatBool(e.RateLimited),
+			strconv.Itoa(len(e.Candidates) + e.CandidatesOmitted),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		s.logger.Error("failed to write trace CSV", "error", err)
+	}
+}
+
+// handleReplayTrace re-runs a traced request through the current scenarios
+// and reports the match result without serving a response.
+func (s *Server) handleReplayTrace(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "entryID"), 10, 64)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		writeAdminJSON(w, r, map[string]string{"error": "invalid_request", "message": "invalid trace entry id"})
+		return
+	}
+	entry, ok := s.traceBuf.Get(id)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		writeAdminJSON(w, r, map[string]string{"error": "not_found", "message": "trace entry not found: " + chi.URLParam(r, "entryID")})
+		return
+	}
+	if entry.Request == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		writeAdminJSON(w, r, map[string]string{"error": "invalid_request", "message": "trace entry has no captured request"})
+		return
+	}
+	candidates, ok := s.lookupCandidates(entry.Method, entry.Path)
+	if !ok {
+		http.Error(w, "server not ready", http.StatusServiceUnavailable)
+		return
+	}
+	result := s.handleReqUC.Replay(usecases.ReplayRequest(entry), candidates)
+
+	w.Header().Set("Content-Type", "application/json")
+	writeAdminJSON(w, r, map[string]any{
+		"entry_id":           entry.ID,
+		"original_match":     entry.MatchedID,
+		"matched":            result.MatchedID != "",
+		"matched_id":         result.MatchedID,
+		"candidates":         result.Candidates,
+		"candidates_omitted": result.CandidatesOmitted,
+	})
+}
+
+// lookupCandidates returns the scenarios a live request for method and path
+// would be evaluated against, resolving the route pattern like the router.
+// It reports false before the first index is built.
+func (s *Server) lookupCandidates(method, path string) ([]*match.CompiledScenario, bool) {
+	idx := s.index.Load()
+	router := s.router.Load()
+	if idx == nil || router == nil {
+		return nil, false
+	}
+	routePath := path
+	if pattern := router.Find(chi.NewRouteContext(), method, path); pattern != "" {
+		routePath = pattern
+	}
+	return idx.Lookup(method + ":" + routePath), true
+}
+
+// handleExplainMatch evaluates a request described as JSON against the
+// current scenarios and reports every candidate's result, without serving
+// a mock response or recording a trace entry.
+func (s *Server) handleExplainMatch(w http.ResponseWriter, r *http.Request) {
+	defer func() { _ = r.Body.Close() }()
+
+	var req struct {
+		Method  string            `json:"method"`
+		Path    string            `json:"path"`
+		Host    string            `json:"host"`
+		Headers map[string]string `json:"headers"`
+		// Body is sent as-is when it is a JSON string, otherwise as JSON text.
+		Body json.RawMessage `json:"body"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		writeAdminJSON(w, r, map[string]string{"error": "invalid_request", "message": "expected JSON body describing a request: " + err.Error()})
+		return
+	}
+	u, err := url.Parse(req.Path)
+	if err != nil || !strings.HasPrefix(u.Path, "/") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		writeAdminJSON(w, r, map[string]string{"error": "invalid_request", "message": "path must be an absolute path, e.g. /api/users?page=2"})
+		return
+	}
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body []byte
+	if len(req.Body) > 0 && !bytes.Equal(req.Body, []byte("null")) {
+		var text string
+		if err := json.Unmarshal(req.Body, &text); err == nil {
+			body = []byte(text)
+		} else {
+			body = req.Body
+		}
+	}
+
+	header := make(http.Header, len(req.Headers))
+	headers := make(map[string]string, len(req.Headers))
+	for k, v := range req.Headers {
+		header.Set(k, v)
+		headers[http.CanonicalHeaderKey(k)] = v
+	}
+	described := &http.Request{Method: method, URL: u, Host: req.Host, Header: header}
+	incoming := &match.IncomingRequest{
+		Method:        method,
+		Path:          u.Path,
+		Host:          requestHost(described),
+		Headers:       headers,
+		Query:         u.Query(),
+		Cookies:       requestCookies(described),
+		Body:          body,
+		ContentLength: match.DeclaredContentLength(headers, body),
+	}
+
+	candidates, ok := s.lookupCandidates(method, u.Path)
+	if !ok {
+		http.Error(w, "server not ready", http.StatusServiceUnavailable)
+		return
+	}
+	result := s.handleReqUC.Replay(incoming, candidates)
+
+	w.Header().Set("Content-Type", "application/json")
+	writeAdminJSON(w, r, map[string]any{
+		"method":             method,
+		"path":               u.Path,
+		"matched":            result.MatchedID != "",
+		"matched_id":         result.MatchedID,
+		"candidates":         result.Candidates,
+		"candidates_omitted": result.CandidatesOmitted,
+	})
+}
+
+// handleStreamTrace pushes each new trace entry as a Server-Sent Event until
+// the client disconnects.
+func (s *Server) handleStreamTrace(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	entries := s.traceBuf.Subscribe()
+	defer s.traceBuf.Unsubscribe(entries)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		s.logger.Debug("trace stream flush unsupported", "error", err)
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-entries:
+			data, err := json.Marshal(e)
+			if err != nil {
+				s.logger.Error("failed to encode trace entry", "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: trace\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	idx, err := s.loadUC.Execute(r.Context())
+	if err != nil {
+		s.logger.Error("reload failed", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		writeAdminJSON(w, r, map[string]string{
+			"error":   "reload_failed",
+			"message": "scenario reload failed, check server logs",
 		})
+		return
 	}
 
+	s.Rebuild(idx)
 	w.Header().Set("Content-Type", "application/json")
-	writeJSON(w, scenarios)
+	writeAdminJSON(w, r, map[string]string{
+		"status":  "ok",
+		"message": "scenarios reloaded",
+	})
 }
 
-func (s *Server) handleSearchScenarios(w http.ResponseWriter, r *http.Request) {
-	q := strings.ToLower(r.URL.Query().Get("q"))
-	idx := s.index.Load()
-	if idx == nil {
+func (s *Server) handleSetLatency(w http.ResponseWriter, r *http.Request) {
+	defer func() { _ = r.Body.Close() }()
+
+	var req struct {
+		Duration string `json:"duration"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&req); err != nil {
 		w.Header().Set("Content-Type", "application/json")
-		writeJSON(w, []any{})
+		w.WriteHeader(http.StatusBadRequest)
+		writeAdminJSON(w, r, map[string]string{"error": "invalid_request", "message": "expected JSON body with a duration field"})
 		return
 	}
 
-	var results []map[string]any
-	for _, cs := range idx.All() {
-		if q == "" ||
-			strings.Contains(strings.ToLower(cs.ID), q) ||
-			strings.Contains(strings.ToLower(cs.Name), q) ||
-			strings.Contains(strings.ToLower(cs.PathKey), q) {
-			results = append(results, map[string]any{
-				"id":       cs.ID,
-				"name":     cs.Name,
-				"priority": cs.Priority,
-				"method":   cs.Method,
-				"path_key": cs.PathKey,
-			})
-		}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil || d < 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		writeAdminJSON(w, r, map[string]string{"error": "invalid_duration", "message": "invalid duration " + strconv.Quote(req.Duration)})
+		return
 	}
 
+	s.handleReqUC.SetGlobalLatency(d)
+	s.logger.Info("global latency set", "latency", d)
 	w.Header().Set("Content-Type", "application/json")
-	writeJSON(w, results)
+	writeAdminJSON(w, r, map[string]string{"latency": d.String()})
 }
 
-func (s *Server) handleListFiles(w http.ResponseWriter, _ *http.Request) {
-	if s.rootDir == "" {
-		w.Header().Set("Content-Type", "application/json")
-		writeJSON(w, []string{})
-		return
+func (s *Server) handleClearLatency(w http.ResponseWriter, _ *http.Request) {
+	s.handleReqUC.SetGlobalLatency(0)
+	s.logger.Info("global latency cleared")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleGetState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeAdminJSON(w, r, s.snapshotState())
+}
+
+// snapshotState adds the sections held by the server to the use case
+// snapshot: disabled scenarios and response sequence positions.
+func (s *Server) snapshotState() usecases.RuntimeState {
+	state := s.handleReqUC.Snapshot()
+
+	s.rebuildMu.Lock()
+	defer s.rebuildMu.Unlock()
+
+	state.Disabled = make([]string, 0, len(s.disabledIDs))
+	for id := range s.disabledIDs {
+		state.Disabled = append(state.Disabled, id)
 	}
+	slices.Sort(state.Disabled)
 
-	var files []string
-	err := filepath.WalkDir(s.rootDir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil // skip inaccessible entries
-		}
-		if d.IsDir() {
-			return nil
-		}
-		rel, relErr := filepath.Rel(s.rootDir, path)
-		if relErr != nil {
-			return nil
+	state.Sequences = make(map[string]uint64)
+	if idx := s.index.Load(); idx != nil {
+		for _, cs := range idx.All() {
+			if len(cs.Responses) > 0 {
+				state.Sequences[cs.ID] = cs.Served()
+			}
 		}
-		files = append(files, rel)
-		return nil
-	})
-	if err != nil {
-		s.logger.Error("failed to list files", "error", err)
 	}
+	return state
+}
 
-	if files == nil {
-		files = []string{}
+// validateServerState checks the sections restoreServerState applies.
+// Disabled IDs need not be loaded, as they apply on the next reload that
+// brings the scenario back; sequence positions must name a loaded scenario
+// with a `responses` sequence.
+func validateServerState(state usecases.RuntimeState, idx *services.ScenarioIndex) error {
+	for _, id := range state.Disabled {
+		if id == "" {
+			return fmt.Errorf("disabled: empty scenario ID")
+		}
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	writeJSON(w, files)
+	for id := range state.Sequences {
+		var cs *match.CompiledScenario
+		if idx != nil {
+			cs, _ = idx.ByID(id)
+		}
+		if cs == nil || len(cs.Responses) == 0 {
+			return fmt.Errorf("sequences: scenario %q is not loaded or has no response sequence", id)
+		}
+	}
+	return nil
 }
 
-func (s *Server) handleGetTrace(w http.ResponseWriter, r *http.Request) {
-	n := 10
-	if lastParam := r.URL.Query().Get("last"); lastParam != "" {
-		if parsed, err := strconv.Atoi(lastParam); err == nil && parsed > 0 {
-			n = parsed
+// restoreServerState applies the sections validated by validateServerState.
+// The caller holds rebuildMu.
+func (s *Server) restoreServerState(state usecases.RuntimeState, idx *services.ScenarioIndex) {
+	if state.Disabled != nil {
+		if idx != nil {
+			for id := range s.disabledIDs {
+				if cs, ok := idx.ByID(id); ok {
+					cs.SetDisabled(false)
+				}
+			}
+		}
+		s.disabledIDs = make(map[string]bool, len(state.Disabled))
+		for _, id := range state.Disabled {
+			s.disabledIDs[id] = true
+			if idx != nil {
+				if cs, ok := idx.ByID(id); ok {
+					cs.SetDisabled(true)
+				}
+			}
+		}
+	}
+	if state.Sequences != nil && idx != nil {
+		for _, cs := range idx.All() {
+			if len(cs.Responses) > 0 {
+				cs.SetServed(state.Sequences[cs.ID])
+			}
 		}
 	}
-
-	entries := s.traceBuf.Last(n)
-	w.Header().Set("Content-Type", "application/json")
-	writeJSON(w, entries)
 }
 
-func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
-	idx, err := s.loadUC.Execute(r.Context())
+func (s *Server) handleRestoreState(w http.ResponseWriter, r *http.Request) {
+	defer func() { _ = r.Body.Close() }()
+
+	var state usecases.RuntimeState
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&state); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		writeAdminJSON(w, r, map[string]string{"error": "invalid_request", "message": "expected a JSON state snapshot"})
+		return
+	}
+
+	// Validate the server sections first: Restore applies the use case
+	// sections as soon as they are valid, and applying ours cannot fail.
+	s.rebuildMu.Lock()
+	idx := s.index.Load()
+	err := validateServerState(state, idx)
+	if err == nil {
+		err = s.handleReqUC.Restore(state)
+	}
+	if err == nil {
+		s.restoreServerState(state, idx)
+	}
+	s.rebuildMu.Unlock()
 	if err != nil {
-		s.logger.Error("reload failed", "error", err)
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		writeJSON(w, map[string]string{
-			"error":   "reload_failed",
-			"message": "scenario reload failed, check server logs",
-		})
+		w.WriteHeader(http.StatusBadRequest)
+		writeAdminJSON(w, r, map[string]string{"error": "invalid_state", "message": err.Error()})
 		return
 	}
 
-	s.Rebuild(idx)
+	s.logger.Info("runtime state restored", "version", state.Version)
 	w.Header().Set("Content-Type", "application/json")
-	writeJSON(w, map[string]string{
-		"status":  "ok",
-		"message": "scenarios reloaded",
-	})
+	writeAdminJSON(w, r, s.snapshotState())
 }
 
 func (s *Server) handleGetScenario(w http.ResponseWriter, r *http.Request) {
@@ -472,12 +1456,12 @@ func (s *Server) handleGetScenario(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, scenario.ErrNotFound) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusNotFound)
-			writeJSON(w, map[string]string{"error": "not_found", "message": "scenario not found: " + id})
+			writeAdminJSON(w, r, map[string]string{"error": "not_found", "message": "scenario not found: " + id})
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		writeJSON(w, map[string]string{"error": "internal", "message": err.Error()})
+		writeAdminJSON(w, r, map[string]string{"error": "internal", "message": err.Error()})
 		return
 	}
 
@@ -503,14 +1487,108 @@ func (s *Server) handleGetScenario(w http.ResponseWriter, r *http.Request) {
 		"source_index": sc.SourceIndex,
 		"source_yaml":  string(sourceYAML),
 		"when":         buildWhenJSON(sc),
-		"response":     buildResponseJSON(sc),
+		"response":     buildResponseJSON(&sc.Response),
+	}
+	if len(sc.Responses) > 0 {
+		responses := make([]map[string]any, 0, len(sc.Responses))
+		for i := range sc.Responses {
+			responses = append(responses, buildResponseJSON(&sc.Responses[i]))
+		}
+		resp["responses"] = responses
+		resp["cycle"] = sc.Cycle
 	}
 	if sc.Policy != nil {
 		resp["policy"] = buildPolicyJSON(sc.Policy)
 	}
+	if len(sc.Profiles) > 0 {
+		resp["profiles"] = sc.Profiles
+	}
+	if sc.Kind != "" {
+		resp["kind"] = sc.Kind
+	}
+	if sc.Deprecated {
+		resp["deprecated"] = true
+		if sc.DeprecationMessage != "" {
+			resp["deprecation_message"] = sc.DeprecationMessage
+		}
+	}
+	if len(sc.RequireContentType) > 0 {
+		resp["require_content_type"] = sc.RequireContentType
+	}
+	if sc.Quiet {
+		resp["log"] = false
+	}
+	if sc.Dynamic {
+		resp["dynamic"] = true
+	}
+	if sc.Static != nil {
+		resp["static"] = map[string]string{
+			"dir":         sc.Static.Dir,
+			"path_prefix": sc.Static.PathPrefix,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeAdminJSON(w, r, resp)
+}
+
+// handleListHistory lists the saved versions of a scenario, newest first.
+func (s *Server) handleListHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "scenarioID")
+	if s.history == nil {
+		http.Error(w, "scenario history not configured", http.StatusNotImplemented)
+		return
+	}
+
+	versions, err := s.history.List(r.Context(), id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		writeAdminJSON(w, r, map[string]string{"error": "internal", "message": err.Error()})
+		return
+	}
+
+	result := make([]map[string]any, 0, len(versions))
+	for _, v := range versions {
+		result = append(result, map[string]any{
+			"version":  v.ID,
+			"saved_at": v.SavedAt.Format(time.RFC3339Nano),
+			"size":     v.Size,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeAdminJSON(w, r, result)
+}
+
+// handleGetHistoryVersion returns the YAML of one saved version of a scenario.
+func (s *Server) handleGetHistoryVersion(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "scenarioID")
+	version := chi.URLParam(r, "version")
+	if s.history == nil {
+		http.Error(w, "scenario history not configured", http.StatusNotImplemented)
+		return
+	}
+
+	data, err := s.history.Get(r.Context(), id, version)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, scenario.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			writeAdminJSON(w, r, map[string]string{"error": "not_found", "message": "version not found: " + version})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		writeAdminJSON(w, r, map[string]string{"error": "internal", "message": err.Error()})
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	writeJSON(w, resp)
+	writeAdminJSON(w, r, map[string]any{
+		"id":          id,
+		"version":     version,
+		"source_yaml": string(data),
+	})
 }
 
 func (s *Server) handleUpdateScenario(w http.ResponseWriter, r *http.Request) {
@@ -530,7 +1608,7 @@ func (s *Server) handleUpdateScenario(w http.ResponseWriter, r *http.Request) {
 	if err := s.saveUC.Execute(r.Context(), id, body); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
-		writeJSON(w, map[string]string{"error": "save_failed", "message": err.Error()})
+		writeAdminJSON(w, r, map[string]string{"error": "save_failed", "message": err.Error()})
 		return
 	}
 
@@ -540,13 +1618,13 @@ func (s *Server) handleUpdateScenario(w http.ResponseWriter, r *http.Request) {
 		s.logger.Error("reload after save failed", "error", err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		writeJSON(w, map[string]string{"error": "reload_failed", "message": err.Error()})
+		writeAdminJSON(w, r, map[string]string{"error": "reload_failed", "message": err.Error()})
 		return
 	}
 	s.Rebuild(idx)
 
 	w.Header().Set("Content-Type", "application/json")
-	writeJSON(w, map[string]string{"status": "ok", "message": "scenario updated", "id": id})
+	writeAdminJSON(w, r, map[string]string{"status": "ok", "message": "scenario updated", "id": id})
 }
 
 func (s *Server) handleCreateScenario(w http.ResponseWriter, r *http.Request) {
@@ -565,7 +1643,7 @@ func (s *Server) handleCreateScenario(w http.ResponseWriter, r *http.Request) {
 	if err := s.saveUC.Execute(r.Context(), "", body); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
-		writeJSON(w, map[string]string{"error": "create_failed", "message": err.Error()})
+		writeAdminJSON(w, r, map[string]string{"error": "create_failed", "message": err.Error()})
 		return
 	}
 
@@ -575,14 +1653,58 @@ func (s *Server) handleCreateScenario(w http.ResponseWriter, r *http.Request) {
 		s.logger.Error("reload after create failed", "error", err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		writeJSON(w, map[string]string{"error": "reload_failed", "message": err.Error()})
+		writeAdminJSON(w, r, map[string]string{"error": "reload_failed", "message": err.Error()})
 		return
 	}
 	s.Rebuild(idx)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	writeJSON(w, map[string]string{"status": "ok", "message": "scenario created"})
+	writeAdminJSON(w, r, map[string]string{"status": "ok", "message": "scenario created"})
+}
+
+func (s *Server) handleValidateScenario(w http.ResponseWriter, r *http.Request) {
+	if s.validateUC == nil {
+		http.Error(w, "validation not configured", http.StatusNotImplemented)
+		return
+	}
+
+	defer func() { _ = r.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	result := s.validateUC.Execute(r.Context(), body)
+
+	w.Header().Set("Content-Type", "application/json")
+	if !result.Valid {
+		w.WriteHeader(http.StatusBadRequest)
+		writeAdminJSON(w, r, map[string]any{"valid": false, "errors": result.Errors})
+		return
+	}
+
+	scenarios := make([]map[string]any, 0, len(result.Compiled))
+	for _, cs := range result.Compiled {
+		fields := make([]string, 0, len(cs.Predicates))
+		for _, fp := range cs.Predicates {
+			fields = append(fields, fp.Field)
+		}
+		entry := map[string]any{
+			"id":         cs.ID,
+			"name":       cs.Name,
+			"priority":   cs.Priority,
+			"method":     cs.Method,
+			"path_key":   cs.PathKey,
+			"predicates": fields,
+		}
+		if len(cs.Warnings) > 0 {
+			entry["warnings"] = cs.Warnings
+		}
+		scenarios = append(scenarios, entry)
+	}
+	writeAdminJSON(w, r, map[string]any{"valid": true, "scenarios": scenarios})
 }
 
 func (s *Server) handleDeleteScenario(w http.ResponseWriter, r *http.Request) {
@@ -596,12 +1718,12 @@ func (s *Server) handleDeleteScenario(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, scenario.ErrNotFound) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusNotFound)
-			writeJSON(w, map[string]string{"error": "not_found", "message": "scenario not found: " + id})
+			writeAdminJSON(w, r, map[string]string{"error": "not_found", "message": "scenario not found: " + id})
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		writeJSON(w, map[string]string{"error": "delete_failed", "message": err.Error()})
+		writeAdminJSON(w, r, map[string]string{"error": "delete_failed", "message": err.Error()})
 		return
 	}
 
@@ -611,7 +1733,7 @@ func (s *Server) handleDeleteScenario(w http.ResponseWriter, r *http.Request) {
 		s.logger.Error("reload after delete failed", "error", err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		writeJSON(w, map[string]string{"error": "reload_failed", "message": err.Error()})
+		writeAdminJSON(w, r, map[string]string{"error": "reload_failed", "message": err.Error()})
 		return
 	}
 	s.Rebuild(idx)
@@ -626,31 +1748,140 @@ func buildWhenJSON(sc *scenario.Scenario) map[string]any {
 		"method": sc.When.Method,
 		"path":   sc.When.Path,
 	}
+	if len(sc.When.Methods) > 0 {
+		when["method"] = sc.When.Methods[0]
+		when["methods"] = sc.When.Methods
+	}
 	if len(sc.When.Headers) > 0 {
 		headers := make(map[string]string, len(sc.When.Headers))
 		for k, v := range sc.When.Headers {
-			headers[k] = v.Value()
+			headers[k] = matcherText(v)
 		}
 		when["headers"] = headers
 	}
+	if sc.When.UserAgent != nil {
+		when["user_agent"] = matcherText(*sc.When.UserAgent)
+	}
+	if sc.When.Referer != nil {
+		when["referer"] = matcherText(*sc.When.Referer)
+	}
+	if len(sc.When.Query) > 0 {
+		query := make(map[string]string, len(sc.When.Query))
+		for k, v := range sc.When.Query {
+			query[k] = matcherText(v)
+		}
+		when["query"] = query
+	}
+	if len(sc.When.Cookies) > 0 {
+		cookies := make(map[string]string, len(sc.When.Cookies))
+		for k, v := range sc.When.Cookies {
+			cookies[k] = matcherText(v)
+		}
+		when["cookies"] = cookies
+	}
+	if len(sc.When.QueryArrays) > 0 {
+		arrays := make(map[string]any, len(sc.When.QueryArrays))
+		for k, v := range sc.When.QueryArrays {
+			m := map[string]any{}
+			if len(v.Contains) > 0 {
+				m["contains"] = v.Contains
+			}
+			if v.Count != nil {
+				m["count"] = buildNumericMatcherJSON(v.Count)
+			}
+			arrays[k] = m
+		}
+		when["query_array"] = arrays
+	}
 	if sc.When.Body != nil {
 		when["body"] = buildBodyClauseJSON(sc.When.Body)
 	}
+	if sc.When.ContentLength != nil {
+		when["content_length"] = buildNumericMatcherJSON(sc.When.ContentLength)
+	}
+	if sc.When.Host != "" {
+		when["host"] = sc.When.Host
+	}
+	if sc.When.Schedule != "" {
+		when["schedule"] = sc.When.Schedule
+	}
+	if sc.When.Secure != nil {
+		when["secure"] = *sc.When.Secure
+	}
+	if sc.When.Expr != "" {
+		when["expr"] = sc.When.Expr
+	}
+	if sc.When.BodyHash != nil {
+		when["body_hash"] = map[string]string{
+			"algorithm": sc.When.BodyHash.Algorithm,
+			"expected":  sc.When.BodyHash.Expected,
+		}
+	}
+	if sc.When.BodyPrefix != nil {
+		prefix := map[string]string{}
+		if sc.When.BodyPrefix.Hex != "" {
+			prefix["hex"] = sc.When.BodyPrefix.Hex
+		}
+		if sc.When.BodyPrefix.Base64 != "" {
+			prefix["base64"] = sc.When.BodyPrefix.Base64
+		}
+		when["body_prefix"] = prefix
+	}
 	return when
 }
 
+func buildNumericMatcherJSON(m *scenario.NumericMatcher) map[string]float64 {
+	result := map[string]float64{}
+	if m.Eq != nil {
+		result["eq"] = *m.Eq
+	}
+	if m.Gt != nil {
+		result["gt"] = *m.Gt
+	}
+	if m.Gte != nil {
+		result["gte"] = *m.Gte
+	}
+	if m.Lt != nil {
+		result["lt"] = *m.Lt
+	}
+	if m.Lte != nil {
+		result["lte"] = *m.Lte
+	}
+	return result
+}
+
 func buildBodyClauseJSON(bc *scenario.BodyClause) map[string]any {
 	result := map[string]any{}
+	if bc.Decode != "" {
+		result["decode"] = bc.Decode
+	}
 	if bc.ContentType != "" {
 		result["content_type"] = bc.ContentType
 	}
+	if bc.Regex != "" {
+		result["regex"] = bc.Regex
+	}
+	if bc.KeyOrder != nil {
+		result["key_order"] = map[string]any{"keys": bc.KeyOrder.Keys, "allow_extra": bc.KeyOrder.AllowExtra}
+	}
 	if len(bc.Conditions) > 0 {
-		conds := make([]map[string]string, 0, len(bc.Conditions))
+		conds := make([]map[string]any, 0, len(bc.Conditions))
 		for _, c := range bc.Conditions {
-			conds = append(conds, map[string]string{
+			cond := map[string]any{
 				"extractor": c.Extractor,
 				"matcher":   c.Matcher.Value(),
-			})
+			}
+			if c.Min != nil {
+				cond["min"] = *c.Min
+			}
+			if c.Max != nil {
+				cond["max"] = *c.Max
+			}
+			if c.AllRegex != "" {
+				cond["all_regex"] = c.AllRegex
+				cond["allow_empty"] = c.AllowEmpty
+			}
+			conds = append(conds, cond)
 		}
 		result["conditions"] = conds
 	}
@@ -674,24 +1905,110 @@ func buildBodyClauseJSON(bc *scenario.BodyClause) map[string]any {
 	return result
 }
 
-func buildResponseJSON(sc *scenario.Scenario) map[string]any {
+// matcherText renders a string matcher the way it is written in YAML, except
+// that exact values are shown without their "=" prefix.
+func matcherText(m scenario.StringMatcher) string {
+	switch {
+	case m.Exists:
+		return "!exists"
+	case m.Absent:
+		return "!absent"
+	case m.Negated:
+		return "!=" + m.Exact
+	}
+	return m.Value()
+}
+
+func buildResponseJSON(r *scenario.Response) map[string]any {
 	resp := map[string]any{
-		"status": sc.Response.Status,
+		"status": r.Status,
+	}
+	if len(r.Headers) > 0 {
+		resp["headers"] = r.Headers
+	}
+	if r.Body != "" {
+		resp["body"] = r.Body
+	}
+	if r.BodyFile != "" {
+		resp["body_file"] = r.BodyFile
+	}
+	if r.TruncateAtBytes > 0 {
+		resp["truncate_at_bytes"] = r.TruncateAtBytes
+	}
+	if len(r.Compression) > 0 {
+		resp["compression"] = r.Compression
+	}
+	if r.Created != nil {
+		resp["created"] = map[string]string{"location": r.Created.Location}
+	}
+	if r.Generate != nil {
+		resp["generate"] = map[string]any{"count": r.Generate.Count, "fields": r.Generate.Fields}
 	}
-	if len(sc.Response.Headers) > 0 {
-		resp["headers"] = sc.Response.Headers
+	if r.Proxy != nil {
+		proxy := map[string]any{"target": r.Proxy.Target}
+		if r.Proxy.StripPrefix != "" {
+			proxy["strip_prefix"] = r.Proxy.StripPrefix
+		}
+		if r.Proxy.TimeoutMs > 0 {
+			proxy["timeout_ms"] = r.Proxy.TimeoutMs
+		}
+		if r.Proxy.Record {
+			proxy["record"] = true
+		}
+		resp["proxy"] = proxy
+	}
+	if r.BodyFileMissingStatus != 0 {
+		resp["body_file_missing_status"] = r.BodyFileMissingStatus
+	}
+	if r.ContentType != "" {
+		resp["content_type"] = r.ContentType
+	}
+	if r.Engine != "" {
+		resp["engine"] = r.Engine
+	}
+	if r.Charset != "" {
+		resp["charset"] = r.Charset
+	}
+	if r.OmitNulls {
+		resp["omit_nulls"] = true
 	}
-	if sc.Response.Body != "" {
-		resp["body"] = sc.Response.Body
+	if r.Raw {
+		resp["raw"] = true
 	}
-	if sc.Response.BodyFile != "" {
-		resp["body_file"] = sc.Response.BodyFile
+	if r.CanonicalizeBody != "" {
+		resp["canonicalize_body"] = r.CanonicalizeBody
 	}
-	if sc.Response.ContentType != "" {
-		resp["content_type"] = sc.Response.ContentType
+	if r.Cache != nil {
+		resp["cache"] = map[string]any{
+			"max_age":    r.Cache.MaxAge,
+			"visibility": r.Cache.Visibility,
+			"immutable":  r.Cache.Immutable,
+		}
+	}
+	if r.Switch != nil {
+		sw := map[string]any{"on": r.Switch.On}
+		if len(r.Switch.Cases) > 0 {
+			cases := make(map[string]any, len(r.Switch.Cases))
+			for value, c := range r.Switch.Cases {
+				cases[value] = buildResponseJSON(&c)
+			}
+			sw["cases"] = cases
+		}
+		if r.Switch.Default != nil {
+			sw["default"] = buildResponseJSON(r.Switch.Default)
+		}
+		resp["switch"] = sw
 	}
-	if sc.Response.Engine != "" {
-		resp["engine"] = sc.Response.Engine
+	if r.TimeSwitch != nil {
+		cases := make([]map[string]any, 0, len(r.TimeSwitch.Cases))
+		for _, c := range r.TimeSwitch.Cases {
+			cases = append(cases, map[string]any{"schedule": c.Schedule, "response": buildResponseJSON(&c.Response)})
+		}
+		ts := map[string]any{"cases": cases}
+		if r.TimeSwitch.Default != nil {
+			ts["default"] = buildResponseJSON(r.TimeSwitch.Default)
+		}
+		resp["time_switch"] = ts
 	}
 	return resp
 }
@@ -699,16 +2016,21 @@ func buildResponseJSON(sc *scenario.Scenario) map[string]any {
 func buildPolicyJSON(p *scenario.Policy) map[string]any {
 	result := map[string]any{}
 	if p.RateLimit != nil {
-		result["rate_limit"] = map[string]any{
+		rl := map[string]any{
 			"rate":  p.RateLimit.Rate,
 			"burst": p.RateLimit.Burst,
 			"key":   p.RateLimit.Key,
 		}
+		if p.RateLimit.Response != nil {
+			rl["response"] = buildResponseJSON(p.RateLimit.Response)
+		}
+		result["rate_limit"] = rl
 	}
 	if p.Latency != nil {
 		result["latency"] = map[string]any{
-			"fixed_ms":  p.Latency.FixedMs,
-			"jitter_ms": p.Latency.JitterMs,
+			"fixed_ms":        p.Latency.FixedMs,
+			"jitter_ms":       p.Latency.JitterMs,
+			"header_delay_ms": p.Latency.HeaderDelayMs,
 		}
 	}
 	if p.Pagination != nil {
@@ -718,11 +2040,45 @@ func buildPolicyJSON(p *scenario.Policy) map[string]any {
 			"max_size":     p.Pagination.MaxSize,
 			"data_path":    p.Pagination.DataPath,
 		}
+		if p.Pagination.TotalItemsSource != "" {
+			pg["total_items_source"] = p.Pagination.TotalItemsSource
+		}
 		result["pagination"] = pg
 	}
+	if p.LoadBalance != nil {
+		result["load_balance"] = map[string]any{
+			"weight": p.LoadBalance.Weight,
+		}
+	}
+	if p.Fault != nil {
+		fault := map[string]any{"type": p.Fault.Type}
+		if p.Fault.Rate != 0 {
+			fault["rate"] = p.Fault.Rate
+		}
+		result["fault"] = fault
+	}
 	return result
 }
 
+// requestHost returns the lower-cased request host without its port.
+// routePattern returns the matched route pattern. chi's RoutePattern trims a
+// trailing slash, but /a and /a/ are distinct mock paths.
+func routePattern(rctx *chi.Context) string {
+	pattern := rctx.RoutePattern()
+	if joined := strings.Join(rctx.RoutePatterns, ""); pattern != "/" && joined == pattern+"/" {
+		return joined
+	}
+	return pattern
+}
+
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
 func extractQueryParams(r *http.Request) map[string]string {
 	params := make(map[string]string)
 	for k, v := range r.URL.Query() {
@@ -746,8 +2102,23 @@ func extractPathParams(r *http.Request) map[string]string {
 	return params
 }
 
+// writeJSON writes v as indented JSON.
 func writeJSON(w http.ResponseWriter, v any) {
+	encodeJSON(w, v, true)
+}
+
+// writeAdminJSON writes an admin API response: indented for humans, or
+// compact when the request asks for ?pretty=false.
+func writeAdminJSON(w http.ResponseWriter, r *http.Request, v any) {
+	pretty, err := strconv.ParseBool(r.URL.Query().Get("pretty"))
+	encodeJSON(w, v, pretty || err != nil)
+}
+
+// encodeJSON writes v as JSON, with two-space indentation when pretty.
+func encodeJSON(w http.ResponseWriter, v any, pretty bool) {
 	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
 	_ = enc.Encode(v)
 }
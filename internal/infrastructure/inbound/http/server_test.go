@@ -1,20 +1,33 @@
 package http_test
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/andybalholm/brotli"
+
 	"github.com/sophialabs/proteusmock/internal/domain/match"
 	"github.com/sophialabs/proteusmock/internal/domain/scenario"
 	"github.com/sophialabs/proteusmock/internal/domain/trace"
 	inboundhttp "github.com/sophialabs/proteusmock/internal/infrastructure/inbound/http"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/filesystem"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/template"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/services"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/usecases"
 	"github.com/sophialabs/proteusmock/internal/testutil"
@@ -145,6 +158,50 @@ func TestMockHandler_NoMatch_Returns404WithDebug(t *testing.T) {
 	}
 }
 
+func TestMockHandler_NoMatch_NotesTruncatedCandidates(t *testing.T) {
+	logger := &testutil.NoopLogger{}
+	traceBuf := trace.NewRingBuffer(10)
+	evaluator := match.NewEvaluator()
+	evaluator.SetMaxCandidates(3)
+	handleReqUC := usecases.NewHandleRequestUseCase(evaluator, &testutil.FixedClock{}, &testutil.StubRateLimiter{AllowAll: true}, logger, traceBuf)
+	srv := inboundhttp.NewServer(handleReqUC, nil, traceBuf, logger)
+
+	idx := services.NewScenarioIndex()
+	for i := range 10 {
+		idx.Add(&match.CompiledScenario{
+			ID:      fmt.Sprintf("tenant-%02d", i),
+			Method:  "GET",
+			PathKey: "GET:/api/items",
+			Predicates: []match.FieldPredicate{
+				{Field: "header:X-Tenant", Predicate: func(string) bool { return false }},
+			},
+			Response: match.CompiledResponse{Status: 200},
+		})
+	}
+	idx.Build()
+	srv.Rebuild(idx)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/items", nil))
+
+	if w.Code != 404 {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+	var debug map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &debug); err != nil {
+		t.Fatalf("failed to parse debug response: %v", err)
+	}
+	if candidates, _ := debug["candidates"].([]any); len(candidates) != 3 {
+		t.Errorf("expected 3 candidates, got %v", debug["candidates"])
+	}
+	if debug["candidates_truncated"] != true {
+		t.Errorf("expected candidates_truncated=true, got %v", debug["candidates_truncated"])
+	}
+	if debug["candidates_omitted"] != 7.0 {
+		t.Errorf("expected 7 omitted candidates, got %v", debug["candidates_omitted"])
+	}
+}
+
 func TestMockHandler_POSTWithBody(t *testing.T) {
 	srv, _ := buildTestServer(&match.CompiledScenario{
 		ID:       "create",
@@ -180,6 +237,7 @@ func TestAdminHandler_ListScenarios(t *testing.T) {
 		},
 		&match.CompiledScenario{
 			ID: "s2", Name: "Scenario 2", Method: "POST", PathKey: "POST:/b", Priority: 5,
+			Profiles: []string{"dev"},
 		},
 	)
 
@@ -196,7 +254,89 @@ func TestAdminHandler_ListScenarios(t *testing.T) {
 		t.Fatalf("failed to parse response: %v", err)
 	}
 	if len(scenarios) != 2 {
-		t.Errorf("expected 2 scenarios, got %d", len(scenarios))
+		t.Fatalf("expected 2 scenarios, got %d", len(scenarios))
+	}
+	if profiles, _ := scenarios[0]["profiles"].([]any); len(profiles) != 0 {
+		t.Errorf("expected empty profiles for s1, got %v", scenarios[0]["profiles"])
+	}
+	if profiles, _ := scenarios[1]["profiles"].([]any); len(profiles) != 1 || profiles[0] != "dev" {
+		t.Errorf("expected profiles [dev] for s2, got %v", scenarios[1]["profiles"])
+	}
+}
+
+func TestAdminHandler_Index(t *testing.T) {
+	always := func(string) bool { return true }
+	srv, _ := buildTestServer(
+		&match.CompiledScenario{
+			ID: "generic", Method: "GET", PathKey: "GET:/items", Priority: 5,
+			Predicates: []match.FieldPredicate{{Field: "method", Predicate: always}},
+		},
+		&match.CompiledScenario{
+			ID: "specific", Method: "GET", PathKey: "GET:/items", Priority: 5,
+			Predicates: []match.FieldPredicate{
+				{Field: "method", Predicate: always},
+				{Field: "header:X-Tier", Predicate: always},
+			},
+		},
+		&match.CompiledScenario{
+			ID: "urgent", Method: "GET", PathKey: "GET:/items", Priority: 50,
+		},
+		&match.CompiledScenario{
+			ID: "create", Method: "POST", PathKey: "POST:/items",
+		},
+	)
+
+	req := httptest.NewRequest("GET", "/__admin/index", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var body struct {
+		Routes []struct {
+			Key        string `json:"key"`
+			Method     string `json:"method"`
+			Path       string `json:"path"`
+			Candidates []struct {
+				Position        int      `json:"position"`
+				ID              string   `json:"id"`
+				Priority        int      `json:"priority"`
+				Specificity     int      `json:"specificity"`
+				PredicateFields []string `json:"predicate_fields"`
+			} `json:"candidates"`
+		} `json:"routes"`
+		Statics []any `json:"statics"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(body.Routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(body.Routes))
+	}
+	get := body.Routes[0]
+	if get.Key != "GET:/items" || get.Method != "GET" || get.Path != "/items" {
+		t.Errorf("unexpected route: %+v", get)
+	}
+
+	var order []string
+	for _, c := range get.Candidates {
+		order = append(order, c.ID)
+	}
+	if strings.Join(order, ",") != "urgent,specific,generic" {
+		t.Errorf("expected priority then specificity order, got %v", order)
+	}
+	specific := get.Candidates[1]
+	if specific.Position != 2 || specific.Specificity != 2 || len(specific.PredicateFields) != 2 || specific.PredicateFields[1] != "header:X-Tier" {
+		t.Errorf("unexpected candidate details: %+v", specific)
+	}
+	if body.Routes[1].Key != "POST:/items" {
+		t.Errorf("expected POST route second, got %q", body.Routes[1].Key)
+	}
+	if body.Statics == nil {
+		t.Error("expected statics to be an empty array, not null")
 	}
 }
 
@@ -254,121 +394,934 @@ func TestAdminHandler_GetTrace(t *testing.T) {
 	}
 }
 
-func TestMockHandler_RateLimited(t *testing.T) {
-	traceBuf := trace.NewRingBuffer(50)
-	evaluator := match.NewEvaluator()
-	clk := &testutil.FixedClock{T: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
-	rl := &testutil.StubRateLimiter{AllowAll: false} // Always deny.
-	logger := &testutil.NoopLogger{}
+func TestAdminHandler_CompactJSON(t *testing.T) {
+	srv, _ := buildTestServer(&match.CompiledScenario{
+		ID:       "compact",
+		Method:   "GET",
+		PathKey:  "GET:/api/compact",
+		Response: match.CompiledResponse{Status: 200},
+	})
 
-	handleReqUC := usecases.NewHandleRequestUseCase(evaluator, clk, rl, logger, traceBuf)
-	srv := inboundhttp.NewServer(handleReqUC, nil, traceBuf, logger)
+	tests := []struct {
+		query   string
+		compact bool
+	}{
+		{"", false},
+		{"?pretty=true", false},
+		{"?pretty=false", true},
+		{"?pretty=0", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/__admin/scenarios"+tt.query, nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+
+		body := strings.TrimSuffix(w.Body.String(), "\n")
+		if compact := !strings.Contains(body, "\n"); compact != tt.compact {
+			t.Errorf("%q: expected compact=%v, got body %q", tt.query, tt.compact, body)
+		}
+		var v []map[string]any
+		if err := json.Unmarshal([]byte(body), &v); err != nil || len(v) != 1 {
+			t.Errorf("%q: expected one scenario, got %q (%v)", tt.query, body, err)
+		}
+	}
+}
 
-	idx := services.NewScenarioIndex()
-	idx.Add(&match.CompiledScenario{
-		ID:       "limited",
+func TestAdminHandler_TraceStream(t *testing.T) {
+	srv, _ := buildTestServer(&match.CompiledScenario{
+		ID:       "streamed",
 		Method:   "GET",
-		PathKey:  "GET:/api/limited",
-		Priority: 10,
-		Predicates: []match.FieldPredicate{
-			{Field: "method", Predicate: func(s string) bool { return s == "GET" }},
-		},
-		Response: match.CompiledResponse{Status: 200, Body: []byte("ok")},
-		Policy: &match.CompiledPolicy{
-			RateLimit: &match.CompiledRateLimit{Rate: 1, Burst: 1, Key: "test"},
-		},
+		PathKey:  "GET:/api/streamed",
+		Response: match.CompiledResponse{Status: 200},
 	})
-	idx.Build()
-	srv.Rebuild(idx)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/__admin/trace/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
 
-	req := httptest.NewRequest("GET", "/api/limited", nil)
-	w := httptest.NewRecorder()
-	srv.ServeHTTP(w, req)
+	// Headers arrive after the handler subscribed, so this request is streamed.
+	mock, err := http.Get(ts.URL + "/api/streamed")
+	if err != nil {
+		t.Fatalf("mock request failed: %v", err)
+	}
+	mock.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if e, ok := strings.CutPrefix(line, "event: "); ok {
+			event = e
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			if event != "trace" {
+				t.Errorf("expected event trace, got %q", event)
+			}
+			var entry trace.Entry
+			if err := json.Unmarshal([]byte(data), &entry); err != nil {
+				t.Fatalf("invalid entry %q: %v", data, err)
+			}
+			if entry.MatchedID != "streamed" || entry.Path != "/api/streamed" {
+				t.Errorf("unexpected entry: %+v", entry)
+			}
+			return
+		}
+	}
+	t.Fatalf("stream ended without an entry: %v", scanner.Err())
+}
 
-	if w.Code != http.StatusTooManyRequests {
-		t.Errorf("expected 429, got %d", w.Code)
+func TestBuildRouter_TrailingSlash(t *testing.T) {
+	scenarios := func() []*match.CompiledScenario {
+		return []*match.CompiledScenario{
+			{ID: "users", Method: "GET", PathKey: "GET:/api/users", Response: match.CompiledResponse{Status: 200, Body: []byte("users")}},
+			{ID: "create-user", Method: "POST", PathKey: "POST:/api/users", Response: match.CompiledResponse{Status: 201}},
+			{ID: "dir", Method: "GET", PathKey: "GET:/api/dir/", Response: match.CompiledResponse{Status: 200, Body: []byte("dir")}},
+		}
 	}
 
-	var body map[string]any
-	json.Unmarshal(w.Body.Bytes(), &body)
-	if body["error"] != "rate_limited" {
-		t.Errorf("expected rate_limited error, got %v", body["error"])
+	type check struct {
+		method, target string
+		status         int
+		location       string
+	}
+	tests := []struct {
+		mode   string
+		checks []check
+	}{
+		{"", []check{
+			{"GET", "/api/users", 200, ""},
+			{"GET", "/api/users/", 404, ""},
+			{"GET", "/api/dir", 404, ""},
+			{"GET", "/api/dir/", 200, ""},
+		}},
+		{inboundhttp.TrailingSlashStrip, []check{
+			{"GET", "/api/users/", 200, ""},
+			{"GET", "/api/dir/", 404, ""}, // now routed as /api/dir
+		}},
+		{inboundhttp.TrailingSlashRedirect, []check{
+			{"GET", "/api/users/?page=2", 301, "/api/users?page=2"},
+			{"POST", "/api/users/", 308, "/api/users"},
+			{"GET", "/api/users", 200, ""},
+			{"GET", "//evil.example/", 301, "/evil.example"},
+			{"GET", "/\\evil.example/", 301, "/evil.example"},
+			{"GET", "///evil.example/path/", 301, "/evil.example/path"},
+		}},
+		{inboundhttp.TrailingSlashEquivalent, []check{
+			{"GET", "/api/users/", 200, ""},
+			{"GET", "/api/dir", 200, ""},
+			{"GET", "/api/dir/", 200, ""},
+			{"GET", "/api/other/", 404, ""},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run("mode="+tt.mode, func(t *testing.T) {
+			srv, idx := buildTestServer(scenarios()...)
+			if err := srv.SetTrailingSlash(tt.mode); err != nil {
+				t.Fatalf("SetTrailingSlash: %v", err)
+			}
+			srv.Rebuild(idx)
+
+			for _, c := range tt.checks {
+				w := httptest.NewRecorder()
+				srv.ServeHTTP(w, httptest.NewRequest(c.method, c.target, nil))
+				if w.Code != c.status {
+					t.Errorf("%s %s: expected %d, got %d", c.method, c.target, c.status, w.Code)
+				}
+				if loc := w.Header().Get("Location"); loc != c.location {
+					t.Errorf("%s %s: expected Location %q, got %q", c.method, c.target, c.location, loc)
+				}
+			}
+
+			// Admin routes are never rewritten.
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/scenarios/", nil))
+			if w.Code != http.StatusNotFound {
+				t.Errorf("expected admin path with trailing slash to stay unrouted, got %d", w.Code)
+			}
+		})
+	}
+
+	srv, _ := buildTestServer()
+	if err := srv.SetTrailingSlash("sometimes"); err == nil {
+		t.Error("expected error for unknown mode")
 	}
 }
 
-func TestNotFoundHandler(t *testing.T) {
-	srv, _ := buildTestServer() // No scenarios.
+func TestBuildRouter_MiddlewarePipeline(t *testing.T) {
+	secret := &match.CompiledScenario{
+		ID: "secret", Method: "GET", PathKey: "GET:/api/secret",
+		Response: match.CompiledResponse{Status: 200, Body: []byte("a b")},
+	}
+	redact := func(from, to string) inboundhttp.MiddlewareSpec {
+		return inboundhttp.MiddlewareSpec{Name: "redact", Params: map[string]string{"pattern": from, "replacement": to}}
+	}
+
+	tests := []struct {
+		name  string
+		specs []inboundhttp.MiddlewareSpec
+		want  string
+	}{
+		// The inner redact runs first on the way out: "a b" -> "a c" -> "b c".
+		{"a->b outside b->c", []inboundhttp.MiddlewareSpec{redact("a", "b"), redact("b", "c")}, "b c"},
+		// "a b" -> "b b" -> "c c".
+		{"b->c outside a->b", []inboundhttp.MiddlewareSpec{redact("b", "c"), redact("a", "b")}, "c c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, idx := buildTestServer(secret)
+			specs := append(tt.specs, inboundhttp.MiddlewareSpec{
+				Name: "response_header", Params: map[string]string{"name": "X-Pipeline", "value": "on"},
+			})
+			if err := srv.SetMiddlewares(specs); err != nil {
+				t.Fatalf("SetMiddlewares: %v", err)
+			}
+			srv.Rebuild(idx)
+
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/secret", nil))
+			if w.Body.String() != tt.want {
+				t.Errorf("expected body %q, got %q", tt.want, w.Body.String())
+			}
+			if w.Header().Get("X-Pipeline") != "on" {
+				t.Errorf("expected X-Pipeline header, got %v", w.Header())
+			}
+
+			// Admin routes bypass the pipeline.
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("GET", "/__health", nil))
+			if w.Header().Get("X-Pipeline") != "" {
+				t.Error("expected /__health to bypass the pipeline")
+			}
+		})
+	}
+
+	t.Run("redact skips encoded and partial responses", func(t *testing.T) {
+		compressed := &match.CompiledScenario{
+			ID: "compressed", Method: "GET", PathKey: "GET:/api/compressed",
+			Response: match.CompiledResponse{Status: 200, Body: []byte(strings.Repeat("a b ", 64)), Compression: []string{"gzip"}},
+		}
+		ranged := &match.CompiledScenario{
+			ID: "ranged", Method: "GET", PathKey: "GET:/api/ranged",
+			Response: match.CompiledResponse{Status: 200, Body: []byte("a b a b"), BodyFile: "data.txt"},
+		}
+		srv, idx := buildTestServer(secret, compressed, ranged)
+		if err := srv.SetMiddlewares([]inboundhttp.MiddlewareSpec{redact("a", "x")}); err != nil {
+			t.Fatalf("SetMiddlewares: %v", err)
+		}
+		srv.Rebuild(idx)
+
+		req := httptest.NewRequest("GET", "/api/compressed", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("expected a gzip response, got %v", w.Header())
+		}
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("expected intact gzip bytes: %v", err)
+		}
+		if plain, _ := io.ReadAll(zr); string(plain) != strings.Repeat("a b ", 64) {
+			t.Errorf("expected the encoded body untouched, got %q", plain)
+		}
+
+		req = httptest.NewRequest("GET", "/api/ranged", nil)
+		req.Header.Set("Range", "bytes=0-2")
+		w = httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusPartialContent || w.Body.String() != "a b" || w.Header().Get("Content-Range") != "bytes 0-2/7" {
+			t.Errorf("expected the partial response untouched, got %d %q %q", w.Code, w.Body.String(), w.Header().Get("Content-Range"))
+		}
+
+		w = httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/secret", nil))
+		if w.Body.String() != "x b" {
+			t.Errorf("expected plain responses redacted, got %q", w.Body.String())
+		}
+	})
+
+	srv, _ := buildTestServer()
+	for _, specs := range [][]inboundhttp.MiddlewareSpec{
+		{{Name: "teleport"}},
+		{{Name: "redact"}},
+		{{Name: "redact", Params: map[string]string{"pattern": "("}}},
+		{{Name: "compress", Params: map[string]string{"level": "11"}}},
+	} {
+		if err := srv.SetMiddlewares(specs); err == nil {
+			t.Errorf("expected error for %+v", specs)
+		}
+	}
+}
+
+func TestParseMiddlewareSpec(t *testing.T) {
+	spec, err := inboundhttp.ParseMiddlewareSpec("response_header:name=X-Mock,value=a=b")
+	if err != nil {
+		t.Fatalf("ParseMiddlewareSpec: %v", err)
+	}
+	if spec.Name != "response_header" || spec.Params["name"] != "X-Mock" || spec.Params["value"] != "a=b" {
+		t.Errorf("unexpected spec: %+v", spec)
+	}
+
+	if spec, err := inboundhttp.ParseMiddlewareSpec("compress"); err != nil || spec.Name != "compress" || len(spec.Params) != 0 {
+		t.Errorf("unexpected spec %+v, err %v", spec, err)
+	}
+	for _, bad := range []string{"", ":level=1", "redact:pattern"} {
+		if _, err := inboundhttp.ParseMiddlewareSpec(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestAdminHandler_DisableScenario(t *testing.T) {
+	newScenario := func() *match.CompiledScenario {
+		return &match.CompiledScenario{
+			ID:       "toggle",
+			Method:   "GET",
+			PathKey:  "GET:/api/toggle",
+			Response: match.CompiledResponse{Status: 200},
+		}
+	}
+	srv, _ := buildTestServer(newScenario())
+
+	status := func(method, target string) int {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w.Code
+	}
+
+	if code := status("POST", "/__admin/scenarios/toggle/disable"); code != http.StatusOK {
+		t.Fatalf("expected 200 from disable, got %d", code)
+	}
+	if code := status("GET", "/api/toggle"); code != http.StatusNotFound {
+		t.Errorf("expected disabled scenario not to match, got %d", code)
+	}
 
-	req := httptest.NewRequest("GET", "/unregistered", nil)
 	w := httptest.NewRecorder()
-	srv.ServeHTTP(w, req)
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/scenarios", nil))
+	var listed []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil || len(listed) != 1 || listed[0]["disabled"] != true {
+		t.Errorf("expected scenario listed as disabled, got %s", w.Body.String())
+	}
 
-	if w.Code != 404 {
-		t.Errorf("expected 404, got %d", w.Code)
+	// A reload compiles a fresh scenario; the override is re-applied.
+	idx := services.NewScenarioIndex()
+	idx.Add(newScenario())
+	idx.Build()
+	srv.Rebuild(idx)
+	if code := status("GET", "/api/toggle"); code != http.StatusNotFound {
+		t.Errorf("expected scenario to stay disabled after rebuild, got %d", code)
 	}
 
-	var body map[string]any
-	json.Unmarshal(w.Body.Bytes(), &body)
-	if body["error"] != "no_match" {
-		t.Errorf("expected 'no_match', got %v", body["error"])
+	if code := status("POST", "/__admin/scenarios/toggle/enable"); code != http.StatusOK {
+		t.Fatalf("expected 200 from enable, got %d", code)
 	}
-	if body["path"] != "/unregistered" {
-		t.Errorf("expected path '/unregistered', got %v", body["path"])
+	if code := status("GET", "/api/toggle"); code != http.StatusOK {
+		t.Errorf("expected enabled scenario to match, got %d", code)
+	}
+
+	if code := status("POST", "/__admin/scenarios/missing/disable"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown scenario, got %d", code)
 	}
 }
 
-func TestMockHandler_TemplateRendering(t *testing.T) {
-	renderer := &fakeRenderer{body: []byte(`Hello, rendered!`)}
+func TestAdminHandler_ExplainMatch(t *testing.T) {
 	srv, _ := buildTestServer(&match.CompiledScenario{
-		ID:       "template",
-		Method:   "GET",
-		PathKey:  "GET:/api/template",
-		Priority: 10,
+		ID:      "premium-user",
+		Name:    "Premium user",
+		Method:  "POST",
+		PathKey: "POST:/api/users/{id}",
 		Predicates: []match.FieldPredicate{
-			{Field: "method", Predicate: func(s string) bool { return s == "GET" }},
-		},
-		Response: match.CompiledResponse{
-			Status:      200,
-			Renderer:    renderer,
-			ContentType: "text/plain",
+			{Field: "header:X-Plan", Predicate: func(s string) bool { return s == "premium" }},
+			{Field: "query:verbose", Predicate: func(s string) bool { return s == "1" }},
+			{Field: "body", Predicate: func(s string) bool { return strings.Contains(s, `"active":true`) }},
 		},
+		Response: match.CompiledResponse{Status: 200},
+	})
+
+	explain := func(t *testing.T, body string) (int, map[string]any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("POST", "/__admin/match", strings.NewReader(body)))
+		var result map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+		}
+		return w.Code, result
+	}
+
+	t.Run("matching request", func(t *testing.T) {
+		code, result := explain(t, `{"method":"post","path":"/api/users/7?verbose=1","headers":{"x-plan":"premium"},"body":{"active":true}}`)
+		if code != http.StatusOK || result["matched"] != true || result["matched_id"] != "premium-user" {
+			t.Errorf("unexpected result %d: %v", code, result)
+		}
+	})
+
+	t.Run("failed field is reported", func(t *testing.T) {
+		code, result := explain(t, `{"method":"POST","path":"/api/users/7?verbose=1","headers":{"X-Plan":"free"},"body":"{\"active\":true}"}`)
+		if code != http.StatusOK || result["matched"] != false {
+			t.Fatalf("unexpected result %d: %v", code, result)
+		}
+		candidates, _ := result["candidates"].([]any)
+		if len(candidates) != 1 {
+			t.Fatalf("expected one candidate, got %v", result["candidates"])
+		}
+		if c := candidates[0].(map[string]any); c["failed_field"] != "header:X-Plan" {
+			t.Errorf("expected header:X-Plan to fail, got %v", c)
+		}
+	})
+
+	t.Run("unrouted path", func(t *testing.T) {
+		code, result := explain(t, `{"path":"/nowhere"}`)
+		if code != http.StatusOK || result["matched"] != false || result["method"] != "GET" {
+			t.Errorf("unexpected result %d: %v", code, result)
+		}
+		if candidates, ok := result["candidates"].([]any); !ok || len(candidates) != 0 {
+			t.Errorf("expected empty candidates, got %v", result["candidates"])
+		}
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		if code, _ := explain(t, `{"path":"relative"}`); code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", code)
+		}
 	})
 
-	req := httptest.NewRequest("GET", "/api/template", nil)
 	w := httptest.NewRecorder()
-	srv.ServeHTTP(w, req)
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/trace", nil))
+	if strings.TrimSpace(w.Body.String()) != "null" {
+		t.Errorf("expected no trace entries, got %s", w.Body.String())
+	}
+}
 
-	if w.Code != 200 {
-		t.Errorf("expected 200, got %d", w.Code)
+func TestAdminHandler_TraceFilters(t *testing.T) {
+	srv, _ := buildTestServer(
+		&match.CompiledScenario{
+			ID:       "users",
+			Method:   "GET",
+			PathKey:  "GET:/api/users",
+			Response: match.CompiledResponse{Status: 200},
+		},
+		&match.CompiledScenario{
+			ID:      "gated",
+			Method:  "GET",
+			PathKey: "GET:/api/gated",
+			Predicates: []match.FieldPredicate{
+				{Field: "header:X-Key", Predicate: func(s string) bool { return s == "secret" }},
+			},
+			Response: match.CompiledResponse{Status: 200},
+		},
+		&match.CompiledScenario{
+			ID:       "other",
+			Method:   "GET",
+			PathKey:  "GET:/other",
+			Response: match.CompiledResponse{Status: 200},
+		},
+		&match.CompiledScenario{
+			ID:       "create-order",
+			Method:   "POST",
+			PathKey:  "POST:/api/orders",
+			Response: match.CompiledResponse{Status: 201},
+		},
+	)
+	for _, req := range []struct{ method, path string }{
+		{"GET", "/api/users"},
+		{"POST", "/api/orders"},
+		{"GET", "/api/gated"},
+		{"GET", "/api/users"},
+		{"DELETE", "/api/orders"},
+		{"GET", "/other"},
+	} {
+		srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
 	}
-	if w.Body.String() != "Hello, rendered!" {
-		t.Errorf("expected rendered body, got %q", w.Body.String())
+
+	tests := []struct {
+		query string
+		want  []string // method + " " + path, oldest first
+	}{
+		{"", []string{"GET /api/users", "POST /api/orders", "GET /api/gated", "GET /api/users", "DELETE /api/orders", "GET /other"}},
+		{"?method=get", []string{"GET /api/users", "GET /api/gated", "GET /api/users", "GET /other"}},
+		{"?path=/api/orders", []string{"POST /api/orders", "DELETE /api/orders"}},
+		{"?matched=true", []string{"GET /api/users", "POST /api/orders", "GET /api/users", "GET /other"}},
+		{"?matched=false&path=/api", []string{"GET /api/gated", "DELETE /api/orders"}},
+		{"?method=GET&matched=true&last=1", []string{"GET /other"}},
+		{"?method=GET&path=/api&last=2", []string{"GET /api/gated", "GET /api/users"}},
+		{"?method=PUT", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/trace"+tt.query, nil))
+			var entries []trace.Entry
+			if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Method+" "+e.Path)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/trace?matched=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid matched, got %d", w.Code)
 	}
 }
 
-func TestMockHandler_TemplateRenderError(t *testing.T) {
-	renderer := &errorRenderer{}
+func TestAdminHandler_ClearTrace(t *testing.T) {
 	srv, _ := buildTestServer(&match.CompiledScenario{
-		ID:       "render-error",
+		ID:       "traced",
 		Method:   "GET",
-		PathKey:  "GET:/api/error",
-		Priority: 10,
-		Predicates: []match.FieldPredicate{
-			{Field: "method", Predicate: func(s string) bool { return s == "GET" }},
-		},
-		Response: match.CompiledResponse{
-			Status:      200,
-			Renderer:    renderer,
-			ContentType: "text/plain",
-		},
+		PathKey:  "GET:/api/traced",
+		Response: match.CompiledResponse{Status: 200},
 	})
+	for range 3 {
+		srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/traced", nil))
+	}
 
-	req := httptest.NewRequest("GET", "/api/error", nil)
 	w := httptest.NewRecorder()
-	srv.ServeHTTP(w, req)
+	srv.ServeHTTP(w, httptest.NewRequest("DELETE", "/__admin/trace", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
 
-	if w.Code != 500 {
-		t.Errorf("expected 500, got %d", w.Code)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/trace", nil))
+	var entries []trace.Entry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries after clear, got %d", len(entries))
+	}
+}
+
+func TestAdminHandler_TraceCSV(t *testing.T) {
+	srv, _ := buildTestServer(&match.CompiledScenario{
+		ID:       "comma",
+		Method:   "GET",
+		PathKey:  "GET:/api/a,b",
+		Response: match.CompiledResponse{Status: 200},
+	})
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/a,b", nil))
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/trace.csv", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("expected text/csv, got %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), `"/api/a,b"`) {
+		t.Errorf("expected the path to be quoted, got %q", w.Body.String())
+	}
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	want := [][]string{
+		{"timestamp", "method", "path", "matched_id", "rate_limited", "candidates"},
+		{"2025-01-01T00:00:00Z", "GET", "/api/a,b", "comma", "false", "1"},
+	}
+	if !slices.EqualFunc(rows, want, slices.Equal[[]string]) {
+		t.Errorf("expected rows %v, got %v", want, rows)
+	}
+}
+
+func TestAdminHandler_TraceReplay(t *testing.T) {
+	tenantScenario := func(tenant string) *match.CompiledScenario {
+		return &match.CompiledScenario{
+			ID:      "orders-" + tenant,
+			Method:  "GET",
+			PathKey: "GET:/api/tenants/{tenant}/orders",
+			Predicates: []match.FieldPredicate{
+				{Field: "method", Predicate: func(s string) bool { return s == "GET" }},
+				{Field: "header:X-Region", Predicate: func(s string) bool { return s == tenant }},
+			},
+			Response: match.CompiledResponse{Status: 200},
+		}
+	}
+	srv, _ := buildTestServer(tenantScenario("eu"))
+
+	req := httptest.NewRequest("GET", "/api/tenants/acme/orders", nil)
+	req.Header.Set("X-Region", "us")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before the fix, got %d", w.Code)
+	}
+
+	// Fix the scenario, then replay the miss.
+	idx := services.NewScenarioIndex()
+	idx.Add(tenantScenario("us"))
+	idx.Build()
+	srv.Rebuild(idx)
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("POST", "/__admin/trace/1/replay", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result struct {
+		Matched       bool                    `json:"matched"`
+		MatchedID     string                  `json:"matched_id"`
+		OriginalMatch string                  `json:"original_match"`
+		Candidates    []trace.CandidateResult `json:"candidates"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !result.Matched || result.MatchedID != "orders-us" || result.OriginalMatch != "" {
+		t.Errorf("unexpected replay result: %+v", result)
+	}
+	if len(result.Candidates) != 1 || !result.Candidates[0].Matched {
+		t.Errorf("expected one matching candidate, got %+v", result.Candidates)
+	}
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/trace", nil))
+	var entries []trace.Entry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil || len(entries) != 1 {
+		t.Errorf("expected replay not to add trace entries, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("POST", "/__admin/trace/99/replay", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown entry, got %d", w.Code)
+	}
+}
+
+func TestMockHandler_RateLimited(t *testing.T) {
+	traceBuf := trace.NewRingBuffer(50)
+	evaluator := match.NewEvaluator()
+	clk := &testutil.FixedClock{T: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	rl := &testutil.StubRateLimiter{AllowAll: false} // Always deny.
+	logger := &testutil.NoopLogger{}
+
+	handleReqUC := usecases.NewHandleRequestUseCase(evaluator, clk, rl, logger, traceBuf)
+	srv := inboundhttp.NewServer(handleReqUC, nil, traceBuf, logger)
+
+	idx := services.NewScenarioIndex()
+	idx.Add(&match.CompiledScenario{
+		ID:       "limited",
+		Method:   "GET",
+		PathKey:  "GET:/api/limited",
+		Priority: 10,
+		Predicates: []match.FieldPredicate{
+			{Field: "method", Predicate: func(s string) bool { return s == "GET" }},
+		},
+		Response: match.CompiledResponse{Status: 200, Body: []byte("ok")},
+		Policy: &match.CompiledPolicy{
+			RateLimit: &match.CompiledRateLimit{Rate: 1, Burst: 1, Key: "test"},
+		},
+	})
+	idx.Build()
+	srv.Rebuild(idx)
+
+	req := httptest.NewRequest("GET", "/api/limited", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", w.Code)
+	}
+
+	var body map[string]any
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body["error"] != "rate_limited" {
+		t.Errorf("expected rate_limited error, got %v", body["error"])
+	}
+}
+
+func TestMockHandler_RateLimitedCustomResponse(t *testing.T) {
+	traceBuf := trace.NewRingBuffer(50)
+	evaluator := match.NewEvaluator()
+	clk := &testutil.FixedClock{T: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	rl := &testutil.StubRateLimiter{AllowAll: false} // Always deny.
+	logger := &testutil.NoopLogger{}
+
+	handleReqUC := usecases.NewHandleRequestUseCase(evaluator, clk, rl, logger, traceBuf)
+	srv := inboundhttp.NewServer(handleReqUC, nil, traceBuf, logger)
+
+	idx := services.NewScenarioIndex()
+	idx.Add(&match.CompiledScenario{
+		ID:       "limited",
+		Method:   "GET",
+		PathKey:  "GET:/api/limited",
+		Response: match.CompiledResponse{Status: 200, Body: []byte("ok")},
+		Policy: &match.CompiledPolicy{
+			RateLimit: &match.CompiledRateLimit{
+				Rate:  1,
+				Burst: 1,
+				Response: &match.CompiledResponse{
+					Status:      http.StatusServiceUnavailable,
+					Headers:     map[string]string{"Retry-After": "30"},
+					Body:        []byte(`{"code":"BACKEND_BUSY"}`),
+					ContentType: "application/json",
+				},
+			},
+		},
+	})
+	idx.Build()
+	srv.Rebuild(idx)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/limited", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", w.Code)
+	}
+	if w.Body.String() != `{"code":"BACKEND_BUSY"}` {
+		t.Errorf("unexpected body: %q", w.Body.String())
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("expected scenario Retry-After to win, got %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected application/json, got %q", got)
+	}
+
+	// Templated rate-limit responses render with the injected clock.
+	idx.Add(&match.CompiledScenario{
+		ID:       "limited-templated",
+		Method:   "GET",
+		PathKey:  "GET:/api/templated",
+		Response: match.CompiledResponse{Status: 200, Body: []byte("ok")},
+		Policy: &match.CompiledPolicy{
+			RateLimit: &match.CompiledRateLimit{
+				Rate:  1,
+				Burst: 1,
+				Response: &match.CompiledResponse{
+					Status: http.StatusTooManyRequests,
+					Renderer: renderFunc(func(ctx match.RenderContext) ([]byte, error) {
+						return []byte("retry after " + ctx.Now), nil
+					}),
+				},
+			},
+		},
+	})
+	idx.Build()
+	srv.Rebuild(idx)
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/templated", nil))
+	if w.Body.String() != "retry after 2025-01-01T00:00:00Z" {
+		t.Errorf("expected the injected clock in the body, got %q", w.Body.String())
+	}
+}
+
+func TestAdminHandler_GetScenarioMatcherShorthands(t *testing.T) {
+	dir := t.TempDir()
+	yamlBody := `id: bots
+when:
+  method: GET
+  path: /api/bots
+  user_agent: "!=curl/8.0"
+  referer: "!absent"
+response:
+  status: 200
+`
+	if err := os.WriteFile(filepath.Join(dir, "bots.yaml"), []byte(yamlBody), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := filesystem.NewYAMLRepository(dir)
+	if err != nil {
+		t.Fatalf("NewYAMLRepository failed: %v", err)
+	}
+	srv, _ := buildTestServer()
+	srv.SetCRUDDeps(nil, nil, repo, dir)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/scenarios/bots", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var detail struct {
+		When map[string]any `json:"when"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got := detail.When["user_agent"]; got != "!=curl/8.0" {
+		t.Errorf("expected negated user_agent, got %v", got)
+	}
+	if got := detail.When["referer"]; got != "!absent" {
+		t.Errorf("expected absent referer, got %v", got)
+	}
+}
+
+func TestNotFoundHandler(t *testing.T) {
+	srv, _ := buildTestServer() // No scenarios.
+
+	req := httptest.NewRequest("GET", "/unregistered", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 404 {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+
+	var body map[string]any
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body["error"] != "no_match" {
+		t.Errorf("expected 'no_match', got %v", body["error"])
+	}
+	if body["path"] != "/unregistered" {
+		t.Errorf("expected path '/unregistered', got %v", body["path"])
+	}
+}
+
+func TestMockHandler_TemplateRendering(t *testing.T) {
+	renderer := &fakeRenderer{body: []byte(`Hello, rendered!`)}
+	srv, _ := buildTestServer(&match.CompiledScenario{
+		ID:       "template",
+		Method:   "GET",
+		PathKey:  "GET:/api/template",
+		Priority: 10,
+		Predicates: []match.FieldPredicate{
+			{Field: "method", Predicate: func(s string) bool { return s == "GET" }},
+		},
+		Response: match.CompiledResponse{
+			Status:      200,
+			Renderer:    renderer,
+			ContentType: "text/plain",
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/api/template", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if w.Body.String() != "Hello, rendered!" {
+		t.Errorf("expected rendered body, got %q", w.Body.String())
+	}
+}
+
+func TestMockHandler_TemplateRenderError(t *testing.T) {
+	renderer := &errorRenderer{}
+	srv, _ := buildTestServer(&match.CompiledScenario{
+		ID:       "render-error",
+		Method:   "GET",
+		PathKey:  "GET:/api/error",
+		Priority: 10,
+		Predicates: []match.FieldPredicate{
+			{Field: "method", Predicate: func(s string) bool { return s == "GET" }},
+		},
+		Response: match.CompiledResponse{
+			Status:      200,
+			Renderer:    renderer,
+			ContentType: "text/plain",
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/api/error", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 500 {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+}
+
+func TestMockHandler_TemplateRenderErrorDevMode(t *testing.T) {
+	srv, idx := buildTestServer(&match.CompiledScenario{
+		ID:       "render-error",
+		Method:   "GET",
+		PathKey:  "GET:/api/error",
+		Priority: 10,
+		Predicates: []match.FieldPredicate{
+			{Field: "method", Predicate: func(s string) bool { return s == "GET" }},
+		},
+		Response: match.CompiledResponse{
+			Status:       200,
+			Renderer:     &errorRenderer{},
+			TemplateName: "templates/user.json",
+			ContentType:  "text/plain",
+		},
+	})
+	srv.SetDevMode(true)
+	srv.Rebuild(idx)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/error", nil))
+
+	if w.Code != 500 {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected a JSON error body, got %q", w.Body.String())
+	}
+	want := map[string]string{
+		"error":    "template_render_error",
+		"scenario": "render-error",
+		"template": "templates/user.json",
+		"message":  io.ErrUnexpectedEOF.Error(),
+	}
+	if !maps.Equal(body, want) {
+		t.Errorf("expected %v, got %v", want, body)
+	}
+}
+
+func TestMockHandler_StatusBodies(t *testing.T) {
+	isGET := []match.FieldPredicate{{Field: "method", Predicate: func(s string) bool { return s == "GET" }}}
+	srv, idx := buildTestServer(
+		&match.CompiledScenario{
+			ID: "forbidden", Method: "GET", PathKey: "GET:/api/admin", Priority: 10, Predicates: isGET,
+			Response: match.CompiledResponse{Status: 403, ContentType: "application/json"},
+		},
+		&match.CompiledScenario{
+			ID: "forbidden-custom", Method: "GET", PathKey: "GET:/api/billing", Priority: 10, Predicates: isGET,
+			Response: match.CompiledResponse{Status: 403, Body: []byte(`{"error":"billing_locked"}`), ContentType: "application/json"},
+		},
+		&match.CompiledScenario{
+			ID: "no-content", Method: "GET", PathKey: "GET:/api/ping", Priority: 10, Predicates: isGET,
+			Response: match.CompiledResponse{Status: 204},
+		},
+	)
+	srv.SetStatusBodies(map[int]match.BodyRenderer{
+		403: &fakeRenderer{body: []byte(`{"error":"forbidden"}`)},
+	})
+	srv.Rebuild(idx)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/api/admin", 403, `{"error":"forbidden"}`},
+		{"/api/billing", 403, `{"error":"billing_locked"}`},
+		{"/api/ping", 204, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.status, w.Code)
+		}
+		if w.Body.String() != tt.body {
+			t.Errorf("%s: expected body %q, got %q", tt.path, tt.body, w.Body.String())
+		}
 	}
 }
 
@@ -587,7 +1540,14 @@ func (r *errorRenderer) Render(_ match.RenderContext) ([]byte, error) {
 	return nil, io.ErrUnexpectedEOF
 }
 
-func TestAdminHandler_ReloadSuccess(t *testing.T) {
+// renderFunc renders with a function of the render context.
+type renderFunc func(ctx match.RenderContext) ([]byte, error)
+
+func (f renderFunc) Render(ctx match.RenderContext) ([]byte, error) {
+	return f(ctx)
+}
+
+func TestAdminHandler_ReloadSuccess(t *testing.T) {
 	traceBuf := trace.NewRingBuffer(50)
 	evaluator := match.NewEvaluator()
 	clk := &testutil.FixedClock{T: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
@@ -746,3 +1706,1856 @@ func TestServer_NotReady(t *testing.T) {
 		t.Errorf("expected 503, got %d", w.Code)
 	}
 }
+
+func TestMockHandler_MatchesDeclaredContentLength(t *testing.T) {
+	srv, _ := buildTestServer(&match.CompiledScenario{
+		ID:      "large-upload",
+		Method:  "POST",
+		PathKey: "POST:/api/upload",
+		Predicates: []match.FieldPredicate{
+			{Field: "content_length", Predicate: func(s string) bool { return s == "500" }},
+		},
+		Response: match.CompiledResponse{Status: 202},
+	})
+
+	// Declared length differs from the actual body size.
+	req := httptest.NewRequest("POST", "/api/upload", strings.NewReader("tiny"))
+	req.ContentLength = 500
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 202 {
+		t.Errorf("expected 202 for declared content length, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/upload", strings.NewReader("tiny"))
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 404 {
+		t.Errorf("expected 404 for actual content length, got %d", w.Code)
+	}
+}
+
+func TestMockHandler_ContentLengthChunked(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	limit := 1024.0
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID: "small-upload",
+		When: scenario.WhenClause{
+			Method:        "POST",
+			Path:          "/api/upload",
+			ContentLength: &scenario.NumericMatcher{Lt: &limit},
+		},
+		Response: scenario.Response{Status: 202},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(cs)
+
+	req := httptest.NewRequest("POST", "/api/upload", strings.NewReader("tiny"))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != 202 {
+		t.Errorf("expected 202 for a small declared length, got %d", w.Code)
+	}
+
+	// A chunked upload has no declared length and must not pass an upper bound.
+	req = httptest.NewRequest("POST", "/api/upload", strings.NewReader(strings.Repeat("x", 4096)))
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != 404 {
+		t.Errorf("expected 404 for a chunked upload, got %d", w.Code)
+	}
+
+	// The explain endpoint applies the same rule to described requests.
+	for _, tt := range []struct {
+		body    string
+		matched bool
+	}{
+		{`{"method":"POST","path":"/api/upload","body":"tiny"}`, true},
+		{`{"method":"POST","path":"/api/upload","headers":{"Content-Length":"4096"},"body":"tiny"}`, false},
+		{`{"method":"POST","path":"/api/upload","headers":{"transfer-encoding":"chunked"},"body":"tiny"}`, false},
+	} {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("POST", "/__admin/match", strings.NewReader(tt.body)))
+		var result map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+		}
+		if result["matched"] != tt.matched {
+			t.Errorf("%s: expected matched=%v, got %v", tt.body, tt.matched, result["matched"])
+		}
+	}
+}
+
+func TestMockHandler_CharsetTranscoding(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:   "latin1",
+		When: scenario.WhenClause{Method: "GET", Path: "/api/latin1"},
+		Response: scenario.Response{
+			Status:      200,
+			Body:        "olá",
+			ContentType: "text/plain",
+			Charset:     "ISO-8859-1",
+		},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(cs)
+
+	req := httptest.NewRequest("GET", "/api/latin1", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=ISO-8859-1" {
+		t.Errorf("unexpected content type: %q", ct)
+	}
+	want := []byte{'o', 'l', 0xE1}
+	if got := w.Body.Bytes(); string(got) != string(want) {
+		t.Errorf("expected body %x, got %x", want, got)
+	}
+}
+
+func TestMockHandler_OmitNulls(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		want        string
+	}{
+		{"json body is pruned", "application/json", `{"a":1,"nested":{"c":true}}`},
+		{"non-json body is untouched", "text/plain", `{"a":1,"b":null,"nested":{"c":true,"d":null}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := buildTestServer(&match.CompiledScenario{
+				ID:      "nulls",
+				Method:  "GET",
+				PathKey: "GET:/api/nulls",
+				Response: match.CompiledResponse{
+					Status:      200,
+					Body:        []byte(`{"a":1,"b":null,"nested":{"c":true,"d":null}}`),
+					ContentType: tt.contentType,
+					OmitNulls:   true,
+				},
+			})
+
+			req := httptest.NewRequest("GET", "/api/nulls", nil)
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+
+			if w.Code != 200 {
+				t.Fatalf("expected 200, got %d", w.Code)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("expected body %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMockHandler_CustomMethod(t *testing.T) {
+	srv, _ := buildTestServer(
+		&match.CompiledScenario{
+			ID:      "purge-cache",
+			Method:  "PURGE",
+			PathKey: "PURGE:/cache/{key}",
+			Predicates: []match.FieldPredicate{
+				{Field: "method", Predicate: func(s string) bool { return s == "PURGE" }},
+			},
+			Response: match.CompiledResponse{Status: 200, Body: []byte("purged")},
+		},
+		&match.CompiledScenario{
+			ID:       "get-cache",
+			Method:   "GET",
+			PathKey:  "GET:/cache/{key}",
+			Response: match.CompiledResponse{Status: 200, Body: []byte("cached")},
+		},
+	)
+
+	req := httptest.NewRequest("PURGE", "/cache/home", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Body.String() != "purged" {
+		t.Errorf("expected body 'purged', got %q", w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/cache/home", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Body.String() != "cached" {
+		t.Errorf("expected GET to still match its own scenario, got %q", w.Body.String())
+	}
+}
+
+func TestRebuild_NewCustomMethodWhileServing(t *testing.T) {
+	traceBuf := trace.NewRingBuffer(50)
+	logger := &testutil.CapturingLogger{}
+	handleReqUC := usecases.NewHandleRequestUseCase(match.NewEvaluator(), &testutil.FixedClock{}, &testutil.StubRateLimiter{AllowAll: true}, logger, traceBuf)
+	srv := inboundhttp.NewServer(handleReqUC, nil, traceBuf, logger)
+
+	build := func(scenarios ...*match.CompiledScenario) *services.ScenarioIndex {
+		idx := services.NewScenarioIndex()
+		for _, cs := range scenarios {
+			idx.Add(cs)
+		}
+		idx.Build()
+		return idx
+	}
+	getCache := func(body string) *match.CompiledScenario {
+		return &match.CompiledScenario{
+			ID:       "get-cache",
+			Method:   "GET",
+			PathKey:  "GET:/cache/{key}",
+			Response: match.CompiledResponse{Status: 200, Body: []byte(body)},
+		}
+	}
+	srv.Rebuild(build(getCache("cached")))
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/cache/home", nil))
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	srv.Rebuild(build(getCache("updated"), &match.CompiledScenario{
+		ID:       "unlock-cache",
+		Method:   "UNLOCKCACHE",
+		PathKey:  "UNLOCKCACHE:/cache/{key}",
+		Response: match.CompiledResponse{Status: 200},
+	}))
+
+	// The rest of the reload applies; only the new method is not routed.
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/cache/home", nil))
+	if w.Body.String() != "updated" {
+		t.Errorf("expected the reloaded scenario, got %q", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("UNLOCKCACHE", "/cache/home", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for the unregistered method, got %d", w.Code)
+	}
+	if warns := logger.Warns(); !slices.ContainsFunc(warns, func(msg string) bool { return strings.Contains(msg, "restart") }) {
+		t.Errorf("expected a warning that a restart is needed, got %v", warns)
+	}
+}
+
+func TestStaticMount(t *testing.T) {
+	root := t.TempDir()
+	assets := filepath.Join(root, "assets")
+	if err := os.MkdirAll(filepath.Join(assets, "css"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(assets, "css", "site.css"), []byte("body { color: red; }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	compiler, err := services.NewCompiler(root, nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:     "cdn",
+		Static: &scenario.StaticMount{Dir: "assets", PathPrefix: "/cdn"},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(cs)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/cdn/css/site.css", nil))
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("expected text/css content type, got %q", ct)
+	}
+	if w.Body.String() != "body { color: red; }" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/cdn/css/site.css", nil)
+	req.Header.Set("Range", "bytes=0-3")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "body" {
+		t.Errorf("expected 206 with 'body', got %d %q", w.Code, w.Body.String())
+	}
+
+	for _, path := range []string{"/cdn/css/missing.css", "/cdn/../secret.txt", "/cdn/%2e%2e/secret.txt"} {
+		w = httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code == 200 || strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("%s: expected not found, got %d %q", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestMockHandler_BodyFileRange(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "clip.bin"), []byte("0123456789abcdef"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	compiler, err := services.NewCompiler(root, nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:       "clip",
+		When:     scenario.WhenClause{Method: "GET", Path: "/media/clip"},
+		Response: scenario.Response{Status: 200, BodyFile: "clip.bin", ContentType: "video/mp4"},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(cs)
+
+	req := httptest.NewRequest("GET", "/media/clip", nil)
+	req.Header.Set("Range", "bytes=4-7")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d", w.Code)
+	}
+	if w.Body.String() != "4567" {
+		t.Errorf("expected '4567', got %q", w.Body.String())
+	}
+	if cr := w.Header().Get("Content-Range"); cr != "bytes 4-7/16" {
+		t.Errorf("unexpected Content-Range %q", cr)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "video/mp4" {
+		t.Errorf("expected configured content type, got %q", ct)
+	}
+
+	// Without Range, the full body is served and ranges are advertised.
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/media/clip", nil))
+	if w.Code != 200 || w.Body.String() != "0123456789abcdef" {
+		t.Errorf("expected full body, got %d %q", w.Code, w.Body.String())
+	}
+	if ar := w.Header().Get("Accept-Ranges"); ar != "bytes" {
+		t.Errorf("expected Accept-Ranges: bytes, got %q", ar)
+	}
+
+	req = httptest.NewRequest("GET", "/media/clip", nil)
+	req.Header.Set("Range", "bytes=100-200")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("expected 416, got %d", w.Code)
+	}
+}
+
+func TestAdminHandler_ValidateScenario(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := filesystem.NewYAMLRepository(dir)
+	if err != nil {
+		t.Fatalf("NewYAMLRepository failed: %v", err)
+	}
+	compiler, err := services.NewCompiler(dir, nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	srv, idx := buildTestServer()
+	srv.SetValidateUseCase(usecases.NewValidateScenarioUseCase(repo, compiler, &testutil.NoopLogger{}))
+
+	t.Run("valid", func(t *testing.T) {
+		yamlBody := `
+id: preview
+name: Preview
+when:
+  method: GET
+  path: /preview
+  headers:
+    X-Mode: =debug
+response:
+  status: 200
+`
+		req := httptest.NewRequest("POST", "/__admin/scenarios/validate", strings.NewReader(yamlBody))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Valid     bool `json:"valid"`
+			Scenarios []struct {
+				ID         string   `json:"id"`
+				PathKey    string   `json:"path_key"`
+				Predicates []string `json:"predicates"`
+			} `json:"scenarios"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if !resp.Valid || len(resp.Scenarios) != 1 {
+			t.Fatalf("unexpected response: %+v", resp)
+		}
+		if resp.Scenarios[0].PathKey != "GET:/preview" {
+			t.Errorf("unexpected path key: %s", resp.Scenarios[0].PathKey)
+		}
+		if fmt.Sprint(resp.Scenarios[0].Predicates) != "[method header:X-Mode]" {
+			t.Errorf("unexpected predicates: %v", resp.Scenarios[0].Predicates)
+		}
+		if len(idx.Keys()) != 0 {
+			t.Error("validation must not touch the index")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		yamlBody := `
+id: broken
+when:
+  method: GET
+  path: /broken
+  headers:
+    X-Key: "[invalid"
+`
+		req := httptest.NewRequest("POST", "/__admin/scenarios/validate", strings.NewReader(yamlBody))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+		var resp struct {
+			Valid  bool `json:"valid"`
+			Errors []struct {
+				Stage string `json:"stage"`
+			} `json:"errors"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if resp.Valid || len(resp.Errors) != 1 || resp.Errors[0].Stage != "compile" {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/__admin/scenarios/validate", strings.NewReader("id: [unclosed"))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+
+		if w.Code != 400 {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Error("validation must not write to disk")
+		}
+	})
+}
+
+// phaseClock records each requested sleep along with whether the response
+// headers had already been flushed to the recorder at that point.
+type phaseClock struct {
+	testutil.FixedClock
+	rec    *httptest.ResponseRecorder
+	sleeps []time.Duration
+	sentAt []bool
+}
+
+func (c *phaseClock) SleepContext(_ context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.sentAt = append(c.sentAt, c.rec.Flushed)
+	return nil
+}
+
+func TestMockHandler_LatencyPhases(t *testing.T) {
+	w := httptest.NewRecorder()
+	clk := &phaseClock{rec: w}
+	logger := &testutil.NoopLogger{}
+	traceBuf := trace.NewRingBuffer(10)
+	handleReqUC := usecases.NewHandleRequestUseCase(match.NewEvaluator(), clk, &testutil.StubRateLimiter{AllowAll: true}, logger, traceBuf)
+	srv := inboundhttp.NewServer(handleReqUC, nil, traceBuf, logger)
+
+	idx := services.NewScenarioIndex()
+	idx.Add(&match.CompiledScenario{
+		ID:       "slow",
+		Method:   "GET",
+		PathKey:  "GET:/api/slow",
+		Response: match.CompiledResponse{Status: 200, Body: []byte("done")},
+		Policy: &match.CompiledPolicy{
+			Latency: &match.CompiledLatency{HeaderDelayMs: 200, FixedMs: 500},
+		},
+	})
+	idx.Build()
+	srv.Rebuild(idx)
+
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/slow", nil))
+
+	if len(clk.sleeps) != 2 {
+		t.Fatalf("expected 2 latency phases, got %v", clk.sleeps)
+	}
+	if clk.sleeps[0] != 200*time.Millisecond || clk.sentAt[0] {
+		t.Errorf("expected 200ms header delay before headers were sent, got %v (sent=%v)", clk.sleeps[0], clk.sentAt[0])
+	}
+	if clk.sleeps[1] != 500*time.Millisecond || !clk.sentAt[1] {
+		t.Errorf("expected 500ms body delay after headers were flushed, got %v (sent=%v)", clk.sleeps[1], clk.sentAt[1])
+	}
+	if w.Code != 200 || w.Body.String() != "done" {
+		t.Errorf("unexpected response: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestAdminHandler_GlobalLatency(t *testing.T) {
+	clk := &phaseClock{}
+	logger := &testutil.NoopLogger{}
+	traceBuf := trace.NewRingBuffer(10)
+	handleReqUC := usecases.NewHandleRequestUseCase(match.NewEvaluator(), clk, &testutil.StubRateLimiter{AllowAll: true}, logger, traceBuf)
+	srv := inboundhttp.NewServer(handleReqUC, nil, traceBuf, logger)
+
+	idx := services.NewScenarioIndex()
+	idx.Add(&match.CompiledScenario{
+		ID:       "plain",
+		Method:   "GET",
+		PathKey:  "GET:/api/plain",
+		Response: match.CompiledResponse{Status: 200, Body: []byte("ok")},
+	})
+	idx.Build()
+	srv.Rebuild(idx)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		clk.rec = w
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(httptest.NewRequest("POST", "/__admin/latency", strings.NewReader(`{"duration":"300ms"}`)))
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	serve(httptest.NewRequest("GET", "/api/plain", nil))
+	if len(clk.sleeps) != 1 || clk.sleeps[0] != 300*time.Millisecond {
+		t.Fatalf("expected a single 300ms delay, got %v", clk.sleeps)
+	}
+
+	w = serve(httptest.NewRequest("DELETE", "/__admin/latency", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+
+	clk.sleeps = nil
+	w = serve(httptest.NewRequest("GET", "/api/plain", nil))
+	if len(clk.sleeps) != 0 {
+		t.Errorf("expected no delay after clearing, got %v", clk.sleeps)
+	}
+	if w.Body.String() != "ok" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+
+	for _, body := range []string{`{"duration":"soon"}`, `{"duration":"-1s"}`, `not json`} {
+		w = serve(httptest.NewRequest("POST", "/__admin/latency", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestAdminHandler_StateRoundTrip(t *testing.T) {
+	sequence := []match.CompiledResponse{{Status: 200}, {Status: 201}, {Status: 202}}
+	srv, _ := buildTestServer(
+		&match.CompiledScenario{
+			ID:        "sequence",
+			Method:    "GET",
+			PathKey:   "GET:/api/sequence",
+			Response:  sequence[0],
+			Responses: sequence,
+		},
+		&match.CompiledScenario{
+			ID:       "toggle",
+			Method:   "GET",
+			PathKey:  "GET:/api/toggle",
+			Response: match.CompiledResponse{Status: 200},
+		},
+	)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	globalLatency := func() string {
+		t.Helper()
+		var state struct {
+			Latency struct {
+				Global string `json:"global"`
+			} `json:"latency"`
+		}
+		if err := json.Unmarshal(do("GET", "/__admin/state", "").Body.Bytes(), &state); err != nil {
+			t.Fatalf("failed to parse state: %v", err)
+		}
+		return state.Latency.Global
+	}
+
+	// Capture a baseline, change the state, then restore the baseline.
+	baseline := do("GET", "/__admin/state", "")
+	if baseline.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", baseline.Code)
+	}
+
+	if w := do("POST", "/__admin/latency", `{"duration":"750ms"}`); w.Code != http.StatusOK {
+		t.Fatalf("set latency: expected 200, got %d", w.Code)
+	}
+	if got := globalLatency(); got != "750ms" {
+		t.Fatalf("expected changed latency in snapshot, got %q", got)
+	}
+	if w := do("POST", "/__admin/scenarios/toggle/disable", ""); w.Code != http.StatusOK {
+		t.Fatalf("disable: expected 200, got %d", w.Code)
+	}
+	for range 2 {
+		do("GET", "/api/sequence", "")
+	}
+	changed := do("GET", "/__admin/state", "")
+	var state usecases.RuntimeState
+	if err := json.Unmarshal(changed.Body.Bytes(), &state); err != nil {
+		t.Fatalf("failed to parse state: %v", err)
+	}
+	if !slices.Equal(state.Disabled, []string{"toggle"}) || state.Sequences["sequence"] != 2 {
+		t.Fatalf("expected disabled scenario and sequence position in snapshot, got %s", changed.Body.String())
+	}
+
+	if w := do("POST", "/__admin/state", baseline.Body.String()); w.Code != http.StatusOK {
+		t.Fatalf("restore: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	restored := do("GET", "/__admin/state", "")
+	if restored.Body.String() != baseline.Body.String() {
+		t.Errorf("expected restored state %s, got %s", baseline.Body.String(), restored.Body.String())
+	}
+	if w := do("GET", "/api/toggle", ""); w.Code != http.StatusOK {
+		t.Errorf("expected restored scenario to be enabled, got %d", w.Code)
+	}
+	if w := do("GET", "/api/sequence", ""); w.Code != http.StatusOK {
+		t.Errorf("expected the sequence to restart at its first response, got %d", w.Code)
+	}
+
+	// Restoring the changed snapshot brings back both sections.
+	if w := do("POST", "/__admin/state", changed.Body.String()); w.Code != http.StatusOK {
+		t.Fatalf("restore changed: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/api/toggle", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected restored scenario to be disabled, got %d", w.Code)
+	}
+	if w := do("GET", "/api/sequence", ""); w.Code != http.StatusAccepted {
+		t.Errorf("expected the sequence to resume at its third response, got %d", w.Code)
+	}
+
+	// Newer snapshots with unknown sections still restore known ones.
+	if w := do("POST", "/__admin/state", `{"version":99,"latency":{"global":"1s"},"future":{"x":1}}`); w.Code != http.StatusOK {
+		t.Fatalf("forward-compatible restore: expected 200, got %d", w.Code)
+	}
+	if got := globalLatency(); got != "1s" {
+		t.Errorf("expected latency from newer snapshot to be applied, got %q", got)
+	}
+
+	// Invalid snapshots are rejected without changing state.
+	if w := do("POST", "/__admin/state", `{"version":1,"latency":{"global":"soon"}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid latency, got %d", w.Code)
+	}
+	if w := do("POST", "/__admin/state", `not json`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for malformed body, got %d", w.Code)
+	}
+	if w := do("POST", "/__admin/state", `{"latency":{"global":"5s"},"disabled":[],"sequences":{"toggle":1}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a position of a scenario without sequence, got %d", w.Code)
+	}
+	if got := globalLatency(); got != "1s" {
+		t.Errorf("expected rejected snapshot to leave state unchanged, got %q", got)
+	}
+	if w := do("GET", "/api/toggle", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected rejected snapshot to leave the scenario disabled, got %d", w.Code)
+	}
+}
+
+func TestMockHandler_CacheHeaders(t *testing.T) {
+	headers := map[string]string{"X-Custom": "1"}
+	srv, _ := buildTestServer(&match.CompiledScenario{
+		ID:      "cached",
+		Method:  "GET",
+		PathKey: "GET:/assets/app.js",
+		Response: match.CompiledResponse{
+			Status:  200,
+			Headers: headers,
+			Body:    []byte("console.log(1)"),
+			Cache: &match.CompiledCache{
+				CacheControl: "public, max-age=3600, immutable",
+				MaxAge:       time.Hour,
+			},
+		},
+	})
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/assets/app.js", nil))
+
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=3600, immutable" {
+		t.Errorf("unexpected Cache-Control: %q", got)
+	}
+	// buildTestServer's clock is fixed at 2025-01-01T00:00:00Z.
+	if got := w.Header().Get("Expires"); got != "Wed, 01 Jan 2025 01:00:00 GMT" {
+		t.Errorf("unexpected Expires: %q", got)
+	}
+	if w.Header().Get("X-Custom") != "1" {
+		t.Error("expected scenario headers to be kept")
+	}
+	if len(headers) != 1 {
+		t.Errorf("expected compiled headers to stay unmodified, got %v", headers)
+	}
+}
+
+func TestMockHandler_HostWildcardParams(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), template.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID: "tenant",
+		When: scenario.WhenClause{
+			Method: "GET",
+			Path:   "/whoami",
+			Host:   "{tenant}.*.Example.com",
+		},
+		Response: scenario.Response{
+			Status: 200,
+			Engine: "expr",
+			Body:   `{"tenant":"${host('tenant')}"}`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(cs)
+
+	tests := []struct {
+		host     string
+		wantCode int
+		wantBody string
+	}{
+		{"acme.eu.example.com", 200, `{"tenant":"acme"}`},
+		{"Globex.US.example.com:8080", 200, `{"tenant":"globex"}`},
+		{"example.com", 404, ""},
+		{"a.b.eu.example.com", 404, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/whoami", nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+
+		if w.Code != tt.wantCode {
+			t.Errorf("host %s: expected %d, got %d", tt.host, tt.wantCode, w.Code)
+			continue
+		}
+		if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+			t.Errorf("host %s: expected body %s, got %s", tt.host, tt.wantBody, w.Body.String())
+		}
+	}
+}
+
+func TestMockHandler_DeprecatedHeaders(t *testing.T) {
+	srv, _ := buildTestServer(
+		&match.CompiledScenario{
+			ID:                 "old",
+			Method:             "GET",
+			PathKey:            "GET:/v1/users",
+			Response:           match.CompiledResponse{Status: 200, Body: []byte("[]")},
+			Deprecated:         true,
+			DeprecationMessage: `Use "/v2/users" instead`,
+		},
+		&match.CompiledScenario{
+			ID:       "current",
+			Method:   "GET",
+			PathKey:  "GET:/v2/users",
+			Response: match.CompiledResponse{Status: 200, Body: []byte("[]")},
+		},
+	)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/v1/users", nil))
+	if got := w.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("expected Deprecation: true, got %q", got)
+	}
+	if got := w.Header().Get("Warning"); got != `299 - "Use \"/v2/users\" instead"` {
+		t.Errorf("unexpected Warning: %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/v2/users", nil))
+	if w.Header().Get("Deprecation") != "" || w.Header().Get("Warning") != "" {
+		t.Errorf("expected no deprecation headers, got %v", w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/scenarios", nil))
+	var scenarios []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &scenarios); err != nil {
+		t.Fatalf("failed to parse scenarios: %v", err)
+	}
+	for _, sc := range scenarios {
+		if want := sc["id"] == "old"; sc["deprecated"] != want {
+			t.Errorf("scenario %v: expected deprecated %v, got %v", sc["id"], want, sc["deprecated"])
+		}
+	}
+}
+
+func TestMockHandler_MethodNotAllowed(t *testing.T) {
+	srv, _ := buildTestServer(
+		&match.CompiledScenario{
+			ID:       "list",
+			Method:   "GET",
+			PathKey:  "GET:/api/items",
+			Response: match.CompiledResponse{Status: 200, Body: []byte("[]")},
+		},
+		&match.CompiledScenario{
+			ID:       "remove",
+			Method:   "DELETE",
+			PathKey:  "DELETE:/api/items",
+			Response: match.CompiledResponse{Status: 204},
+		},
+	)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("POST", "/api/items", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 while disabled, got %d", w.Code)
+	}
+
+	srv.SetMethodNotAllowed(true)
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("POST", "/api/items", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+	if got := w.Header().Get("Allow"); got != "DELETE, GET" {
+		t.Errorf("unexpected Allow header: %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/items", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for a registered method, got %d", w.Code)
+	}
+}
+
+func TestMockHandler_CaptureEcho(t *testing.T) {
+	srv, _ := buildTestServer(&match.CompiledScenario{
+		ID:      "admin-only",
+		Method:  "POST",
+		PathKey: "POST:/api/orders",
+		Predicates: []match.FieldPredicate{
+			{Field: "header:X-Role", Predicate: func(s string) bool { return s == "admin" }},
+		},
+		Response: match.CompiledResponse{Status: 201},
+	})
+	srv.SetCaptureEcho(true)
+
+	for _, path := range []string{"/api/orders", "/api/unknown"} {
+		req := httptest.NewRequest("POST", path+"?dry_run=1", strings.NewReader(`{"sku":"A1"}`))
+		req.Header.Set("X-Role", "guest")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, w.Code)
+		}
+		var echo struct {
+			Matched bool                `json:"matched"`
+			Method  string              `json:"method"`
+			Path    string              `json:"path"`
+			Query   map[string][]string `json:"query"`
+			Headers map[string]string   `json:"headers"`
+			Body    string              `json:"body"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &echo); err != nil {
+			t.Fatalf("%s: failed to parse echo: %v", path, err)
+		}
+		if echo.Matched || echo.Method != "POST" || echo.Path != path {
+			t.Errorf("%s: unexpected echo %+v", path, echo)
+		}
+		if echo.Query["dry_run"][0] != "1" || echo.Headers["X-Role"] != "guest" || echo.Body != `{"sku":"A1"}` {
+			t.Errorf("%s: request details not echoed: %+v", path, echo)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/trace", nil))
+	var entries []trace.Entry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("failed to parse trace: %v", err)
+	}
+	if len(entries) != 2 || entries[0].MatchedID != "" || entries[1].Path != "/api/unknown" {
+		t.Errorf("expected both misses in the trace, got %+v", entries)
+	}
+}
+
+func TestMockHandler_TemplatedBodyFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "fixtures"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fixtures", "a.json"), []byte(`{"id":"a"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	compiler, err := services.NewCompiler(dir, template.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:   "fixture",
+		When: scenario.WhenClause{Method: "GET", Path: "/items/{id}"},
+		Response: scenario.Response{
+			BodyFile:              "fixtures/${pathParam('id')}.json",
+			BodyFileMissingStatus: 410,
+		},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(cs)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/items/a", nil))
+	if w.Code != http.StatusOK || w.Body.String() != `{"id":"a"}` {
+		t.Errorf("expected fixture a, got %d %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/items/b", nil))
+	if w.Code != http.StatusGone {
+		t.Errorf("expected configured 410 for a missing fixture, got %d", w.Code)
+	}
+}
+
+func TestMockHandler_CreatedLocation(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), template.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:   "create-order",
+		When: scenario.WhenClause{Method: "POST", Path: "/tenants/{tenant}/orders"},
+		Response: scenario.Response{
+			Body:    `{"ok":true}`,
+			Created: &scenario.Created{Location: "/tenants/${pathParam('tenant')}/orders/${uuid()}"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(cs)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("POST", "/tenants/acme/orders", nil))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", w.Code)
+	}
+	location := w.Header().Get("Location")
+	if !regexp.MustCompile(`^/tenants/acme/orders/[0-9a-f-]{36}$`).MatchString(location) {
+		t.Errorf("unexpected Location: %q", location)
+	}
+}
+
+func TestMockHandler_TruncateAtBytes(t *testing.T) {
+	body := strings.Repeat("x", 1024)
+	srv, _ := buildTestServer(&match.CompiledScenario{
+		ID:      "truncated",
+		Method:  "GET",
+		PathKey: "GET:/api/download",
+		Response: match.CompiledResponse{
+			Status:          200,
+			Body:            []byte(body),
+			ContentType:     "text/plain",
+			TruncateAtBytes: 100,
+		},
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/download")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ContentLength != int64(len(body)) {
+		t.Errorf("expected Content-Length %d, got %d", len(body), resp.ContentLength)
+	}
+	got, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Error("expected read error for truncated body")
+	}
+	if len(got) != 100 {
+		t.Errorf("expected 100 bytes before the connection closed, got %d", len(got))
+	}
+}
+
+func TestMockHandler_Compression(t *testing.T) {
+	body := strings.Repeat(`{"item":"value"}`, 64)
+	srv, _ := buildTestServer(&match.CompiledScenario{
+		ID:      "compressed",
+		Method:  "GET",
+		PathKey: "GET:/api/items",
+		Response: match.CompiledResponse{
+			Status:      200,
+			Body:        []byte(body),
+			ContentType: "application/json",
+			Compression: []string{"br", "gzip"},
+		},
+	})
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{"gzip only client", "gzip, deflate", "gzip"},
+		{"br preferred by scenario", "gzip, br", "br"},
+		{"client q-values win", "br;q=0.5, gzip", "gzip"},
+		{"br refused", "br;q=0, *", "gzip"},
+		{"identity client", "identity", ""},
+		{"no header", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("expected Content-Encoding %q, got %q", tt.wantEncoding, got)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("expected Vary: Accept-Encoding, got %q", got)
+			}
+
+			var r io.Reader = w.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				gz, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("gzip reader: %v", err)
+				}
+				r = gz
+			case "br":
+				r = brotli.NewReader(w.Body)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if string(got) != body {
+				t.Errorf("decoded body mismatch: got %d bytes, want %d", len(got), len(body))
+			}
+		})
+	}
+}
+
+func TestMockHandler_Proxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `{"path":%q,"query":%q,"token":%q,"body":%q}`, r.URL.Path, r.URL.RawQuery, r.Header.Get("X-Token"), body)
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL + "/v2")
+
+	proxied := func(record bool) *match.CompiledScenario {
+		return &match.CompiledScenario{
+			ID:       "passthrough",
+			Method:   "POST",
+			PathKey:  "POST:/api/*",
+			Priority: 5,
+			Response: match.CompiledResponse{Proxy: &match.CompiledProxy{
+				Target:      target,
+				StripPrefix: "/api",
+				Timeout:     5 * time.Second,
+				Record:      record,
+			}},
+		}
+	}
+
+	t.Run("forwards and traces upstream status", func(t *testing.T) {
+		srv, _ := buildTestServer(proxied(false))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/users?page=2", strings.NewReader("hello"))
+		req.Header.Set("X-Token", "secret")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+		}
+		want := `{"path":"/v2/users","query":"page=2","token":"secret","body":"hello"}`
+		if w.Body.String() != want {
+			t.Errorf("expected %s, got %s", want, w.Body.String())
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/__admin/trace?last=1", nil)
+		w = httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		var entries []trace.Entry
+		if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("failed to decode trace: %v", err)
+		}
+		if len(entries) != 1 || entries[0].MatchedID != "passthrough" || entries[0].UpstreamStatus != http.StatusAccepted {
+			t.Errorf("expected one passthrough entry with upstream status 202, got %+v", entries)
+		}
+	})
+
+	t.Run("unreachable upstream", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		sc := proxied(false)
+		sc.Response.Proxy.Target, _ = url.Parse(closed.URL)
+		srv, _ := buildTestServer(sc)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusBadGateway {
+			t.Errorf("expected 502, got %d", w.Code)
+		}
+	})
+
+	t.Run("record", func(t *testing.T) {
+		dir := t.TempDir()
+		repo, err := filesystem.NewYAMLRepository(dir)
+		if err != nil {
+			t.Fatalf("NewYAMLRepository failed: %v", err)
+		}
+		srv, _ := buildTestServer(proxied(true))
+		srv.SetCRUDDeps(usecases.NewSaveScenarioUseCase(repo, &testutil.NoopLogger{}), nil, repo, dir)
+
+		var first string
+		for i := range 3 {
+			req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader("hi"))
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("request %d: expected 202, got %d", i, w.Code)
+			}
+			if i == 0 {
+				first = w.Body.String()
+			}
+			srv.WaitRecordings()
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/orders", nil)
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+		srv.WaitRecordings()
+
+		recorded, err := repo.LoadAll(context.Background())
+		if err != nil {
+			t.Fatalf("LoadAll failed: %v", err)
+		}
+		if len(recorded) != 2 {
+			t.Fatalf("expected one recording per method and path, got %d", len(recorded))
+		}
+		rec, err := repo.LoadByID(context.Background(), usecases.RecordingID("passthrough", http.MethodPost, "/api/users"))
+		if err != nil {
+			t.Fatalf("LoadByID failed: %v", err)
+		}
+		if rec.Priority != 6 {
+			t.Errorf("unexpected recorded priority: %d", rec.Priority)
+		}
+		if rec.Name != "Recorded POST /api/users at 2025-01-01T00:00:00Z" {
+			t.Errorf("expected the injected clock in the name, got %q", rec.Name)
+		}
+		if len(rec.Profiles) != 1 || rec.Profiles[0] != usecases.RecordedProfile {
+			t.Errorf("expected recorded profile, got %v", rec.Profiles)
+		}
+		if !rec.Dynamic {
+			t.Error("expected recording to be marked dynamic")
+		}
+		if rec.When.Path != "/api/users" || rec.Response.Status != http.StatusAccepted || rec.Response.Body != first {
+			t.Errorf("unexpected recording: %+v", rec)
+		}
+
+		// Deleting the recording records the next exchange again.
+		if err := repo.DeleteScenario(context.Background(), rec.SourceFile, rec.SourceIndex); err != nil {
+			t.Fatalf("DeleteScenario failed: %v", err)
+		}
+		srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader("hi")))
+		srv.WaitRecordings()
+		if _, err := repo.LoadByID(context.Background(), rec.ID); err != nil {
+			t.Errorf("expected a deleted recording to be recorded again: %v", err)
+		}
+	})
+}
+
+func TestMockHandler_QuietScenario(t *testing.T) {
+	traceBuf := trace.NewRingBuffer(50)
+	logger := &testutil.CapturingLogger{}
+	handleReqUC := usecases.NewHandleRequestUseCase(match.NewEvaluator(), &testutil.FixedClock{}, &testutil.StubRateLimiter{AllowAll: true}, logger, traceBuf)
+	srv := inboundhttp.NewServer(handleReqUC, nil, traceBuf, logger)
+
+	idx := services.NewScenarioIndex()
+	idx.Add(&match.CompiledScenario{
+		ID:       "health",
+		Method:   "GET",
+		PathKey:  "GET:/health",
+		Quiet:    true,
+		Response: match.CompiledResponse{Status: 200, Body: []byte("ok")},
+	})
+	idx.Add(&match.CompiledScenario{
+		ID:       "users",
+		Method:   "GET",
+		PathKey:  "GET:/users",
+		Response: match.CompiledResponse{Status: 200, Body: []byte("[]")},
+	})
+	if err := idx.Build(); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	srv.Rebuild(idx)
+	before := len(logger.Infos())
+
+	for _, path := range []string{"/health", "/users"} {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200 for %s, got %d", path, w.Code)
+		}
+		if got := logger.Infos()[before:]; path == "/health" && !slices.Equal(got, []string{"request received"}) {
+			t.Errorf("expected only the received log for quiet scenario, got %v", got)
+		}
+	}
+	want := []string{"request received", "request received", "request matched"}
+	if got := logger.Infos()[before:]; !slices.Equal(got, want) {
+		t.Errorf("expected received/matched logs for other scenarios, got %v", got)
+	}
+}
+
+func TestMockHandler_RawBody(t *testing.T) {
+	srv, _ := buildTestServer(
+		&match.CompiledScenario{
+			ID:      "broken-json",
+			Method:  "GET",
+			PathKey: "GET:/api/broken",
+			Response: match.CompiledResponse{
+				Status:      200,
+				Body:        []byte(`{"a":}`),
+				ContentType: "application/json",
+				Raw:         true,
+			},
+		},
+		&match.CompiledScenario{
+			ID:       "untyped",
+			Method:   "GET",
+			PathKey:  "GET:/api/untyped",
+			Response: match.CompiledResponse{Status: 200, Body: []byte(`{"a":}`), Raw: true},
+		},
+	)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/broken", nil))
+	if w.Body.String() != `{"a":}` {
+		t.Errorf("expected body served verbatim, got %q", w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected application/json, got %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/untyped", nil))
+	if w.Body.String() != `{"a":}` {
+		t.Errorf("expected body served verbatim, got %q", w.Body.String())
+	}
+	if got, ok := w.Header()["Content-Type"]; ok && len(got) > 0 {
+		t.Errorf("expected no inferred Content-Type, got %q", got)
+	}
+}
+
+func TestMockHandler_Fault(t *testing.T) {
+	faulty := func(id, fault string) *match.CompiledScenario {
+		return &match.CompiledScenario{
+			ID:       id,
+			Method:   "GET",
+			PathKey:  "GET:/" + id,
+			Response: match.CompiledResponse{Status: 200, Body: []byte("never sent")},
+			Policy:   &match.CompiledPolicy{Fault: &match.CompiledFault{Type: fault, Rate: 1}},
+		}
+	}
+	srv, _ := buildTestServer(faulty("reset", match.FaultResetConnection), faulty("empty", match.FaultEmptyResponse))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	if resp, err := http.Get(ts.URL + "/reset"); err == nil {
+		resp.Body.Close()
+		t.Fatalf("expected connection error, got status %d", resp.StatusCode)
+	}
+
+	resp, err := http.Get(ts.URL + "/empty")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if len(body) != 0 {
+		t.Errorf("expected empty body, got %q", body)
+	}
+}
+
+func TestMockHandler_SecureMatching(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), template.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	secure, insecure := true, false
+	https, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:       "https",
+		When:     scenario.WhenClause{Method: "GET", Path: "/login", Secure: &secure},
+		Response: scenario.Response{Status: 200, Body: "login form"},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	plain, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:       "plain",
+		When:     scenario.WhenClause{Method: "GET", Path: "/login", Secure: &insecure},
+		Response: scenario.Response{Status: 301, Headers: map[string]string{"Location": "https://example.com/login"}},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(https, plain)
+
+	req := httptest.NewRequest("GET", "https://example.com/login", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != 200 || w.Body.String() != "login form" {
+		t.Errorf("expected TLS request to match https scenario, got %d %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "http://example.com/login", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != 301 || w.Header().Get("Location") != "https://example.com/login" {
+		t.Errorf("expected plain request to be redirected, got %d", w.Code)
+	}
+}
+
+func TestBuildRouter_AdminAndDashboardDisabled(t *testing.T) {
+	users := &match.CompiledScenario{
+		ID:       "users",
+		Method:   "GET",
+		PathKey:  "GET:/api/users",
+		Response: match.CompiledResponse{Status: 200, Body: []byte("[]")},
+	}
+
+	tests := []struct {
+		name              string
+		disableAdmin      bool
+		disableDashboard  bool
+		wantAdminStatus   int
+		wantDashboardCode int
+	}{
+		{"both enabled", false, false, http.StatusOK, http.StatusOK},
+		{"admin disabled", true, false, http.StatusNotFound, http.StatusOK},
+		{"dashboard disabled", false, true, http.StatusOK, http.StatusNotFound},
+		{"both disabled", true, true, http.StatusNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, idx := buildTestServer(users)
+			srv.SetAdminDisabled(tt.disableAdmin)
+			srv.SetDashboardDisabled(tt.disableDashboard)
+			srv.Rebuild(idx)
+
+			get := func(path string) int {
+				w := httptest.NewRecorder()
+				srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+				return w.Code
+			}
+			if got := get("/__admin/scenarios"); got != tt.wantAdminStatus {
+				t.Errorf("/__admin/scenarios: expected %d, got %d", tt.wantAdminStatus, got)
+			}
+			if got := get("/__admin/trace"); got != tt.wantAdminStatus {
+				t.Errorf("/__admin/trace: expected %d, got %d", tt.wantAdminStatus, got)
+			}
+			if got := get("/__ui/"); got != tt.wantDashboardCode {
+				t.Errorf("/__ui/: expected %d, got %d", tt.wantDashboardCode, got)
+			}
+			if got := get("/__health"); got != http.StatusOK {
+				t.Errorf("/__health: expected 200, got %d", got)
+			}
+			if got := get("/api/users"); got != http.StatusOK {
+				t.Errorf("/api/users: expected 200, got %d", got)
+			}
+		})
+	}
+}
+
+func TestBuildRouter_AdminBasicAuth(t *testing.T) {
+	srv, idx := buildTestServer(&match.CompiledScenario{
+		ID:       "users",
+		Method:   "GET",
+		PathKey:  "GET:/api/users",
+		Response: match.CompiledResponse{Status: 200, Body: []byte("[]")},
+	})
+	srv.SetAdminAuth("admin", "s3cret")
+	srv.Rebuild(idx)
+
+	tests := []struct {
+		name       string
+		path       string
+		user, pass string
+		want       int
+	}{
+		{"admin without credentials", "/__admin/scenarios", "", "", http.StatusUnauthorized},
+		{"admin with wrong password", "/__admin/scenarios", "admin", "nope", http.StatusUnauthorized},
+		{"admin with wrong user", "/__admin/trace", "root", "s3cret", http.StatusUnauthorized},
+		{"admin authorized", "/__admin/scenarios", "admin", "s3cret", http.StatusOK},
+		{"dashboard without credentials", "/__ui/", "", "", http.StatusUnauthorized},
+		{"dashboard authorized", "/__ui/", "admin", "s3cret", http.StatusOK},
+		{"mock routes stay open", "/api/users", "", "", http.StatusOK},
+		{"health stays open", "/__health", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, w.Code)
+			}
+			if tt.want == http.StatusUnauthorized && !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Basic ") {
+				t.Errorf("expected a Basic WWW-Authenticate challenge, got %q", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestBuildRouter_CORS(t *testing.T) {
+	users := &match.CompiledScenario{
+		ID:       "users",
+		Method:   "GET",
+		PathKey:  "GET:/api/users",
+		Response: match.CompiledResponse{Status: 200, Body: []byte("[]")},
+	}
+	custom := &match.CompiledScenario{
+		ID:      "custom-cors",
+		Method:  "GET",
+		PathKey: "GET:/api/custom",
+		Response: match.CompiledResponse{
+			Status:  200,
+			Headers: map[string]string{"Access-Control-Allow-Origin": "https://override.example"},
+		},
+	}
+	explicitOptions := &match.CompiledScenario{
+		ID:       "orders-options",
+		Method:   "OPTIONS",
+		PathKey:  "OPTIONS:/api/orders",
+		Response: match.CompiledResponse{Status: 200, Body: []byte("scenario")},
+	}
+	srv, idx := buildTestServer(users, custom, explicitOptions)
+	srv.SetCORS(inboundhttp.CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example"},
+		AllowCredentials: true,
+	})
+	srv.Rebuild(idx)
+
+	send := func(method, path, origin string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("allowed origin is echoed", func(t *testing.T) {
+		w := send(http.MethodGet, "/api/users", "https://app.example", nil)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example" {
+			t.Errorf("expected origin echoed, got %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+			t.Errorf("expected credentials allowed, got %q", got)
+		}
+	})
+
+	t.Run("other origin gets no CORS headers", func(t *testing.T) {
+		w := send(http.MethodGet, "/api/users", "https://evil.example", nil)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("expected no allow-origin, got %q", got)
+		}
+		if w.Code != http.StatusOK {
+			t.Errorf("expected the mock to still answer, got %d", w.Code)
+		}
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		w := send(http.MethodOptions, "/api/users", "https://app.example", map[string]string{
+			"Access-Control-Request-Method":  "GET",
+			"Access-Control-Request-Headers": "X-Token",
+		})
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET" {
+			t.Errorf("expected the path's methods, got %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Headers"); got != "X-Token" {
+			t.Errorf("expected requested headers allowed, got %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example" {
+			t.Errorf("expected origin echoed on preflight, got %q", got)
+		}
+	})
+
+	t.Run("scenario headers win", func(t *testing.T) {
+		w := send(http.MethodGet, "/api/custom", "https://app.example", nil)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://override.example" {
+			t.Errorf("expected scenario header to win, got %q", got)
+		}
+	})
+
+	t.Run("OPTIONS scenario handles its own preflight", func(t *testing.T) {
+		w := send(http.MethodOptions, "/api/orders", "https://app.example", map[string]string{"Access-Control-Request-Method": "GET"})
+		if w.Code != http.StatusOK || w.Body.String() != "scenario" {
+			t.Errorf("expected the OPTIONS scenario, got %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("admin routes get no CORS headers", func(t *testing.T) {
+		for _, path := range []string{"/__admin/scenarios", "/__admin/trace"} {
+			w := send(http.MethodGet, path, "https://app.example", nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: expected 200, got %d", path, w.Code)
+			}
+			for k := range w.Header() {
+				if strings.HasPrefix(k, "Access-Control-") {
+					t.Errorf("%s: unexpected %s header", path, k)
+				}
+			}
+		}
+	})
+}
+
+func TestMockHandler_Mirror(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), template.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	mirror, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:   "mirror",
+		Kind: scenario.KindMirror,
+		When: scenario.WhenClause{Method: "POST", Path: "/mirror"},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(mirror)
+
+	body := "caf\xc3\xa9=1&x=%20{\"not\": json"
+	req := httptest.NewRequest(http.MethodPost, "/mirror", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Body.String() != body {
+		t.Errorf("expected body returned verbatim, got %q", w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/x-www-form-urlencoded; charset=utf-8" {
+		t.Errorf("expected request Content-Type, got %q", got)
+	}
+
+	_, err = compiler.CompileScenario(&scenario.Scenario{
+		ID:       "mirror-with-body",
+		Kind:     scenario.KindMirror,
+		When:     scenario.WhenClause{Method: "POST", Path: "/mirror"},
+		Response: scenario.Response{Body: "ignored"},
+	})
+	if err == nil {
+		t.Error("expected error for a mirror scenario with a body")
+	}
+}
+
+func TestMockHandler_RemoteIP(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), template.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:   "whoami",
+		When: scenario.WhenClause{Method: "GET", Path: "/whoami"},
+		Response: scenario.Response{
+			Status: 200,
+			Body:   `${remoteIP()}`,
+			Engine: "expr",
+		},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(cs)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"direct", nil, "192.0.2.1"},
+		{"forwarded", map[string]string{"X-Forwarded-For": "203.0.113.7, 10.0.0.1"}, "203.0.113.7"},
+		{"real ip", map[string]string{"X-Real-Ip": "198.51.100.4"}, "198.51.100.4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/whoami", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMockHandler_RequireContentType(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), template.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID: "create-user",
+		When: scenario.WhenClause{
+			Method: "POST",
+			Path:   "/api/users",
+			Body: &scenario.BodyClause{
+				ContentType: "json",
+				Conditions: []scenario.BodyCondition{
+					{Extractor: "$.name", Matcher: scenario.StringMatcher{Exact: "alice"}},
+				},
+			},
+		},
+		RequireContentType: []string{"application/json"},
+		Response:           scenario.Response{Status: 201, Body: `{"ok":true}`},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(cs)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{"json accepted", "application/json; charset=utf-8", `{"name":"alice"}`, http.StatusCreated},
+		{"json body mismatch falls through", "application/json", `{"name":"bob"}`, http.StatusNotFound},
+		{"form rejected", "application/x-www-form-urlencoded", "name=alice", http.StatusUnsupportedMediaType},
+		{"missing content type rejected", "", `{"name":"alice"}`, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusUnsupportedMediaType {
+				var resp map[string]any
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("invalid JSON: %v", err)
+				}
+				if resp["error"] != "unsupported_media_type" {
+					t.Errorf("expected unsupported_media_type error, got %v", resp["error"])
+				}
+			}
+		})
+	}
+}
+
+func TestMockHandler_CookieMatching(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), template.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	session, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:       "session",
+		Priority: 10,
+		When: scenario.WhenClause{
+			Method: "GET",
+			Path:   "/api/me",
+			Cookies: map[string]scenario.StringMatcher{
+				"session": {Exact: "abc 123"},
+				"theme":   {},
+			},
+		},
+		Response: scenario.Response{Status: 200, Body: "session"},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	anonymous, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:       "anonymous",
+		When:     scenario.WhenClause{Method: "GET", Path: "/api/me"},
+		Response: scenario.Response{Status: 401, Body: "anonymous"},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	srv, _ := buildTestServer(session, anonymous)
+
+	tests := []struct {
+		name   string
+		cookie string
+		want   string
+	}{
+		{"url-decoded value", "session=abc%20123", "session"},
+		{"first duplicate wins", "session=abc%20123; session=other", "session"},
+		{"later duplicate ignored", "session=other; session=abc%20123", "anonymous"},
+		{"wrong value", "session=nope", "anonymous"},
+		{"no cookies", "", "anonymous"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+			if tt.cookie != "" {
+				req.Header.Set("Cookie", tt.cookie)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestServer_SelfTest(t *testing.T) {
+	isGET := []match.FieldPredicate{{Field: "method", Predicate: func(s string) bool { return s == "GET" }}}
+	example := func(path string) *match.IncomingRequest {
+		return &match.IncomingRequest{Method: "GET", Path: path, Headers: map[string]string{}}
+	}
+	srv, _ := buildTestServer(
+		&match.CompiledScenario{
+			ID: "ok", Method: "GET", PathKey: "GET:/api/ok", Predicates: isGET,
+			Response: match.CompiledResponse{Status: 200, Renderer: &fakeRenderer{body: []byte(`[1,2,3]`)}},
+			Policy:   &match.CompiledPolicy{Pagination: &match.CompiledPagination{Style: "page_size", DefaultSize: 2, PageParam: "page", SizeParam: "size", DataPath: "$"}},
+			Example:  example("/api/ok"),
+		},
+		&match.CompiledScenario{
+			ID: "broken", Method: "GET", PathKey: "GET:/api/broken", Predicates: isGET,
+			Response: match.CompiledResponse{Status: 200, Renderer: &errorRenderer{}},
+			Example:  example("/api/broken"),
+		},
+		&match.CompiledScenario{
+			ID: "unmatched", Method: "GET", PathKey: "GET:/api/tenant",
+			Predicates: []match.FieldPredicate{{Field: "header:X-Tenant", Predicate: func(s string) bool { return s == "acme" }}},
+			Response:   match.CompiledResponse{Status: 200},
+			Example:    example("/api/tenant"),
+		},
+		&match.CompiledScenario{
+			ID: "no-example", Method: "GET", PathKey: "GET:/api/other", Predicates: isGET,
+			Response: match.CompiledResponse{Status: 200, Renderer: &errorRenderer{}},
+		},
+		&match.CompiledScenario{
+			ID: "tenant", Method: "GET", PathKey: "GET:/api/session",
+			Predicates:  []match.FieldPredicate{{Field: "cookie:session", Predicate: func(s string) bool { return s == "abc" }}},
+			HostPattern: regexp.MustCompile(`^(?P<tenant>[a-z]+)\.example\.com$`),
+			Response: match.CompiledResponse{Status: 200, Renderer: renderFunc(func(ctx match.RenderContext) ([]byte, error) {
+				if ctx.HostParams["tenant"] != "acme" || ctx.RemoteIP == "" {
+					return nil, fmt.Errorf("missing host params or remote IP: %+v", ctx)
+				}
+				return []byte("ok"), nil
+			})},
+			Example: &match.IncomingRequest{Method: "GET", Path: "/api/session", Headers: map[string]string{
+				"Host":   "acme.example.com",
+				"Cookie": "session=abc",
+			}},
+		},
+		&match.CompiledScenario{
+			ID: "charset", Method: "GET", PathKey: "GET:/api/charset", Predicates: isGET,
+			Response: match.CompiledResponse{Status: 200, Body: []byte("€"), Encoder: func([]byte) ([]byte, error) {
+				return nil, io.ErrShortWrite
+			}},
+			Example: example("/api/charset"),
+		},
+	)
+
+	tested, failures := srv.SelfTest()
+	if tested != 5 {
+		t.Errorf("expected 5 scenarios tested, got %d", tested)
+	}
+	failed := map[string]string{}
+	for _, f := range failures {
+		failed[f.ScenarioID] = f.Err.Error()
+	}
+	if len(failed) != 3 {
+		t.Fatalf("expected 3 failures, got %v", failed)
+	}
+	if !strings.Contains(failed["charset"], "charset transcoding failed") {
+		t.Errorf("expected a transcoding failure for charset, got %q", failed["charset"])
+	}
+	if !strings.Contains(failed["broken"], "render failed") {
+		t.Errorf("expected a render failure for broken, got %q", failed["broken"])
+	}
+	if !strings.Contains(failed["unmatched"], "did not match") {
+		t.Errorf("expected a match failure for unmatched, got %q", failed["unmatched"])
+	}
+
+	// The self-test serves nothing, so no trace entry is recorded.
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/__admin/trace", nil))
+	if body := strings.TrimSpace(w.Body.String()); body != "null" && body != "[]" {
+		t.Errorf("expected an empty trace, got %s", body)
+	}
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Trailing slash modes. The default ("") keeps /a and /a/ distinct.
+const (
+	// TrailingSlashStrip removes a trailing slash before routing, so /a/
+	// is served by /a.
+	TrailingSlashStrip = "strip"
+	// TrailingSlashRedirect answers /a/ with a permanent redirect to /a.
+	TrailingSlashRedirect = "redirect"
+	// TrailingSlashEquivalent serves /a and /a/ from whichever of them is
+	// mocked, preferring an exact match.
+	TrailingSlashEquivalent = "equivalent"
+)
+
+// SetTrailingSlash selects how mock paths with and without a trailing slash
+// relate. Admin, dashboard and health routes are never affected.
+func (s *Server) SetTrailingSlash(mode string) error {
+	switch mode {
+	case "", TrailingSlashStrip, TrailingSlashRedirect, TrailingSlashEquivalent:
+		s.trailingSlash = mode
+		return nil
+	}
+	return fmt.Errorf("invalid trailing slash mode %q (supported: strip, redirect, equivalent)", mode)
+}
+
+// trailingSlashMiddleware applies the trailing slash mode to mock requests
+// before mux routes them.
+func (s *Server) trailingSlashMiddleware(mux *chi.Mux) func(http.Handler) http.Handler {
+	routed := func(method, path string) bool {
+		return mux.Find(chi.NewRouteContext(), method, path) != ""
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := r.URL.Path
+			if path == "/" || strings.HasPrefix(path, "/__") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			trimmed, slashed := strings.CutSuffix(path, "/")
+
+			switch s.trailingSlash {
+			case TrailingSlashStrip:
+				if slashed {
+					r = withPath(r, trimmed)
+				}
+			case TrailingSlashRedirect:
+				if slashed {
+					// Collapse leading slashes (and backslashes, which
+					// browsers read as slashes) so //host/ cannot redirect
+					// to another site.
+					target := "/" + strings.TrimLeft(trimmed, `/\`)
+					if r.URL.RawQuery != "" {
+						target += "?" + r.URL.RawQuery
+					}
+					// 308 keeps the method and body of non-idempotent requests.
+					status := http.StatusPermanentRedirect
+					if r.Method == http.MethodGet || r.Method == http.MethodHead {
+						status = http.StatusMovedPermanently
+					}
+					http.Redirect(w, r, target, status)
+					return
+				}
+			case TrailingSlashEquivalent:
+				if !routed(r.Method, path) {
+					alt := path + "/"
+					if slashed {
+						alt = trimmed
+					}
+					if routed(r.Method, alt) {
+						r = withPath(r, alt)
+					}
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// withPath returns a shallow copy of r routed and matched as path.
+func withPath(r *http.Request, path string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	u := *r.URL
+	u.Path = path
+	u.RawPath = ""
+	r2.URL = &u
+	return r2
+}
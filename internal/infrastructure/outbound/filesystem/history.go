@@ -0,0 +1,158 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sophialabs/proteusmock/internal/domain/scenario"
+)
+
+// HistoryDirName is the directory under the root holding scenario snapshots.
+// Scenario loading and the file watcher skip it.
+const HistoryDirName = ".history"
+
+// historyLayout names snapshot files; it sorts lexically in time order.
+const historyLayout = "20060102T150405.000000000Z"
+
+var _ scenario.History = (*HistoryStore)(nil)
+
+// HistoryStore keeps timestamped scenario snapshots under
+// <root>/.history/<id>/<version>.yaml, pruned to a maximum count per scenario.
+type HistoryStore struct {
+	dir   string
+	limit int
+	now   func() time.Time
+}
+
+// NewHistoryStore creates a store under rootDir keeping at most limit
+// versions per scenario.
+func NewHistoryStore(rootDir string, limit int) (*HistoryStore, error) {
+	absRoot, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve root directory: %w", err)
+	}
+	return &HistoryStore{
+		dir:   filepath.Join(absRoot, HistoryDirName),
+		limit: limit,
+		now:   time.Now,
+	}, nil
+}
+
+// Record writes yamlContent as a new version and prunes the oldest versions
+// beyond the limit.
+func (h *HistoryStore) Record(_ context.Context, id string, yamlContent []byte) error {
+	dir, err := h.scenarioDir(id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	version := h.now().UTC().Format(historyLayout)
+	if err := atomicWriteFile(filepath.Join(dir, version+".yaml"), yamlContent); err != nil {
+		return err
+	}
+	return h.prune(dir)
+}
+
+// List returns the stored versions of a scenario, newest first.
+func (h *HistoryStore) List(_ context.Context, id string) ([]scenario.Version, error) {
+	dir, err := h.scenarioDir(id)
+	if err != nil {
+		return nil, err
+	}
+	names, err := versionNames(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]scenario.Version, 0, len(names))
+	for _, name := range slices.Backward(names) {
+		info, err := os.Stat(filepath.Join(dir, name+".yaml"))
+		if err != nil {
+			continue
+		}
+		savedAt, _ := time.Parse(historyLayout, name)
+		versions = append(versions, scenario.Version{ID: name, SavedAt: savedAt, Size: int(info.Size())})
+	}
+	return versions, nil
+}
+
+// Get returns the YAML stored for one version.
+func (h *HistoryStore) Get(_ context.Context, id, version string) ([]byte, error) {
+	dir, err := h.scenarioDir(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse(historyLayout, version); err != nil {
+		return nil, scenario.ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(dir, version+".yaml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, scenario.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history version: %w", err)
+	}
+	return data, nil
+}
+
+// scenarioDir maps a scenario ID to its snapshot directory. IDs are escaped so
+// they cannot address paths outside the history directory.
+func (h *HistoryStore) scenarioDir(id string) (string, error) {
+	name := url.PathEscape(id)
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid scenario ID for history: %q", id)
+	}
+	return filepath.Join(h.dir, name), nil
+}
+
+func (h *HistoryStore) prune(dir string) error {
+	if h.limit <= 0 {
+		return nil
+	}
+	names, err := versionNames(dir)
+	if err != nil {
+		return err
+	}
+	for len(names) > h.limit {
+		if err := os.Remove(filepath.Join(dir, names[0]+".yaml")); err != nil {
+			return fmt.Errorf("failed to prune history: %w", err)
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// versionNames returns the version IDs stored in dir, oldest first.
+func versionNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".yaml")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(historyLayout, name); err != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
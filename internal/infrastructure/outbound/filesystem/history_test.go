@@ -0,0 +1,56 @@
+package filesystem_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sophialabs/proteusmock/internal/domain/scenario"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/filesystem"
+)
+
+func TestHistoryStore_PrunesToLimit(t *testing.T) {
+	store, err := filesystem.NewHistoryStore(t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("NewHistoryStore failed: %v", err)
+	}
+	ctx := context.Background()
+
+	for i := range 3 {
+		if err := store.Record(ctx, "orders", []byte(fmt.Sprintf("v%d", i))); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	versions, err := store.List(ctx, "orders")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions after pruning, got %d", len(versions))
+	}
+	got, err := store.Get(ctx, "orders", versions[0].ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(got) != "v2" {
+		t.Errorf("expected newest version v2 first, got %q", got)
+	}
+}
+
+func TestHistoryStore_GetUnknownVersion(t *testing.T) {
+	store, err := filesystem.NewHistoryStore(t.TempDir(), 5)
+	if err != nil {
+		t.Fatalf("NewHistoryStore failed: %v", err)
+	}
+
+	for _, version := range []string{"20250101T000000.000000000Z", "../../etc/passwd"} {
+		if _, err := store.Get(context.Background(), "orders", version); !errors.Is(err, scenario.ErrNotFound) {
+			t.Errorf("Get(%q): expected ErrNotFound, got %v", version, err)
+		}
+	}
+	if err := store.Record(context.Background(), "..", []byte("x")); err == nil {
+		t.Error("expected error for ID escaping the history directory")
+	}
+}
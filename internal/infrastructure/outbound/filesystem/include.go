@@ -19,8 +19,9 @@ func NewIncludeResolver(rootDir string) *IncludeResolver {
 	return &IncludeResolver{rootDir: rootDir}
 }
 
-// ResolveIncludes walks a yaml.Node tree and replaces !include tagged nodes
-// with the contents of the referenced files.
+// ResolveIncludes walks a yaml.Node tree and replaces !include tagged nodes,
+// and their JSON form {"$include": "path"}, with the contents of the
+// referenced files.
 func (r *IncludeResolver) ResolveIncludes(node *yaml.Node, currentDir string) error {
 	return r.walk(node, currentDir, 0)
 }
@@ -38,6 +39,13 @@ func (r *IncludeResolver) walk(node *yaml.Node, currentDir string, depth int) er
 	if node.Tag == "!include" {
 		return r.resolveInclude(node, currentDir, depth)
 	}
+	if node.Tag == "!include@glob" {
+		return r.resolveGlobInclude(node, currentDir, depth)
+	}
+	if ref, ok := jsonInclude(node); ok {
+		*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!include", Value: ref}
+		return r.resolveInclude(node, currentDir, depth)
+	}
 
 	for _, child := range node.Content {
 		if err := r.walk(child, currentDir, depth); err != nil {
@@ -96,6 +104,62 @@ func (r *IncludeResolver) resolveInclude(node *yaml.Node, currentDir string, dep
 	return nil
 }
 
+// resolveGlobInclude replaces a !include@glob node with a sequence holding
+// each matched file in lexical order. YAML and JSON files are parsed (and
+// their includes resolved); other files become raw strings. A pattern that
+// matches no file is an error.
+func (r *IncludeResolver) resolveGlobInclude(node *yaml.Node, currentDir string, depth int) error {
+	pattern := node.Value
+	if pattern == "" {
+		return fmt.Errorf("!include@glob tag has empty value")
+	}
+
+	resolved, err := r.resolvePath(pattern, currentDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve !include@glob %q: %w", pattern, err)
+	}
+	matches, err := filepath.Glob(resolved)
+	if err != nil {
+		return fmt.Errorf("invalid !include@glob pattern %q: %w", pattern, err)
+	}
+
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, match := range matches {
+		if info, err := os.Stat(match); err != nil || info.IsDir() {
+			continue
+		}
+		if err := r.validatePath(match); err != nil {
+			return fmt.Errorf("!include@glob match %q is not allowed: %w", match, err)
+		}
+		data, err := os.ReadFile(match)
+		if err != nil {
+			return fmt.Errorf("failed to read included file %q: %w", match, err)
+		}
+
+		item := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(data)}
+		switch strings.ToLower(filepath.Ext(match)) {
+		case ".yaml", ".yml", ".json":
+			var included yaml.Node
+			if err := yaml.Unmarshal(data, &included); err != nil {
+				return fmt.Errorf("failed to parse included file %q: %w", match, err)
+			}
+			if err := r.walk(&included, filepath.Dir(match), depth+1); err != nil {
+				return err
+			}
+			if included.Kind == yaml.DocumentNode && len(included.Content) > 0 {
+				item = included.Content[0]
+			}
+		}
+		seq.Content = append(seq.Content, item)
+	}
+	if len(seq.Content) == 0 {
+		return fmt.Errorf("!include@glob %q matched no files", pattern)
+	}
+
+	*node = *seq
+	return nil
+}
+
 func (r *IncludeResolver) resolvePath(ref, currentDir string) (string, error) {
 	switch {
 	case strings.HasPrefix(ref, "@root/"):
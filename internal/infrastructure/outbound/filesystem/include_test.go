@@ -236,3 +236,68 @@ func TestIncludeResolver_NilNode(t *testing.T) {
 		t.Errorf("expected nil error for nil node, got %v", err)
 	}
 }
+
+func TestIncludeResolver_GlobInclude(t *testing.T) {
+	dir := t.TempDir()
+	fixtures := filepath.Join(dir, "fixtures")
+	if err := os.MkdirAll(fixtures, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"b.json":    `{"name": "b"}`,
+		"a.json":    `{"name": "a"}`,
+		"c.txt":     "not matched",
+		"skip.yaml": "name: skip",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(fixtures, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(`items: !include@glob "@root/fixtures/*.json"`), &node); err != nil {
+		t.Fatal(err)
+	}
+	if err := filesystem.NewIncludeResolver(dir).ResolveIncludes(&node, dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded struct {
+		Items []struct {
+			Name string `yaml:"name"`
+		} `yaml:"items"`
+	}
+	if err := node.Decode(&decoded); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(decoded.Items) != 2 || decoded.Items[0].Name != "a" || decoded.Items[1].Name != "b" {
+		t.Errorf("expected parsed fixtures a, b in lexical order, got %+v", decoded.Items)
+	}
+}
+
+func TestIncludeResolver_GlobIncludeErrors(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "root")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(parent, "outside.json"), []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{
+		`items: !include@glob "fixtures/*.json"`, // no match
+		`items: !include@glob "../*.json"`,       // escapes the root
+		`items: !include@glob "["`,               // bad pattern
+		`items: !include@glob ""`,
+	} {
+		var node yaml.Node
+		if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+			t.Fatal(err)
+		}
+		if err := filesystem.NewIncludeResolver(dir).ResolveIncludes(&node, dir); err == nil {
+			t.Errorf("expected error for %s", content)
+		}
+	}
+}
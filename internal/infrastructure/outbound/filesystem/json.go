@@ -0,0 +1,147 @@
+package filesystem
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonIncludeKey is the JSON spelling of the !include tag: an object whose
+// only key is "$include" is replaced by the referenced file's contents.
+const jsonIncludeKey = "$include"
+
+// Top-level keys that mark a JSON document as scenarios rather than a body
+// fixture, besides a {"vars", "scenarios"} wrapper. Override files only need
+// an id to target their base scenario.
+var (
+	scenarioJSONKeys = []string{"when", "static", "scenarios"}
+	overrideJSONKeys = []string{"id", "scenarios"}
+)
+
+// isJSONFile reports whether name has a .json extension.
+func isJSONFile(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".json")
+}
+
+// isScenarioJSON reports whether data is a JSON object, or a non-empty array
+// of objects, in which every object has at least one of keys. JSON with
+// another shape is a response fixture and is not loaded.
+func isScenarioJSON(data []byte, keys []string) bool {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	items, ok := doc.([]any)
+	if !ok {
+		items = []any{doc}
+	}
+	if len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok || !hasAnyKey(obj, keys) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAnyKey(obj map[string]any, keys []string) bool {
+	for _, k := range keys {
+		if _, ok := obj[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonInclude returns the reference of a {"$include": "path"} object.
+func jsonInclude(node *yaml.Node) (string, bool) {
+	if node.Kind != yaml.MappingNode || len(node.Content) != 2 {
+		return "", false
+	}
+	key, value := node.Content[0], node.Content[1]
+	if key.Value != jsonIncludeKey || value.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	return value.Value, true
+}
+
+// marshalNode encodes node in the format implied by path's extension, so a
+// rewritten .json file stays JSON.
+func marshalNode(path string, node *yaml.Node) ([]byte, error) {
+	if !isJSONFile(path) {
+		return yaml.Marshal(node)
+	}
+	var buf bytes.Buffer
+	if err := encodeJSONNode(&buf, node); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// encodeJSONNode writes node as compact JSON, keeping mapping key order and
+// writing !include scalars back as {"$include": "path"}.
+func encodeJSONNode(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return encodeJSONNode(buf, node.Content[0])
+	case yaml.AliasNode:
+		return encodeJSONNode(buf, node.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(node.Content[i].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := encodeJSONNode(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, child := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSONNode(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case yaml.ScalarNode:
+		var v any = node.Value
+		if node.Tag == "!include" {
+			v = map[string]string{jsonIncludeKey: node.Value}
+		} else if err := node.Decode(&v); err != nil {
+			return fmt.Errorf("failed to decode scalar %q: %w", node.Value, err)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode scalar %q: %w", node.Value, err)
+		}
+		buf.Write(b)
+		return nil
+	}
+	return fmt.Errorf("unsupported YAML node kind %d", node.Kind)
+}
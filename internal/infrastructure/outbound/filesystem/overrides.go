@@ -0,0 +1,97 @@
+package filesystem
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sophialabs/proteusmock/internal/domain/scenario"
+)
+
+// SetOverridesDir enables layered loading: scenarios found under dir are
+// deep-merged onto the base scenario with the same ID instead of being
+// reported as duplicates. A dir inside the root is excluded from the base walk.
+func (r *YAMLRepository) SetOverridesDir(dir string) error {
+	if dir == "" {
+		r.overridesDir = ""
+		return nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve overrides directory: %w", err)
+	}
+	r.overridesDir = abs
+	return nil
+}
+
+// applyOverrides merges every scenario in the overrides directory onto the
+// base scenario sharing its ID. Files are applied in lexical path order, so
+// when several overrides target one ID the last one wins field by field.
+// Merged scenarios keep the base SourceFile and SourceIndex; overrides without
+// a base scenario are added as new scenarios.
+func (r *YAMLRepository) applyOverrides(scenarios []*scenario.Scenario, nodes []*yaml.Node) ([]*scenario.Scenario, error) {
+	byID := make(map[string]int, len(scenarios))
+	for i, s := range scenarios {
+		byID[s.ID] = i
+	}
+
+	err := r.walkYAML(r.overridesDir, func(path string) error {
+		loaded, overrideNodes, err := r.loadFile(path, overrideJSONKeys)
+		if err != nil {
+			return err
+		}
+		for i, o := range loaded {
+			base, ok := byID[o.ID]
+			if !ok {
+				byID[o.ID] = len(scenarios)
+				scenarios = append(scenarios, o)
+				nodes = append(nodes, overrideNodes[i])
+				continue
+			}
+
+			merged := mergeNodes(nodes[base], overrideNodes[i])
+			s, err := decodeScenarioNode(merged)
+			if err != nil {
+				return fmt.Errorf("failed to apply override %q: %w", o.ID, err)
+			}
+			s.SourceFile = scenarios[base].SourceFile
+			s.SourceIndex = scenarios[base].SourceIndex
+			scenarios[base] = s
+			nodes[base] = merged
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk overrides directory: %w", err)
+	}
+	return scenarios, nil
+}
+
+// mergeNodes deep-merges override onto base: mapping keys merge recursively,
+// while scalars and sequences in override replace the base value. The base
+// node is modified in place.
+func mergeNodes(base, override *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.MappingNode || override.Kind != yaml.MappingNode {
+		return override
+	}
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		if j := mappingKeyIndex(base, key.Value); j >= 0 {
+			base.Content[j+1] = mergeNodes(base.Content[j+1], value)
+			continue
+		}
+		base.Content = append(base.Content, key, value)
+	}
+	return base
+}
+
+// mappingKeyIndex returns the index of key within a mapping node's content, or -1.
+func mappingKeyIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -2,22 +2,28 @@ package filesystem
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/sophialabs/proteusmock/internal/domain/scenario"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
 )
 
 var _ scenario.Repository = (*YAMLRepository)(nil)
 
-// YAMLRepository loads scenarios from YAML files in a directory tree.
+// YAMLRepository loads scenarios from YAML and JSON files in a directory tree.
 type YAMLRepository struct {
-	rootDir  string
-	resolver *IncludeResolver
+	rootDir      string
+	overridesDir string
+	resolver     *IncludeResolver
+	logger       ports.Logger
+	strict       bool
 }
 
 // NewYAMLRepository creates a repository rooted at rootDir.
@@ -32,84 +38,174 @@ func NewYAMLRepository(rootDir string) (*YAMLRepository, error) {
 	}, nil
 }
 
-// LoadAll walks the root directory for .yaml files and returns parsed scenarios.
+// SetLogger sets the logger that reports .json files skipped because they
+// don't parse.
+func (r *YAMLRepository) SetLogger(logger ports.Logger) {
+	r.logger = logger
+}
+
+// SetStrict makes loading fail on a .json file that doesn't parse, instead
+// of skipping it with a warning.
+func (r *YAMLRepository) SetStrict(strict bool) {
+	r.strict = strict
+}
+
+// LoadAll walks the root directory for scenario files and returns parsed scenarios.
+// When an overrides directory is set, its scenarios are then layered onto the
+// base scenarios (see applyOverrides).
 func (r *YAMLRepository) LoadAll(_ context.Context) ([]*scenario.Scenario, error) {
 	var scenarios []*scenario.Scenario
+	var nodes []*yaml.Node
 
-	err := filepath.WalkDir(r.rootDir, func(path string, d os.DirEntry, err error) error {
+	err := r.walkYAML(r.rootDir, func(path string) error {
+		loaded, loadedNodes, err := r.loadFile(path, scenarioJSONKeys)
+		if err != nil {
+			return err
+		}
+		scenarios = append(scenarios, loaded...)
+		nodes = append(nodes, loadedNodes...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk scenarios directory: %w", err)
+	}
+
+	if r.overridesDir == "" {
+		return scenarios, nil
+	}
+	return r.applyOverrides(scenarios, nodes)
+}
+
+// walkYAML calls fn for every .yaml/.yml/.json file under dir in lexical order,
+// skipping history snapshots and the overrides directory when it lives inside dir.
+func (r *YAMLRepository) walkYAML(dir string, fn func(path string) error) error {
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 		if d.IsDir() {
+			if path != dir && (path == r.overridesDir || d.Name() == HistoryDirName) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
-		ext := strings.ToLower(filepath.Ext(path))
-		if ext != ".yaml" && ext != ".yml" {
+		if !isScenarioFile(path) {
 			return nil
 		}
-
-		loaded, err := r.loadFile(path)
-		if err != nil {
+		if err := fn(path); err != nil {
 			return fmt.Errorf("failed to load %s: %w", path, err)
 		}
-		scenarios = append(scenarios, loaded...)
 		return nil
 	})
+}
+
+// loadFile decodes the scenarios in path, returning each alongside the
+// include-resolved node it was decoded from. A JSON file is skipped unless
+// it is scenario-shaped (see isScenarioJSON); other JSON files are response
+// fixtures that live alongside the scenarios. A JSON file that doesn't parse
+// is skipped with a warning, or fails the load in strict mode, since it may
+// be a scenario file with a typo.
+func (r *YAMLRepository) loadFile(path string, jsonKeys []string) ([]*scenario.Scenario, []*yaml.Node, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to walk scenarios directory: %w", err)
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if isJSONFile(path) {
+		if err := json.Unmarshal(data, new(json.RawMessage)); err != nil {
+			if r.strict {
+				return nil, nil, fmt.Errorf("failed to parse JSON: %w", err)
+			}
+			if r.logger != nil {
+				r.logger.Warn("skipping unparsable JSON file", "file", path, "error", err)
+			}
+			return nil, nil, nil
+		}
+		if !isScenarioJSON(data, jsonKeys) {
+			return nil, nil, nil
+		}
 	}
 
-	return scenarios, nil
+	nodes, single, err := r.scenarioNodes(data, filepath.Dir(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	scenarios, err := decodeScenarioNodes(nodes, single)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, s := range scenarios {
+		s.SourceFile = path
+	}
+	return scenarios, nodes, nil
 }
 
-func (r *YAMLRepository) loadFile(path string) ([]*scenario.Scenario, error) {
-	data, err := os.ReadFile(path)
+// DecodeScenarios parses raw scenario YAML (a single scenario or a list) without
+// touching the filesystem beyond resolving !include references relative to the root.
+func (r *YAMLRepository) DecodeScenarios(yamlContent []byte) ([]*scenario.Scenario, error) {
+	return r.decodeDocument(yamlContent, r.rootDir)
+}
+
+// decodeDocument parses a YAML document, resolves includes relative to fileDir,
+// and decodes it into scenarios with SourceIndex populated.
+func (r *YAMLRepository) decodeDocument(data []byte, fileDir string) ([]*scenario.Scenario, error) {
+	nodes, single, err := r.scenarioNodes(data, fileDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, err
 	}
+	return decodeScenarioNodes(nodes, single)
+}
 
+// scenarioNodes parses a YAML document, resolves includes relative to fileDir
+// and then ${vars.*} references, and returns one node per scenario. single
+// reports a document holding a single scenario rather than a list.
+func (r *YAMLRepository) scenarioNodes(data []byte, fileDir string) (nodes []*yaml.Node, single bool, err error) {
 	// Parse into yaml.Node tree to handle !include tags.
 	var rootNode yaml.Node
 	if err := yaml.Unmarshal(data, &rootNode); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		return nil, false, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	fileDir := filepath.Dir(path)
 	if err := r.resolver.ResolveIncludes(&rootNode, fileDir); err != nil {
-		return nil, fmt.Errorf("failed to resolve includes: %w", err)
+		return nil, false, fmt.Errorf("failed to resolve includes: %w", err)
 	}
 
-	// Decode resolved node tree into typed structures.
-	// Support both single scenario and list of scenarios.
-	var scenarios []*scenario.Scenario
+	if rootNode.Kind != yaml.DocumentNode || len(rootNode.Content) == 0 {
+		return nil, false, fmt.Errorf("unexpected YAML structure")
+	}
 
-	// Try as a list first.
-	if rootNode.Kind == yaml.DocumentNode && len(rootNode.Content) > 0 {
-		content := rootNode.Content[0]
-		if content.Kind == yaml.SequenceNode {
-			for i, item := range content.Content {
-				s, err := decodeScenarioNode(item)
-				if err != nil {
-					return nil, err
-				}
-				s.SourceFile = path
-				s.SourceIndex = i
-				scenarios = append(scenarios, s)
-			}
-			return scenarios, nil
+	vars, content, err := splitVars(rootNode.Content[0])
+	if err != nil {
+		return nil, false, err
+	}
+	if vars != nil {
+		if err := substituteVars(content, vars); err != nil {
+			return nil, false, fmt.Errorf("failed to resolve vars: %w", err)
 		}
+	}
 
-		// Single scenario.
-		s, err := decodeScenarioNode(content)
+	// Support both single scenario and list of scenarios.
+	if content.Kind == yaml.SequenceNode {
+		return content.Content, false, nil
+	}
+	return []*yaml.Node{content}, true, nil
+}
+
+// decodeScenarioNodes decodes scenario nodes into typed structures with
+// SourceIndex populated (-1 for a single-scenario document).
+func decodeScenarioNodes(nodes []*yaml.Node, single bool) ([]*scenario.Scenario, error) {
+	scenarios := make([]*scenario.Scenario, 0, len(nodes))
+	for i, node := range nodes {
+		s, err := decodeScenarioNode(node)
 		if err != nil {
 			return nil, err
 		}
-		s.SourceFile = path
-		s.SourceIndex = -1
-		return []*scenario.Scenario{s}, nil
+		s.SourceIndex = i
+		if single {
+			s.SourceIndex = -1
+		}
+		scenarios = append(scenarios, s)
 	}
-
-	return nil, fmt.Errorf("unexpected YAML structure in %s", path)
+	return scenarios, nil
 }
 
 // LoadByID loads a single scenario by its ID.
@@ -158,7 +254,14 @@ func (r *YAMLRepository) SaveScenario(_ context.Context, s *scenario.Scenario, y
 	}
 
 	if s.SourceIndex < 0 {
-		// Single-scenario file — replace entire file.
+		// Single-scenario file — replace entire file, keeping JSON files JSON.
+		if isJSONFile(s.SourceFile) {
+			out, err := marshalNode(s.SourceFile, &check)
+			if err != nil {
+				return fmt.Errorf("failed to marshal scenario: %w", err)
+			}
+			yamlContent = out
+		}
 		return atomicWriteFile(s.SourceFile, yamlContent)
 	}
 
@@ -261,12 +364,9 @@ func (r *YAMLRepository) replaceInSequence(filePath string, index int, newConten
 		return fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	if rootNode.Kind != yaml.DocumentNode || len(rootNode.Content) == 0 {
-		return fmt.Errorf("unexpected YAML structure")
-	}
-	seq := rootNode.Content[0]
-	if seq.Kind != yaml.SequenceNode {
-		return fmt.Errorf("file is not a YAML sequence")
+	seq, err := scenarioSequence(&rootNode)
+	if err != nil {
+		return err
 	}
 	if index >= len(seq.Content) {
 		return fmt.Errorf("index %d out of range (file has %d entries)", index, len(seq.Content))
@@ -283,9 +383,9 @@ func (r *YAMLRepository) replaceInSequence(filePath string, index int, newConten
 
 	seq.Content[index] = newNode.Content[0]
 
-	out, err := yaml.Marshal(&rootNode)
+	out, err := marshalNode(filePath, &rootNode)
 	if err != nil {
-		return fmt.Errorf("failed to marshal YAML: %w", err)
+		return fmt.Errorf("failed to marshal scenarios: %w", err)
 	}
 	return atomicWriteFile(filePath, out)
 }
@@ -302,12 +402,9 @@ func (r *YAMLRepository) removeFromSequence(filePath string, index int) error {
 		return fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	if rootNode.Kind != yaml.DocumentNode || len(rootNode.Content) == 0 {
-		return fmt.Errorf("unexpected YAML structure")
-	}
-	seq := rootNode.Content[0]
-	if seq.Kind != yaml.SequenceNode {
-		return fmt.Errorf("file is not a YAML sequence")
+	seq, err := scenarioSequence(&rootNode)
+	if err != nil {
+		return err
 	}
 	if index >= len(seq.Content) {
 		return fmt.Errorf("index %d out of range (file has %d entries)", index, len(seq.Content))
@@ -321,13 +418,31 @@ func (r *YAMLRepository) removeFromSequence(filePath string, index int) error {
 		return os.Remove(filePath)
 	}
 
-	out, err := yaml.Marshal(&rootNode)
+	out, err := marshalNode(filePath, &rootNode)
 	if err != nil {
-		return fmt.Errorf("failed to marshal YAML: %w", err)
+		return fmt.Errorf("failed to marshal scenarios: %w", err)
 	}
 	return atomicWriteFile(filePath, out)
 }
 
+// scenarioSequence returns the list of scenarios in a multi-scenario
+// document: the document itself, or its "scenarios" key when it shares vars.
+func scenarioSequence(rootNode *yaml.Node) (*yaml.Node, error) {
+	if rootNode.Kind != yaml.DocumentNode || len(rootNode.Content) == 0 {
+		return nil, fmt.Errorf("unexpected YAML structure")
+	}
+	seq := rootNode.Content[0]
+	if seq.Kind == yaml.MappingNode {
+		if i := mappingKeyIndex(seq, "scenarios"); i >= 0 {
+			seq = seq.Content[i+1]
+		}
+	}
+	if seq.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("file is not a YAML sequence")
+	}
+	return seq, nil
+}
+
 // extractFromSequence extracts a single entry from a YAML sequence.
 func (r *YAMLRepository) extractFromSequence(data []byte, index int) ([]byte, error) {
 	var rootNode yaml.Node
@@ -335,12 +450,9 @@ func (r *YAMLRepository) extractFromSequence(data []byte, index int) ([]byte, er
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	if rootNode.Kind != yaml.DocumentNode || len(rootNode.Content) == 0 {
-		return nil, fmt.Errorf("unexpected YAML structure")
-	}
-	seq := rootNode.Content[0]
-	if seq.Kind != yaml.SequenceNode {
-		return nil, fmt.Errorf("file is not a YAML sequence")
+	seq, err := scenarioSequence(&rootNode)
+	if err != nil {
+		return nil, err
 	}
 	if index >= len(seq.Content) {
 		return nil, fmt.Errorf("index %d out of range (file has %d entries)", index, len(seq.Content))
@@ -363,21 +475,37 @@ func decodeScenarioNode(node *yaml.Node) (*scenario.Scenario, error) {
 
 func toScenario(ys *yamlScenario) *scenario.Scenario {
 	s := &scenario.Scenario{
-		ID:       ys.ID,
-		Name:     ys.Name,
-		Priority: ys.Priority,
+		ID:                 ys.ID,
+		Name:               ys.Name,
+		Priority:           ys.Priority,
+		Profiles:           ys.Profiles,
+		Kind:               ys.Kind,
+		Deprecated:         ys.Deprecated,
+		DeprecationMessage: ys.DeprecationMessage,
+		RequireContentType: ys.RequireContentType,
+		Quiet:              ys.Log != nil && !*ys.Log,
+		Dynamic:            ys.Dynamic,
 		When: scenario.WhenClause{
-			Method: ys.When.Method,
-			Path:   ys.When.Path,
-		},
-		Response: scenario.Response{
-			Status:      ys.Response.Status,
-			Headers:     ys.Response.Headers,
-			Body:        ys.Response.Body,
-			BodyFile:    ys.Response.BodyFile,
-			ContentType: ys.Response.ContentType,
-			Engine:      ys.Response.Engine,
+			Path:     ys.When.Path,
+			Host:     ys.When.Host,
+			Schedule: ys.When.Schedule,
+			Secure:   ys.When.Secure,
+			Expr:     ys.When.Expr,
 		},
+		Response: toResponse(&ys.Response),
+		Cycle:    ys.Cycle,
+	}
+	if er := ys.ExampleRequest; er != nil {
+		s.ExampleRequest = &scenario.ExampleRequest{
+			Method:  er.Method,
+			Path:    er.Path,
+			Headers: er.Headers,
+			Query:   er.Query,
+			Body:    er.Body,
+		}
+	}
+	for i := range ys.Responses {
+		s.Responses = append(s.Responses, toResponse(&ys.Responses[i]))
 	}
 
 	if ys.When.Headers != nil {
@@ -387,37 +515,229 @@ func toScenario(ys *yamlScenario) *scenario.Scenario {
 		}
 	}
 
+	if len(ys.When.Method) == 1 {
+		s.When.Method = ys.When.Method[0]
+	} else if ys.When.Method != nil {
+		s.When.Methods = ys.When.Method
+	}
+	if ys.When.UserAgent != nil {
+		m := parseStringMatcher(*ys.When.UserAgent)
+		s.When.UserAgent = &m
+	}
+	if ys.When.Referer != nil {
+		m := parseStringMatcher(*ys.When.Referer)
+		s.When.Referer = &m
+	}
+	if ys.When.Query != nil {
+		s.When.Query = make(map[string]scenario.StringMatcher, len(ys.When.Query))
+		for k, v := range ys.When.Query {
+			s.When.Query[k] = parseStringMatcher(v)
+		}
+	}
+
+	if ys.When.Cookies != nil {
+		s.When.Cookies = make(map[string]scenario.StringMatcher, len(ys.When.Cookies))
+		for k, v := range ys.When.Cookies {
+			s.When.Cookies[k] = parseStringMatcher(v)
+		}
+	}
+
+	if ys.When.QueryArray != nil {
+		s.When.QueryArrays = make(map[string]scenario.QueryArrayMatcher, len(ys.When.QueryArray))
+		for k, v := range ys.When.QueryArray {
+			m := scenario.QueryArrayMatcher{Contains: v.Contains}
+			if v.Count != nil {
+				m.Count = toNumericMatcher(v.Count)
+			}
+			s.When.QueryArrays[k] = m
+		}
+	}
+
 	if ys.When.Body != nil {
 		s.When.Body = toBodyClause(ys.When.Body)
 	}
 
+	if ys.When.ContentLength != nil {
+		s.When.ContentLength = toNumericMatcher(ys.When.ContentLength)
+	}
+
+	if ys.When.BodyHash != nil {
+		s.When.BodyHash = &scenario.BodyHash{
+			Algorithm: ys.When.BodyHash.Algorithm,
+			Expected:  ys.When.BodyHash.Expected,
+		}
+	}
+
+	if ys.When.BodyPrefix != nil {
+		s.When.BodyPrefix = &scenario.BodyPrefix{
+			Hex:    ys.When.BodyPrefix.Hex,
+			Base64: ys.When.BodyPrefix.Base64,
+		}
+	}
+
 	if ys.Policy != nil {
 		s.Policy = toPolicy(ys.Policy)
 	}
 
+	if ys.Static != nil {
+		s.Static = &scenario.StaticMount{
+			Dir:        ys.Static.Dir,
+			PathPrefix: ys.Static.PathPrefix,
+		}
+	}
+
 	return s
 }
 
+func toResponse(yr *yamlResponse) scenario.Response {
+	r := scenario.Response{
+		Status:           yr.Status,
+		Headers:          yr.Headers,
+		Body:             yr.Body,
+		BodyFile:         yr.BodyFile,
+		ContentType:      yr.ContentType,
+		Engine:           yr.Engine,
+		Charset:          yr.Charset,
+		OmitNulls:        yr.OmitNulls,
+		CanonicalizeBody: yr.CanonicalizeBody,
+		Raw:              yr.Raw,
+
+		BodyFileMissingStatus: yr.BodyFileMissingStatus,
+		TruncateAtBytes:       yr.TruncateAtBytes,
+		Compression:           yr.Compression,
+	}
+	if yr.Created != nil {
+		r.Created = &scenario.Created{Location: yr.Created.Location}
+	}
+	if yr.Generate != nil {
+		r.Generate = &scenario.Generate{Count: yr.Generate.Count, Fields: yr.Generate.Fields}
+	}
+	if yr.Proxy != nil {
+		r.Proxy = &scenario.Proxy{
+			Target:      yr.Proxy.Target,
+			StripPrefix: yr.Proxy.StripPrefix,
+			TimeoutMs:   yr.Proxy.TimeoutMs,
+			Record:      yr.Proxy.Record,
+		}
+	}
+	if yr.Cache != nil {
+		r.Cache = &scenario.Cache{
+			MaxAge:     yr.Cache.MaxAge,
+			Visibility: yr.Cache.Visibility,
+			Immutable:  yr.Cache.Immutable,
+		}
+	}
+	if yr.Switch != nil {
+		r.Switch = &scenario.ResponseSwitch{On: yr.Switch.On}
+		if len(yr.Switch.Cases) > 0 {
+			r.Switch.Cases = make(map[string]scenario.Response, len(yr.Switch.Cases))
+			for value, c := range yr.Switch.Cases {
+				r.Switch.Cases[value] = toResponse(&c)
+			}
+		}
+		if yr.Switch.Default != nil {
+			d := toResponse(yr.Switch.Default)
+			r.Switch.Default = &d
+		}
+	}
+	if yr.TimeSwitch != nil {
+		r.TimeSwitch = &scenario.TimeSwitch{}
+		for i := range yr.TimeSwitch.Cases {
+			c := &yr.TimeSwitch.Cases[i]
+			r.TimeSwitch.Cases = append(r.TimeSwitch.Cases, scenario.TimeCase{
+				Schedule: c.Schedule,
+				Response: toResponse(&c.Response),
+			})
+		}
+		if yr.TimeSwitch.Default != nil {
+			d := toResponse(yr.TimeSwitch.Default)
+			r.TimeSwitch.Default = &d
+		}
+	}
+	return r
+}
+
 func parseStringMatcher(raw string) scenario.StringMatcher {
+	if raw == "!exists" {
+		return scenario.StringMatcher{Exists: true}
+	}
+	if raw == "!absent" {
+		return scenario.StringMatcher{Absent: true}
+	}
+	if rest, ok := strings.CutPrefix(raw, "!="); ok {
+		return scenario.StringMatcher{Exact: rest, Negated: true}
+	}
+	if n, ok := parseComparison(raw); ok {
+		return scenario.StringMatcher{Pattern: raw, Numeric: n}
+	}
 	if strings.HasPrefix(raw, "=") {
 		return scenario.StringMatcher{Exact: raw[1:]}
 	}
 	return scenario.StringMatcher{Pattern: raw}
 }
 
+// parseComparison parses a numeric comparison such as ">100" or "<=1.5".
+// Anything else, including an operator followed by a non-number, is not one.
+func parseComparison(raw string) (*scenario.NumericMatcher, bool) {
+	for _, op := range []string{">=", "<=", "==", ">", "<"} {
+		rest, ok := strings.CutPrefix(raw, op)
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(rest), 64)
+		if err != nil {
+			return nil, false
+		}
+		var m scenario.NumericMatcher
+		switch op {
+		case ">=":
+			m.Gte = &v
+		case "<=":
+			m.Lte = &v
+		case "==":
+			m.Eq = &v
+		case ">":
+			m.Gt = &v
+		case "<":
+			m.Lt = &v
+		}
+		return &m, true
+	}
+	return nil, false
+}
+
+func toNumericMatcher(yn *yamlNumericMatcher) *scenario.NumericMatcher {
+	return &scenario.NumericMatcher{
+		Eq:  yn.Eq,
+		Gt:  yn.Gt,
+		Gte: yn.Gte,
+		Lt:  yn.Lt,
+		Lte: yn.Lte,
+	}
+}
+
 func toBodyClause(yb *yamlBody) *scenario.BodyClause {
 	if yb == nil {
 		return nil
 	}
 
 	bc := &scenario.BodyClause{
+		Decode:      yb.Decode,
 		ContentType: yb.ContentType,
+		Regex:       yb.Regex,
+	}
+	if yb.KeyOrder != nil {
+		bc.KeyOrder = &scenario.KeyOrder{Keys: yb.KeyOrder.Keys, AllowExtra: yb.KeyOrder.AllowExtra}
 	}
 
 	for _, c := range yb.Conditions {
 		bc.Conditions = append(bc.Conditions, scenario.BodyCondition{
-			Extractor: c.Extractor,
-			Matcher:   parseStringMatcher(c.Matcher),
+			Extractor:  c.Extractor,
+			Matcher:    parseStringMatcher(c.Matcher),
+			Min:        c.Min,
+			Max:        c.Max,
+			AllRegex:   c.AllRegex,
+			AllowEmpty: c.AllowEmpty,
 		})
 	}
 
@@ -453,12 +773,17 @@ func toPolicy(yp *yamlPolicy) *scenario.Policy {
 			Burst: yp.RateLimit.Burst,
 			Key:   yp.RateLimit.Key,
 		}
+		if yp.RateLimit.Response != nil {
+			r := toResponse(yp.RateLimit.Response)
+			p.RateLimit.Response = &r
+		}
 	}
 
 	if yp.Latency != nil {
 		p.Latency = &scenario.Latency{
-			FixedMs:  yp.Latency.FixedMs,
-			JitterMs: yp.Latency.JitterMs,
+			FixedMs:       yp.Latency.FixedMs,
+			JitterMs:      yp.Latency.JitterMs,
+			HeaderDelayMs: yp.Latency.HeaderDelayMs,
 		}
 	}
 
@@ -466,6 +791,14 @@ func toPolicy(yp *yamlPolicy) *scenario.Policy {
 		p.Pagination = toPagination(yp.Pagination)
 	}
 
+	if yp.LoadBalance != nil {
+		p.LoadBalance = &scenario.LoadBalance{Weight: yp.LoadBalance.Weight}
+	}
+
+	if yp.Fault != nil {
+		p.Fault = &scenario.Fault{Type: yp.Fault.Type, Rate: yp.Fault.Rate}
+	}
+
 	return p
 }
 
@@ -479,6 +812,8 @@ func toPagination(yp *yamlPagination) *scenario.Pagination {
 		DefaultSize: yp.DefaultSize,
 		MaxSize:     yp.MaxSize,
 		DataPath:    yp.DataPath,
+
+		TotalItemsSource: yp.TotalItemsSource,
 	}
 
 	switch p.Style {
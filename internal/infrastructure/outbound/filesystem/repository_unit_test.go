@@ -4,9 +4,11 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/filesystem"
+	"github.com/sophialabs/proteusmock/internal/testutil"
 )
 
 func newTestRepo(t *testing.T, rootDir string) *filesystem.YAMLRepository {
@@ -58,6 +60,7 @@ policy:
     default_size: 20
     max_size: 50
     data_path: "$.results"
+    total_items_source: 50
     envelope:
       data_field: items
       total_items_field: total
@@ -95,6 +98,9 @@ response:
 	if p.Envelope.TotalItemsField != "total" {
 		t.Errorf("expected total_items_field 'total', got %q", p.Envelope.TotalItemsField)
 	}
+	if p.TotalItemsSource != "50" {
+		t.Errorf("expected total_items_source '50', got %q", p.TotalItemsSource)
+	}
 }
 
 func TestYAMLRepository_LoadAll_PaginationInvalidStyle(t *testing.T) {
@@ -231,6 +237,40 @@ response:
 	}
 }
 
+func TestYAMLRepository_LoadAll_MethodList(t *testing.T) {
+	dir := t.TempDir()
+
+	content := `
+- id: single
+  when:
+    method: GET
+    path: /a
+- id: multiple
+  when:
+    method: [GET, HEAD]
+    path: /b
+`
+	if err := os.WriteFile(filepath.Join(dir, "methods.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if len(scenarios) != 2 {
+		t.Fatalf("expected 2 scenarios, got %d", len(scenarios))
+	}
+
+	if scenarios[0].When.Method != "GET" || scenarios[0].When.Methods != nil {
+		t.Errorf("single: expected Method GET, got %q / %v", scenarios[0].When.Method, scenarios[0].When.Methods)
+	}
+	if got := scenarios[1].When.Methods; len(got) != 2 || got[0] != "GET" || got[1] != "HEAD" {
+		t.Errorf("multiple: expected Methods [GET HEAD], got %v", got)
+	}
+}
+
 func TestYAMLRepository_LoadAll_IgnoresNonYAMLFiles(t *testing.T) {
 	dir := t.TempDir()
 
@@ -489,3 +529,647 @@ response:
 		t.Errorf("expected pattern 'secret-.*', got %q", hdr.Pattern)
 	}
 }
+
+func TestYAMLRepository_LoadAll_ContentLengthMatcher(t *testing.T) {
+	dir := t.TempDir()
+
+	content := `
+id: content-length
+name: Content length
+when:
+  method: POST
+  path: /upload
+  content_length:
+    gte: 10
+    lte: 100
+response:
+  status: 200
+`
+	os.WriteFile(filepath.Join(dir, "cl.yaml"), []byte(content), 0o644)
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+
+	cl := scenarios[0].When.ContentLength
+	if cl == nil {
+		t.Fatal("expected content_length matcher")
+	}
+	if cl.Gte == nil || *cl.Gte != 10 {
+		t.Errorf("expected gte 10, got %v", cl.Gte)
+	}
+	if cl.Lte == nil || *cl.Lte != 100 {
+		t.Errorf("expected lte 100, got %v", cl.Lte)
+	}
+	if cl.Eq != nil || cl.Gt != nil || cl.Lt != nil {
+		t.Error("expected unset bounds to remain nil")
+	}
+}
+
+func TestYAMLRepository_LoadAll_BodyHashMatcher(t *testing.T) {
+	dir := t.TempDir()
+
+	content := `
+id: body-hash
+name: Body hash
+when:
+  method: POST
+  path: /orders
+  body_hash:
+    algorithm: sha256
+    expected: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+response:
+  status: 200
+`
+	os.WriteFile(filepath.Join(dir, "hash.yaml"), []byte(content), 0o644)
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+
+	bh := scenarios[0].When.BodyHash
+	if bh == nil {
+		t.Fatal("expected body_hash matcher")
+	}
+	if bh.Algorithm != "sha256" {
+		t.Errorf("expected algorithm sha256, got %q", bh.Algorithm)
+	}
+	if bh.Expected != "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" {
+		t.Errorf("unexpected digest %q", bh.Expected)
+	}
+}
+
+func TestYAMLRepository_LoadAll_StaticMount(t *testing.T) {
+	dir := t.TempDir()
+
+	content := `
+id: cdn
+name: CDN assets
+static:
+  dir: assets
+  path_prefix: /cdn
+`
+	os.WriteFile(filepath.Join(dir, "cdn.yaml"), []byte(content), 0o644)
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+
+	st := scenarios[0].Static
+	if st == nil {
+		t.Fatal("expected static mount")
+	}
+	if st.Dir != "assets" || st.PathPrefix != "/cdn" {
+		t.Errorf("unexpected static mount %+v", st)
+	}
+}
+
+func TestYAMLRepository_LoadAll_BodyConditionRange(t *testing.T) {
+	dir := t.TempDir()
+
+	content := `
+id: amount-range
+name: Amount range
+when:
+  method: POST
+  path: /payments
+  body:
+    content_type: json
+    conditions:
+      - extractor: "$.amount"
+        min: 100
+        max: 500
+response:
+  status: 200
+`
+	os.WriteFile(filepath.Join(dir, "range.yaml"), []byte(content), 0o644)
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+
+	cond := scenarios[0].When.Body.Conditions[0]
+	if cond.Min == nil || *cond.Min != 100 {
+		t.Errorf("expected min 100, got %v", cond.Min)
+	}
+	if cond.Max == nil || *cond.Max != 500 {
+		t.Errorf("expected max 500, got %v", cond.Max)
+	}
+}
+
+func TestYAMLRepository_LoadAll_QueryMatchers(t *testing.T) {
+	dir := t.TempDir()
+
+	content := `
+id: query
+name: Query
+when:
+  method: GET
+  path: /items
+  query:
+    debug: "!exists"
+    cursor: "!absent"
+    page: "=2"
+  query_array:
+    id: { contains: ["2"], count: { gte: 2 } }
+response:
+  status: 200
+`
+	os.WriteFile(filepath.Join(dir, "query.yaml"), []byte(content), 0o644)
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+
+	q := scenarios[0].When.Query
+	if !q["debug"].Exists {
+		t.Errorf("expected debug to be an exists matcher, got %+v", q["debug"])
+	}
+	if !q["cursor"].Absent {
+		t.Errorf("expected cursor to be an absent matcher, got %+v", q["cursor"])
+	}
+	arr := scenarios[0].When.QueryArrays["id"]
+	if len(arr.Contains) != 1 || arr.Contains[0] != "2" || arr.Count == nil || *arr.Count.Gte != 2 {
+		t.Errorf("unexpected query array matcher: %+v", arr)
+	}
+	if q["page"].Exact != "2" || q["page"].Exists {
+		t.Errorf("expected exact page matcher, got %+v", q["page"])
+	}
+}
+
+func TestYAMLRepository_LoadAll_ResponseSwitch(t *testing.T) {
+	dir := t.TempDir()
+
+	content := `
+id: dispatcher
+name: Dispatcher
+when:
+  method: POST
+  path: /events
+response:
+  status: 202
+  switch:
+    on: "$.type"
+    cases:
+      created: { status: 201, body: '{"ok":true}' }
+      deleted: { status: 204 }
+    default: { status: 400 }
+`
+	os.WriteFile(filepath.Join(dir, "switch.yaml"), []byte(content), 0o644)
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+
+	sw := scenarios[0].Response.Switch
+	if sw == nil || sw.On != "$.type" {
+		t.Fatalf("expected switch on $.type, got %+v", sw)
+	}
+	if sw.Cases["created"].Status != 201 || sw.Cases["created"].Body != `{"ok":true}` || sw.Cases["deleted"].Status != 204 {
+		t.Errorf("unexpected cases: %+v", sw.Cases)
+	}
+	if sw.Default == nil || sw.Default.Status != 400 {
+		t.Errorf("expected default status 400, got %+v", sw.Default)
+	}
+}
+
+func TestYAMLRepository_LoadAll_Overrides(t *testing.T) {
+	dir := t.TempDir()
+	base := `id: get-order
+name: Get order
+when:
+  method: GET
+  path: /orders/{id}
+  headers:
+    X-Tenant: acme
+response:
+  status: 200
+  headers:
+    X-Source: base
+  body: '{"id": 1}'
+`
+	override := `id: get-order
+response:
+  status: 503
+`
+	overridesDir := filepath.Join(dir, "env", "staging")
+	if err := os.MkdirAll(overridesDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "orders.yaml"), []byte(base), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(overridesDir, "orders.yaml"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newTestRepo(t, dir)
+	if err := repo.SetOverridesDir(overridesDir); err != nil {
+		t.Fatalf("SetOverridesDir failed: %v", err)
+	}
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if len(scenarios) != 1 {
+		t.Fatalf("expected 1 merged scenario, got %d", len(scenarios))
+	}
+
+	s := scenarios[0]
+	if s.Response.Status != 503 {
+		t.Errorf("expected overridden status 503, got %d", s.Response.Status)
+	}
+	if s.Response.Body != `{"id": 1}` {
+		t.Errorf("expected base body to be kept, got %q", s.Response.Body)
+	}
+	if s.Response.Headers["X-Source"] != "base" {
+		t.Errorf("expected base headers to be kept, got %v", s.Response.Headers)
+	}
+	if s.Name != "Get order" || s.When.Path != "/orders/{id}" {
+		t.Errorf("expected base fields to be kept, got name %q path %q", s.Name, s.When.Path)
+	}
+	if s.SourceFile != filepath.Join(dir, "orders.yaml") {
+		t.Errorf("expected base source file, got %q", s.SourceFile)
+	}
+}
+
+func TestYAMLRepository_LoadAll_OverridesLexicalOrder(t *testing.T) {
+	dir := t.TempDir()
+	overridesDir := t.TempDir()
+	files := map[string]string{
+		filepath.Join(dir, "base.yaml"):          "id: a\nwhen:\n  method: GET\n  path: /a\nresponse:\n  status: 200\n",
+		filepath.Join(overridesDir, "1.yaml"):    "id: a\nresponse:\n  status: 201\n",
+		filepath.Join(overridesDir, "2.yaml"):    "id: a\nresponse:\n  status: 202\n",
+		filepath.Join(overridesDir, "only.yaml"): "id: b\nwhen:\n  method: GET\n  path: /b\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := newTestRepo(t, dir)
+	if err := repo.SetOverridesDir(overridesDir); err != nil {
+		t.Fatalf("SetOverridesDir failed: %v", err)
+	}
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if len(scenarios) != 2 {
+		t.Fatalf("expected 2 scenarios, got %d", len(scenarios))
+	}
+	if scenarios[0].ID != "a" || scenarios[0].Response.Status != 202 {
+		t.Errorf("expected last override to win with status 202, got %q %d", scenarios[0].ID, scenarios[0].Response.Status)
+	}
+	if scenarios[1].ID != "b" {
+		t.Errorf("expected override without base to be added, got %q", scenarios[1].ID)
+	}
+}
+
+func TestYAMLRepository_LoadAll_NumericComparisonMatcher(t *testing.T) {
+	dir := t.TempDir()
+
+	content := `
+id: payments
+when:
+  method: POST
+  path: /payments
+  body:
+    content_type: json
+    conditions:
+      - extractor: "$.amount"
+        matcher: ">100"
+      - extractor: "$.fee"
+        matcher: "<=1.5"
+      - extractor: "$.tag"
+        matcher: "<b>"
+`
+	if err := os.WriteFile(filepath.Join(dir, "payments.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	conds := scenarios[0].When.Body.Conditions
+	if len(conds) != 3 {
+		t.Fatalf("expected 3 conditions, got %d", len(conds))
+	}
+
+	if n := conds[0].Matcher.Numeric; n == nil || n.Gt == nil || *n.Gt != 100 {
+		t.Errorf("expected gt 100, got %+v", n)
+	}
+	if n := conds[1].Matcher.Numeric; n == nil || n.Lte == nil || *n.Lte != 1.5 {
+		t.Errorf("expected lte 1.5, got %+v", n)
+	}
+	if m := conds[2].Matcher; m.Numeric != nil || m.Pattern != "<b>" {
+		t.Errorf("expected non-numeric operand to stay a regex, got %+v", m)
+	}
+}
+
+func TestYAMLRepository_LoadAll_NegatedMatcher(t *testing.T) {
+	dir := t.TempDir()
+
+	content := `
+id: not-expired
+when:
+  method: GET
+  path: /orders
+  headers:
+    Authorization: "!=Bearer expired"
+  query:
+    mode: "!=dry-run"
+`
+	if err := os.WriteFile(filepath.Join(dir, "orders.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	when := scenarios[0].When
+	if m := when.Headers["Authorization"]; !m.Negated || m.Exact != "Bearer expired" {
+		t.Errorf("expected negated header matcher, got %+v", m)
+	}
+	if m := when.Query["mode"]; !m.Negated || m.Exact != "dry-run" {
+		t.Errorf("expected negated query matcher, got %+v", m)
+	}
+}
+
+func TestYAMLRepository_LoadAll_ResponseSequence(t *testing.T) {
+	dir := t.TempDir()
+
+	content := `
+id: flaky
+when:
+  method: GET
+  path: /flaky
+responses:
+  - status: 503
+  - status: 200
+    body: ok
+cycle: true
+`
+	if err := os.WriteFile(filepath.Join(dir, "flaky.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	s := scenarios[0]
+	if len(s.Responses) != 2 || s.Responses[0].Status != 503 || s.Responses[1].Body != "ok" {
+		t.Errorf("unexpected responses: %+v", s.Responses)
+	}
+	if !s.Cycle {
+		t.Error("expected cycle to be set")
+	}
+}
+
+func TestYAMLRepository_LoadAll_JSONScenarios(t *testing.T) {
+	dir := t.TempDir()
+	single := `{
+	"id": "json-single",
+	"when": {"method": "GET", "path": "/single"},
+	"response": {"status": 200, "body": {"$include": "responses/ok.json"}}
+}`
+	list := `[
+  {"id": "json-one", "when": {"method": "GET", "path": "/one"}, "response": {"status": 200}},
+  {"id": "json-two", "when": {"method": "POST", "path": "/two"}, "response": {"status": 201}}
+]`
+	if err := os.MkdirAll(filepath.Join(dir, "responses"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"single.json":       single,
+		"list.json":         list,
+		"responses/ok.json": `{"ok": true}`,
+		"tsconfig.json":     "{ // not JSON\n}",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	byID := make(map[string]int, len(scenarios))
+	for i, s := range scenarios {
+		byID[s.ID] = i
+	}
+	if len(scenarios) != 3 {
+		t.Fatalf("expected 3 scenarios, got %d: %v", len(scenarios), byID)
+	}
+
+	s := scenarios[byID["json-single"]]
+	if s.Response.Body != `{"ok": true}` {
+		t.Errorf("expected $include to resolve, got body %q", s.Response.Body)
+	}
+	if s.SourceIndex != -1 {
+		t.Errorf("expected single-scenario SourceIndex -1, got %d", s.SourceIndex)
+	}
+	two := scenarios[byID["json-two"]]
+	if two.When.Method != "POST" || two.Response.Status != 201 || two.SourceIndex != 1 {
+		t.Errorf("unexpected json-two: %+v", two)
+	}
+}
+
+func TestYAMLRepository_LoadAll_UnparsableJSON(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ok.json":    `{"id": "ok", "when": {"method": "GET", "path": "/ok"}, "response": {"status": 200}}`,
+		"typo.json":  `{"id": "typo", "when": {"method": "GET", "path": "/typo"},}`,
+		"other.yaml": "id: other\nwhen:\n  method: GET\n  path: /other\nresponse:\n  status: 200\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := newTestRepo(t, dir)
+	logger := &testutil.CapturingLogger{}
+	repo.SetLogger(logger)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if len(scenarios) != 2 {
+		t.Errorf("expected the parsable files to load, got %d scenarios", len(scenarios))
+	}
+	if len(logger.Warns()) != 1 {
+		t.Errorf("expected one warning for typo.json, got %v", logger.Warns())
+	}
+
+	repo.SetStrict(true)
+	if _, err := repo.LoadAll(context.Background()); err == nil || !strings.Contains(err.Error(), "typo.json") {
+		t.Errorf("expected strict load to fail on typo.json, got %v", err)
+	}
+}
+
+func TestYAMLRepository_DeleteScenario_KeepsJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "list.json")
+	list := `[
+  {"id": "a", "when": {"method": "GET", "path": "/a"}, "response": {"status": 200, "body": {"$include": "a.txt"}}},
+  {"id": "b", "when": {"method": "GET", "path": "/b"}, "response": {"status": 204}}
+]`
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newTestRepo(t, dir)
+	if err := repo.DeleteScenario(context.Background(), path, 1); err != nil {
+		t.Fatalf("DeleteScenario failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+  {
+    "id": "a",
+    "when": {
+      "method": "GET",
+      "path": "/a"
+    },
+    "response": {
+      "status": 200,
+      "body": {
+        "$include": "a.txt"
+      }
+    }
+  }
+]
+`
+	if string(data) != want {
+		t.Errorf("expected JSON output:\n%s\ngot:\n%s", want, data)
+	}
+}
+
+func TestYAMLRepository_LoadAll_SharedVars(t *testing.T) {
+	dir := t.TempDir()
+	content := `vars:
+  base_url: https://api.example.com
+  tenant:
+    header: X-Tenant
+    id: acme
+  ok_headers:
+    Content-Type: application/json
+scenarios:
+  - id: list-users
+    when:
+      method: GET
+      path: /users
+      headers:
+        X-Tenant: ${vars.tenant.id}
+    response:
+      status: 200
+      headers: ${vars.ok_headers}
+      body: '{"next": "${vars.base_url}/users?page=2", "self": "${ vars.base_url }/users"}'
+  - id: get-user
+    when:
+      method: GET
+      path: /users/{id}
+    response:
+      status: 302
+      headers:
+        Location: ${vars.base_url}/profiles/1
+`
+	path := filepath.Join(dir, "users.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newTestRepo(t, dir)
+	scenarios, err := repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if len(scenarios) != 2 {
+		t.Fatalf("expected 2 scenarios, got %d", len(scenarios))
+	}
+
+	list, get := scenarios[0], scenarios[1]
+	if list.When.Headers["X-Tenant"].Pattern != "acme" {
+		t.Errorf("expected tenant header matcher, got %+v", list.When.Headers)
+	}
+	if list.Response.Headers["Content-Type"] != "application/json" {
+		t.Errorf("expected map var to be inlined, got %v", list.Response.Headers)
+	}
+	wantBody := `{"next": "https://api.example.com/users?page=2", "self": "https://api.example.com/users"}`
+	if list.Response.Body != wantBody {
+		t.Errorf("expected body %q, got %q", wantBody, list.Response.Body)
+	}
+	if get.Response.Headers["Location"] != "https://api.example.com/profiles/1" {
+		t.Errorf("unexpected Location: %q", get.Response.Headers["Location"])
+	}
+	if get.SourceIndex != 1 {
+		t.Errorf("expected SourceIndex 1, got %d", get.SourceIndex)
+	}
+
+	// Deleting from a file with shared vars keeps the vars block.
+	if err := repo.DeleteScenario(context.Background(), path, 0); err != nil {
+		t.Fatalf("DeleteScenario failed: %v", err)
+	}
+	scenarios, err = repo.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll after delete failed: %v", err)
+	}
+	if len(scenarios) != 1 || scenarios[0].Response.Headers["Location"] != "https://api.example.com/profiles/1" {
+		t.Errorf("unexpected scenarios after delete: %+v", scenarios)
+	}
+}
+
+func TestYAMLRepository_LoadAll_VarsErrors(t *testing.T) {
+	tests := map[string]string{
+		"undefined": `vars: {a: 1}
+id: x
+when: {method: GET, path: /x}
+response: {status: 200, body: "${vars.b}"}
+`,
+		"map embedded in string": `vars: {a: {b: 1}}
+id: x
+when: {method: GET, path: /x}
+response: {status: 200, body: "value: ${vars.a}"}
+`,
+		"vars not a map": `vars: [1, 2]
+scenarios: []
+`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := newTestRepo(t, dir).LoadAll(context.Background()); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
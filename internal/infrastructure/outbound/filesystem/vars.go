@@ -0,0 +1,133 @@
+package filesystem
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// varRefPattern matches a ${vars.name} reference; dots select nested keys.
+var varRefPattern = regexp.MustCompile(`\$\{\s*vars\.([A-Za-z0-9_.-]+)\s*\}`)
+
+// splitVars separates a document's vars block from its scenarios. A file
+// shares vars either as a top-level "vars" key next to a single scenario's
+// fields, or as {vars: ..., scenarios: [...]} for several scenarios. vars is
+// nil when the document declares none.
+func splitVars(content *yaml.Node) (vars, scenarios *yaml.Node, err error) {
+	if content.Kind != yaml.MappingNode {
+		return nil, content, nil
+	}
+	if i := mappingKeyIndex(content, "scenarios"); i >= 0 {
+		scenarios = content.Content[i+1]
+		if scenarios.Kind != yaml.SequenceNode {
+			return nil, nil, fmt.Errorf("scenarios must be a list")
+		}
+		if j := mappingKeyIndex(content, "vars"); j >= 0 {
+			vars = content.Content[j+1]
+		}
+	} else if i := mappingKeyIndex(content, "vars"); i >= 0 {
+		vars = content.Content[i+1]
+		content.Content = append(content.Content[:i], content.Content[i+2:]...)
+		scenarios = content
+	} else {
+		return nil, content, nil
+	}
+	if vars.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("vars must be a map")
+	}
+	return vars, scenarios, nil
+}
+
+// substituteVars replaces ${vars.name} references in the scalars under node.
+// A scalar that is only a reference takes the referenced value whole, so a
+// var may hold a map or a list; references inside a longer string must name
+// scalar vars.
+func substituteVars(node, vars *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return substituteScalar(node, vars)
+	case yaml.MappingNode:
+		// Substitute values only; keys stay literal.
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := substituteVars(node.Content[i], vars); err != nil {
+				return err
+			}
+		}
+	default:
+		for _, child := range node.Content {
+			if err := substituteVars(child, vars); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func substituteScalar(node, vars *yaml.Node) error {
+	if !strings.Contains(node.Value, "${") {
+		return nil
+	}
+	if m := varRefPattern.FindStringSubmatch(node.Value); m != nil && m[0] == node.Value {
+		v, err := lookupVar(vars, m[1])
+		if err != nil {
+			return err
+		}
+		*node = *deepCopyNode(v)
+		return nil
+	}
+
+	var lookupErr error
+	node.Value = varRefPattern.ReplaceAllStringFunc(node.Value, func(ref string) string {
+		name := varRefPattern.FindStringSubmatch(ref)[1]
+		v, err := lookupVar(vars, name)
+		if err == nil && v.Kind != yaml.ScalarNode {
+			err = fmt.Errorf("var %q is not a scalar and cannot be embedded in a string", name)
+		}
+		if err != nil {
+			if lookupErr == nil {
+				lookupErr = err
+			}
+			return ref
+		}
+		return v.Value
+	})
+	if lookupErr != nil {
+		return lookupErr
+	}
+	// The result is a plain string, whatever the original tag resolved to.
+	node.Tag = "!!str"
+	return nil
+}
+
+// lookupVar resolves a dotted name such as "api.base_url" in vars.
+func lookupVar(vars *yaml.Node, name string) (*yaml.Node, error) {
+	cur := vars
+	for _, key := range strings.Split(name, ".") {
+		if cur.Kind == yaml.AliasNode {
+			cur = cur.Alias
+		}
+		i := -1
+		if cur.Kind == yaml.MappingNode {
+			i = mappingKeyIndex(cur, key)
+		}
+		if i < 0 {
+			return nil, fmt.Errorf("undefined var %q", name)
+		}
+		cur = cur.Content[i+1]
+	}
+	return cur, nil
+}
+
+func deepCopyNode(n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.AliasNode {
+		return deepCopyNode(n.Alias)
+	}
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = deepCopyNode(child)
+	}
+	return &c
+}
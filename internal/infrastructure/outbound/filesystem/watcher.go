@@ -12,7 +12,7 @@ import (
 	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
 )
 
-// Watcher watches for YAML file changes and triggers a reload callback.
+// Watcher watches for YAML and JSON file changes and triggers a reload callback.
 type Watcher struct {
 	rootDir  string
 	debounce time.Duration
@@ -79,8 +79,8 @@ func (w *Watcher) loop() {
 				return
 			}
 
-			// Only care about YAML files.
-			if !isYAMLFile(event.Name) {
+			// Only care about scenario files.
+			if !isScenarioFile(event.Name) {
 				// Check if a new directory was created.
 				if event.Has(fsnotify.Create) {
 					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
@@ -119,13 +119,16 @@ func (w *Watcher) addRecursive(dir string) error {
 			return err
 		}
 		if d.IsDir() {
+			if d.Name() == HistoryDirName {
+				return filepath.SkipDir
+			}
 			return w.watcher.Add(path)
 		}
 		return nil
 	})
 }
 
-func isYAMLFile(name string) bool {
+func isScenarioFile(name string) bool {
 	ext := strings.ToLower(filepath.Ext(name))
-	return ext == ".yaml" || ext == ".yml"
+	return ext == ".yaml" || ext == ".yml" || ext == ".json"
 }
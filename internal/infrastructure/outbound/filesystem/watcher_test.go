@@ -146,6 +146,29 @@ func TestWatcher_YMLExtension(t *testing.T) {
 	}
 }
 
+func TestWatcher_JSONExtension(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var reloadCount atomic.Int32
+	w, err := filesystem.NewWatcher(tmpDir, 100*time.Millisecond, &testutil.NoopLogger{}, func() {
+		reloadCount.Add(1)
+	})
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	defer w.Stop()
+	w.Start()
+
+	// Create a .json file.
+	os.WriteFile(filepath.Join(tmpDir, "test.json"), []byte(`{"id": "test"}`), 0o644)
+
+	time.Sleep(500 * time.Millisecond)
+
+	if reloadCount.Load() < 1 {
+		t.Error("expected at least one reload for .json file")
+	}
+}
+
 func TestWatcher_Debounce(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -1,5 +1,7 @@
 package filesystem
 
+import "gopkg.in/yaml.v3"
+
 // yamlScenario is the YAML deserialization target for scenario files.
 type yamlScenario struct {
 	ID       string       `yaml:"id"`
@@ -8,64 +10,231 @@ type yamlScenario struct {
 	When     yamlWhen     `yaml:"when"`
 	Response yamlResponse `yaml:"response"`
 	Policy   *yamlPolicy  `yaml:"policy,omitempty"`
+
+	Responses []yamlResponse `yaml:"responses,omitempty"`
+	Cycle     bool           `yaml:"cycle,omitempty"`
+
+	Profiles []string    `yaml:"profiles,omitempty"`
+	Static   *yamlStatic `yaml:"static,omitempty"`
+	Kind     string      `yaml:"kind,omitempty"`
+
+	Deprecated         bool   `yaml:"deprecated,omitempty"`
+	DeprecationMessage string `yaml:"deprecation_message,omitempty"`
+
+	RequireContentType []string `yaml:"require_content_type,omitempty"`
+
+	// Log defaults to true; log: false silences per-request info logs.
+	Log *bool `yaml:"log,omitempty"`
+
+	// Dynamic marks generated scenarios that may be evicted.
+	Dynamic bool `yaml:"dynamic,omitempty"`
+
+	ExampleRequest *yamlExampleRequest `yaml:"example_request,omitempty"`
 }
 
-type yamlWhen struct {
-	Method  string            `yaml:"method"`
-	Path    string            `yaml:"path"`
+type yamlExampleRequest struct {
+	Method  string            `yaml:"method,omitempty"`
+	Path    string            `yaml:"path,omitempty"`
 	Headers map[string]string `yaml:"headers,omitempty"`
-	Body    *yamlBody         `yaml:"body,omitempty"`
+	Query   map[string]string `yaml:"query,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+}
+
+type yamlStatic struct {
+	Dir        string `yaml:"dir"`
+	PathPrefix string `yaml:"path_prefix"`
+}
+
+type yamlWhen struct {
+	Method        yamlMethod                `yaml:"method"`
+	Path          string                    `yaml:"path"`
+	Host          string                    `yaml:"host,omitempty"`
+	Headers       map[string]string         `yaml:"headers,omitempty"`
+	UserAgent     *string                   `yaml:"user_agent,omitempty"`
+	Referer       *string                   `yaml:"referer,omitempty"`
+	Query         map[string]string         `yaml:"query,omitempty"`
+	Cookies       map[string]string         `yaml:"cookies,omitempty"`
+	QueryArray    map[string]yamlQueryArray `yaml:"query_array,omitempty"`
+	Body          *yamlBody                 `yaml:"body,omitempty"`
+	ContentLength *yamlNumericMatcher       `yaml:"content_length,omitempty"`
+	BodyHash      *yamlBodyHash             `yaml:"body_hash,omitempty"`
+	BodyPrefix    *yamlBodyPrefix           `yaml:"body_prefix,omitempty"`
+	Schedule      string                    `yaml:"schedule,omitempty"`
+	Secure        *bool                     `yaml:"secure,omitempty"`
+	Expr          string                    `yaml:"expr,omitempty"`
+}
+
+// yamlMethod accepts when.method as a single method or a list of methods.
+type yamlMethod []string
+
+func (m *yamlMethod) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		methods := []string{}
+		if err := node.Decode(&methods); err != nil {
+			return err
+		}
+		*m = methods
+		return nil
+	}
+	var method string
+	if err := node.Decode(&method); err != nil {
+		return err
+	}
+	*m = yamlMethod{method}
+	return nil
+}
+
+type yamlBodyHash struct {
+	Algorithm string `yaml:"algorithm"`
+	Expected  string `yaml:"expected"`
+}
+
+type yamlBodyPrefix struct {
+	Hex    string `yaml:"hex,omitempty"`
+	Base64 string `yaml:"base64,omitempty"`
+}
+
+type yamlQueryArray struct {
+	Contains []string            `yaml:"contains,omitempty"`
+	Count    *yamlNumericMatcher `yaml:"count,omitempty"`
+}
+
+type yamlNumericMatcher struct {
+	Eq  *float64 `yaml:"eq,omitempty"`
+	Gt  *float64 `yaml:"gt,omitempty"`
+	Gte *float64 `yaml:"gte,omitempty"`
+	Lt  *float64 `yaml:"lt,omitempty"`
+	Lte *float64 `yaml:"lte,omitempty"`
 }
 
 type yamlBody struct {
+	Decode      string          `yaml:"decode,omitempty"`
 	ContentType string          `yaml:"content_type,omitempty"`
+	Regex       string          `yaml:"regex,omitempty"`
+	KeyOrder    *yamlKeyOrder   `yaml:"key_order,omitempty"`
 	Conditions  []yamlCondition `yaml:"conditions,omitempty"`
 	All         []yamlBody      `yaml:"all,omitempty"`
 	Any         []yamlBody      `yaml:"any,omitempty"`
 	Not         *yamlBody       `yaml:"not,omitempty"`
 }
 
+type yamlKeyOrder struct {
+	Keys       []string `yaml:"keys"`
+	AllowExtra bool     `yaml:"allow_extra,omitempty"`
+}
+
 type yamlCondition struct {
-	Extractor string `yaml:"extractor"`
-	Matcher   string `yaml:"matcher"`
+	Extractor  string   `yaml:"extractor"`
+	Matcher    string   `yaml:"matcher"`
+	Min        *float64 `yaml:"min,omitempty"`
+	Max        *float64 `yaml:"max,omitempty"`
+	AllRegex   string   `yaml:"all_regex,omitempty"`
+	AllowEmpty bool     `yaml:"allow_empty,omitempty"`
 }
 
 type yamlResponse struct {
-	Status      int               `yaml:"status"`
-	Headers     map[string]string `yaml:"headers,omitempty"`
-	Body        string            `yaml:"body,omitempty"`
-	BodyFile    string            `yaml:"body_file,omitempty"`
-	ContentType string            `yaml:"content_type,omitempty"`
-	Engine      string            `yaml:"engine,omitempty"`
+	Status           int               `yaml:"status"`
+	Headers          map[string]string `yaml:"headers,omitempty"`
+	Body             string            `yaml:"body,omitempty"`
+	BodyFile         string            `yaml:"body_file,omitempty"`
+	ContentType      string            `yaml:"content_type,omitempty"`
+	Engine           string            `yaml:"engine,omitempty"`
+	Charset          string            `yaml:"charset,omitempty"`
+	OmitNulls        bool              `yaml:"omit_nulls,omitempty"`
+	CanonicalizeBody string            `yaml:"canonicalize_body,omitempty"`
+	Raw              bool              `yaml:"raw,omitempty"`
+	Switch           *yamlSwitch       `yaml:"switch,omitempty"`
+	TimeSwitch       *yamlTimeSwitch   `yaml:"time_switch,omitempty"`
+	Cache            *yamlCache        `yaml:"cache,omitempty"`
+	Created          *yamlCreated      `yaml:"created,omitempty"`
+	TruncateAtBytes  int               `yaml:"truncate_at_bytes,omitempty"`
+	Compression      []string          `yaml:"compression,omitempty"`
+	Proxy            *yamlProxy        `yaml:"proxy,omitempty"`
+	Generate         *yamlGenerate     `yaml:"generate,omitempty"`
+
+	BodyFileMissingStatus int `yaml:"body_file_missing_status,omitempty"`
+}
+
+type yamlGenerate struct {
+	Count  int               `yaml:"count"`
+	Fields map[string]string `yaml:"fields"`
+}
+
+type yamlProxy struct {
+	Target      string `yaml:"target"`
+	StripPrefix string `yaml:"strip_prefix,omitempty"`
+	TimeoutMs   int    `yaml:"timeout_ms,omitempty"`
+	Record      bool   `yaml:"record,omitempty"`
+}
+
+type yamlCreated struct {
+	Location string `yaml:"location"`
+}
+
+type yamlCache struct {
+	MaxAge     int    `yaml:"max_age"`
+	Visibility string `yaml:"visibility,omitempty"`
+	Immutable  bool   `yaml:"immutable,omitempty"`
+}
+
+type yamlSwitch struct {
+	On      string                  `yaml:"on"`
+	Cases   map[string]yamlResponse `yaml:"cases,omitempty"`
+	Default *yamlResponse           `yaml:"default,omitempty"`
+}
+
+type yamlTimeSwitch struct {
+	Cases   []yamlTimeCase `yaml:"cases"`
+	Default *yamlResponse  `yaml:"default,omitempty"`
+}
+
+type yamlTimeCase struct {
+	Schedule string       `yaml:"schedule"`
+	Response yamlResponse `yaml:"response"`
 }
 
 type yamlPolicy struct {
-	RateLimit  *yamlRateLimit  `yaml:"rate_limit,omitempty"`
-	Latency    *yamlLatency    `yaml:"latency,omitempty"`
-	Pagination *yamlPagination `yaml:"pagination,omitempty"`
+	RateLimit   *yamlRateLimit   `yaml:"rate_limit,omitempty"`
+	Latency     *yamlLatency     `yaml:"latency,omitempty"`
+	Pagination  *yamlPagination  `yaml:"pagination,omitempty"`
+	LoadBalance *yamlLoadBalance `yaml:"load_balance,omitempty"`
+	Fault       *yamlFault       `yaml:"fault,omitempty"`
+}
+
+type yamlFault struct {
+	Type string  `yaml:"type"`
+	Rate float64 `yaml:"rate,omitempty"`
+}
+
+type yamlLoadBalance struct {
+	Weight int `yaml:"weight,omitempty"`
 }
 
 type yamlRateLimit struct {
-	Rate  float64 `yaml:"rate"`
-	Burst int     `yaml:"burst"`
-	Key   string  `yaml:"key,omitempty"`
+	Rate     float64       `yaml:"rate"`
+	Burst    int           `yaml:"burst"`
+	Key      string        `yaml:"key,omitempty"`
+	Response *yamlResponse `yaml:"response,omitempty"`
 }
 
 type yamlLatency struct {
-	FixedMs  int `yaml:"fixed_ms,omitempty"`
-	JitterMs int `yaml:"jitter_ms,omitempty"`
+	FixedMs       int `yaml:"fixed_ms,omitempty"`
+	JitterMs      int `yaml:"jitter_ms,omitempty"`
+	HeaderDelayMs int `yaml:"header_delay_ms,omitempty"`
 }
 
 type yamlPagination struct {
-	Style       string                  `yaml:"style,omitempty"`
-	PageParam   string                  `yaml:"page_param,omitempty"`
-	SizeParam   string                  `yaml:"size_param,omitempty"`
-	OffsetParam string                  `yaml:"offset_param,omitempty"`
-	LimitParam  string                  `yaml:"limit_param,omitempty"`
-	DefaultSize int                     `yaml:"default_size,omitempty"`
-	MaxSize     int                     `yaml:"max_size,omitempty"`
-	DataPath    string                  `yaml:"data_path,omitempty"`
-	Envelope    *yamlPaginationEnvelope `yaml:"envelope,omitempty"`
+	Style       string `yaml:"style,omitempty"`
+	PageParam   string `yaml:"page_param,omitempty"`
+	SizeParam   string `yaml:"size_param,omitempty"`
+	OffsetParam string `yaml:"offset_param,omitempty"`
+	LimitParam  string `yaml:"limit_param,omitempty"`
+	DefaultSize int    `yaml:"default_size,omitempty"`
+	MaxSize     int    `yaml:"max_size,omitempty"`
+	DataPath    string `yaml:"data_path,omitempty"`
+	// TotalItemsSource is a JSONPath into the body or a fixed number.
+	TotalItemsSource string                  `yaml:"total_items_source,omitempty"`
+	Envelope         *yamlPaginationEnvelope `yaml:"envelope,omitempty"`
 }
 
 type yamlPaginationEnvelope struct {
@@ -0,0 +1,38 @@
+package random
+
+import (
+	"math/rand/v2"
+	"sync"
+
+	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
+)
+
+var _ ports.RandomSource = (*Seeded)(nil)
+
+// New returns the random source for a run: the math/rand/v2 global generator
+// when seed is zero, otherwise a Seeded source.
+func New(seed uint64) ports.RandomSource {
+	if seed == 0 {
+		return ports.RandomFunc(rand.IntN)
+	}
+	return NewSeeded(seed)
+}
+
+// Seeded implements ports.RandomSource with a generator seeded for
+// reproducible runs. Draws are serialized by a mutex, so the sequence is
+// only repeatable when requests arrive in the same order.
+type Seeded struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewSeeded creates a Seeded source from seed.
+func NewSeeded(seed uint64) *Seeded {
+	return &Seeded{rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+func (s *Seeded) IntN(n int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rng.IntN(n)
+}
@@ -0,0 +1,51 @@
+package random_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/random"
+)
+
+func TestSeeded_Reproducible(t *testing.T) {
+	a := random.NewSeeded(42)
+	b := random.NewSeeded(42)
+
+	for i := range 100 {
+		if x, y := a.IntN(1000), b.IntN(1000); x != y {
+			t.Fatalf("draw %d: %d != %d", i, x, y)
+		}
+	}
+}
+
+func TestSeeded_ConcurrentUse(t *testing.T) {
+	src := random.NewSeeded(7)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if n := src.IntN(10); n < 0 || n >= 10 {
+					t.Errorf("IntN(10) = %d, out of range", n)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNew(t *testing.T) {
+	if _, ok := random.New(0).(*random.Seeded); ok {
+		t.Error("New(0) should use the global generator")
+	}
+	if _, ok := random.New(42).(*random.Seeded); !ok {
+		t.Error("New(42) should return a Seeded source")
+	}
+	for range 100 {
+		if n := random.New(0).IntN(5); n < 0 || n >= 5 {
+			t.Fatalf("IntN(5) = %d, out of range", n)
+		}
+	}
+}
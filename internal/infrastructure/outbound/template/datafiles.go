@@ -0,0 +1,137 @@
+package template
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dataFiles loads JSON and CSV datasets from the mock root for the lookup
+// template function. Parsed files are cached and reloaded when they change.
+type dataFiles struct {
+	root string
+
+	mu    sync.Mutex
+	cache map[string]*dataset
+}
+
+type dataset struct {
+	modTime time.Time
+	size    int64
+	records []map[string]any
+}
+
+func newDataFiles(root string) *dataFiles {
+	return &dataFiles{root: root, cache: make(map[string]*dataset)}
+}
+
+// lookup returns the first record whose keyField equals keyValue, encoded as
+// JSON. Unreadable files, paths outside the root and missing matches all
+// yield an empty string.
+func (d *dataFiles) lookup(file, keyField string, keyValue any) string {
+	if d == nil {
+		return ""
+	}
+	records, err := d.load(file)
+	if err != nil {
+		return ""
+	}
+
+	want := fmt.Sprint(keyValue)
+	for _, rec := range records {
+		v, ok := rec[keyField]
+		if !ok || fmt.Sprint(v) != want {
+			continue
+		}
+		b, err := json.Marshal(rec)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+	return ""
+}
+
+func (d *dataFiles) load(file string) ([]map[string]any, error) {
+	root, err := os.OpenRoot(d.root)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	name := filepath.Clean(file)
+	info, err := root.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	cached, ok := d.cache[name]
+	d.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.records, nil
+	}
+
+	f, err := root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []map[string]any
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		records, err = parseCSVRecords(f)
+	} else {
+		records, err = parseJSONRecords(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse data file %q: %w", file, err)
+	}
+
+	d.mu.Lock()
+	d.cache[name] = &dataset{modTime: info.ModTime(), size: info.Size(), records: records}
+	d.mu.Unlock()
+	return records, nil
+}
+
+// parseJSONRecords decodes a JSON array of objects. Numbers keep their
+// literal form so keys like 1 and "1" compare equal.
+func parseJSONRecords(r io.Reader) ([]map[string]any, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var records []map[string]any
+	if err := dec.Decode(&records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// parseCSVRecords reads a CSV file whose first row names the columns.
+func parseCSVRecords(r io.Reader) ([]map[string]any, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	header := rows[0]
+	records := make([]map[string]any, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		rec := make(map[string]any, len(header))
+		for i, col := range header {
+			if i < len(row) {
+				rec[col] = row[i]
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
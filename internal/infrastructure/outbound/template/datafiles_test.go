@@ -0,0 +1,121 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+)
+
+func writeDataFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+}
+
+func TestLookup_JSONDataset(t *testing.T) {
+	dir := t.TempDir()
+	writeDataFile(t, dir, "data/users.json", `[
+  {"id": 1, "name": "Alice", "role": "admin"},
+  {"id": 2, "name": "Bob", "role": "viewer"}
+]`)
+
+	c := &ExprCompiler{data: newDataFiles(dir)}
+	renderer, err := c.Compile("test", `${lookup('data/users.json', 'id', pathParam('id'))}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"2", `{"id":2,"name":"Bob","role":"viewer"}`},
+		{"1", `{"id":1,"name":"Alice","role":"admin"}`},
+		{"3", ""},
+	}
+	for _, tt := range tests {
+		result, err := renderer.Render(match.RenderContext{PathParams: map[string]string{"id": tt.id}})
+		if err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		if string(result) != tt.want {
+			t.Errorf("id %s: expected %q, got %q", tt.id, tt.want, result)
+		}
+	}
+}
+
+func TestLookup_CSVDataset(t *testing.T) {
+	dir := t.TempDir()
+	writeDataFile(t, dir, "users.csv", "id,name\n1,Alice\n2,Bob\n")
+
+	c := &Jinja2Compiler{data: newDataFiles(dir)}
+	renderer, err := c.Compile("test", `{{ lookup("users.csv", "name", "Bob")|safe }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	result, err := renderer.Render(match.RenderContext{})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != `{"id":"2","name":"Bob"}` {
+		t.Errorf("unexpected result: %q", result)
+	}
+}
+
+func TestLookup_OutsideRootAndMissingFile(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "root")
+	writeDataFile(t, parent, "secret.json", `[{"id": "1"}]`)
+	writeDataFile(t, root, "ok.json", `[{"id": "1"}]`)
+
+	data := newDataFiles(root)
+	for _, file := range []string{"../secret.json", filepath.Join(parent, "secret.json"), "missing.json"} {
+		if got := data.lookup(file, "id", "1"); got != "" {
+			t.Errorf("lookup(%q): expected empty result, got %q", file, got)
+		}
+	}
+	if got := data.lookup("ok.json", "id", "1"); got != `{"id":"1"}` {
+		t.Errorf("expected record from within root, got %q", got)
+	}
+}
+
+func TestLookup_ReloadsChangedFile(t *testing.T) {
+	dir := t.TempDir()
+	writeDataFile(t, dir, "items.json", `[{"id": "a", "v": 1}]`)
+
+	data := newDataFiles(dir)
+	if got := data.lookup("items.json", "id", "a"); got != `{"id":"a","v":1}` {
+		t.Fatalf("unexpected first lookup: %q", got)
+	}
+
+	writeDataFile(t, dir, "items.json", `[{"id": "a", "v": 22}]`)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "items.json"), later, later); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+	if got := data.lookup("items.json", "id", "a"); got != `{"id":"a","v":22}` {
+		t.Errorf("expected reloaded record, got %q", got)
+	}
+}
+
+func TestLookup_WithoutDataRoot(t *testing.T) {
+	c := &GoTemplateCompiler{}
+	renderer, err := c.Compile("test", `[{{ lookup "users.json" "id" "1" }}]`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	result, err := renderer.Render(match.RenderContext{})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "[]" {
+		t.Errorf("expected empty lookup without a data root, got %q", result)
+	}
+}
@@ -8,14 +8,19 @@ import (
 	"github.com/expr-lang/expr/vm"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
 )
 
 // ExprCompiler compiles body templates using the Expr language with ${ } interpolation.
-type ExprCompiler struct{}
+type ExprCompiler struct {
+	data   *dataFiles
+	funcs  map[string]TemplateFunc
+	random ports.RandomSource
+}
 
 // Compile parses the source for ${ } delimiters and compiles each expression.
 func (c *ExprCompiler) Compile(name, source string) (match.BodyRenderer, error) {
-	segments, err := parseExprSegments(source)
+	segments, err := parseExprSegments(source, c.funcs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse expr template %q: %w", name, err)
 	}
@@ -32,7 +37,7 @@ func (c *ExprCompiler) Compile(name, source string) (match.BodyRenderer, error)
 		return &staticRenderer{body: []byte(source)}, nil
 	}
 
-	return &exprRenderer{segments: segments}, nil
+	return &exprRenderer{segments: segments, data: c.data, random: randomFuncs{src: c.random}}, nil
 }
 
 type exprSegment struct {
@@ -40,7 +45,12 @@ type exprSegment struct {
 	program *vm.Program
 }
 
-func parseExprSegments(source string) ([]exprSegment, error) {
+func parseExprSegments(source string, funcs map[string]TemplateFunc) ([]exprSegment, error) {
+	opts := []expr.Option{expr.Env(exprEnv{})}
+	for name, fn := range funcs {
+		opts = append(opts, expr.Function(name, fn))
+	}
+
 	var segments []exprSegment
 	remaining := source
 
@@ -66,7 +76,7 @@ func parseExprSegments(source string) ([]exprSegment, error) {
 		}
 
 		expression := rest[:closeIdx]
-		program, err := expr.Compile(expression, expr.Env(exprEnv{}))
+		program, err := expr.Compile(expression, opts...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to compile expression %q: %w", expression, err)
 		}
@@ -112,25 +122,48 @@ func findClosingBrace(s string) int {
 
 // exprEnv defines the environment available to Expr expressions.
 type exprEnv struct {
-	PathParam  func(string) string  `expr:"pathParam"`
-	QueryParam func(string) string  `expr:"queryParam"`
-	Header     func(string) string  `expr:"header"`
-	Body       func() string        `expr:"body"`
-	Now        func() string        `expr:"now"`
-	NowFormat  func(string) string  `expr:"nowFormat"`
-	UUID       func() string        `expr:"uuid"`
-	RandomInt  func(int, int) int   `expr:"randomInt"`
-	Seq        func(int, int) []int `expr:"seq"`
-	ToJSON     func(any) string     `expr:"toJSON"`
-	JsonPath   func(string) string  `expr:"jsonPath"`
+	PathParams     map[string]string                `expr:"pathParams"`
+	QueryParams    map[string]string                `expr:"queryParams"`
+	PathParam      func(string) string              `expr:"pathParam"`
+	QueryParam     func(string) string              `expr:"queryParam"`
+	Host           func(string) string              `expr:"host"`
+	Header         func(string) string              `expr:"header"`
+	RemoteIP       func() string                    `expr:"remoteIP"`
+	Body           func() string                    `expr:"body"`
+	CanonicalBody  func() string                    `expr:"canonicalBody"`
+	Now            func() string                    `expr:"now"`
+	NowFormat      func(string) string              `expr:"nowFormat"`
+	NowPlus        func(string) string              `expr:"nowPlus"`
+	NowMinus       func(string) string              `expr:"nowMinus"`
+	Timestamp      func() int64                     `expr:"timestamp"`
+	UUID           func() string                    `expr:"uuid"`
+	HashID         func(...any) string              `expr:"hashId"`
+	Base64Encode   func(string) string              `expr:"base64Encode"`
+	Base64Decode   func(string) string              `expr:"base64Decode"`
+	SHA256         func(string) string              `expr:"sha256"`
+	MD5            func(string) string              `expr:"md5"`
+	HMACSHA256     func(string, string) string      `expr:"hmacSHA256"`
+	RandomInt      func(int, int) int               `expr:"randomInt"`
+	RandomString   func(int) string                 `expr:"randomString"`
+	RandomChoice   func(any) any                    `expr:"randomChoice"`
+	Seq            func(int, int) []int             `expr:"seq"`
+	WeightedChoice func(...any) any                 `expr:"weightedChoice"`
+	Jitter         func(any, any) any               `expr:"jitter"`
+	ToJSON         func(any) string                 `expr:"toJSON"`
+	ToYAML         func(any) string                 `expr:"toYAML"`
+	FromJSON       func(string) any                 `expr:"fromJSON"`
+	JsonPath       func(string) string              `expr:"jsonPath"`
+	Lookup         func(string, string, any) string `expr:"lookup"`
 }
 
 type exprRenderer struct {
 	segments []exprSegment
+	data     *dataFiles
+	random   randomFuncs
 }
 
 func (r *exprRenderer) Render(ctx match.RenderContext) ([]byte, error) {
-	env := buildExprEnv(ctx)
+	env := buildExprEnv(ctx, r.data, r.random)
 
 	var buf strings.Builder
 	for _, seg := range r.segments {
@@ -1,6 +1,10 @@
 package template
 
 import (
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -194,6 +198,39 @@ func TestExprCompiler_UUID(t *testing.T) {
 	}
 }
 
+func TestExprCompiler_HashID(t *testing.T) {
+	c := &ExprCompiler{}
+	renderer, err := c.Compile("test", `${hashId(pathParam('id'), header('X-Tenant'))}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	render := func(id, tenant string) string {
+		t.Helper()
+		result, err := renderer.Render(match.RenderContext{
+			PathParams: map[string]string{"id": id},
+			Headers:    map[string]string{"X-Tenant": tenant},
+		})
+		if err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		return string(result)
+	}
+
+	first := render("42", "acme")
+	if !regexp.MustCompile(`^[0-9a-f]{12}$`).MatchString(first) {
+		t.Fatalf("expected 12 hex digits, got %q", first)
+	}
+	if again := render("42", "acme"); again != first {
+		t.Errorf("expected identical inputs to give %q, got %q", first, again)
+	}
+	for _, in := range [][2]string{{"43", "acme"}, {"42", "globex"}, {"4", "2acme"}} {
+		if other := render(in[0], in[1]); other == first {
+			t.Errorf("expected %v to give a different ID than (42, acme)", in)
+		}
+	}
+}
+
 func TestExprCompiler_RandomInt(t *testing.T) {
 	c := &ExprCompiler{}
 	renderer, err := c.Compile("test", `${randomInt(1, 10)}`)
@@ -465,3 +502,344 @@ func TestExprCompiler_DoubleQuotedString(t *testing.T) {
 		t.Errorf("expected 'test', got %q", result)
 	}
 }
+
+func TestExprCompiler_CanonicalBody(t *testing.T) {
+	c := &ExprCompiler{}
+	renderer, err := c.Compile("test", `${canonicalBody()}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		style string
+		want  string
+	}{
+		{"compact sorts keys", `{ "b": 2, "a": {"y": 1, "x": [3, 1]} }`, "", `{"a":{"x":[3,1],"y":1},"b":2}`},
+		{"pretty indents", `{"b":2,"a":1}`, "pretty", "{\n  \"a\": 1,\n  \"b\": 2\n}"},
+		{"large numbers preserved", `{"id": 9007199254740993}`, "compact", `{"id":9007199254740993}`},
+		{"invalid JSON degrades to raw", `not {json`, "compact", `not {json`},
+		{"trailing data degrades to raw", `{"a":1} {"b":2}`, "compact", `{"a":1} {"b":2}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := renderer.Render(match.RenderContext{
+				Body:             []byte(tt.body),
+				CanonicalizeBody: tt.style,
+			})
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if string(result) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestExprCompiler_CanonicalizeBodyOption(t *testing.T) {
+	c := &ExprCompiler{}
+	renderer, err := c.Compile("test", `echo: ${body()}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	raw := []byte(`{ "z": true, "a": null }`)
+
+	result, err := renderer.Render(match.RenderContext{Body: raw})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != `echo: { "z": true, "a": null }` {
+		t.Errorf("expected raw body without option, got %q", result)
+	}
+
+	result, err = renderer.Render(match.RenderContext{Body: raw, CanonicalizeBody: "compact"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != `echo: {"a":null,"z":true}` {
+		t.Errorf("expected canonical body with option, got %q", result)
+	}
+}
+
+func TestExprCompiler_FromJSON(t *testing.T) {
+	c := &ExprCompiler{}
+	renderer, err := c.Compile("test", `${join(map(fromJSON(body()).items, .name), ",")}|${fromJSON('nope') == nil}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{
+		Body: []byte(`{"items":[{"name":"a"},{"name":"b"}]}`),
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "a,b|true" {
+		t.Errorf("expected 'a,b|true', got %q", result)
+	}
+}
+
+func TestExprCompiler_ToYAML(t *testing.T) {
+	c := &ExprCompiler{}
+	renderer, err := c.Compile("test", `${toYAML({"enabled": true})}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "enabled: true" {
+		t.Errorf("expected 'enabled: true', got %q", result)
+	}
+}
+
+// fixedRandom always returns the same value, clamped to [0, n).
+type fixedRandom int
+
+func (f fixedRandom) IntN(n int) int {
+	return min(int(f), n-1)
+}
+
+func TestExprCompiler_WeightedChoiceSeeded(t *testing.T) {
+	draw := func(seed uint64) []string {
+		c := &ExprCompiler{random: rand.New(rand.NewPCG(seed, seed))}
+		renderer, err := c.Compile("test", `${weightedChoice("a", 1, "b", 3, "never", 0)}`)
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+
+		var out []string
+		for range 50 {
+			result, err := renderer.Render(match.RenderContext{})
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			out = append(out, string(result))
+		}
+		return out
+	}
+
+	first, second := draw(42), draw(42)
+	counts := map[string]int{}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("draw %d differs with same seed: %q vs %q", i, first[i], second[i])
+		}
+		counts[first[i]]++
+	}
+	if counts["never"] != 0 {
+		t.Errorf("zero-weight value selected %d times", counts["never"])
+	}
+	if counts["b"] <= counts["a"] {
+		t.Errorf("expected heavier value to dominate, got %v", counts)
+	}
+}
+
+func TestExprCompiler_WeightedChoiceForms(t *testing.T) {
+	tests := []struct {
+		name   string
+		tmpl   string
+		random fixedRandom
+		want   string
+	}{
+		{"first of flat list", `${weightedChoice(["x", 1, "y", 1])}`, 0, "x"},
+		{"last of flat list", `${weightedChoice(["x", 1, "y", 1])}`, 1<<30 - 1, "y"},
+		{"pair list", `${weightedChoice([["x", 1], ["y", 1]])}`, 1<<30 - 1, "y"},
+		{"map sorted by key", `${weightedChoice({"b": 1, "a": 1})}`, 0, "a"},
+		{"empty input", `${weightedChoice()}`, 0, ""},
+		{"no positive weights", `${weightedChoice("x", 0)}`, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ExprCompiler{random: tt.random}
+			renderer, err := c.Compile("test", tt.tmpl)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			result, err := renderer.Render(match.RenderContext{})
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if string(result) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestExprCompiler_Jitter(t *testing.T) {
+	tests := []struct {
+		name   string
+		tmpl   string
+		random fixedRandom
+		want   string
+	}{
+		{"lowest draw", `${jitter(100, 5)}`, 0, "95"},
+		{"highest draw", `${jitter(100, 5)}`, 1 << 30, "105"},
+		{"middle draw", `${jitter(100, 5)}`, 1 << 29, "100"},
+		{"float keeps fraction", `${jitter(2.5, 10)}`, 0, "2.25"},
+		{"numeric string", `${jitter("200", 10)}`, 1 << 30, "220"},
+		{"non-numeric unchanged", `${jitter("abc", 5)}`, 0, "abc"},
+		{"zero percent unchanged", `${jitter(42, 0)}`, 0, "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ExprCompiler{random: tt.random}
+			renderer, err := c.Compile("test", tt.tmpl)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			result, err := renderer.Render(match.RenderContext{})
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if string(result) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestExprCompiler_JitterSeeded(t *testing.T) {
+	draw := func(seed uint64) []string {
+		c := &ExprCompiler{random: rand.New(rand.NewPCG(seed, seed))}
+		renderer, err := c.Compile("test", `${jitter(1000, 5)}`)
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+
+		var out []string
+		for range 20 {
+			result, err := renderer.Render(match.RenderContext{})
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			out = append(out, string(result))
+		}
+		return out
+	}
+
+	first, second := draw(7), draw(7)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("draw %d differs with same seed: %q vs %q", i, first[i], second[i])
+		}
+		n, err := strconv.Atoi(first[i])
+		if err != nil {
+			t.Fatalf("expected integer result, got %q", first[i])
+		}
+		if n < 950 || n > 1050 {
+			t.Errorf("draw %d out of ±5%% range: %d", i, n)
+		}
+	}
+}
+
+func TestExprCompiler_EncodingAndHashes(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`${base64Encode("hello")}`, "aGVsbG8="},
+		{`${base64Decode("aGVsbG8=")}`, "hello"},
+		{`${base64Decode("aGVsbG8")}`, "hello"},
+		{`${base64Decode("not base64!")}`, ""},
+		{`${sha256("abc")}`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{`${md5("abc")}`, "900150983cd24fb0d6963f7d28e17f72"},
+		{`${hmacSHA256("key", "The quick brown fox jumps over the lazy dog")}`, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{`${base64Encode(header("X-User"))}`, "YWxpY2U="},
+	}
+	c := &ExprCompiler{}
+	for _, tt := range tests {
+		renderer, err := c.Compile("test", tt.expr)
+		if err != nil {
+			t.Fatalf("%s: Compile failed: %v", tt.expr, err)
+		}
+		result, err := renderer.Render(match.RenderContext{Headers: map[string]string{"X-User": "alice"}})
+		if err != nil {
+			t.Fatalf("%s: Render failed: %v", tt.expr, err)
+		}
+		if string(result) != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.expr, tt.want, result)
+		}
+	}
+}
+
+func TestExprCompiler_RandomStringAndChoice(t *testing.T) {
+	c := &ExprCompiler{}
+	renderer, err := c.Compile("test", `${randomString(12)}|${randomChoice(["red", "green", "blue"])}|${randomChoice([])}|${randomString(0)}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	for range 20 {
+		result, err := renderer.Render(match.RenderContext{})
+		if err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		parts := strings.Split(string(result), "|")
+		if len(parts) != 4 {
+			t.Fatalf("unexpected result %q", result)
+		}
+		if len(parts[0]) != 12 || strings.Trim(parts[0], randomStringAlphabet) != "" {
+			t.Errorf("expected 12 alphanumeric characters, got %q", parts[0])
+		}
+		if !slices.Contains([]string{"red", "green", "blue"}, parts[1]) {
+			t.Errorf("expected a color, got %q", parts[1])
+		}
+		if parts[2] != "" || parts[3] != "" {
+			t.Errorf("expected empty results for empty input, got %q and %q", parts[2], parts[3])
+		}
+	}
+}
+
+func TestExprCompiler_NowArithmetic(t *testing.T) {
+	c := &ExprCompiler{}
+	renderer, err := c.Compile("test", `${nowPlus('1h30m')}|${nowMinus('24h')}|${timestamp()}|${nowPlus('soon')}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{Now: "2025-01-15T10:30:00Z"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := "2025-01-15T12:00:00Z|2025-01-14T10:30:00Z|1736937000|2025-01-15T10:30:00Z"
+	if string(result) != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+
+	result, err = renderer.Render(match.RenderContext{Now: "not-a-date"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if parts := strings.Split(string(result), "|"); parts[0] != "not-a-date" || parts[1] != "not-a-date" {
+		t.Errorf("expected fallback to the raw now, got %q", result)
+	}
+}
+
+func TestExprCompiler_ParamMaps(t *testing.T) {
+	c := &ExprCompiler{}
+	renderer, err := c.Compile("test", `${toJSON(pathParams)}|${queryParams["q"]}|${len(queryParams)}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{
+		PathParams:  map[string]string{"id": "42", "slug": "intro"},
+		QueryParams: map[string]string{"q": "go"},
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if want := `{"id":"42","slug":"intro"}|go|1`; string(result) != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
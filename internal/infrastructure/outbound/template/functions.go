@@ -1,25 +1,43 @@
 package template
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand/v2"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PaesslerAG/jsonpath"
+	"gopkg.in/yaml.v3"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
 )
 
-func buildExprEnv(ctx match.RenderContext) exprEnv {
+func buildExprEnv(ctx match.RenderContext, data *dataFiles, rnd randomFuncs) exprEnv {
+	body := requestBody(ctx)
 	return exprEnv{
+		PathParams:  ctx.PathParams,
+		QueryParams: ctx.QueryParams,
 		PathParam: func(name string) string {
 			return ctx.PathParams[name]
 		},
 		QueryParam: func(name string) string {
 			return ctx.QueryParams[name]
 		},
+		Host: func(name string) string {
+			return ctx.HostParams[name]
+		},
 		Header: func(name string) string {
 			// Case-insensitive header lookup.
 			for k, v := range ctx.Headers {
@@ -30,7 +48,13 @@ func buildExprEnv(ctx match.RenderContext) exprEnv {
 			return ""
 		},
 		Body: func() string {
-			return string(ctx.Body)
+			return body
+		},
+		RemoteIP: func() string {
+			return ctx.RemoteIP
+		},
+		CanonicalBody: func() string {
+			return canonicalJSON(ctx.Body, ctx.CanonicalizeBody == "pretty")
 		},
 		Now: func() string {
 			return ctx.Now
@@ -42,24 +66,43 @@ func buildExprEnv(ctx match.RenderContext) exprEnv {
 			}
 			return t.Format(layout)
 		},
-		UUID: func() string {
-			return generateUUID()
+		NowPlus: func(d string) string {
+			return nowOffset(ctx.Now, d, 1)
 		},
-		RandomInt: func(min, max int) int {
-			if min >= max {
-				return min
-			}
-			return min + randIntN(max-min+1)
+		NowMinus: func(d string) string {
+			return nowOffset(ctx.Now, d, -1)
 		},
+		Timestamp: func() int64 {
+			return nowTimestamp(ctx.Now)
+		},
+		UUID:         rnd.uuid,
+		HashID:       hashID,
+		Base64Encode: base64Encode,
+		Base64Decode: base64Decode,
+		SHA256:       sha256Hex,
+		MD5:          md5Hex,
+		HMACSHA256:   hmacSHA256,
+		RandomInt:    rnd.randomInt,
+		RandomString: rnd.randomString,
+		RandomChoice: rnd.randomChoice,
 		Seq: func(start, end int) []int {
 			return seqInts(start, end)
 		},
+		WeightedChoice: rnd.weightedChoice,
+		Jitter:         rnd.jitter,
 		ToJSON: func(v any) string {
 			return toJSONString(v)
 		},
+		ToYAML: func(v any) string {
+			return toYAMLString(v)
+		},
+		FromJSON: func(s string) any {
+			return parseJSONValue(s)
+		},
 		JsonPath: func(expression string) string {
 			return extractJSONPath(ctx.Body, expression)
 		},
+		Lookup: data.lookup,
 	}
 }
 
@@ -74,8 +117,166 @@ func seqInts(start, end int) []int {
 	return s
 }
 
-func randIntN(n int) int {
-	return rand.IntN(n)
+// randomFuncs implements the random helpers (uuid, randomInt, randomString,
+// randomChoice, weightedChoice and jitter) on top of one RandomSource. A nil
+// source draws from the math/rand/v2 global generator.
+type randomFuncs struct {
+	src ports.RandomSource
+}
+
+func (f randomFuncs) intN(n int) int {
+	if f.src == nil {
+		return rand.IntN(n)
+	}
+	return f.src.IntN(n)
+}
+
+// randomInt returns a number in [min, max], or min when the range is empty.
+func (f randomFuncs) randomInt(min, max int) int {
+	if min >= max {
+		return min
+	}
+	return min + f.intN(max-min+1)
+}
+
+const randomStringAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// randomString returns n random alphanumeric characters.
+func (f randomFuncs) randomString(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = randomStringAlphabet[f.intN(len(randomStringAlphabet))]
+	}
+	return string(b)
+}
+
+// randomChoice picks one element of a list uniformly. An empty list, or a
+// value that is not a list, yields an empty string.
+func (f randomFuncs) randomChoice(list any) any {
+	v := reflect.ValueOf(list)
+	if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Len() == 0 {
+		return ""
+	}
+	return v.Index(f.intN(v.Len())).Interface()
+}
+
+// weightedChoice picks one value from value/weight pairs with probability
+// proportional to its weight. Pairs may be passed as alternating arguments,
+// as a single list (flat or of [value, weight] pairs) or as a single map of
+// value to weight. Entries with non-positive or non-numeric weights are
+// ignored; when nothing is selectable the result is an empty string.
+func (f randomFuncs) weightedChoice(pairs ...any) any {
+	type entry struct {
+		value  any
+		weight float64
+	}
+
+	if len(pairs) == 1 {
+		switch v := pairs[0].(type) {
+		case []any:
+			pairs = v
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			pairs = make([]any, 0, len(keys)*2)
+			for _, k := range keys {
+				pairs = append(pairs, k, v[k])
+			}
+		}
+	}
+
+	var (
+		entries []entry
+		total   float64
+	)
+	add := func(value, weight any) {
+		w, ok := toWeight(weight)
+		if !ok || w <= 0 {
+			return
+		}
+		entries = append(entries, entry{value: value, weight: w})
+		total += w
+	}
+
+	for i := 0; i < len(pairs); {
+		if pair, ok := pairs[i].([]any); ok && len(pair) == 2 {
+			add(pair[0], pair[1])
+			i++
+			continue
+		}
+		if i+1 >= len(pairs) {
+			break
+		}
+		add(pairs[i], pairs[i+1])
+		i += 2
+	}
+
+	if len(entries) == 0 {
+		return ""
+	}
+
+	const resolution = 1 << 30
+	pick := float64(f.intN(resolution)) / resolution * total
+	for _, e := range entries {
+		if pick < e.weight {
+			return e.value
+		}
+		pick -= e.weight
+	}
+	return entries[len(entries)-1].value
+}
+
+// jitter perturbs a numeric value by a random amount of up to pct percent in
+// either direction. Integer inputs stay integers (rounded); non-numeric
+// values are returned unchanged.
+func (f randomFuncs) jitter(value, pct any) any {
+	n, ok := toWeight(value)
+	if !ok {
+		return value
+	}
+	p, ok := toWeight(pct)
+	if !ok || p <= 0 {
+		return value
+	}
+
+	const resolution = 1 << 30
+	offset := float64(2*f.intN(resolution+1))/resolution - 1 // [-1, 1]
+	result := n * (1 + offset*p/100)
+
+	switch v := value.(type) {
+	case int, int64:
+		return int(math.Round(result))
+	case string:
+		if _, err := strconv.Atoi(v); err == nil {
+			return int(math.Round(result))
+		}
+	}
+	return result
+}
+
+func toWeight(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
 }
 
 func toJSONString(v any) string {
@@ -86,6 +287,59 @@ func toJSONString(v any) string {
 	return string(b)
 }
 
+// requestBody returns the request body as templates should see it, applying
+// the canonicalize_body render option when set.
+func requestBody(ctx match.RenderContext) string {
+	if ctx.CanonicalizeBody == "" {
+		return string(ctx.Body)
+	}
+	return canonicalJSON(ctx.Body, ctx.CanonicalizeBody == "pretty")
+}
+
+// canonicalJSON re-encodes a JSON document with sorted object keys, either
+// compact or indented. Bodies that are not valid JSON are returned unchanged.
+func canonicalJSON(body []byte, pretty bool) string {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var data any
+	if err := dec.Decode(&data); err != nil || dec.More() {
+		return string(body)
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	if pretty {
+		b, err = json.MarshalIndent(data, "", "  ")
+	} else {
+		b, err = json.Marshal(data)
+	}
+	if err != nil {
+		return string(body)
+	}
+	return string(b)
+}
+
+// toYAMLString marshals v to YAML without the trailing newline.
+func toYAMLString(v any) string {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSuffix(string(b), "\n")
+}
+
+// parseJSONValue parses s into maps, slices and scalars. Invalid JSON yields nil.
+func parseJSONValue(s string) any {
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil
+	}
+	return v
+}
+
 func extractJSONPath(body []byte, expression string) string {
 	var data any
 	if err := json.Unmarshal(body, &data); err != nil {
@@ -107,10 +361,83 @@ func extractJSONPath(body []byte, expression string) string {
 	}
 }
 
-func generateUUID() string {
+// hashIDLength is the number of hex digits hashID keeps.
+const hashIDLength = 12
+
+// hashID derives a short stable ID from its arguments: the first hex digits
+// of the SHA-256 of their string forms. Arguments are NUL-separated, so
+// ("ab", "c") and ("a", "bc") differ.
+func hashID(parts ...any) string {
+	h := sha256.New()
+	for i, p := range parts {
+		if i > 0 {
+			h.Write([]byte{0})
+		}
+		fmt.Fprint(h, p)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:hashIDLength]
+}
+
+func base64Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// base64Decode decodes standard or URL-safe base64, padded or not. Invalid
+// input decodes to "".
+func base64Decode(s string) string {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return string(b)
+		}
+	}
+	return ""
+}
+
+// sha256Hex, md5Hex and hmacSHA256 return lowercase hex digests.
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key, msg string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(msg))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// nowOffset shifts the RFC 3339 time now by sign times duration d. Like
+// nowFormat, it returns now unchanged when now or d cannot be parsed.
+func nowOffset(now, d string, sign time.Duration) string {
+	t, err := time.Parse(time.RFC3339, now)
+	if err != nil {
+		return now
+	}
+	offset, err := time.ParseDuration(d)
+	if err != nil {
+		return now
+	}
+	return t.Add(sign * offset).Format(time.RFC3339)
+}
+
+// nowTimestamp returns now as unix epoch seconds, falling back to the wall
+// clock when now cannot be parsed.
+func nowTimestamp(now string) int64 {
+	t, err := time.Parse(time.RFC3339, now)
+	if err != nil {
+		return time.Now().Unix()
+	}
+	return t.Unix()
+}
+
+func (f randomFuncs) uuid() string {
 	var uuid [16]byte
 	for i := range uuid {
-		uuid[i] = byte(rand.IntN(256))
+		uuid[i] = byte(f.intN(256))
 	}
 	uuid[6] = (uuid[6] & 0x0f) | 0x40 // version 4
 	uuid[8] = (uuid[8] & 0x3f) | 0x80 // variant 10
@@ -128,6 +455,10 @@ func pongo2QueryParam(ctx match.RenderContext) func(string) string {
 	return func(name string) string { return ctx.QueryParams[name] }
 }
 
+func pongo2Host(ctx match.RenderContext) func(string) string {
+	return func(name string) string { return ctx.HostParams[name] }
+}
+
 func pongo2Header(ctx match.RenderContext) func(string) string {
 	return func(name string) string {
 		for k, v := range ctx.Headers {
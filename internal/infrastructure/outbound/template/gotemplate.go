@@ -0,0 +1,129 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
+)
+
+// GoTemplateCompiler compiles body templates using Go's text/template.
+type GoTemplateCompiler struct {
+	data   *dataFiles
+	funcs  map[string]TemplateFunc
+	random ports.RandomSource
+}
+
+// Compile parses the source as a text/template. Helper functions are bound to
+// the request at render time, so parsing uses request-independent placeholders.
+func (c *GoTemplateCompiler) Compile(name, source string) (match.BodyRenderer, error) {
+	rnd := randomFuncs{src: c.random}
+	funcs := goTemplateFuncs(match.RenderContext{}, c.data, rnd)
+	for name, fn := range c.funcs {
+		funcs[name] = fn
+	}
+	tpl, err := template.New(name).Funcs(funcs).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile go template %q: %w", name, err)
+	}
+	return &goTemplateRenderer{tpl: tpl, data: c.data, random: rnd}, nil
+}
+
+type goTemplateRenderer struct {
+	tpl    *template.Template
+	data   *dataFiles
+	random randomFuncs
+}
+
+func (r *goTemplateRenderer) Render(ctx match.RenderContext) ([]byte, error) {
+	// Clone so concurrent renders each bind their own request-scoped funcs.
+	tpl, err := r.tpl.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("go template clone failed: %w", err)
+	}
+	tpl.Funcs(goTemplateFuncs(ctx, r.data, r.random))
+
+	data := map[string]any{
+		"method":      ctx.Method,
+		"path":        ctx.Path,
+		"headers":     ctx.Headers,
+		"queryParams": ctx.QueryParams,
+		"pathParams":  ctx.PathParams,
+		"body":        requestBody(ctx),
+		"now":         ctx.Now,
+	}
+
+	var buf strings.Builder
+	if err := tpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("go template render failed: %w", err)
+	}
+	return []byte(buf.String()), nil
+}
+
+func goTemplateFuncs(ctx match.RenderContext, data *dataFiles, rnd randomFuncs) template.FuncMap {
+	return template.FuncMap{
+		"pathParam":  pongo2PathParam(ctx),
+		"queryParam": pongo2QueryParam(ctx),
+		"host":       pongo2Host(ctx),
+		"header":     pongo2Header(ctx),
+		"remoteIP": func() string {
+			return ctx.RemoteIP
+		},
+		"body": func() string {
+			return requestBody(ctx)
+		},
+		"canonicalBody": func() string {
+			return canonicalJSON(ctx.Body, ctx.CanonicalizeBody == "pretty")
+		},
+		"now": func() string {
+			return ctx.Now
+		},
+		"nowFormat": func(layout string) string {
+			t, err := time.Parse(time.RFC3339, ctx.Now)
+			if err != nil {
+				return ctx.Now
+			}
+			return t.Format(layout)
+		},
+		"nowPlus": func(d string) string {
+			return nowOffset(ctx.Now, d, 1)
+		},
+		"nowMinus": func(d string) string {
+			return nowOffset(ctx.Now, d, -1)
+		},
+		"timestamp": func() int64 {
+			return nowTimestamp(ctx.Now)
+		},
+		"uuid":         rnd.uuid,
+		"hashId":       hashID,
+		"base64Encode": base64Encode,
+		"base64Decode": base64Decode,
+		"sha256":       sha256Hex,
+		"md5":          md5Hex,
+		"hmacSHA256":   hmacSHA256,
+		"randomInt":    rnd.randomInt,
+		"randomString": rnd.randomString,
+		"randomChoice": rnd.randomChoice,
+		"seq": func(start, end int) []int {
+			return seqInts(start, end)
+		},
+		"weightedChoice": rnd.weightedChoice,
+		"jitter":         rnd.jitter,
+		"toJSON": func(v any) string {
+			return toJSONString(v)
+		},
+		"toYAML": func(v any) string {
+			return toYAMLString(v)
+		},
+		"fromJSON": func(s string) any {
+			return parseJSONValue(s)
+		},
+		"jsonPath": func(expression string) string {
+			return extractJSONPath(ctx.Body, expression)
+		},
+		"lookup": data.lookup,
+	}
+}
@@ -0,0 +1,211 @@
+package template
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+)
+
+func TestGoTemplateCompiler_SimpleInterpolation(t *testing.T) {
+	c := &GoTemplateCompiler{}
+	renderer, err := c.Compile("test", `Hello {{ pathParam "name" }}!`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{
+		PathParams: map[string]string{"name": "World"},
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "Hello World!" {
+		t.Errorf("expected 'Hello World!', got %q", result)
+	}
+}
+
+func TestGoTemplateCompiler_Variables(t *testing.T) {
+	c := &GoTemplateCompiler{}
+	renderer, err := c.Compile("test", `{{ .method }} {{ .path }} {{ index .queryParams "q" }} {{ .body }} {{ .now }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{
+		Method:      "POST",
+		Path:        "/items",
+		QueryParams: map[string]string{"q": "search"},
+		Body:        []byte("payload"),
+		Now:         "2025-01-01T00:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "POST /items search payload 2025-01-01T00:00:00Z" {
+		t.Errorf("unexpected result: %q", result)
+	}
+}
+
+func TestGoTemplateCompiler_Conditional(t *testing.T) {
+	c := &GoTemplateCompiler{}
+	renderer, err := c.Compile("test", `{{ if eq (header "x-tier") "premium" }}1000{{ else }}10{{ end }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	tests := []struct {
+		headers map[string]string
+		want    string
+	}{
+		{map[string]string{"X-Tier": "premium"}, "1000"},
+		{map[string]string{"X-Tier": "free"}, "10"},
+		{nil, "10"},
+	}
+
+	for _, tt := range tests {
+		result, err := renderer.Render(match.RenderContext{Headers: tt.headers})
+		if err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		if string(result) != tt.want {
+			t.Errorf("headers %v: expected %q, got %q", tt.headers, tt.want, result)
+		}
+	}
+}
+
+func TestGoTemplateCompiler_Helpers(t *testing.T) {
+	c := &GoTemplateCompiler{}
+	renderer, err := c.Compile("test", `{{ toJSON (seq 1 3) }}|{{ jsonPath "$.user.name" }}|{{ nowFormat "2006" }}|{{ randomInt 5 5 }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{
+		Body: []byte(`{"user":{"name":"Alice"}}`),
+		Now:  "2025-06-15T10:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "[1,2,3]|Alice|2025|5" {
+		t.Errorf("unexpected result: %q", result)
+	}
+}
+
+func TestGoTemplateCompiler_UUID(t *testing.T) {
+	c := &GoTemplateCompiler{}
+	renderer, err := c.Compile("test", `{{ uuid }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if len(result) != 36 || strings.Count(string(result), "-") != 4 {
+		t.Errorf("expected UUID, got %q", result)
+	}
+}
+
+func TestGoTemplateCompiler_InvalidSyntax(t *testing.T) {
+	c := &GoTemplateCompiler{}
+	if _, err := c.Compile("test", `{{ if }}`); err == nil {
+		t.Error("expected compile error for invalid syntax")
+	}
+	if _, err := c.Compile("test", `{{ unknownFunc }}`); err == nil {
+		t.Error("expected compile error for unknown function")
+	}
+}
+
+func TestGoTemplateCompiler_RenderError(t *testing.T) {
+	c := &GoTemplateCompiler{}
+	renderer, err := c.Compile("test", `{{ index .pathParams }}{{ template "missing" }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if _, err := renderer.Render(match.RenderContext{}); err == nil {
+		t.Error("expected render error")
+	}
+}
+
+func TestGoTemplateCompiler_ConcurrentRenders(t *testing.T) {
+	c := &GoTemplateCompiler{}
+	renderer, err := c.Compile("test", `{{ pathParam "id" }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				result, err := renderer.Render(match.RenderContext{PathParams: map[string]string{"id": id}})
+				if err != nil {
+					t.Errorf("Render failed: %v", err)
+					return
+				}
+				if string(result) != id {
+					t.Errorf("expected %q, got %q", id, result)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGoTemplateCompiler_FromJSONRange(t *testing.T) {
+	c := &GoTemplateCompiler{}
+	renderer, err := c.Compile("test", `{{ range (fromJSON .body).items }}[{{ .name }}]{{ end }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{
+		Body: []byte(`{"items":[{"name":"x"},{"name":"y"}]}`),
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "[x][y]" {
+		t.Errorf("expected '[x][y]', got %q", result)
+	}
+}
+
+func TestGoTemplateCompiler_WeightedChoice(t *testing.T) {
+	c := &GoTemplateCompiler{random: fixedRandom(0)}
+	renderer, err := c.Compile("test", `{{ weightedChoice "x" 1 "y" 1 }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "x" {
+		t.Errorf("expected 'x', got %q", result)
+	}
+}
+
+func TestGoTemplateCompiler_HashID(t *testing.T) {
+	c := &GoTemplateCompiler{}
+	renderer, err := c.Compile("test", `{{ hashId "order" 42 }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	// Same ID as every other engine for the same arguments.
+	if want := hashID("order", 42); string(result) != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
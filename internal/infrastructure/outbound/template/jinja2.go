@@ -7,10 +7,15 @@ import (
 	"github.com/flosch/pongo2/v6"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
 )
 
 // Jinja2Compiler compiles body templates using Pongo2 (Django/Jinja2-style).
-type Jinja2Compiler struct{}
+type Jinja2Compiler struct {
+	data   *dataFiles
+	funcs  map[string]TemplateFunc
+	random ports.RandomSource
+}
 
 // Compile parses the source as a Pongo2 template.
 func (c *Jinja2Compiler) Compile(name, source string) (match.BodyRenderer, error) {
@@ -18,43 +23,66 @@ func (c *Jinja2Compiler) Compile(name, source string) (match.BodyRenderer, error
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile jinja2 template %q: %w", name, err)
 	}
-	return &jinja2Renderer{tpl: tpl}, nil
+	return &jinja2Renderer{tpl: tpl, data: c.data, funcs: c.funcs, random: randomFuncs{src: c.random}}, nil
 }
 
 type jinja2Renderer struct {
-	tpl *pongo2.Template
+	tpl    *pongo2.Template
+	data   *dataFiles
+	funcs  map[string]TemplateFunc
+	random randomFuncs
 }
 
 func (r *jinja2Renderer) Render(ctx match.RenderContext) ([]byte, error) {
+	rnd := r.random
 	pongoCtx := pongo2.Context{
 		"method":      ctx.Method,
 		"path":        ctx.Path,
 		"headers":     ctx.Headers,
 		"queryParams": ctx.QueryParams,
 		"pathParams":  ctx.PathParams,
-		"body":        string(ctx.Body),
+		"body":        requestBody(ctx),
 		"now":         ctx.Now,
 
 		// Helper functions.
 		"pathParam":  pongo2PathParam(ctx),
 		"queryParam": pongo2QueryParam(ctx),
+		"host":       pongo2Host(ctx),
 		"header":     pongo2Header(ctx),
-		"uuid":       generateUUID,
-		"randomInt": func(min, max int) int {
-			if min >= max {
-				return min
-			}
-			return min + randIntN(max-min+1)
+		"remoteIP": func() string {
+			return ctx.RemoteIP
 		},
+		"uuid":         rnd.uuid,
+		"hashId":       hashID,
+		"base64Encode": base64Encode,
+		"base64Decode": base64Decode,
+		"sha256":       sha256Hex,
+		"md5":          md5Hex,
+		"hmacSHA256":   hmacSHA256,
+		"randomInt":    rnd.randomInt,
+		"randomString": rnd.randomString,
+		"randomChoice": rnd.randomChoice,
 		"seq": func(start, end int) []int {
 			return seqInts(start, end)
 		},
+		"weightedChoice": rnd.weightedChoice,
+		"jitter":         rnd.jitter,
 		"toJSON": func(v any) string {
 			return toJSONString(v)
 		},
+		"toYAML": func(v any) string {
+			return toYAMLString(v)
+		},
+		"fromJSON": func(s string) any {
+			return parseJSONValue(s)
+		},
 		"jsonPath": func(expression string) string {
 			return extractJSONPath(ctx.Body, expression)
 		},
+		"lookup": r.data.lookup,
+		"canonicalBody": func() string {
+			return canonicalJSON(ctx.Body, ctx.CanonicalizeBody == "pretty")
+		},
 		"nowFormat": func(layout string) string {
 			t, err := time.Parse(time.RFC3339, ctx.Now)
 			if err != nil {
@@ -62,6 +90,19 @@ func (r *jinja2Renderer) Render(ctx match.RenderContext) ([]byte, error) {
 			}
 			return t.Format(layout)
 		},
+		"nowPlus": func(d string) string {
+			return nowOffset(ctx.Now, d, 1)
+		},
+		"nowMinus": func(d string) string {
+			return nowOffset(ctx.Now, d, -1)
+		},
+		"timestamp": func() int64 {
+			return nowTimestamp(ctx.Now)
+		},
+	}
+
+	for name, fn := range r.funcs {
+		pongoCtx[name] = fn
 	}
 
 	result, err := r.tpl.Execute(pongoCtx)
@@ -1,6 +1,7 @@
 package template
 
 import (
+	"slices"
 	"strings"
 	"testing"
 
@@ -376,3 +377,191 @@ func TestJinja2Compiler_JsonPathInvalidJSON(t *testing.T) {
 		t.Errorf("expected '[]', got %q", result)
 	}
 }
+
+func TestJinja2Compiler_CanonicalBody(t *testing.T) {
+	c := &Jinja2Compiler{}
+	renderer, err := c.Compile("test", `{{ canonicalBody()|safe }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{
+		Body: []byte(`{"b": [1, 2], "a": "x"}`),
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != `{"a":"x","b":[1,2]}` {
+		t.Errorf("expected canonical JSON, got %q", result)
+	}
+
+	result, err = renderer.Render(match.RenderContext{Body: []byte("plain text")})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "plain text" {
+		t.Errorf("expected raw body for invalid JSON, got %q", result)
+	}
+}
+
+func TestJinja2Compiler_FromJSONLoop(t *testing.T) {
+	c := &Jinja2Compiler{}
+	renderer, err := c.Compile("test", `{% set data = fromJSON(body) %}{% for item in data.items %}[{{ item.name }}]{% endfor %}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{
+		Body: []byte(`{"items":[{"name":"a"},{"name":"b"},{"name":"c"}]}`),
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "[a][b][c]" {
+		t.Errorf("expected '[a][b][c]', got %q", result)
+	}
+
+	// Invalid JSON yields nil, so the loop renders nothing.
+	result, err = renderer.Render(match.RenderContext{Body: []byte("not json")})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "" {
+		t.Errorf("expected empty output for invalid JSON, got %q", result)
+	}
+}
+
+func TestJinja2Compiler_ToYAML(t *testing.T) {
+	c := &Jinja2Compiler{}
+	renderer, err := c.Compile("test", `{{ toYAML(fromJSON(body))|safe }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{
+		Body: []byte(`{"name":"svc","ports":[80,443]}`),
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := "name: svc\nports:\n    - 80\n    - 443"
+	if string(result) != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+func TestJinja2Compiler_WeightedChoice(t *testing.T) {
+	c := &Jinja2Compiler{random: fixedRandom(1<<30 - 1)}
+	renderer, err := c.Compile("test", `{{ weightedChoice("x", 1, "y", 1) }}|{{ weightedChoice() }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "y|" {
+		t.Errorf("expected 'y|', got %q", result)
+	}
+}
+
+func TestJinja2Compiler_HashID(t *testing.T) {
+	c := &Jinja2Compiler{}
+	renderer, err := c.Compile("test", `{{ hashId("order", 42) }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	// Same ID as every other engine for the same arguments.
+	if want := hashID("order", 42); string(result) != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+func TestJinja2Compiler_EncodingAndHashes(t *testing.T) {
+	c := &Jinja2Compiler{}
+	renderer, err := c.Compile("test", `{{ base64Encode("hello") }}|{{ base64Decode("aGVsbG8") }}|{{ sha256("abc") }}|{{ md5("abc") }}|{{ hmacSHA256("key", "The quick brown fox jumps over the lazy dog") }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := "aGVsbG8=|hello|" +
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad|" +
+		"900150983cd24fb0d6963f7d28e17f72|" +
+		"f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if string(result) != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+func TestJinja2Compiler_RandomStringAndChoice(t *testing.T) {
+	c := &Jinja2Compiler{}
+	renderer, err := c.Compile("test", `{{ randomString(8) }}|{{ randomChoice(fromJSON('["red", "green", "blue"]')) }}|{{ randomChoice(fromJSON('[]')) }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	for range 20 {
+		result, err := renderer.Render(match.RenderContext{})
+		if err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		parts := strings.Split(string(result), "|")
+		if len(parts) != 3 {
+			t.Fatalf("unexpected result %q", result)
+		}
+		if len(parts[0]) != 8 || strings.Trim(parts[0], randomStringAlphabet) != "" {
+			t.Errorf("expected 8 alphanumeric characters, got %q", parts[0])
+		}
+		if !slices.Contains([]string{"red", "green", "blue"}, parts[1]) {
+			t.Errorf("expected a color, got %q", parts[1])
+		}
+		if parts[2] != "" {
+			t.Errorf("expected empty result for an empty list, got %q", parts[2])
+		}
+	}
+}
+
+func TestJinja2Compiler_NowArithmetic(t *testing.T) {
+	c := &Jinja2Compiler{}
+	renderer, err := c.Compile("test", `{{ nowPlus("15m") }}|{{ nowMinus("1h") }}|{{ timestamp() }}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{Now: "2025-01-15T10:30:00Z"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := "2025-01-15T10:45:00Z|2025-01-15T09:30:00Z|1736937000"
+	if string(result) != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+func TestJinja2Compiler_IteratePathParams(t *testing.T) {
+	c := &Jinja2Compiler{}
+	renderer, err := c.Compile("test", `{% for k, v in pathParams sorted %}{{ k }}={{ v }};{% endfor %}`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := renderer.Render(match.RenderContext{
+		PathParams: map[string]string{"slug": "intro", "id": "42"},
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if string(result) != "id=42;slug=intro;" {
+		t.Errorf("expected 'id=42;slug=intro;', got %q", result)
+	}
+}
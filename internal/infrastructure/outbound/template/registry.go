@@ -2,8 +2,11 @@ package template
 
 import (
 	"fmt"
+	"maps"
+	"regexp"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
 )
 
 // EngineCompiler compiles a template source string into a BodyRenderer.
@@ -11,26 +14,122 @@ type EngineCompiler interface {
 	Compile(name, source string) (match.BodyRenderer, error)
 }
 
+// TemplateFunc is a custom helper callable from templates by name. Arguments
+// are passed as the template evaluated them; a non-nil error aborts the render.
+type TemplateFunc func(args ...any) (any, error)
+
 // Registry maps engine names to their compilers.
 type Registry struct {
 	engines map[string]EngineCompiler
+	funcs   map[string]TemplateFunc
 }
 
-// NewRegistry creates a registry with the built-in engines (expr, jinja2).
+// NewRegistry creates a registry with the built-in engines (expr, jinja2, go).
 func NewRegistry() *Registry {
 	return &Registry{
 		engines: map[string]EngineCompiler{
 			"expr":   &ExprCompiler{},
 			"jinja2": &Jinja2Compiler{},
+			"go":     &GoTemplateCompiler{},
 		},
 	}
 }
 
+// SetDataRoot enables the lookup function in the built-in engines, reading
+// data files relative to root.
+func (r *Registry) SetDataRoot(root string) {
+	data := newDataFiles(root)
+	for _, ec := range r.engines {
+		switch c := ec.(type) {
+		case *ExprCompiler:
+			c.data = data
+		case *Jinja2Compiler:
+			c.data = data
+		case *GoTemplateCompiler:
+			c.data = data
+		}
+	}
+}
+
+// SetRandomSource sets the source behind the random helpers in the built-in
+// engines. Like RegisterFunc, call it during startup before any scenario is
+// loaded; a nil source uses the global generator.
+func (r *Registry) SetRandomSource(src ports.RandomSource) {
+	for _, ec := range r.engines {
+		switch c := ec.(type) {
+		case *ExprCompiler:
+			c.random = src
+		case *Jinja2Compiler:
+			c.random = src
+		case *GoTemplateCompiler:
+			c.random = src
+		}
+	}
+}
+
+var funcNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RegisterFunc makes fn available under name in the built-in engines. Names
+// must be identifiers and may not shadow a built-in helper or template
+// variable, or a function registered earlier.
+//
+// Functions are bound when a template is compiled, so register them during
+// startup before any scenario is loaded; RegisterFunc must not be called
+// concurrently with Compile. fn itself may be invoked from concurrent
+// renders and must be safe for that.
+func (r *Registry) RegisterFunc(name string, fn TemplateFunc) error {
+	if !funcNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid template function name %q", name)
+	}
+	if fn == nil {
+		return fmt.Errorf("template function %q is nil", name)
+	}
+	if isBuiltinName(name) {
+		return fmt.Errorf("template function %q collides with a built-in", name)
+	}
+	if _, ok := r.funcs[name]; ok {
+		return fmt.Errorf("template function %q is already registered", name)
+	}
+
+	// Copy on write so renderers compiled earlier keep a stable map.
+	funcs := maps.Clone(r.funcs)
+	if funcs == nil {
+		funcs = make(map[string]TemplateFunc)
+	}
+	funcs[name] = fn
+	r.funcs = funcs
+
+	for _, ec := range r.engines {
+		switch c := ec.(type) {
+		case *ExprCompiler:
+			c.funcs = funcs
+		case *Jinja2Compiler:
+			c.funcs = funcs
+		case *GoTemplateCompiler:
+			c.funcs = funcs
+		}
+	}
+	return nil
+}
+
+// isBuiltinName reports whether name is already bound by the engines, either
+// as a helper function or as a template variable.
+func isBuiltinName(name string) bool {
+	if _, ok := goTemplateFuncs(match.RenderContext{}, nil, randomFuncs{})[name]; ok {
+		return true
+	}
+	switch name {
+	case "method", "path", "headers", "queryParams", "pathParams", "body", "now":
+		return true
+	}
+	return false
+}
+
 // Compile resolves the engine by name and compiles the source.
 func (r *Registry) Compile(engine, name, source string) (match.BodyRenderer, error) {
 	ec, ok := r.engines[engine]
 	if !ok {
-		return nil, fmt.Errorf("unknown template engine: %q (supported: expr, jinja2)", engine)
+		return nil, fmt.Errorf("unknown template engine: %q (supported: expr, jinja2, go)", engine)
 	}
 	return ec.Compile(name, source)
 }
@@ -1,6 +1,8 @@
 package template
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
@@ -15,6 +17,7 @@ func TestRegistry_KnownEngines(t *testing.T) {
 	}{
 		{"expr", `Hello ${pathParam('name')}`},
 		{"jinja2", `Hello {{ pathParam("name") }}`},
+		{"go", `Hello {{ pathParam "name" }}`},
 	}
 
 	for _, tt := range tests {
@@ -45,3 +48,105 @@ func TestRegistry_UnknownEngine(t *testing.T) {
 		t.Error("expected error for unknown engine")
 	}
 }
+
+func TestRegistry_SetDataRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeDataFile(t, dir, "users.json", `[{"id": "7", "name": "Grace"}]`)
+
+	r := NewRegistry()
+	r.SetDataRoot(dir)
+
+	sources := map[string]string{
+		"expr":   `${lookup('users.json', 'id', '7')}`,
+		"jinja2": `{{ lookup("users.json", "id", "7")|safe }}`,
+		"go":     `{{ lookup "users.json" "id" "7" }}`,
+	}
+	for engine, source := range sources {
+		renderer, err := r.Compile(engine, "test", source)
+		if err != nil {
+			t.Fatalf("Compile failed for engine %q: %v", engine, err)
+		}
+		result, err := renderer.Render(match.RenderContext{})
+		if err != nil {
+			t.Fatalf("Render failed for engine %q: %v", engine, err)
+		}
+		if string(result) != `{"id":"7","name":"Grace"}` {
+			t.Errorf("engine %q: unexpected result %q", engine, result)
+		}
+	}
+}
+
+func TestRegistry_SetRandomSource(t *testing.T) {
+	r := NewRegistry()
+	r.SetRandomSource(fixedRandom(1))
+
+	sources := map[string]string{
+		"expr":   `${randomInt(10, 20)}|${randomChoice(["a", "b", "c"])}`,
+		"jinja2": `{{ randomInt(10, 20) }}|{{ randomChoice(fromJSON('["a", "b", "c"]')) }}`,
+		"go":     `{{ randomInt 10 20 }}|{{ randomChoice (fromJSON "[\"a\", \"b\", \"c\"]") }}`,
+	}
+	for engine, source := range sources {
+		renderer, err := r.Compile(engine, "test", source)
+		if err != nil {
+			t.Fatalf("Compile failed for engine %q: %v", engine, err)
+		}
+		result, err := renderer.Render(match.RenderContext{})
+		if err != nil {
+			t.Fatalf("Render failed for engine %q: %v", engine, err)
+		}
+		if string(result) != "11|b" {
+			t.Errorf("engine %q: expected '11|b', got %q", engine, result)
+		}
+	}
+}
+
+func TestRegistry_RegisterFunc(t *testing.T) {
+	r := NewRegistry()
+	err := r.RegisterFunc("shout", func(args ...any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("shout expects 1 argument, got %d", len(args))
+		}
+		return strings.ToUpper(fmt.Sprint(args[0])) + "!", nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterFunc failed: %v", err)
+	}
+
+	sources := map[string]string{
+		"expr":   `${shout(pathParam('name'))}`,
+		"jinja2": `{{ shout(pathParam("name")) }}`,
+		"go":     `{{ shout (pathParam "name") }}`,
+	}
+	for engine, source := range sources {
+		t.Run(engine, func(t *testing.T) {
+			renderer, err := r.Compile(engine, "test", source)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			result, err := renderer.Render(match.RenderContext{
+				PathParams: map[string]string{"name": "ada"},
+			})
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if string(result) != "ADA!" {
+				t.Errorf("expected %q, got %q", "ADA!", result)
+			}
+		})
+	}
+}
+
+func TestRegistry_RegisterFuncRejectsCollisions(t *testing.T) {
+	r := NewRegistry()
+	fn := func(args ...any) (any, error) { return nil, nil }
+
+	if err := r.RegisterFunc("tenant", fn); err != nil {
+		t.Fatalf("RegisterFunc failed: %v", err)
+	}
+
+	for _, name := range []string{"uuid", "headers", "tenant", "bad-name", ""} {
+		if err := r.RegisterFunc(name, fn); err == nil {
+			t.Errorf("expected error registering %q", name)
+		}
+	}
+}
@@ -26,3 +26,14 @@ type RateLimiter interface {
 	// rate is tokens per second, burst is the max burst size.
 	Allow(ctx context.Context, key string, rate float64, burst int) bool
 }
+
+// RandomSource provides pseudo-random numbers (injectable for deterministic tests).
+type RandomSource interface {
+	// IntN returns a pseudo-random int in [0, n). n must be positive.
+	IntN(n int) int
+}
+
+// RandomFunc adapts a function such as math/rand/v2.IntN to RandomSource.
+type RandomFunc func(n int) int
+
+func (f RandomFunc) IntN(n int) int { return f(n) }
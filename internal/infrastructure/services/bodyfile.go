@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+)
+
+// containsExpr reports whether s contains ${ } expressions.
+func containsExpr(s string) bool {
+	return strings.Contains(s, "${")
+}
+
+// fixedRenderer renders the same bytes for every request.
+type fixedRenderer []byte
+
+func (r fixedRenderer) Render(match.RenderContext) ([]byte, error) {
+	return r, nil
+}
+
+// bodyFileRenderer serves a body_file whose path is an expr template. The
+// path is rendered per request and the file is read lazily, cached until it
+// changes on disk. Paths that resolve outside the root or to missing files
+// yield match.ErrBodyFileNotFound.
+type bodyFileRenderer struct {
+	path    match.BodyRenderer
+	resolve func(string) (string, error)
+	// compile turns file contents into a renderer; nil serves them as-is.
+	compile func(name, source string) (match.BodyRenderer, error)
+
+	mu    sync.Mutex
+	cache map[string]*cachedBodyFile
+}
+
+type cachedBodyFile struct {
+	modTime  time.Time
+	size     int64
+	body     []byte
+	renderer match.BodyRenderer
+}
+
+func (r *bodyFileRenderer) Render(ctx match.RenderContext) ([]byte, error) {
+	rel, err := r.path.Render(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render body_file path: %w", err)
+	}
+	resolved, err := r.resolve(string(rel))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", match.ErrBodyFileNotFound, err)
+	}
+
+	entry, err := r.load(string(rel), resolved)
+	if err != nil {
+		return nil, err
+	}
+	if entry.renderer != nil {
+		return entry.renderer.Render(ctx)
+	}
+	return entry.body, nil
+}
+
+func (r *bodyFileRenderer) load(rel, resolved string) (*cachedBodyFile, error) {
+	info, err := os.Stat(resolved)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, fmt.Errorf("%w: %s", match.ErrBodyFileNotFound, rel)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body_file %q: %w", rel, err)
+	}
+
+	r.mu.Lock()
+	cached, ok := r.cache[resolved]
+	r.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached, nil
+	}
+
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body_file %q: %w", rel, err)
+	}
+	entry := &cachedBodyFile{modTime: info.ModTime(), size: info.Size(), body: data}
+	if r.compile != nil {
+		entry.renderer, err = r.compile(rel, string(data))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	r.cache[resolved] = entry
+	r.mu.Unlock()
+	return entry, nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// newCharsetEncoder resolves an IANA charset name and returns a function that
+// transcodes UTF-8 bytes into it, along with the canonical MIME charset name.
+// Characters not representable in the target charset are replaced.
+func newCharsetEncoder(charset string) (func([]byte) ([]byte, error), string, error) {
+	enc, err := ianaindex.IANA.Encoding(charset)
+	if err != nil || enc == nil {
+		return nil, "", fmt.Errorf("unsupported charset %q", charset)
+	}
+
+	name, err := ianaindex.MIME.Name(enc)
+	if err != nil || name == "" {
+		name = charset
+	}
+
+	encode := func(body []byte) ([]byte, error) {
+		out, err := encoding.ReplaceUnsupported(enc.NewEncoder()).Bytes(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transcode body to %s: %w", name, err)
+		}
+		return out, nil
+	}
+	return encode, name, nil
+}
+
+// WithCharset returns contentType with its charset parameter set to charset,
+// replacing any existing charset parameter.
+func WithCharset(contentType, charset string) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		base, _, _ := strings.Cut(contentType, ";")
+		return strings.TrimSpace(base) + "; charset=" + charset
+	}
+	params["charset"] = charset
+	return mime.FormatMediaType(mediaType, params)
+}
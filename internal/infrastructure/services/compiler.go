@@ -1,19 +1,35 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PaesslerAG/jsonpath"
 	"github.com/antchfx/xmlquery"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
 	"github.com/sophialabs/proteusmock/internal/domain/scenario"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
 )
 
 // TemplateRegistry compiles template sources into body renderers by engine name.
@@ -25,6 +41,10 @@ type TemplateRegistry interface {
 type Compiler struct {
 	rootDir  string
 	registry TemplateRegistry // nil means no template support
+	bodies   *bodyInterner
+	// globalMaxPageSize caps every scenario's pagination size (0 = unlimited).
+	globalMaxPageSize int
+	random            ports.RandomSource // nil = math/rand/v2 global generator
 }
 
 // NewCompiler creates a new Compiler bound to the given root directory for body_file resolution.
@@ -34,46 +54,269 @@ func NewCompiler(rootDir string, registry TemplateRegistry) (*Compiler, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve root directory: %w", err)
 	}
-	return &Compiler{rootDir: absRoot, registry: registry}, nil
+	return &Compiler{rootDir: absRoot, registry: registry, bodies: newBodyInterner()}, nil
+}
+
+// SetGlobalMaxPageSize caps the page size of every paginated scenario,
+// whatever its own max_size. Zero means unlimited.
+func (c *Compiler) SetGlobalMaxPageSize(n int) {
+	c.globalMaxPageSize = n
+}
+
+// ResetBodyCache forgets previously interned static bodies. Call it before a
+// full reload so bodies from the replaced index can be garbage collected.
+func (c *Compiler) ResetBodyCache() {
+	c.bodies.reset()
 }
 
 // CompileScenario turns a Scenario into a CompiledScenario.
 func (c *Compiler) CompileScenario(s *scenario.Scenario) (*match.CompiledScenario, error) {
-	predicates, err := c.compileWhen(&s.When)
+	if s.Static != nil {
+		return c.compileStatic(s)
+	}
+
+	methods, err := compileMethods(&s.When)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile scenario %q: %w", s.ID, err)
 	}
 
-	resp, err := c.compileResponse(&s.Response)
+	predicates, err := c.compileWhen(&s.When, methods)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile response for %q: %w", s.ID, err)
+		return nil, fmt.Errorf("failed to compile scenario %q: %w", s.ID, err)
+	}
+
+	if err := checkKind(s); err != nil {
+		return nil, fmt.Errorf("failed to compile scenario %q: %w", s.ID, err)
+	}
+
+	var responses []match.CompiledResponse
+	for i := range s.Responses {
+		resp, err := c.compileResponse(&s.Responses[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile responses[%d] for %q: %w", i, s.ID, err)
+		}
+		responses = append(responses, resp)
+	}
+
+	var resp match.CompiledResponse
+	if len(responses) > 0 {
+		resp = responses[0]
+	} else {
+		resp, err = c.compileResponse(&s.Response)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile response for %q: %w", s.ID, err)
+		}
+	}
+	if s.Kind == scenario.KindMirror {
+		resp.Mirror = true
+		resp.Raw = true
 	}
 
 	cs := &match.CompiledScenario{
 		ID:         s.ID,
 		Name:       s.Name,
 		Priority:   s.Priority,
-		Method:     s.When.Method,
-		PathKey:    s.When.Method + ":" + s.When.Path,
 		Predicates: predicates,
 		Response:   resp,
+		Responses:  responses,
+		Cycle:      s.Cycle,
+		Profiles:   s.Profiles,
+		Quiet:      s.Quiet,
+	}
+	if len(methods) > 0 {
+		cs.Method = methods[0]
+	}
+	cs.PathKey = cs.Method + ":" + s.When.Path
+	if len(methods) > 1 {
+		cs.Methods = methods
+	}
+	if s.Deprecated {
+		cs.Deprecated = true
+		cs.DeprecationMessage = s.DeprecationMessage
+		if cs.DeprecationMessage == "" {
+			cs.DeprecationMessage = "Deprecated API"
+		}
+	}
+	if len(s.RequireContentType) > 0 {
+		required, err := compileRequireContentType(s.RequireContentType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile scenario %q: %w", s.ID, err)
+		}
+		cs.RequireContentType = required
+	}
+
+	if s.ExampleRequest != nil {
+		example, err := compileExampleRequest(s.ExampleRequest, cs.Method, s.When.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile scenario %q: %w", s.ID, err)
+		}
+		cs.Example = example
+	}
+
+	if s.When.Host != "" {
+		re, err := compileHostPattern(s.When.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile scenario %q: %w", s.ID, err)
+		}
+		cs.HostPattern = re
+		cs.Predicates = append(cs.Predicates, match.FieldPredicate{
+			Field:     "host",
+			Predicate: re.MatchString,
+		})
+	}
+
+	// Schedule predicate. An unparsable schedule leaves the scenario always active.
+	if s.When.Schedule != "" {
+		sched, err := parseCron(s.When.Schedule)
+		if err != nil {
+			cs.Warnings = append(cs.Warnings, fmt.Sprintf("invalid schedule %q, scenario is always active: %v", s.When.Schedule, err))
+		} else {
+			cs.Predicates = append(cs.Predicates, match.FieldPredicate{
+				Field:     "now",
+				Predicate: schedulePredicate(sched),
+			})
+		}
 	}
 
 	if s.Policy != nil {
 		cs.Policy = compilePolicy(s.Policy)
+		if cs.Policy.Pagination != nil {
+			cs.Policy.Pagination.GlobalMaxSize = c.globalMaxPageSize
+			if src := s.Policy.Pagination.TotalItemsSource; src != "" {
+				if err := compileTotalItemsSource(cs.Policy.Pagination, src); err != nil {
+					return nil, fmt.Errorf("failed to compile scenario %q: %w", s.ID, err)
+				}
+			}
+		}
+		if rl := s.Policy.RateLimit; rl != nil && rl.Response != nil {
+			limited := *rl.Response
+			if limited.Status == 0 {
+				limited.Status = http.StatusTooManyRequests
+			}
+			resp, err := c.compileResponse(&limited)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile rate_limit response for %q: %w", s.ID, err)
+			}
+			cs.Policy.RateLimit.Response = &resp
+		}
+		if s.Policy.LoadBalance != nil {
+			weight := s.Policy.LoadBalance.Weight
+			if weight == 0 {
+				weight = s.Priority
+			}
+			cs.Policy.LoadBalance = &match.CompiledLoadBalance{Weight: max(weight, 1)}
+		}
+		if s.Policy.Fault != nil {
+			fault, err := compileFault(s.Policy.Fault)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile scenario %q: %w", s.ID, err)
+			}
+			cs.Policy.Fault = fault
+		}
 	}
 
 	return cs, nil
 }
 
-func (c *Compiler) compileWhen(w *scenario.WhenClause) ([]match.FieldPredicate, error) {
+// compileExampleRequest builds the self-test request, defaulting to the
+// scenario's method and path.
+func compileExampleRequest(er *scenario.ExampleRequest, method, path string) (*match.IncomingRequest, error) {
+	if er.Method != "" {
+		method = strings.ToUpper(er.Method)
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	if er.Path != "" {
+		path = er.Path
+	} else if strings.Contains(path, "{") {
+		return nil, fmt.Errorf("example_request: path is required when when.path has parameters")
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("example_request: path must start with /, got %q", path)
+	}
+
+	headers := make(map[string]string, len(er.Headers))
+	for k, v := range er.Headers {
+		headers[http.CanonicalHeaderKey(k)] = v
+	}
+	query := make(url.Values, len(er.Query))
+	for k, v := range er.Query {
+		query.Set(k, v)
+	}
+	return &match.IncomingRequest{
+		Method:        method,
+		Path:          path,
+		Headers:       headers,
+		Query:         query,
+		Body:          []byte(er.Body),
+		ContentLength: match.DeclaredContentLength(headers, []byte(er.Body)),
+	}, nil
+}
+
+// compileFault validates a fault policy. A zero rate faults every request.
+func compileFault(f *scenario.Fault) (*match.CompiledFault, error) {
+	switch f.Type {
+	case match.FaultResetConnection, match.FaultEmptyResponse:
+	default:
+		return nil, fmt.Errorf("unsupported fault type %q (expected %q or %q)", f.Type, match.FaultResetConnection, match.FaultEmptyResponse)
+	}
+	if f.Rate < 0 || f.Rate > 1 {
+		return nil, fmt.Errorf("fault rate must be between 0 and 1, got %g", f.Rate)
+	}
+	rate := f.Rate
+	if rate == 0 {
+		rate = 1
+	}
+	return &match.CompiledFault{Type: f.Type, Rate: rate}, nil
+}
+
+// compileStatic resolves a static directory mount. Static scenarios have no
+// predicates or response; the directory is served for GET and HEAD requests.
+func (c *Compiler) compileStatic(s *scenario.Scenario) (*match.CompiledScenario, error) {
+	prefix := strings.TrimRight(s.Static.PathPrefix, "/")
+	if !strings.HasPrefix(prefix, "/") {
+		return nil, fmt.Errorf("static scenario %q: path_prefix must start with / and not be the root", s.ID)
+	}
+
+	dir, err := c.resolveWithinRoot("static dir", s.Static.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("static scenario %q: %w", s.ID, err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("static scenario %q: %w", s.ID, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("static scenario %q: %s is not a directory", s.ID, s.Static.Dir)
+	}
+
+	return &match.CompiledScenario{
+		ID:       s.ID,
+		Name:     s.Name,
+		Priority: s.Priority,
+		Method:   http.MethodGet,
+		PathKey:  http.MethodGet + ":" + prefix + "/*",
+		Profiles: s.Profiles,
+		Static:   &match.CompiledStatic{Dir: dir, PathPrefix: prefix},
+	}, nil
+}
+
+func (c *Compiler) compileWhen(w *scenario.WhenClause, methods []string) ([]match.FieldPredicate, error) {
 	var predicates []match.FieldPredicate
 
-	// Method predicate — always exact.
-	if w.Method != "" {
+	// Method predicate — always exact, against any of the listed methods.
+	switch len(methods) {
+	case 0:
+	case 1:
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "method",
+			Predicate: exactPredicate(methods[0]),
+		})
+	default:
 		predicates = append(predicates, match.FieldPredicate{
 			Field:     "method",
-			Predicate: exactPredicate(w.Method),
+			Predicate: func(s string) bool { return slices.Contains(methods, s) },
 		})
 	}
 
@@ -85,8 +328,7 @@ func (c *Compiler) compileWhen(w *scenario.WhenClause) ([]match.FieldPredicate,
 	sort.Strings(headerNames)
 
 	for _, name := range headerNames {
-		matcher := w.Headers[name]
-		p, err := compileStringMatcher(matcher)
+		p, err := compileHeaderMatcher(w.Headers[name])
 		if err != nil {
 			return nil, fmt.Errorf("header %q: %w", name, err)
 		}
@@ -98,6 +340,129 @@ func (c *Compiler) compileWhen(w *scenario.WhenClause) ([]match.FieldPredicate,
 		})
 	}
 
+	// Convenience header matchers, ANDed with any explicit entry above.
+	for _, h := range []struct {
+		name    string
+		matcher *scenario.StringMatcher
+	}{
+		{"User-Agent", w.UserAgent},
+		{"Referer", w.Referer},
+	} {
+		if h.matcher == nil {
+			continue
+		}
+		p, err := compileHeaderMatcher(*h.matcher)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.ToLower(h.name), err)
+		}
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "header:" + h.name,
+			Predicate: p,
+		})
+	}
+
+	// Query predicates — sorted for deterministic ordering.
+	queryNames := make([]string, 0, len(w.Query))
+	for name := range w.Query {
+		queryNames = append(queryNames, name)
+	}
+	sort.Strings(queryNames)
+
+	for _, name := range queryNames {
+		p, err := compileQueryMatcher(w.Query[name])
+		if err != nil {
+			return nil, fmt.Errorf("query %q: %w", name, err)
+		}
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "query:" + name,
+			Predicate: p,
+		})
+	}
+
+	// Cookie predicates follow the query parameter presence rules.
+	cookieNames := make([]string, 0, len(w.Cookies))
+	for name := range w.Cookies {
+		cookieNames = append(cookieNames, name)
+	}
+	sort.Strings(cookieNames)
+
+	for _, name := range cookieNames {
+		p, err := compileQueryMatcher(w.Cookies[name])
+		if err != nil {
+			return nil, fmt.Errorf("cookie %q: %w", name, err)
+		}
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "cookie:" + name,
+			Predicate: p,
+		})
+	}
+
+	arrayNames := make([]string, 0, len(w.QueryArrays))
+	for name := range w.QueryArrays {
+		arrayNames = append(arrayNames, name)
+	}
+	sort.Strings(arrayNames)
+
+	for _, name := range arrayNames {
+		m := w.QueryArrays[name]
+		if len(m.Contains) == 0 && m.Count == nil {
+			return nil, fmt.Errorf("query array %q: contains or count is required", name)
+		}
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "query:" + name + ":array",
+			Predicate: queryArrayPredicate(m),
+		})
+	}
+
+	// Declared Content-Length predicate.
+	if w.ContentLength != nil {
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "content_length",
+			Predicate: numericPredicate(*w.ContentLength),
+		})
+	}
+
+	if w.Secure != nil {
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "secure",
+			Predicate: exactPredicate(strconv.FormatBool(*w.Secure)),
+		})
+	}
+
+	if w.Expr != "" {
+		p, err := whenExprPredicate(w.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("expr: %w", err)
+		}
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "body",
+			Predicate: p,
+		})
+	}
+
+	// Body hash predicate.
+	if w.BodyHash != nil {
+		p, err := bodyHashPredicate(*w.BodyHash)
+		if err != nil {
+			return nil, fmt.Errorf("body_hash: %w", err)
+		}
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "body:hash",
+			Predicate: p,
+		})
+	}
+
+	if w.BodyPrefix != nil {
+		p, err := bodyPrefixPredicate(*w.BodyPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("body_prefix: %w", err)
+		}
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "body:prefix",
+			Predicate: p,
+		})
+	}
+
 	// Body predicates.
 	if w.Body != nil {
 		bodyPreds, err := c.compileBody(w.Body)
@@ -113,6 +478,28 @@ func (c *Compiler) compileWhen(w *scenario.WhenClause) ([]match.FieldPredicate,
 func (c *Compiler) compileBody(bc *scenario.BodyClause) ([]match.FieldPredicate, error) {
 	var predicates []match.FieldPredicate
 
+	if bc.Regex != "" {
+		p, err := regexPredicate(bc.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("body regex: %w", err)
+		}
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "body:regex",
+			Predicate: p,
+		})
+	}
+
+	if bc.KeyOrder != nil {
+		p, err := keyOrderPredicate(*bc.KeyOrder)
+		if err != nil {
+			return nil, fmt.Errorf("body key_order: %w", err)
+		}
+		predicates = append(predicates, match.FieldPredicate{
+			Field:     "body:key_order",
+			Predicate: p,
+		})
+	}
+
 	for _, cond := range bc.Conditions {
 		p, err := c.compileBodyCondition(cond, bc.ContentType)
 		if err != nil {
@@ -173,19 +560,90 @@ func (c *Compiler) compileBody(bc *scenario.BodyClause) ([]match.FieldPredicate,
 		}
 	}
 
+	if bc.Decode != "" {
+		if _, err := jsonpath.New(bc.Decode); err != nil {
+			return nil, fmt.Errorf("body decode %q: invalid JSONPath: %w", bc.Decode, err)
+		}
+		for i := range predicates {
+			predicates[i].Predicate = base64DecodePredicate(bc.Decode, predicates[i].Predicate)
+		}
+		if len(predicates) == 0 {
+			// Without conditions the clause still requires a decodable payload.
+			predicates = append(predicates, match.FieldPredicate{
+				Field:     "body:decode",
+				Predicate: base64DecodePredicate(bc.Decode, match.Always()),
+			})
+		}
+	}
+
 	return predicates, nil
 }
 
+// base64DecodePredicate creates a predicate that extracts a base64 string
+// from the JSON body via JSONPath, decodes it and passes the payload to inner.
+// Any failure along the way fails the match.
+func base64DecodePredicate(expr string, inner match.Predicate) match.Predicate {
+	return func(body string) bool {
+		var data any
+		if err := parseJSON(body, &data); err != nil {
+			return false
+		}
+		result, err := jsonpath.Get(expr, data)
+		if err != nil {
+			return false
+		}
+		encoded, ok := result.(string)
+		if !ok {
+			return false
+		}
+		decoded, ok := decodeBase64(encoded)
+		if !ok {
+			return false
+		}
+		return inner(string(decoded))
+	}
+}
+
+// decodeBase64 decodes standard or URL-safe base64, padded or not.
+func decodeBase64(s string) ([]byte, bool) {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
 func (c *Compiler) compileBodyCondition(cond scenario.BodyCondition, contentType string) (match.FieldPredicate, error) {
+	if cond.AllRegex != "" {
+		return compileAllRegexCondition(cond, contentType)
+	}
+
 	matcher, err := compileStringMatcher(cond.Matcher)
 	if err != nil {
 		return match.FieldPredicate{}, fmt.Errorf("body condition %q: %w", cond.Extractor, err)
 	}
+	if cond.Min != nil || cond.Max != nil {
+		if cond.Min != nil && cond.Max != nil && *cond.Min > *cond.Max {
+			return match.FieldPredicate{}, fmt.Errorf("body condition %q: min %v is greater than max %v", cond.Extractor, *cond.Min, *cond.Max)
+		}
+		matcher = match.And(matcher, numericPredicate(scenario.NumericMatcher{Gte: cond.Min, Lte: cond.Max}))
+	}
 
 	fieldName := "body:" + cond.Extractor
 
 	switch strings.ToLower(contentType) {
-	case "json":
+	case "json", "json-pointer":
+		if strings.EqualFold(contentType, "json-pointer") || strings.HasPrefix(cond.Extractor, "#") {
+			tokens, err := parseJSONPointer(cond.Extractor)
+			if err != nil {
+				return match.FieldPredicate{}, fmt.Errorf("body condition: %w", err)
+			}
+			return match.FieldPredicate{
+				Field:     fieldName,
+				Predicate: jsonPointerPredicate(tokens, matcher),
+			}, nil
+		}
 		return match.FieldPredicate{
 			Field:     fieldName,
 			Predicate: jsonPathPredicate(cond.Extractor, matcher),
@@ -195,6 +653,20 @@ func (c *Compiler) compileBodyCondition(cond scenario.BodyCondition, contentType
 			Field:     fieldName,
 			Predicate: xpathPredicate(cond.Extractor, matcher),
 		}, nil
+	case "form":
+		return match.FieldPredicate{
+			Field:     fieldName,
+			Predicate: formFieldPredicate(cond.Extractor, matcher),
+		}, nil
+	case "protobuf":
+		path, err := parseProtoPath(cond.Extractor)
+		if err != nil {
+			return match.FieldPredicate{}, fmt.Errorf("body condition: %w", err)
+		}
+		return match.FieldPredicate{
+			Field:     fieldName,
+			Predicate: protobufPredicate(path, matcher),
+		}, nil
 	default:
 		// No content type specified — match against raw body.
 		return match.FieldPredicate{
@@ -204,7 +676,38 @@ func (c *Compiler) compileBodyCondition(cond scenario.BodyCondition, contentType
 	}
 }
 
+// compileAllRegexCondition compiles an all_regex condition, which applies a
+// regex to every element of a JSONPath-selected array.
+func compileAllRegexCondition(cond scenario.BodyCondition, contentType string) (match.FieldPredicate, error) {
+	if !strings.EqualFold(contentType, "json") || strings.HasPrefix(cond.Extractor, "#") {
+		return match.FieldPredicate{}, fmt.Errorf("body condition %q: all_regex requires content_type json and a JSONPath extractor", cond.Extractor)
+	}
+	if cond.Matcher != (scenario.StringMatcher{}) || cond.Min != nil || cond.Max != nil {
+		return match.FieldPredicate{}, fmt.Errorf("body condition %q: all_regex cannot be combined with matcher, min or max", cond.Extractor)
+	}
+	re, err := regexp.Compile(cond.AllRegex)
+	if err != nil {
+		return match.FieldPredicate{}, fmt.Errorf("body condition %q: invalid all_regex %q: %w", cond.Extractor, cond.AllRegex, err)
+	}
+	return match.FieldPredicate{
+		Field:     "body:" + cond.Extractor,
+		Predicate: jsonPathAllPredicate(cond.Extractor, re, cond.AllowEmpty),
+	}, nil
+}
+
 func compileStringMatcher(m scenario.StringMatcher) (match.Predicate, error) {
+	if m.Exists {
+		return func(s string) bool { return s != match.Missing }, nil
+	}
+	if m.Absent {
+		return func(s string) bool { return s == match.Missing }, nil
+	}
+	if m.Numeric != nil {
+		return numericPredicate(*m.Numeric), nil
+	}
+	if m.Negated {
+		return match.Not(exactPredicate(m.Exact)), nil
+	}
 	if m.IsExact() {
 		return exactPredicate(m.Exact), nil
 	}
@@ -214,6 +717,78 @@ func compileStringMatcher(m scenario.StringMatcher) (match.Predicate, error) {
 	return regexPredicate(m.Pattern)
 }
 
+// compileMethods validates the clause's methods and drops duplicates,
+// keeping the declared order.
+func compileMethods(w *scenario.WhenClause) ([]string, error) {
+	if w.Methods != nil && len(w.Methods) == 0 {
+		return nil, fmt.Errorf("method list must not be empty")
+	}
+	var methods []string
+	for _, m := range w.MethodList() {
+		if !isMethodToken(m) {
+			return nil, fmt.Errorf("invalid method %q: must be an HTTP token", m)
+		}
+		if !slices.Contains(methods, m) {
+			methods = append(methods, m)
+		}
+	}
+	return methods, nil
+}
+
+// compileHeaderMatcher compiles a header matcher. Only !exists and !absent
+// see a missing header as such; every other matcher sees it as empty.
+func compileHeaderMatcher(m scenario.StringMatcher) (match.Predicate, error) {
+	p, err := compileStringMatcher(m)
+	if err != nil || m.Exists || m.Absent {
+		return p, err
+	}
+	return func(s string) bool {
+		if s == match.Missing {
+			s = ""
+		}
+		return p(s)
+	}, nil
+}
+
+// compileQueryMatcher compiles a query parameter or cookie matcher. An empty
+// pattern matches whether or not the parameter is present; any other value
+// matcher requires the parameter to be present.
+func compileQueryMatcher(m scenario.StringMatcher) (match.Predicate, error) {
+	p, err := compileStringMatcher(m)
+	if err != nil || m.Exists || m.Absent || (!m.IsExact() && m.Pattern == "") {
+		return p, err
+	}
+	return func(s string) bool {
+		return s != match.Missing && p(s)
+	}, nil
+}
+
+// queryArrayPredicate matches the JSON-encoded values of a repeated query
+// parameter. A missing parameter has no values.
+func queryArrayPredicate(m scenario.QueryArrayMatcher) match.Predicate {
+	var count match.Predicate
+	if m.Count != nil {
+		count = numericPredicate(*m.Count)
+	}
+	return func(s string) bool {
+		var values []string
+		if s != match.Missing {
+			if err := parseJSON(s, &values); err != nil {
+				return false
+			}
+		}
+		if count != nil && !count(strconv.Itoa(len(values))) {
+			return false
+		}
+		for _, want := range m.Contains {
+			if !slices.Contains(values, want) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
 func exactPredicate(expected string) match.Predicate {
 	return func(s string) bool {
 		return s == expected
@@ -230,6 +805,189 @@ func regexPredicate(pattern string) (match.Predicate, error) {
 	}, nil
 }
 
+var hostParamName = regexp.MustCompile(`^\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// compileHostPattern turns a host pattern into an anchored regexp matched
+// against the lower-cased request host. Each dot-separated label is a
+// literal, "*" for any single label, or "{name}" to capture a label as a
+// named group.
+func compileHostPattern(pattern string) (*regexp.Regexp, error) {
+	labels := strings.Split(pattern, ".")
+	parts := make([]string, 0, len(labels))
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		switch {
+		case label == "":
+			return nil, fmt.Errorf("invalid host %q: empty label", pattern)
+		case label == "*":
+			parts = append(parts, `[^.]+`)
+		case hostParamName.MatchString(label):
+			name := hostParamName.FindStringSubmatch(label)[1]
+			if seen[name] {
+				return nil, fmt.Errorf("invalid host %q: duplicate parameter %q", pattern, name)
+			}
+			seen[name] = true
+			parts = append(parts, `(?P<`+name+`>[^.]+)`)
+		case strings.ContainsAny(label, "{}*"):
+			return nil, fmt.Errorf("invalid host %q: label %q must be literal, * or {name}", pattern, label)
+		default:
+			parts = append(parts, regexp.QuoteMeta(strings.ToLower(label)))
+		}
+	}
+	return regexp.Compile(`^` + strings.Join(parts, `\.`) + `$`)
+}
+
+// isMethodToken reports whether m is a valid HTTP method token (RFC 9110 tchar).
+func isMethodToken(m string) bool {
+	return strings.IndexFunc(m, func(r rune) bool {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return false
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+			return false
+		}
+		return true
+	}) < 0
+}
+
+// numericPredicate creates a predicate that parses the value as a number and
+// checks every configured bound. Non-numeric values never match.
+func numericPredicate(m scenario.NumericMatcher) match.Predicate {
+	return func(s string) bool {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return false
+		}
+		if m.Eq != nil && v != *m.Eq {
+			return false
+		}
+		if m.Gt != nil && v <= *m.Gt {
+			return false
+		}
+		if m.Gte != nil && v < *m.Gte {
+			return false
+		}
+		if m.Lt != nil && v >= *m.Lt {
+			return false
+		}
+		if m.Lte != nil && v > *m.Lte {
+			return false
+		}
+		return true
+	}
+}
+
+// bodyHashPredicate creates a predicate that digests the raw body with the
+// configured algorithm and compares it to the expected hex value.
+func bodyHashPredicate(h scenario.BodyHash) (match.Predicate, error) {
+	newHash, ok := hashAlgorithms[strings.ToLower(h.Algorithm)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm %q", h.Algorithm)
+	}
+	expected, err := hex.DecodeString(strings.TrimSpace(h.Expected))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex digest %q: %w", h.Expected, err)
+	}
+	return func(body string) bool {
+		hasher := newHash()
+		hasher.Write([]byte(body))
+		return bytes.Equal(hasher.Sum(nil), expected)
+	}, nil
+}
+
+// bodyPrefixPredicate decodes the signature and matches bodies that start
+// with it.
+func bodyPrefixPredicate(bp scenario.BodyPrefix) (match.Predicate, error) {
+	var (
+		signature []byte
+		err       error
+	)
+	switch {
+	case bp.Hex != "" && bp.Base64 != "":
+		return nil, fmt.Errorf("set only one of hex or base64")
+	case bp.Hex != "":
+		signature, err = hex.DecodeString(strings.Join(strings.Fields(bp.Hex), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex signature %q: %w", bp.Hex, err)
+		}
+	case bp.Base64 != "":
+		signature, err = base64.StdEncoding.DecodeString(bp.Base64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 signature %q: %w", bp.Base64, err)
+		}
+	}
+	if len(signature) == 0 {
+		return nil, fmt.Errorf("hex or base64 signature is required")
+	}
+	return func(body string) bool {
+		return strings.HasPrefix(body, string(signature))
+	}, nil
+}
+
+// keyOrderPredicate matches JSON object bodies whose top-level keys appear
+// in the order of ko.Keys. The body is read token by token, since decoding
+// into a map would lose the order.
+func keyOrderPredicate(ko scenario.KeyOrder) (match.Predicate, error) {
+	if len(ko.Keys) == 0 {
+		return nil, fmt.Errorf("keys must not be empty")
+	}
+	return func(body string) bool {
+		keys, ok := topLevelKeys(body)
+		if !ok {
+			return false
+		}
+		if !ko.AllowExtra {
+			return slices.Equal(keys, ko.Keys)
+		}
+		next := 0
+		for _, k := range keys {
+			if next < len(ko.Keys) && k == ko.Keys[next] {
+				next++
+			} else if slices.Contains(ko.Keys, k) {
+				// A listed key out of order, or repeated.
+				return false
+			}
+		}
+		return next == len(ko.Keys)
+	}, nil
+}
+
+// topLevelKeys returns the keys of a JSON object body in document order.
+func topLevelKeys(body string) ([]string, bool) {
+	dec := json.NewDecoder(strings.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, false
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, false
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, false
+		}
+		keys = append(keys, key)
+		// Skip the value, however deeply nested.
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, false
+		}
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('}') {
+		return nil, false
+	}
+	return keys, true
+}
+
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
 // jsonPathPredicate creates a predicate that extracts a value via JSONPath and matches it.
 func jsonPathPredicate(expr string, valueMatcher match.Predicate) match.Predicate {
 	return func(body string) bool {
@@ -247,6 +1005,36 @@ func jsonPathPredicate(expr string, valueMatcher match.Predicate) match.Predicat
 	}
 }
 
+// jsonPathAllPredicate creates a predicate that extracts an array via JSONPath
+// and requires every element to match re. Non-array results never match;
+// an empty array matches only when allowEmpty is set.
+func jsonPathAllPredicate(expr string, re *regexp.Regexp, allowEmpty bool) match.Predicate {
+	return func(body string) bool {
+		var data any
+		if err := parseJSON(body, &data); err != nil {
+			return false
+		}
+
+		result, err := jsonpath.Get(expr, data)
+		if err != nil {
+			return false
+		}
+		items, ok := result.([]any)
+		if !ok {
+			return false
+		}
+		if len(items) == 0 {
+			return allowEmpty
+		}
+		for _, item := range items {
+			if !re.MatchString(fmt.Sprintf("%v", item)) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
 func parseJSON(s string, v any) error {
 	dec := strings.NewReader(s)
 	return decodeJSON(dec, v)
@@ -269,16 +1057,137 @@ func xpathPredicate(expr string, valueMatcher match.Predicate) match.Predicate {
 	}
 }
 
+// formFieldPredicate creates a predicate that parses an
+// application/x-www-form-urlencoded body and matches the first value of
+// field. A missing field or malformed body never matches.
+func formFieldPredicate(field string, valueMatcher match.Predicate) match.Predicate {
+	return func(body string) bool {
+		values, err := url.ParseQuery(body)
+		if err != nil || !values.Has(field) {
+			return false
+		}
+		return valueMatcher(values.Get(field))
+	}
+}
+
 func (c *Compiler) compileResponse(r *scenario.Response) (match.CompiledResponse, error) {
 	resp := match.CompiledResponse{
-		Status:      r.Status,
-		Headers:     r.Headers,
-		ContentType: r.ContentType,
+		Status:          r.Status,
+		Headers:         r.Headers,
+		ContentType:     r.ContentType,
+		OmitNulls:       r.OmitNulls,
+		TruncateAtBytes: r.TruncateAtBytes,
+		Raw:             r.Raw,
 	}
 
 	if resp.Status == 0 {
 		resp.Status = 200
 	}
+	if r.TruncateAtBytes < 0 {
+		return resp, fmt.Errorf("truncate_at_bytes must not be negative")
+	}
+
+	switch r.CanonicalizeBody {
+	case "", "compact", "pretty":
+		resp.CanonicalizeBody = r.CanonicalizeBody
+	default:
+		return resp, fmt.Errorf("unsupported canonicalize_body %q (expected \"compact\" or \"pretty\")", r.CanonicalizeBody)
+	}
+
+	if r.Raw {
+		if err := checkRaw(r); err != nil {
+			return resp, err
+		}
+	}
+
+	compression, err := compileCompression(r.Compression)
+	if err != nil {
+		return resp, err
+	}
+	resp.Compression = compression
+
+	if r.Charset != "" {
+		encode, name, err := newCharsetEncoder(r.Charset)
+		if err != nil {
+			return resp, err
+		}
+		resp.Charset = name
+		resp.Encoder = encode
+	}
+
+	if r.Cache != nil {
+		cache, err := compileCache(r.Cache)
+		if err != nil {
+			return resp, err
+		}
+		resp.Cache = cache
+	}
+
+	if r.Proxy != nil {
+		proxy, err := compileProxy(r.Proxy)
+		if err != nil {
+			return resp, err
+		}
+		resp.Proxy = proxy
+	}
+
+	if r.Switch != nil {
+		sw, err := c.compileSwitch(r.Switch)
+		if err != nil {
+			return resp, err
+		}
+		resp.Switch = sw
+	}
+
+	if r.TimeSwitch != nil {
+		ts, err := c.compileTimeSwitch(r.TimeSwitch)
+		if err != nil {
+			return resp, err
+		}
+		resp.TimeSwitch = ts
+	}
+
+	if r.Created != nil {
+		if r.Status == 0 {
+			resp.Status = http.StatusCreated
+		}
+		location, err := c.compileCreated(r.Created, resp.Status)
+		if err != nil {
+			return resp, err
+		}
+		resp.Location = location
+	}
+
+	if containsExpr(r.BodyFile) {
+		renderer, err := c.compileTemplatedBodyFile(r)
+		if err != nil {
+			return resp, err
+		}
+		resp.Renderer = renderer
+		resp.BodyFileMissingStatus = r.BodyFileMissingStatus
+		if resp.BodyFileMissingStatus == 0 {
+			resp.BodyFileMissingStatus = 404
+		}
+		if resp.ContentType == "" {
+			resp.ContentType = InferContentType("", r.BodyFile, nil)
+		}
+		return resp, nil
+	}
+
+	if r.Generate != nil {
+		if r.Body != "" || r.BodyFile != "" || r.Engine != "" {
+			return resp, fmt.Errorf("generate: cannot be combined with body, body_file or engine")
+		}
+		body, err := generateBody(r.Generate, c.randomSource())
+		if err != nil {
+			return resp, err
+		}
+		resp.Body = body
+		if resp.ContentType == "" {
+			resp.ContentType = "application/json"
+		}
+		return resp, nil
+	}
 
 	// Resolve body content (inline or from file).
 	var bodySource string
@@ -310,17 +1219,263 @@ func (c *Compiler) compileResponse(r *scenario.Response) (match.CompiledResponse
 			return resp, fmt.Errorf("failed to compile template (engine=%s): %w", r.Engine, err)
 		}
 		resp.Renderer = renderer
+		resp.TemplateName = name
 	} else {
-		resp.Body = []byte(bodySource)
+		resp.Body = c.bodies.intern([]byte(bodySource))
+		resp.BodyFile = r.BodyFile
 	}
 
 	return resp, nil
 }
 
+// checkKind validates a scenario kind. Mirror scenarios take their body
+// from the request, so they can't declare one.
+func checkKind(s *scenario.Scenario) error {
+	switch s.Kind {
+	case "":
+		return nil
+	case scenario.KindMirror:
+		r := &s.Response
+		if len(s.Responses) > 0 || r.Body != "" || r.BodyFile != "" || r.Engine != "" {
+			return fmt.Errorf("kind mirror: response body, body_file, engine and responses are not allowed")
+		}
+		return checkRaw(r)
+	default:
+		return fmt.Errorf("unsupported kind %q (expected %q)", s.Kind, scenario.KindMirror)
+	}
+}
+
+// checkRaw rejects options that would rewrite a raw body.
+func checkRaw(r *scenario.Response) error {
+	switch {
+	case r.Engine != "":
+		return fmt.Errorf("raw: cannot be combined with engine")
+	case containsExpr(r.BodyFile):
+		return fmt.Errorf("raw: body_file cannot be templated")
+	case r.OmitNulls:
+		return fmt.Errorf("raw: cannot be combined with omit_nulls")
+	case r.Charset != "":
+		return fmt.Errorf("raw: cannot be combined with charset")
+	case r.Switch != nil || r.TimeSwitch != nil || r.Proxy != nil:
+		return fmt.Errorf("raw: cannot be combined with switch, time_switch or proxy")
+	}
+	return nil
+}
+
+// defaultProxyTimeout bounds proxied requests without a timeout_ms.
+const defaultProxyTimeout = 30 * time.Second
+
+// compileProxy validates a proxy block's upstream URL and resolves its timeout.
+func compileProxy(p *scenario.Proxy) (*match.CompiledProxy, error) {
+	target, err := url.Parse(p.Target)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: invalid target %q: %w", p.Target, err)
+	}
+	if (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("proxy: target %q must be an absolute http or https URL", p.Target)
+	}
+	if p.TimeoutMs < 0 {
+		return nil, fmt.Errorf("proxy: timeout_ms must not be negative")
+	}
+	if p.StripPrefix != "" && !strings.HasPrefix(p.StripPrefix, "/") {
+		return nil, fmt.Errorf("proxy: strip_prefix %q must start with /", p.StripPrefix)
+	}
+
+	timeout := defaultProxyTimeout
+	if p.TimeoutMs > 0 {
+		timeout = time.Duration(p.TimeoutMs) * time.Millisecond
+	}
+	return &match.CompiledProxy{
+		Target:      target,
+		StripPrefix: p.StripPrefix,
+		Timeout:     timeout,
+		Record:      p.Record,
+	}, nil
+}
+
+// compileCreated compiles the Location header of the created helper. The
+// location is an expr template whatever the body engine.
+func (c *Compiler) compileCreated(cr *scenario.Created, status int) (match.BodyRenderer, error) {
+	if status < 200 || status > 299 {
+		return nil, fmt.Errorf("created: status must be 2xx, got %d", status)
+	}
+	if cr.Location == "" {
+		return nil, fmt.Errorf("created: location is required")
+	}
+	if !containsExpr(cr.Location) {
+		return fixedRenderer(cr.Location), nil
+	}
+	if c.registry == nil {
+		return nil, fmt.Errorf("created: templated location %q requires a template registry", cr.Location)
+	}
+	location, err := c.registry.Compile("expr", "location", cr.Location)
+	if err != nil {
+		return nil, fmt.Errorf("created: failed to compile location: %w", err)
+	}
+	return location, nil
+}
+
+// compileTemplatedBodyFile compiles a body_file path containing ${ }
+// expressions. The path is an expr template whatever the engine; the engine,
+// if any, applies to the contents of each file it selects.
+func (c *Compiler) compileTemplatedBodyFile(r *scenario.Response) (match.BodyRenderer, error) {
+	if c.registry == nil {
+		return nil, fmt.Errorf("templated body_file %q requires a template registry", r.BodyFile)
+	}
+	if filepath.IsAbs(r.BodyFile) {
+		return nil, fmt.Errorf("absolute paths not allowed in body_file: %s", r.BodyFile)
+	}
+	path, err := c.registry.Compile("expr", "body_file", r.BodyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile body_file path: %w", err)
+	}
+
+	renderer := &bodyFileRenderer{
+		path:    path,
+		resolve: c.resolveBodyFilePath,
+		cache:   make(map[string]*cachedBodyFile),
+	}
+	if r.Engine != "" {
+		engine := r.Engine
+		renderer.compile = func(name, source string) (match.BodyRenderer, error) {
+			tpl, err := c.registry.Compile(engine, name, source)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile template (engine=%s): %w", engine, err)
+			}
+			return tpl, nil
+		}
+	}
+	return renderer, nil
+}
+
+// compileCache builds the Cache-Control directive list, e.g.
+// "public, max-age=3600, immutable".
+func compileCache(cc *scenario.Cache) (*match.CompiledCache, error) {
+	if cc.MaxAge < 0 {
+		return nil, fmt.Errorf("cache: max_age must not be negative")
+	}
+
+	var directives []string
+	switch cc.Visibility {
+	case "":
+	case "public", "private":
+		directives = append(directives, cc.Visibility)
+	default:
+		return nil, fmt.Errorf("cache: unsupported visibility %q (expected \"public\" or \"private\")", cc.Visibility)
+	}
+	directives = append(directives, "max-age="+strconv.Itoa(cc.MaxAge))
+	if cc.Immutable {
+		directives = append(directives, "immutable")
+	}
+
+	return &match.CompiledCache{
+		CacheControl: strings.Join(directives, ", "),
+		MaxAge:       time.Duration(cc.MaxAge) * time.Second,
+	}, nil
+}
+
+// compileSwitch compiles the discriminator and every case response. Cases
+// cannot nest further switches.
+func (c *Compiler) compileSwitch(sw *scenario.ResponseSwitch) (*match.CompiledSwitch, error) {
+	if sw.On == "" {
+		return nil, fmt.Errorf("switch: on must be a JSONPath expression")
+	}
+	extract, err := jsonPathExtractor(sw.On)
+	if err != nil {
+		return nil, fmt.Errorf("switch: %w", err)
+	}
+
+	compiled := &match.CompiledSwitch{
+		Extract: extract,
+		Cases:   make(map[string]match.CompiledResponse, len(sw.Cases)),
+	}
+	for value, r := range sw.Cases {
+		if r.Switch != nil || r.TimeSwitch != nil {
+			return nil, fmt.Errorf("switch case %q: nested switch is not supported", value)
+		}
+		resp, err := c.compileResponse(&r)
+		if err != nil {
+			return nil, fmt.Errorf("switch case %q: %w", value, err)
+		}
+		compiled.Cases[value] = resp
+	}
+	if sw.Default != nil {
+		if sw.Default.Switch != nil || sw.Default.TimeSwitch != nil {
+			return nil, fmt.Errorf("switch default: nested switch is not supported")
+		}
+		resp, err := c.compileResponse(sw.Default)
+		if err != nil {
+			return nil, fmt.Errorf("switch default: %w", err)
+		}
+		compiled.Default = &resp
+	}
+	return compiled, nil
+}
+
+// compileTimeSwitch compiles every case's cron schedule and response. Case
+// responses may still switch on the body, but not on time again.
+func (c *Compiler) compileTimeSwitch(ts *scenario.TimeSwitch) (*match.CompiledTimeSwitch, error) {
+	if len(ts.Cases) == 0 {
+		return nil, fmt.Errorf("time_switch: cases must not be empty")
+	}
+	compiled := &match.CompiledTimeSwitch{Cases: make([]match.CompiledTimeCase, 0, len(ts.Cases))}
+	for i, tc := range ts.Cases {
+		sched, err := parseCron(tc.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("time_switch case %d: invalid schedule %q: %w", i, tc.Schedule, err)
+		}
+		if tc.Response.TimeSwitch != nil {
+			return nil, fmt.Errorf("time_switch case %d: nested time_switch is not supported", i)
+		}
+		resp, err := c.compileResponse(&tc.Response)
+		if err != nil {
+			return nil, fmt.Errorf("time_switch case %d: %w", i, err)
+		}
+		compiled.Cases = append(compiled.Cases, match.CompiledTimeCase{Active: sched.active, Response: resp})
+	}
+	if ts.Default != nil {
+		if ts.Default.TimeSwitch != nil {
+			return nil, fmt.Errorf("time_switch default: nested time_switch is not supported")
+		}
+		resp, err := c.compileResponse(ts.Default)
+		if err != nil {
+			return nil, fmt.Errorf("time_switch default: %w", err)
+		}
+		compiled.Default = &resp
+	}
+	return compiled, nil
+}
+
+// jsonPathExtractor compiles expr into a function that reads a value from a
+// JSON body, formatted like jsonPathPredicate formats extracted values.
+func jsonPathExtractor(expr string) (func([]byte) (string, bool), error) {
+	eval, err := jsonpath.New(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %q: %w", expr, err)
+	}
+	return func(body []byte) (string, bool) {
+		var data any
+		if err := parseJSON(string(body), &data); err != nil {
+			return "", false
+		}
+		result, err := eval(context.Background(), data)
+		if err != nil {
+			return "", false
+		}
+		return fmt.Sprintf("%v", result), true
+	}, nil
+}
+
 // resolveBodyFilePath resolves and validates body_file paths to prevent directory traversal.
 func (c *Compiler) resolveBodyFilePath(path string) (string, error) {
+	return c.resolveWithinRoot("body_file", path)
+}
+
+// resolveWithinRoot joins a relative path onto the root directory, rejecting
+// absolute paths and paths that escape the root (including via symlinks).
+func (c *Compiler) resolveWithinRoot(field, path string) (string, error) {
 	if filepath.IsAbs(path) {
-		return "", fmt.Errorf("absolute paths not allowed in body_file: %s", path)
+		return "", fmt.Errorf("absolute paths not allowed in %s: %s", field, path)
 	}
 
 	resolved := filepath.Join(c.rootDir, path)
@@ -337,7 +1492,7 @@ func (c *Compiler) resolveBodyFilePath(path string) (string, error) {
 	}
 
 	if !strings.HasPrefix(realPath, absRoot) {
-		return "", fmt.Errorf("body_file path %q escapes root directory", path)
+		return "", fmt.Errorf("%s path %q escapes root directory", field, path)
 	}
 
 	return resolved, nil
@@ -356,8 +1511,9 @@ func compilePolicy(p *scenario.Policy) *match.CompiledPolicy {
 
 	if p.Latency != nil {
 		cp.Latency = &match.CompiledLatency{
-			FixedMs:  p.Latency.FixedMs,
-			JitterMs: p.Latency.JitterMs,
+			FixedMs:       p.Latency.FixedMs,
+			JitterMs:      p.Latency.JitterMs,
+			HeaderDelayMs: p.Latency.HeaderDelayMs,
 		}
 	}
 
@@ -385,3 +1541,37 @@ func compilePolicy(p *scenario.Policy) *match.CompiledPolicy {
 
 	return cp
 }
+
+// compileTotalItemsSource sets the pagination total from a JSONPath into the
+// body or a fixed non-negative number.
+func compileTotalItemsSource(cp *match.CompiledPagination, src string) error {
+	if strings.HasPrefix(src, "$") {
+		if _, err := jsonpath.New(src); err != nil {
+			return fmt.Errorf("invalid total_items_source %q: %w", src, err)
+		}
+		cp.TotalItemsPath = src
+		return nil
+	}
+	n, err := strconv.Atoi(src)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid total_items_source %q: expected a JSONPath or a non-negative number", src)
+	}
+	cp.TotalItems = &n
+	return nil
+}
+
+// compileRequireContentType normalizes require_content_type entries to bare
+// lower-case media types. Parameters are dropped since matching ignores them.
+func compileRequireContentType(types []string) ([]string, error) {
+	out := make([]string, 0, len(types))
+	for _, t := range types {
+		mediaType, _, err := mime.ParseMediaType(t)
+		if err != nil || !strings.Contains(mediaType, "/") {
+			return nil, fmt.Errorf("invalid require_content_type entry %q: expected a media type such as application/json", t)
+		}
+		if !slices.Contains(out, mediaType) {
+			out = append(out, mediaType)
+		}
+	}
+	return out, nil
+}
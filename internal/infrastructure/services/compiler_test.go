@@ -1,14 +1,25 @@
 package services_test
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+	"unsafe"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
 	"github.com/sophialabs/proteusmock/internal/domain/scenario"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/template"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/services"
 )
 
@@ -137,6 +148,56 @@ func TestCompiler_RegexHeaderMatcher(t *testing.T) {
 	t.Error("header predicate not found")
 }
 
+func TestCompiler_UserAgentMatcher(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	s := &scenario.Scenario{
+		ID: "ua",
+		When: scenario.WhenClause{
+			Method: "GET",
+			Path:   "/api/test",
+			Headers: map[string]scenario.StringMatcher{
+				"user-agent": {Pattern: ".*Mobile.*"},
+			},
+			UserAgent: &scenario.StringMatcher{Pattern: "^Mozilla/5\\.0"},
+		},
+		Response: scenario.Response{Status: 200},
+	}
+
+	cs, err := compiler.CompileScenario(s)
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	// The explicit header entry and the shorthand both apply.
+	matches := func(ua string) bool {
+		found := 0
+		for _, p := range cs.Predicates {
+			if p.Field != "header:User-Agent" {
+				continue
+			}
+			found++
+			if !p.Predicate(ua) {
+				return false
+			}
+		}
+		if found != 2 {
+			t.Fatalf("expected 2 User-Agent predicates, got %d", found)
+		}
+		return true
+	}
+
+	if !matches("Mozilla/5.0 (iPhone) Mobile Safari") {
+		t.Error("should match a mobile Mozilla user agent")
+	}
+	if matches("Mozilla/5.0 (X11; Linux x86_64)") {
+		t.Error("should not match without the explicit header pattern")
+	}
+	if matches("curl/8.0 Mobile") {
+		t.Error("should not match without the user_agent pattern")
+	}
+}
+
 func TestCompiler_JSONPathBody(t *testing.T) {
 	compiler := newTestCompiler(t)
 
@@ -177,6 +238,283 @@ func TestCompiler_JSONPathBody(t *testing.T) {
 	t.Error("body predicate not found")
 }
 
+func TestCompiler_FormBody(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	s := &scenario.Scenario{
+		ID: "form-body",
+		When: scenario.WhenClause{
+			Method: "POST",
+			Path:   "/oauth/token",
+			Body: &scenario.BodyClause{
+				ContentType: "form",
+				Conditions: []scenario.BodyCondition{
+					{
+						Extractor: "grant_type",
+						Matcher:   scenario.StringMatcher{Exact: "client_credentials"},
+					},
+				},
+			},
+		},
+		Response: scenario.Response{Status: 200},
+	}
+
+	cs, err := compiler.CompileScenario(s)
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	for _, p := range cs.Predicates {
+		if p.Field == "body:grant_type" {
+			if !p.Predicate("grant_type=client_credentials&scope=read%20write") {
+				t.Error("should match form with grant_type=client_credentials")
+			}
+			if !p.Predicate("grant_type=client_credentials&grant_type=password") {
+				t.Error("should match on the first value of a repeated field")
+			}
+			if p.Predicate("grant_type=password") {
+				t.Error("should not match form with grant_type=password")
+			}
+			if p.Predicate("scope=read") {
+				t.Error("should not match form without grant_type")
+			}
+			return
+		}
+	}
+	t.Error("body predicate not found")
+}
+
+func TestCompiler_BodyDecodeBase64(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID: "envelope",
+		When: scenario.WhenClause{
+			Method: "POST",
+			Path:   "/events",
+			Body: &scenario.BodyClause{
+				Decode:      "$.data",
+				ContentType: "json",
+				Conditions: []scenario.BodyCondition{
+					{Extractor: "$.type", Matcher: scenario.StringMatcher{Exact: "order.created"}},
+				},
+				Not: &scenario.BodyClause{
+					ContentType: "json",
+					Conditions: []scenario.BodyCondition{
+						{Extractor: "$.test", Matcher: scenario.StringMatcher{Exact: "true"}},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	envelope := func(inner string) string {
+		return `{"data":"` + base64.StdEncoding.EncodeToString([]byte(inner)) + `"}`
+	}
+	evaluator := match.NewEvaluator()
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"decoded payload matches", envelope(`{"type":"order.created"}`), true},
+		{"decoded payload differs", envelope(`{"type":"order.deleted"}`), false},
+		{"nested not sees decoded payload", envelope(`{"type":"order.created","test":true}`), false},
+		{"raw body is not decoded payload", `{"type":"order.created"}`, false},
+		{"invalid base64", `{"data":"%%%"}`, false},
+		{"non-string field", `{"data":42}`, false},
+	}
+	for _, tt := range tests {
+		req := &match.IncomingRequest{Method: "POST", Path: "/events", Body: []byte(tt.body)}
+		if got := evaluator.Evaluate(req, []*match.CompiledScenario{cs}).Matched != nil; got != tt.want {
+			t.Errorf("%s: expected match %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCompiler_JSONPathNumericComparison(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	gt := 100.0
+	s := &scenario.Scenario{
+		ID: "json-amount",
+		When: scenario.WhenClause{
+			Method: "POST",
+			Path:   "/api/payments",
+			Body: &scenario.BodyClause{
+				ContentType: "json",
+				Conditions: []scenario.BodyCondition{
+					{
+						Extractor: "$.amount",
+						Matcher:   scenario.StringMatcher{Pattern: ">100", Numeric: &scenario.NumericMatcher{Gt: &gt}},
+					},
+				},
+			},
+		},
+		Response: scenario.Response{Status: 200},
+	}
+
+	cs, err := compiler.CompileScenario(s)
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	for _, p := range cs.Predicates {
+		if p.Field == "body:$.amount" {
+			tests := []struct {
+				body string
+				want bool
+			}{
+				{`{"amount": 150}`, true},
+				{`{"amount": 100.5}`, true},
+				{`{"amount": "250"}`, true},
+				{`{"amount": 100}`, false},
+				{`{"amount": 99}`, false},
+				{`{"amount": "lots"}`, false},
+				{`{"amount": {"value": 500}}`, false},
+				{`{"other": 500}`, false},
+			}
+			for _, tt := range tests {
+				if got := p.Predicate(tt.body); got != tt.want {
+					t.Errorf("%s: expected %v, got %v", tt.body, tt.want, got)
+				}
+			}
+			return
+		}
+	}
+	t.Error("body predicate not found")
+}
+
+func TestCompiler_JSONPointerBody(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	tests := []struct {
+		name        string
+		contentType string
+		extractor   string
+		matcher     scenario.StringMatcher
+		body        string
+		want        bool
+	}{
+		{"nested match", "json-pointer", "/method/params/contract_id", scenario.StringMatcher{Exact: "C-1"}, `{"method":{"params":{"contract_id":"C-1"}}}`, true},
+		{"nested mismatch", "json-pointer", "/method/params/contract_id", scenario.StringMatcher{Exact: "C-1"}, `{"method":{"params":{"contract_id":"C-2"}}}`, false},
+		{"array index", "json-pointer", "/items/1/sku", scenario.StringMatcher{Pattern: "^SKU-"}, `{"items":[{"sku":"X"},{"sku":"SKU-9"}]}`, true},
+		{"index out of range", "json-pointer", "/items/2", scenario.StringMatcher{Pattern: ".*"}, `{"items":[1,2]}`, false},
+		{"leading zero index", "json-pointer", "/items/01", scenario.StringMatcher{Pattern: ".*"}, `{"items":[1,2]}`, false},
+		{"escaped key", "json-pointer", "/a~1b/c~0d", scenario.StringMatcher{Exact: "1"}, `{"a/b":{"c~d":1}}`, true},
+		{"number value", "json-pointer", "/amount", scenario.StringMatcher{Exact: "42"}, `{"amount":42}`, true},
+		{"missing field", "json-pointer", "/nonexistent", scenario.StringMatcher{Exact: "val"}, `{"name":"test"}`, false},
+		{"invalid JSON", "json-pointer", "/name", scenario.StringMatcher{Exact: "test"}, "not json", false},
+		{"fragment under json", "json", "#/user/first%20name", scenario.StringMatcher{Exact: "Alice"}, `{"user":{"first name":"Alice"}}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := compiler.CompileScenario(&scenario.Scenario{
+				ID: "json-pointer",
+				When: scenario.WhenClause{
+					Method: "POST",
+					Path:   "/api/query",
+					Body: &scenario.BodyClause{
+						ContentType: tt.contentType,
+						Conditions:  []scenario.BodyCondition{{Extractor: tt.extractor, Matcher: tt.matcher}},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("CompileScenario failed: %v", err)
+			}
+
+			for _, p := range cs.Predicates {
+				if p.Field == "body:"+tt.extractor {
+					if got := p.Predicate(tt.body); got != tt.want {
+						t.Errorf("expected %v, got %v", tt.want, got)
+					}
+					return
+				}
+			}
+			t.Error("body predicate not found")
+		})
+	}
+}
+
+func TestCompiler_JSONPointerInvalid(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	for _, extractor := range []string{"name", "/a~2b", "/trailing~"} {
+		_, err := compiler.CompileScenario(&scenario.Scenario{
+			ID: "bad-pointer",
+			When: scenario.WhenClause{
+				Method: "POST",
+				Path:   "/api/query",
+				Body: &scenario.BodyClause{
+					ContentType: "json-pointer",
+					Conditions:  []scenario.BodyCondition{{Extractor: extractor, Matcher: scenario.StringMatcher{Exact: "x"}}},
+				},
+			},
+		})
+		if err == nil {
+			t.Errorf("expected error for pointer %q", extractor)
+		}
+	}
+}
+
+func TestCompiler_BodyRegex(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	compile := func(bc *scenario.BodyClause) *match.CompiledScenario {
+		t.Helper()
+		cs, err := compiler.CompileScenario(&scenario.Scenario{
+			ID:   "body-regex",
+			When: scenario.WhenClause{Method: "POST", Path: "/api/events", Body: bc},
+		})
+		if err != nil {
+			t.Fatalf("CompileScenario failed: %v", err)
+		}
+		return cs
+	}
+	matches := func(cs *match.CompiledScenario, body string) bool {
+		req := &match.IncomingRequest{Method: "POST", Path: "/api/events", Body: []byte(body)}
+		return match.NewEvaluator().Evaluate(req, []*match.CompiledScenario{cs}).Matched != nil
+	}
+
+	regexOnly := compile(&scenario.BodyClause{ContentType: "json", Regex: `"type":\s*"order\.`})
+	if !matches(regexOnly, `{"type": "order.created"}`) {
+		t.Error("expected regex to match the raw body under a json content type")
+	}
+	if matches(regexOnly, `{"type": "user.created"}`) {
+		t.Error("expected regex mismatch")
+	}
+
+	combined := compile(&scenario.BodyClause{
+		ContentType: "json",
+		Regex:       `^\{`,
+		Conditions: []scenario.BodyCondition{
+			{Extractor: "$.type", Matcher: scenario.StringMatcher{Exact: "order.created"}},
+		},
+	})
+	if !matches(combined, `{"type":"order.created"}`) {
+		t.Error("expected match when regex and condition both match")
+	}
+	if matches(combined, `{"type":"order.cancelled"}`) {
+		t.Error("expected no match when the condition fails")
+	}
+	if matches(combined, ` {"type":"order.created"}`) {
+		t.Error("expected no match when the regex fails")
+	}
+
+	_, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:   "bad-regex",
+		When: scenario.WhenClause{Method: "POST", Path: "/api/events", Body: &scenario.BodyClause{Regex: "("}},
+	})
+	if err == nil {
+		t.Error("expected error for an invalid body regex")
+	}
+}
+
 func TestCompiler_XPathBody(t *testing.T) {
 	compiler := newTestCompiler(t)
 
@@ -593,6 +931,160 @@ func TestCompiler_JSONPathMissingField(t *testing.T) {
 	t.Error("body predicate not found")
 }
 
+func TestCompiler_JSONPathNumericRange(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	minAmount, maxAmount := 100.0, 500.0
+	s := &scenario.Scenario{
+		ID: "jsonpath-range",
+		When: scenario.WhenClause{
+			Method: "POST",
+			Path:   "/payments",
+			Body: &scenario.BodyClause{
+				ContentType: "json",
+				Conditions: []scenario.BodyCondition{
+					{Extractor: "$.amount", Min: &minAmount, Max: &maxAmount},
+				},
+			},
+		},
+		Response: scenario.Response{Status: 200},
+	}
+
+	cs, err := compiler.CompileScenario(s)
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{`{"amount": 250}`, true},
+		{`{"amount": 100}`, true},
+		{`{"amount": 500.0}`, true},
+		{`{"amount": 99.99}`, false},
+		{`{"amount": 501}`, false},
+		{`{"amount": "lots"}`, false},
+		{`{"amount": "300"}`, true},
+		{`{}`, false},
+	}
+
+	for _, p := range cs.Predicates {
+		if p.Field == "body:$.amount" {
+			for _, tt := range tests {
+				if got := p.Predicate(tt.body); got != tt.want {
+					t.Errorf("body %s: expected %v, got %v", tt.body, tt.want, got)
+				}
+			}
+			return
+		}
+	}
+	t.Error("body predicate not found")
+}
+
+func TestCompiler_BodyRangeMinAboveMax(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	minAmount, maxAmount := 10.0, 1.0
+	s := &scenario.Scenario{
+		ID: "jsonpath-bad-range",
+		When: scenario.WhenClause{
+			Method: "POST",
+			Path:   "/payments",
+			Body: &scenario.BodyClause{
+				ContentType: "json",
+				Conditions: []scenario.BodyCondition{
+					{Extractor: "$.amount", Min: &minAmount, Max: &maxAmount},
+				},
+			},
+		},
+		Response: scenario.Response{Status: 200},
+	}
+
+	if _, err := compiler.CompileScenario(s); err == nil {
+		t.Error("expected error when min is greater than max")
+	}
+}
+
+func TestCompiler_ProtobufBody(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	// Message {1: 150, 2: "Alice", 3: {1: "x"}} in wire format.
+	msg := "\x08\x96\x01" + "\x12\x05Alice" + "\x1a\x03\x0a\x01x"
+	// The same message inside a gRPC length-prefixed frame.
+	framed := "\x00\x00\x00\x00\x0f" + msg
+
+	tests := []struct {
+		extractor string
+		matcher   scenario.StringMatcher
+		body      string
+		want      bool
+	}{
+		{"1", scenario.StringMatcher{Exact: "150"}, msg, true},
+		{"2", scenario.StringMatcher{Exact: "Alice"}, msg, true},
+		{"2", scenario.StringMatcher{Exact: "Bob"}, msg, false},
+		{"3.1", scenario.StringMatcher{}, msg, true},
+		{"4", scenario.StringMatcher{}, msg, false},
+		{"2", scenario.StringMatcher{Pattern: "^Ali"}, framed, true},
+		{"1", scenario.StringMatcher{}, "not protobuf", false},
+	}
+
+	for _, tt := range tests {
+		s := &scenario.Scenario{
+			ID: "proto-body",
+			When: scenario.WhenClause{
+				Method: "POST",
+				Path:   "/grpc",
+				Body: &scenario.BodyClause{
+					ContentType: "protobuf",
+					Conditions:  []scenario.BodyCondition{{Extractor: tt.extractor, Matcher: tt.matcher}},
+				},
+			},
+			Response: scenario.Response{Status: 200},
+		}
+
+		cs, err := compiler.CompileScenario(s)
+		if err != nil {
+			t.Fatalf("CompileScenario failed: %v", err)
+		}
+
+		var found bool
+		for _, p := range cs.Predicates {
+			if p.Field == "body:"+tt.extractor {
+				found = true
+				if got := p.Predicate(tt.body); got != tt.want {
+					t.Errorf("extractor %q on %q: expected %v, got %v", tt.extractor, tt.body, tt.want, got)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("extractor %q: body predicate not found", tt.extractor)
+		}
+	}
+}
+
+func TestCompiler_ProtobufInvalidPath(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	for _, path := range []string{"$.name", "0", "1..2"} {
+		s := &scenario.Scenario{
+			ID: "proto-invalid",
+			When: scenario.WhenClause{
+				Method: "POST",
+				Path:   "/grpc",
+				Body: &scenario.BodyClause{
+					ContentType: "protobuf",
+					Conditions:  []scenario.BodyCondition{{Extractor: path}},
+				},
+			},
+			Response: scenario.Response{Status: 200},
+		}
+		if _, err := compiler.CompileScenario(s); err == nil {
+			t.Errorf("expected error for protobuf path %q", path)
+		}
+	}
+}
+
 func TestCompiler_XPathInvalidXML(t *testing.T) {
 	compiler := newTestCompiler(t)
 
@@ -1207,3 +1699,1350 @@ func TestCompiler_BodyFileWithTemplate(t *testing.T) {
 		t.Error("expected renderer for body_file + engine")
 	}
 }
+
+func TestCompiler_TemplatedBodyFile(t *testing.T) {
+	outside := t.TempDir()
+	dir := filepath.Join(outside, "root")
+	if err := os.MkdirAll(filepath.Join(dir, "fixtures"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"root/fixtures/1.json": `{"id": 1}`,
+		"root/fixtures/2.json": `{"id": 2, "path": "${pathParam('id')}"}`,
+		"secret.json":          `{"secret": true}`,
+	} {
+		if err := os.WriteFile(filepath.Join(outside, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	compiler, err := services.NewCompiler(dir, template.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	compile := func(engine string) *match.CompiledScenario {
+		t.Helper()
+		cs, err := compiler.CompileScenario(&scenario.Scenario{
+			ID:   "fixture-" + engine,
+			When: scenario.WhenClause{Method: "GET", Path: "/items/{id}"},
+			Response: scenario.Response{
+				BodyFile: "fixtures/${pathParam('id')}.json",
+				Engine:   engine,
+			},
+		})
+		if err != nil {
+			t.Fatalf("CompileScenario failed: %v", err)
+		}
+		return cs
+	}
+	render := func(cs *match.CompiledScenario, id string) (string, error) {
+		body, err := cs.Response.Renderer.Render(match.RenderContext{PathParams: map[string]string{"id": id}})
+		return string(body), err
+	}
+
+	static := compile("")
+	if static.Response.ContentType != "application/json" {
+		t.Errorf("expected content type from the path extension, got %q", static.Response.ContentType)
+	}
+	if static.Response.BodyFileMissingStatus != 404 {
+		t.Errorf("expected default missing status 404, got %d", static.Response.BodyFileMissingStatus)
+	}
+	if got, err := render(static, "1"); err != nil || got != `{"id": 1}` {
+		t.Errorf("expected fixture 1, got %q (%v)", got, err)
+	}
+	if got, err := render(static, "2"); err != nil || got != `{"id": 2, "path": "${pathParam('id')}"}` {
+		t.Errorf("expected raw fixture 2 without an engine, got %q (%v)", got, err)
+	}
+
+	templated := compile("expr")
+	if got, err := render(templated, "2"); err != nil || got != `{"id": 2, "path": "2"}` {
+		t.Errorf("expected rendered fixture 2, got %q (%v)", got, err)
+	}
+
+	for _, id := range []string{"3", "../../secret"} {
+		if _, err := render(static, id); !errors.Is(err, match.ErrBodyFileNotFound) {
+			t.Errorf("id %q: expected ErrBodyFileNotFound, got %v", id, err)
+		}
+	}
+
+	// Cached files are re-read when they change.
+	if err := os.WriteFile(filepath.Join(dir, "fixtures/1.json"), []byte(`{"id": "one"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := render(static, "1"); got != `{"id": "one"}` {
+		t.Errorf("expected updated fixture, got %q", got)
+	}
+}
+
+func TestCompiler_CreatedStatus(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus int
+		wantErr    bool
+	}{
+		{"defaults to 201", 0, 201, false},
+		{"keeps other 2xx", 202, 202, false},
+		{"rejects non-2xx", 302, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := compiler.CompileScenario(&scenario.Scenario{
+				ID:   "create",
+				When: scenario.WhenClause{Method: "POST", Path: "/orders"},
+				Response: scenario.Response{
+					Status:  tt.status,
+					Created: &scenario.Created{Location: "/orders/1"},
+				},
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompileScenario failed: %v", err)
+			}
+			if cs.Response.Status != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, cs.Response.Status)
+			}
+			location, err := cs.Response.Location.Render(match.RenderContext{})
+			if err != nil || string(location) != "/orders/1" {
+				t.Errorf("expected static location, got %q (%v)", location, err)
+			}
+		})
+	}
+}
+
+func TestCompiler_RateLimitResponse(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:   "limited",
+		When: scenario.WhenClause{Method: "GET", Path: "/api/limited"},
+		Policy: &scenario.Policy{
+			RateLimit: &scenario.RateLimit{
+				Rate:     1,
+				Burst:    1,
+				Response: &scenario.Response{Body: `{"error":"slow down"}`},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	resp := cs.Policy.RateLimit.Response
+	if resp == nil {
+		t.Fatal("expected compiled rate limit response")
+	}
+	if resp.Status != 429 {
+		t.Errorf("expected default status 429, got %d", resp.Status)
+	}
+	if string(resp.Body) != `{"error":"slow down"}` {
+		t.Errorf("unexpected body %q", resp.Body)
+	}
+}
+
+func TestCompiler_ContentLengthRange(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	minLen, maxLen := 10.0, 100.0
+	s := &scenario.Scenario{
+		ID: "content-length",
+		When: scenario.WhenClause{
+			Method:        "POST",
+			Path:          "/api/upload",
+			ContentLength: &scenario.NumericMatcher{Gte: &minLen, Lt: &maxLen},
+		},
+		Response: scenario.Response{Status: 200},
+	}
+
+	cs, err := compiler.CompileScenario(s)
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"10", true},
+		{"50", true},
+		{"99", true},
+		{"9", false},
+		{"100", false},
+		{"-1", false},
+		{"abc", false},
+	}
+
+	for _, p := range cs.Predicates {
+		if p.Field == "content_length" {
+			for _, tt := range tests {
+				if got := p.Predicate(tt.value); got != tt.want {
+					t.Errorf("content_length %q: got %v, want %v", tt.value, got, tt.want)
+				}
+			}
+			return
+		}
+	}
+	t.Error("content_length predicate not found")
+}
+
+func TestCompiler_BodyHash(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	payload := `{"order":42}`
+	sum := sha256.Sum256([]byte(payload))
+
+	s := &scenario.Scenario{
+		ID: "body-hash",
+		When: scenario.WhenClause{
+			Method: "POST",
+			Path:   "/api/orders",
+			BodyHash: &scenario.BodyHash{
+				Algorithm: "SHA256",
+				Expected:  strings.ToUpper(hex.EncodeToString(sum[:])),
+			},
+		},
+		Response: scenario.Response{Status: 200},
+	}
+
+	cs, err := compiler.CompileScenario(s)
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	evaluator := match.NewEvaluator()
+	matching := &match.IncomingRequest{Method: "POST", Path: "/api/orders", Body: []byte(payload)}
+	if result := evaluator.Evaluate(matching, []*match.CompiledScenario{cs}); result.Matched == nil {
+		t.Error("expected identical payload to match")
+	}
+
+	other := &match.IncomingRequest{Method: "POST", Path: "/api/orders", Body: []byte(`{"order":43}`)}
+	if result := evaluator.Evaluate(other, []*match.CompiledScenario{cs}); result.Matched != nil {
+		t.Error("expected different payload not to match")
+	}
+}
+
+func TestCompiler_BodyPrefix(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	jpeg := []byte("\xff\xd8\xff\xe0\x00\x10JFIF")
+
+	for _, prefix := range []scenario.BodyPrefix{
+		{Hex: "89 50 4E 47 0D 0A 1A 0A"},
+		{Base64: "iVBORw0KGgo="},
+	} {
+		s := &scenario.Scenario{
+			ID: "png-upload",
+			When: scenario.WhenClause{
+				Method:     "POST",
+				Path:       "/api/uploads",
+				BodyPrefix: &prefix,
+			},
+			Response: scenario.Response{Status: 201},
+		}
+		cs, err := compiler.CompileScenario(s)
+		if err != nil {
+			t.Fatalf("CompileScenario(%+v) failed: %v", prefix, err)
+		}
+
+		evaluator := match.NewEvaluator()
+		for _, tt := range []struct {
+			name  string
+			body  []byte
+			match bool
+		}{
+			{"png", png, true},
+			{"jpeg", jpeg, false},
+			{"short", png[:4], false},
+			{"empty", nil, false},
+		} {
+			req := &match.IncomingRequest{Method: "POST", Path: "/api/uploads", Body: tt.body}
+			if got := evaluator.Evaluate(req, []*match.CompiledScenario{cs}).Matched != nil; got != tt.match {
+				t.Errorf("%+v %s: expected match=%v, got %v", prefix, tt.name, tt.match, got)
+			}
+		}
+	}
+}
+
+func TestCompiler_BodyPrefixInvalid(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	for _, prefix := range []scenario.BodyPrefix{
+		{},
+		{Hex: "8950", Base64: "iVA="},
+		{Hex: "zz"},
+		{Base64: "not base64!"},
+	} {
+		s := &scenario.Scenario{
+			ID:       "bad-prefix",
+			When:     scenario.WhenClause{Method: "POST", Path: "/x", BodyPrefix: &prefix},
+			Response: scenario.Response{Status: 200},
+		}
+		if _, err := compiler.CompileScenario(s); err == nil {
+			t.Errorf("%+v: expected compile error", prefix)
+		}
+	}
+}
+
+func TestCompiler_BodyHashInvalid(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	tests := []struct {
+		name string
+		hash scenario.BodyHash
+	}{
+		{"unknown algorithm", scenario.BodyHash{Algorithm: "crc32", Expected: "00"}},
+		{"bad hex", scenario.BodyHash{Algorithm: "sha256", Expected: "not-hex"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &scenario.Scenario{
+				ID:       "bad-hash",
+				When:     scenario.WhenClause{Method: "POST", Path: "/x", BodyHash: &tt.hash},
+				Response: scenario.Response{Status: 200},
+			}
+			if _, err := compiler.CompileScenario(s); err == nil {
+				t.Error("expected compile error")
+			}
+		})
+	}
+}
+
+func TestCompiler_Charset(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	s := &scenario.Scenario{
+		ID:   "latin1",
+		When: scenario.WhenClause{Method: "GET", Path: "/latin1"},
+		Response: scenario.Response{
+			Status:  200,
+			Body:    "café",
+			Charset: "iso-8859-1",
+		},
+	}
+
+	cs, err := compiler.CompileScenario(s)
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	if cs.Response.Charset != "ISO-8859-1" {
+		t.Errorf("expected canonical charset ISO-8859-1, got %q", cs.Response.Charset)
+	}
+	if cs.Response.Encoder == nil {
+		t.Fatal("expected encoder")
+	}
+
+	encoded, err := cs.Response.Encoder(cs.Response.Body)
+	if err != nil {
+		t.Fatalf("Encoder failed: %v", err)
+	}
+	want := []byte{'c', 'a', 'f', 0xE9}
+	if string(encoded) != string(want) {
+		t.Errorf("expected %x, got %x", want, encoded)
+	}
+}
+
+func TestCompiler_UnsupportedCharset(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	s := &scenario.Scenario{
+		ID:       "bad-charset",
+		When:     scenario.WhenClause{Method: "GET", Path: "/bad"},
+		Response: scenario.Response{Status: 200, Body: "x", Charset: "klingon-8"},
+	}
+
+	if _, err := compiler.CompileScenario(s); err == nil {
+		t.Error("expected error for unsupported charset")
+	}
+}
+
+func TestCompiler_LoadBalanceWeightDefaultsToPriority(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	tests := []struct {
+		name     string
+		priority int
+		weight   int
+		want     int
+	}{
+		{"explicit weight", 10, 3, 3},
+		{"defaults to priority", 7, 0, 7},
+		{"non-positive priority floors at 1", 0, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := compiler.CompileScenario(&scenario.Scenario{
+				ID:       "lb",
+				Priority: tt.priority,
+				When:     scenario.WhenClause{Method: "GET", Path: "/lb"},
+				Policy:   &scenario.Policy{LoadBalance: &scenario.LoadBalance{Weight: tt.weight}},
+			})
+			if err != nil {
+				t.Fatalf("CompileScenario failed: %v", err)
+			}
+			if cs.Policy.LoadBalance == nil || cs.Policy.LoadBalance.Weight != tt.want {
+				t.Errorf("expected weight %d, got %+v", tt.want, cs.Policy.LoadBalance)
+			}
+		})
+	}
+}
+
+func TestCompiler_InvalidCanonicalizeBody(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	s := &scenario.Scenario{
+		ID:   "bad-canonical",
+		When: scenario.WhenClause{Method: "POST", Path: "/echo"},
+		Response: scenario.Response{
+			Status:           200,
+			Body:             "${canonicalBody()}",
+			Engine:           "expr",
+			CanonicalizeBody: "sorted",
+		},
+	}
+
+	if _, err := compiler.CompileScenario(s); err == nil {
+		t.Error("expected error for unsupported canonicalize_body")
+	}
+}
+
+func TestCompiler_MethodToken(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	for _, method := range []string{"PROPFIND", "PURGE", "X-CUSTOM"} {
+		s := &scenario.Scenario{
+			ID:       "m",
+			When:     scenario.WhenClause{Method: method, Path: "/x"},
+			Response: scenario.Response{Status: 200},
+		}
+		if _, err := compiler.CompileScenario(s); err != nil {
+			t.Errorf("method %q: unexpected error: %v", method, err)
+		}
+	}
+
+	for _, method := range []string{"GET POST", "BAD:METHOD", "(GET)"} {
+		s := &scenario.Scenario{
+			ID:       "m",
+			When:     scenario.WhenClause{Method: method, Path: "/x"},
+			Response: scenario.Response{Status: 200},
+		}
+		if _, err := compiler.CompileScenario(s); err == nil {
+			t.Errorf("method %q: expected error", method)
+		}
+	}
+}
+
+func TestCompiler_StaticMount(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "assets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "file.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	compiler, err := services.NewCompiler(root, nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:     "cdn",
+		Static: &scenario.StaticMount{Dir: "assets", PathPrefix: "/cdn/"},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	if cs.Static == nil {
+		t.Fatal("expected static mount")
+	}
+	if cs.Static.PathPrefix != "/cdn" {
+		t.Errorf("expected trailing slash trimmed, got %q", cs.Static.PathPrefix)
+	}
+	if cs.Static.Dir != filepath.Join(compilerRoot(t, root), "assets") {
+		t.Errorf("unexpected dir %q", cs.Static.Dir)
+	}
+	if cs.PathKey != "GET:/cdn/*" {
+		t.Errorf("unexpected path key %q", cs.PathKey)
+	}
+
+	invalid := []scenario.StaticMount{
+		{Dir: "../outside", PathPrefix: "/cdn"},
+		{Dir: "/etc", PathPrefix: "/cdn"},
+		{Dir: "missing", PathPrefix: "/cdn"},
+		{Dir: "file.txt", PathPrefix: "/cdn"},
+		{Dir: "assets", PathPrefix: "/"},
+		{Dir: "assets", PathPrefix: "cdn"},
+	}
+	for _, st := range invalid {
+		if _, err := compiler.CompileScenario(&scenario.Scenario{ID: "bad", Static: &st}); err == nil {
+			t.Errorf("expected error for %+v", st)
+		}
+	}
+}
+
+func compilerRoot(t *testing.T, root string) string {
+	t.Helper()
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return abs
+}
+
+func TestCompiler_InternsIdenticalStaticBodies(t *testing.T) {
+	dir := t.TempDir()
+	fixture := strings.Repeat(`{"item":"fixture"}`, 1024)
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(fixture), 0o644); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+	}
+
+	compiler, err := services.NewCompiler(dir, nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+
+	compile := func(id string, resp scenario.Response) []byte {
+		t.Helper()
+		cs, err := compiler.CompileScenario(&scenario.Scenario{
+			ID:       id,
+			When:     scenario.WhenClause{Method: "GET", Path: "/" + id},
+			Response: resp,
+		})
+		if err != nil {
+			t.Fatalf("CompileScenario(%s) failed: %v", id, err)
+		}
+		return cs.Response.Body
+	}
+
+	fromA := compile("a", scenario.Response{BodyFile: "a.json"})
+	fromB := compile("b", scenario.Response{BodyFile: "b.json"})
+	inline := compile("inline", scenario.Response{Body: fixture})
+	other := compile("other", scenario.Response{Body: "different"})
+
+	if unsafe.SliceData(fromA) != unsafe.SliceData(fromB) || unsafe.SliceData(fromA) != unsafe.SliceData(inline) {
+		t.Error("expected identical bodies to share one backing array")
+	}
+	if unsafe.SliceData(other) == unsafe.SliceData(fromA) {
+		t.Error("expected different bodies to have separate storage")
+	}
+	if string(fromB) != fixture {
+		t.Error("interned body content changed")
+	}
+
+	compiler.ResetBodyCache()
+	if unsafe.SliceData(compile("after-reset", scenario.Response{Body: fixture})) == unsafe.SliceData(fromA) {
+		t.Error("expected reset to drop interned bodies")
+	}
+}
+
+func TestCompiler_QueryExists(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	compile := func(id string, m scenario.StringMatcher) *match.CompiledScenario {
+		t.Helper()
+		cs, err := compiler.CompileScenario(&scenario.Scenario{
+			ID: id,
+			When: scenario.WhenClause{
+				Method: "GET",
+				Path:   "/items",
+				Query:  map[string]scenario.StringMatcher{"debug": m},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CompileScenario failed: %v", err)
+		}
+		return cs
+	}
+
+	exists := compile("exists", scenario.StringMatcher{Exists: true})
+	empty := compile("empty", scenario.StringMatcher{Pattern: "^$"})
+	evaluator := match.NewEvaluator()
+
+	tests := []struct {
+		name      string
+		query     map[string][]string
+		wantExist bool
+		wantEmpty bool
+	}{
+		{"?debug", map[string][]string{"debug": {""}}, true, true},
+		{"?debug=1", map[string][]string{"debug": {"1"}}, true, false},
+		{"absent", map[string][]string{"other": {"x"}}, false, false},
+		{"no query", nil, false, false},
+	}
+
+	for _, tt := range tests {
+		req := &match.IncomingRequest{Method: "GET", Path: "/items", Query: tt.query}
+		if got := evaluator.Evaluate(req, []*match.CompiledScenario{exists}).Matched != nil; got != tt.wantExist {
+			t.Errorf("%s: exists matcher expected %v, got %v", tt.name, tt.wantExist, got)
+		}
+		if got := evaluator.Evaluate(req, []*match.CompiledScenario{empty}).Matched != nil; got != tt.wantEmpty {
+			t.Errorf("%s: empty-value matcher expected %v, got %v", tt.name, tt.wantEmpty, got)
+		}
+	}
+
+	result := evaluator.Evaluate(&match.IncomingRequest{Method: "GET", Path: "/items"}, []*match.CompiledScenario{exists})
+	if reason := result.Candidates[0].FailedReason; reason != "field not present" {
+		t.Errorf("expected missing-field reason, got %q", reason)
+	}
+}
+
+func TestCompiler_QueryValueMatchers(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	compile := func(id string, m scenario.StringMatcher) *match.CompiledScenario {
+		t.Helper()
+		cs, err := compiler.CompileScenario(&scenario.Scenario{
+			ID: id,
+			When: scenario.WhenClause{
+				Method: "GET",
+				Path:   "/search",
+				Query:  map[string]scenario.StringMatcher{"type": m},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CompileScenario failed: %v", err)
+		}
+		return cs
+	}
+
+	exact := compile("exact", scenario.StringMatcher{Exact: "fuzzy"})
+	regex := compile("regex", scenario.StringMatcher{Pattern: "^ex"})
+	empty := compile("empty", scenario.StringMatcher{})
+	evaluator := match.NewEvaluator()
+
+	tests := []struct {
+		name                string
+		query               map[string][]string
+		exact, regex, empty bool
+	}{
+		{"?type=fuzzy", map[string][]string{"type": {"fuzzy"}}, true, false, true},
+		{"?type=exact", map[string][]string{"type": {"exact"}}, false, true, true},
+		{"repeated uses first", map[string][]string{"type": {"fuzzy", "exact"}}, true, false, true},
+		{"missing", nil, false, false, true},
+	}
+
+	for _, tt := range tests {
+		req := &match.IncomingRequest{Method: "GET", Path: "/search", Query: tt.query}
+		for _, c := range []struct {
+			cs   *match.CompiledScenario
+			want bool
+		}{{exact, tt.exact}, {regex, tt.regex}, {empty, tt.empty}} {
+			if got := evaluator.Evaluate(req, []*match.CompiledScenario{c.cs}).Matched != nil; got != c.want {
+				t.Errorf("%s: %s matcher expected %v, got %v", tt.name, c.cs.ID, c.want, got)
+			}
+		}
+	}
+}
+
+func TestCompiler_NegatedStringMatcher(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID: "not-expired",
+		When: scenario.WhenClause{
+			Method:  "POST",
+			Path:    "/orders",
+			Headers: map[string]scenario.StringMatcher{"Authorization": {Exact: "Bearer expired", Negated: true}},
+			Query:   map[string]scenario.StringMatcher{"mode": {Exact: "dry-run", Negated: true}},
+			Body: &scenario.BodyClause{
+				ContentType: "json",
+				Not: &scenario.BodyClause{
+					ContentType: "json",
+					Conditions: []scenario.BodyCondition{
+						{Extractor: "$.status", Matcher: scenario.StringMatcher{Exact: "draft", Negated: true}},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	evaluator := match.NewEvaluator()
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		query   map[string][]string
+		body    string
+		want    bool
+	}{
+		{"valid token", map[string]string{"Authorization": "Bearer good"}, map[string][]string{"mode": {"live"}}, `{"status":"draft"}`, true},
+		{"missing header", nil, map[string][]string{"mode": {"live"}}, `{"status":"draft"}`, true},
+		{"expired token", map[string]string{"Authorization": "Bearer expired"}, map[string][]string{"mode": {"live"}}, `{"status":"draft"}`, false},
+		{"excluded query value", nil, map[string][]string{"mode": {"dry-run"}}, `{"status":"draft"}`, false},
+		{"missing query", nil, nil, `{"status":"draft"}`, false},
+		{"body not of negation", nil, map[string][]string{"mode": {"live"}}, `{"status":"sent"}`, false},
+	}
+	for _, tt := range tests {
+		req := &match.IncomingRequest{Method: "POST", Path: "/orders", Headers: tt.headers, Query: tt.query, Body: []byte(tt.body)}
+		if got := evaluator.Evaluate(req, []*match.CompiledScenario{cs}).Matched != nil; got != tt.want {
+			t.Errorf("%s: expected match %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCompiler_BodyAllRegex(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	compile := func(id string, allowEmpty bool) *match.CompiledScenario {
+		t.Helper()
+		cs, err := compiler.CompileScenario(&scenario.Scenario{
+			ID: id,
+			When: scenario.WhenClause{
+				Method: "POST",
+				Path:   "/posts",
+				Body: &scenario.BodyClause{
+					ContentType: "json",
+					Conditions: []scenario.BodyCondition{
+						{Extractor: "$.tags", AllRegex: "^[a-z]+$", AllowEmpty: allowEmpty},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CompileScenario failed: %v", err)
+		}
+		return cs
+	}
+
+	strict := compile("strict", false)
+	lenient := compile("lenient", true)
+	evaluator := match.NewEvaluator()
+
+	tests := []struct {
+		name            string
+		body            string
+		strict, lenient bool
+	}{
+		{"all match", `{"tags":["go","mock"]}`, true, true},
+		{"one mismatch", `{"tags":["go","Mock"]}`, false, false},
+		{"non-string element", `{"tags":["go",42]}`, false, false},
+		{"empty array", `{"tags":[]}`, false, true},
+		{"not an array", `{"tags":"go"}`, false, false},
+		{"missing", `{}`, false, false},
+	}
+	for _, tt := range tests {
+		req := &match.IncomingRequest{Method: "POST", Path: "/posts", Body: []byte(tt.body)}
+		for _, c := range []struct {
+			cs   *match.CompiledScenario
+			want bool
+		}{{strict, tt.strict}, {lenient, tt.lenient}} {
+			if got := evaluator.Evaluate(req, []*match.CompiledScenario{c.cs}).Matched != nil; got != c.want {
+				t.Errorf("%s: %s expected %v, got %v", tt.name, c.cs.ID, c.want, got)
+			}
+		}
+	}
+
+	_, err := compiler.CompileScenario(&scenario.Scenario{
+		ID: "xml",
+		When: scenario.WhenClause{
+			Method: "POST",
+			Path:   "/posts",
+			Body: &scenario.BodyClause{
+				ContentType: "xml",
+				Conditions:  []scenario.BodyCondition{{Extractor: "//tag", AllRegex: "^[a-z]+$"}},
+			},
+		},
+	})
+	if err == nil {
+		t.Error("expected error for all_regex on a non-JSON body")
+	}
+}
+
+func TestCompiler_QueryAbsent(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID: "absent",
+		When: scenario.WhenClause{
+			Method: "GET",
+			Path:   "/items",
+			Query:  map[string]scenario.StringMatcher{"cursor": {Absent: true}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	evaluator := match.NewEvaluator()
+
+	tests := []struct {
+		name  string
+		query map[string][]string
+		want  bool
+	}{
+		{"?cursor=abc", map[string][]string{"cursor": {"abc"}}, false},
+		{"?cursor", map[string][]string{"cursor": {""}}, false},
+		{"other param", map[string][]string{"page": {"2"}}, true},
+		{"no query", nil, true},
+	}
+
+	for _, tt := range tests {
+		req := &match.IncomingRequest{Method: "GET", Path: "/items", Query: tt.query}
+		if got := evaluator.Evaluate(req, []*match.CompiledScenario{cs}).Matched != nil; got != tt.want {
+			t.Errorf("%s: expected match %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCompiler_QueryArray(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	compile := func(id string, m scenario.QueryArrayMatcher) *match.CompiledScenario {
+		t.Helper()
+		cs, err := compiler.CompileScenario(&scenario.Scenario{
+			ID: id,
+			When: scenario.WhenClause{
+				Method:      "GET",
+				Path:        "/items",
+				QueryArrays: map[string]scenario.QueryArrayMatcher{"id": m},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CompileScenario failed: %v", err)
+		}
+		return cs
+	}
+
+	two, zero := 2.0, 0.0
+	contains := compile("contains", scenario.QueryArrayMatcher{Contains: []string{"2", "3"}})
+	atLeastTwo := compile("count", scenario.QueryArrayMatcher{Count: &scenario.NumericMatcher{Gte: &two}})
+	none := compile("none", scenario.QueryArrayMatcher{Count: &scenario.NumericMatcher{Eq: &zero}})
+	evaluator := match.NewEvaluator()
+
+	tests := []struct {
+		name         string
+		query        map[string][]string
+		wantContains bool
+		wantCount    bool
+		wantNone     bool
+	}{
+		{"?id=1&id=2&id=3", map[string][]string{"id": {"1", "2", "3"}}, true, true, false},
+		{"?id=3&id=2", map[string][]string{"id": {"3", "2"}}, true, true, false},
+		{"?id=2", map[string][]string{"id": {"2"}}, false, false, false},
+		{"?id=1&id=1", map[string][]string{"id": {"1", "1"}}, false, true, false},
+		{"no id", map[string][]string{"other": {"x"}}, false, false, true},
+	}
+
+	for _, tt := range tests {
+		req := &match.IncomingRequest{Method: "GET", Path: "/items", Query: tt.query}
+		for _, c := range []struct {
+			cs   *match.CompiledScenario
+			want bool
+		}{{contains, tt.wantContains}, {atLeastTwo, tt.wantCount}, {none, tt.wantNone}} {
+			if got := evaluator.Evaluate(req, []*match.CompiledScenario{c.cs}).Matched != nil; got != c.want {
+				t.Errorf("%s: %s matcher expected %v, got %v", tt.name, c.cs.ID, c.want, got)
+			}
+		}
+	}
+
+	if _, err := compiler.CompileScenario(&scenario.Scenario{
+		ID: "empty",
+		When: scenario.WhenClause{
+			Method:      "GET",
+			Path:        "/items",
+			QueryArrays: map[string]scenario.QueryArrayMatcher{"id": {}},
+		},
+	}); err == nil {
+		t.Error("expected error for a query array matcher without contains or count")
+	}
+}
+
+func TestCompiler_HeaderPresence(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	compile := func(id string, m scenario.StringMatcher) *match.CompiledScenario {
+		t.Helper()
+		cs, err := compiler.CompileScenario(&scenario.Scenario{
+			ID: id,
+			When: scenario.WhenClause{
+				Method:  "GET",
+				Path:    "/items",
+				Headers: map[string]scenario.StringMatcher{"authorization": m},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CompileScenario failed: %v", err)
+		}
+		return cs
+	}
+
+	absent := compile("absent", scenario.StringMatcher{Absent: true})
+	exists := compile("exists", scenario.StringMatcher{Exists: true})
+	empty := compile("empty", scenario.StringMatcher{Pattern: "^$"})
+	evaluator := match.NewEvaluator()
+
+	tests := []struct {
+		name                  string
+		headers               map[string]string
+		absent, exists, empty bool
+	}{
+		{"missing", nil, true, false, true},
+		{"present but empty", map[string]string{"Authorization": ""}, false, true, true},
+		{"present", map[string]string{"Authorization": "Bearer x"}, false, true, false},
+	}
+	for _, tt := range tests {
+		req := &match.IncomingRequest{Method: "GET", Path: "/items", Headers: tt.headers}
+		for _, c := range []struct {
+			cs   *match.CompiledScenario
+			want bool
+		}{{absent, tt.absent}, {exists, tt.exists}, {empty, tt.empty}} {
+			if got := evaluator.Evaluate(req, []*match.CompiledScenario{c.cs}).Matched != nil; got != c.want {
+				t.Errorf("%s: %s matcher expected %v, got %v", tt.name, c.cs.ID, c.want, got)
+			}
+		}
+	}
+}
+
+func TestCompiler_SwitchErrors(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	tests := []struct {
+		name string
+		sw   *scenario.ResponseSwitch
+	}{
+		{"missing on", &scenario.ResponseSwitch{Cases: map[string]scenario.Response{"a": {}}}},
+		{"invalid JSONPath", &scenario.ResponseSwitch{On: "$[", Cases: map[string]scenario.Response{"a": {}}}},
+		{"nested switch", &scenario.ResponseSwitch{On: "$.type", Cases: map[string]scenario.Response{
+			"a": {Switch: &scenario.ResponseSwitch{On: "$.sub"}},
+		}}},
+		{"bad case", &scenario.ResponseSwitch{On: "$.type", Cases: map[string]scenario.Response{
+			"a": {CanonicalizeBody: "sideways"},
+		}}},
+	}
+
+	for _, tt := range tests {
+		_, err := compiler.CompileScenario(&scenario.Scenario{
+			ID:       "switch",
+			When:     scenario.WhenClause{Method: "POST", Path: "/events"},
+			Response: scenario.Response{Switch: tt.sw},
+		})
+		if err == nil {
+			t.Errorf("%s: expected compile error", tt.name)
+		}
+	}
+}
+
+func TestCompiler_CacheHeaders(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	tests := []struct {
+		cache   scenario.Cache
+		want    string
+		wantErr bool
+	}{
+		{scenario.Cache{MaxAge: 3600, Visibility: "public", Immutable: true}, "public, max-age=3600, immutable", false},
+		{scenario.Cache{MaxAge: 60, Visibility: "private"}, "private, max-age=60", false},
+		{scenario.Cache{}, "max-age=0", false},
+		{scenario.Cache{MaxAge: 60, Visibility: "shared"}, "", true},
+		{scenario.Cache{MaxAge: -1}, "", true},
+	}
+
+	for _, tt := range tests {
+		cache := tt.cache
+		cs, err := compiler.CompileScenario(&scenario.Scenario{
+			ID:       "cache",
+			When:     scenario.WhenClause{Method: "GET", Path: "/asset"},
+			Response: scenario.Response{Status: 200, Cache: &cache},
+		})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%+v: expected error", tt.cache)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%+v: CompileScenario failed: %v", tt.cache, err)
+		}
+		if cs.Response.Cache.CacheControl != tt.want {
+			t.Errorf("%+v: expected %q, got %q", tt.cache, tt.want, cs.Response.Cache.CacheControl)
+		}
+		if cs.Response.Cache.MaxAge != time.Duration(tt.cache.MaxAge)*time.Second {
+			t.Errorf("%+v: unexpected max age %v", tt.cache, cs.Response.Cache.MaxAge)
+		}
+	}
+}
+
+func TestCompiler_InvalidHostPattern(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	for _, host := range []string{"api..example.com", "{a}.{a}.example.com", "api-*.example.com", "{1x}.example.com"} {
+		_, err := compiler.CompileScenario(&scenario.Scenario{
+			ID:   "host",
+			When: scenario.WhenClause{Method: "GET", Path: "/", Host: host},
+		})
+		if err == nil {
+			t.Errorf("expected error for host pattern %q", host)
+		}
+	}
+}
+
+func TestCompiler_TruncateAtBytesNegative(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	_, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:       "truncate",
+		When:     scenario.WhenClause{Method: "GET", Path: "/download"},
+		Response: scenario.Response{Status: 200, Body: "data", TruncateAtBytes: -1},
+	})
+	if err == nil {
+		t.Fatal("expected error for negative truncate_at_bytes")
+	}
+}
+
+func TestCompiler_Compression(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:       "compressed",
+		When:     scenario.WhenClause{Method: "GET", Path: "/items"},
+		Response: scenario.Response{Status: 200, Body: "data", Compression: []string{"BR", "gzip", "br"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cs.Response.Compression; !slices.Equal(got, []string{"br", "gzip"}) {
+		t.Errorf("expected [br gzip], got %v", got)
+	}
+
+	_, err = compiler.CompileScenario(&scenario.Scenario{
+		ID:       "deflate",
+		When:     scenario.WhenClause{Method: "GET", Path: "/items"},
+		Response: scenario.Response{Status: 200, Body: "data", Compression: []string{"deflate"}},
+	})
+	if err == nil {
+		t.Fatal("expected error for unsupported compression")
+	}
+}
+
+func TestCompiler_RawRejectsBodyRewrites(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:       "broken",
+		When:     scenario.WhenClause{Method: "GET", Path: "/broken"},
+		Response: scenario.Response{Status: 200, Body: `{"a":}`, Raw: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cs.Response.Raw || string(cs.Response.Body) != `{"a":}` {
+		t.Errorf("expected raw body to be kept verbatim, got %q", cs.Response.Body)
+	}
+
+	for name, resp := range map[string]scenario.Response{
+		"engine":     {Body: "${ 1 }", Engine: "expr", Raw: true},
+		"omit_nulls": {Body: `{"a":null}`, OmitNulls: true, Raw: true},
+		"charset":    {Body: "x", Charset: "ISO-8859-1", Raw: true},
+	} {
+		_, err := compiler.CompileScenario(&scenario.Scenario{
+			ID:       "raw-" + name,
+			When:     scenario.WhenClause{Method: "GET", Path: "/broken"},
+			Response: resp,
+		})
+		if err == nil {
+			t.Errorf("expected error combining raw with %s", name)
+		}
+	}
+}
+
+func TestCompiler_Fault(t *testing.T) {
+	compiler := newTestCompiler(t)
+	compile := func(f *scenario.Fault) (*match.CompiledScenario, error) {
+		return compiler.CompileScenario(&scenario.Scenario{
+			ID:       "flaky",
+			When:     scenario.WhenClause{Method: "GET", Path: "/flaky"},
+			Response: scenario.Response{Status: 200},
+			Policy:   &scenario.Policy{Fault: f},
+		})
+	}
+
+	cs, err := compile(&scenario.Fault{Type: "empty_response"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f := cs.Policy.Fault; f.Type != match.FaultEmptyResponse || f.Rate != 1 {
+		t.Errorf("expected empty_response at rate 1, got %+v", f)
+	}
+
+	if _, err := compile(&scenario.Fault{Type: "timeout"}); err == nil {
+		t.Error("expected error for unsupported fault type")
+	}
+	if _, err := compile(&scenario.Fault{Type: "reset_connection", Rate: 1.5}); err == nil {
+		t.Error("expected error for rate above 1")
+	}
+}
+
+func TestCompiler_PaginationTotalItemsSource(t *testing.T) {
+	compiler := newTestCompiler(t)
+	compile := func(src string) (*match.CompiledScenario, error) {
+		return compiler.CompileScenario(&scenario.Scenario{
+			ID:       "paged",
+			When:     scenario.WhenClause{Method: "GET", Path: "/items"},
+			Response: scenario.Response{Status: 200, Body: `{"items": []}`},
+			Policy: &scenario.Policy{Pagination: &scenario.Pagination{
+				DataPath: "$.items", DefaultSize: 10, MaxSize: 100, TotalItemsSource: src,
+			}},
+		})
+	}
+
+	cs, err := compile("50")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cs.Policy.Pagination.TotalItems; got == nil || *got != 50 {
+		t.Errorf("expected fixed total 50, got %v", got)
+	}
+
+	cs, err = compile("$.meta.total")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cs.Policy.Pagination.TotalItemsPath; got != "$.meta.total" {
+		t.Errorf("expected total path, got %q", got)
+	}
+
+	if _, err := compile("lots"); err == nil {
+		t.Error("expected error for invalid total_items_source")
+	}
+}
+
+func TestCompiler_WhenExpr(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	s := &scenario.Scenario{
+		ID: "ordered-range",
+		When: scenario.WhenClause{
+			Method: "POST",
+			Path:   "/api/bookings",
+			Expr:   "json('$.start') < json('$.end')",
+		},
+		Response: scenario.Response{Status: 200},
+	}
+	cs, err := compiler.CompileScenario(s)
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		match bool
+	}{
+		{"valid numeric ordering", `{"start":5,"end":10}`, true},
+		{"invalid numeric ordering", `{"start":10,"end":5}`, false},
+		{"valid date ordering", `{"start":"2025-01-01","end":"2025-02-01"}`, true},
+		{"invalid date ordering", `{"start":"2025-03-01","end":"2025-02-01"}`, false},
+		{"missing field", `{"start":5}`, false},
+		{"not json", `start=5&end=10`, false},
+	}
+
+	evaluator := match.NewEvaluator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &match.IncomingRequest{Method: "POST", Path: "/api/bookings", Body: []byte(tt.body)}
+			result := evaluator.Evaluate(req, []*match.CompiledScenario{cs})
+			if got := result.Matched != nil; got != tt.match {
+				t.Errorf("expected match=%v, got %v", tt.match, got)
+			}
+		})
+	}
+}
+
+func TestCompiler_WhenExprInvalid(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	for _, src := range []string{"json('$.a') <", "body()"} {
+		s := &scenario.Scenario{
+			ID:       "bad-expr",
+			When:     scenario.WhenClause{Method: "POST", Path: "/x", Expr: src},
+			Response: scenario.Response{Status: 200},
+		}
+		if _, err := compiler.CompileScenario(s); err == nil {
+			t.Errorf("%q: expected compile error", src)
+		}
+	}
+}
+
+func TestCompiler_Generate(t *testing.T) {
+	compile := func(seed uint64) []map[string]any {
+		t.Helper()
+		compiler := newTestCompiler(t)
+		compiler.SetRandomSource(rand.New(rand.NewPCG(seed, seed)))
+		s := &scenario.Scenario{
+			ID:   "users",
+			When: scenario.WhenClause{Method: "GET", Path: "/users"},
+			Response: scenario.Response{Generate: &scenario.Generate{
+				Count: 5,
+				Fields: map[string]string{
+					"id": "uuid", "n": "seq", "name": "name", "email": "email",
+					"age": "int", "score": "float", "active": "bool", "joined": "date",
+				},
+			}},
+		}
+		cs, err := compiler.CompileScenario(s)
+		if err != nil {
+			t.Fatalf("CompileScenario failed: %v", err)
+		}
+		if cs.Response.ContentType != "application/json" {
+			t.Errorf("expected application/json, got %q", cs.Response.ContentType)
+		}
+		var items []map[string]any
+		if err := json.Unmarshal(cs.Response.Body, &items); err != nil {
+			t.Fatalf("invalid JSON body: %v", err)
+		}
+		return items
+	}
+
+	items := compile(1)
+	if len(items) != 5 {
+		t.Fatalf("expected 5 items, got %d", len(items))
+	}
+	uuidRe := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for i, item := range items {
+		if id, _ := item["id"].(string); !uuidRe.MatchString(id) {
+			t.Errorf("item %d: expected UUID id, got %v", i, item["id"])
+		}
+		if item["n"] != float64(i+1) {
+			t.Errorf("item %d: expected seq %d, got %v", i, i+1, item["n"])
+		}
+		if name, _ := item["name"].(string); !strings.Contains(name, " ") {
+			t.Errorf("item %d: expected full name, got %v", i, item["name"])
+		}
+		if email, _ := item["email"].(string); !strings.HasSuffix(email, "@example.com") {
+			t.Errorf("item %d: expected email, got %v", i, item["email"])
+		}
+		if _, ok := item["age"].(float64); !ok {
+			t.Errorf("item %d: expected numeric age, got %T", i, item["age"])
+		}
+		if _, ok := item["score"].(float64); !ok {
+			t.Errorf("item %d: expected numeric score, got %T", i, item["score"])
+		}
+		if _, ok := item["active"].(bool); !ok {
+			t.Errorf("item %d: expected bool active, got %T", i, item["active"])
+		}
+		if joined, _ := item["joined"].(string); len(joined) != len("2006-01-02") {
+			t.Errorf("item %d: expected date, got %v", i, item["joined"])
+		}
+	}
+
+	again := compile(1)
+	if fmt.Sprint(again) != fmt.Sprint(items) {
+		t.Error("expected the same seed to generate the same data")
+	}
+}
+
+func TestCompiler_GenerateInvalid(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	for _, resp := range []scenario.Response{
+		{Generate: &scenario.Generate{Count: 1, Fields: map[string]string{"x": "zodiac"}}},
+		{Generate: &scenario.Generate{Count: -1, Fields: map[string]string{"x": "int"}}},
+		{Generate: &scenario.Generate{Count: 1}},
+		{Body: "[]", Generate: &scenario.Generate{Count: 1, Fields: map[string]string{"x": "int"}}},
+	} {
+		s := &scenario.Scenario{
+			ID:       "bad-generate",
+			When:     scenario.WhenClause{Method: "GET", Path: "/x"},
+			Response: resp,
+		}
+		if _, err := compiler.CompileScenario(s); err == nil {
+			t.Errorf("%+v: expected compile error", resp.Generate)
+		}
+	}
+}
+
+func TestCompiler_BodyKeyOrder(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	compile := func(ko scenario.KeyOrder) *match.CompiledScenario {
+		t.Helper()
+		cs, err := compiler.CompileScenario(&scenario.Scenario{
+			ID:   "key-order",
+			When: scenario.WhenClause{Method: "POST", Path: "/api/sign", Body: &scenario.BodyClause{KeyOrder: &ko}},
+		})
+		if err != nil {
+			t.Fatalf("CompileScenario failed: %v", err)
+		}
+		return cs
+	}
+	matches := func(cs *match.CompiledScenario, body string) bool {
+		req := &match.IncomingRequest{Method: "POST", Path: "/api/sign", Body: []byte(body)}
+		return match.NewEvaluator().Evaluate(req, []*match.CompiledScenario{cs}).Matched != nil
+	}
+
+	strict := compile(scenario.KeyOrder{Keys: []string{"id", "amount", "currency"}})
+	loose := compile(scenario.KeyOrder{Keys: []string{"id", "amount"}, AllowExtra: true})
+
+	tests := []struct {
+		name   string
+		body   string
+		strict bool
+		loose  bool
+	}{
+		{"exact order", `{"id":1,"amount":{"v":[1,2]},"currency":"EUR"}`, true, true},
+		{"scrambled", `{"amount":5,"id":1,"currency":"EUR"}`, false, false},
+		{"extra key", `{"id":1,"note":"x","amount":5,"currency":"EUR"}`, false, true},
+		{"missing key", `{"id":1,"amount":5}`, false, true},
+		{"repeated key", `{"id":1,"amount":5,"id":2}`, false, false},
+		{"array body", `[{"id":1}]`, false, false},
+		{"invalid json", `{"id":1,`, false, false},
+	}
+	for _, tt := range tests {
+		if got := matches(strict, tt.body); got != tt.strict {
+			t.Errorf("strict %s: expected match=%v, got %v", tt.name, tt.strict, got)
+		}
+		if got := matches(loose, tt.body); got != tt.loose {
+			t.Errorf("allow_extra %s: expected match=%v, got %v", tt.name, tt.loose, got)
+		}
+	}
+
+	_, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:   "key-order-empty",
+		When: scenario.WhenClause{Method: "POST", Path: "/x", Body: &scenario.BodyClause{KeyOrder: &scenario.KeyOrder{}}},
+	})
+	if err == nil {
+		t.Error("expected compile error for empty keys")
+	}
+}
+
+func TestCompiler_ExampleRequest(t *testing.T) {
+	compiler := newTestCompiler(t)
+
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:             "example",
+		When:           scenario.WhenClause{Method: "POST", Path: "/api/orders"},
+		ExampleRequest: &scenario.ExampleRequest{Headers: map[string]string{"x-tenant": "acme"}, Query: map[string]string{"dry": "1"}, Body: `{}`},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	ex := cs.Example
+	if ex.Method != "POST" || ex.Path != "/api/orders" {
+		t.Errorf("expected defaults from the scenario, got %s %s", ex.Method, ex.Path)
+	}
+	if ex.Headers["X-Tenant"] != "acme" || strings.Join(ex.Query["dry"], ",") != "1" || string(ex.Body) != "{}" {
+		t.Errorf("unexpected example request %+v", ex)
+	}
+
+	_, err = compiler.CompileScenario(&scenario.Scenario{
+		ID:             "example-params",
+		When:           scenario.WhenClause{Method: "GET", Path: "/api/orders/{id}"},
+		ExampleRequest: &scenario.ExampleRequest{},
+	})
+	if err == nil {
+		t.Error("expected compile error when a parameterized path has no example path")
+	}
+}
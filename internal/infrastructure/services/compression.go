@@ -0,0 +1,103 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Supported response content codings.
+const (
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// compileCompression validates a scenario's compression list, lower-cases
+// it and drops duplicates while keeping the preference order.
+func compileCompression(encodings []string) ([]string, error) {
+	if len(encodings) == 0 {
+		return nil, nil
+	}
+	out := make([]string, 0, len(encodings))
+	for _, e := range encodings {
+		e = strings.ToLower(strings.TrimSpace(e))
+		switch e {
+		case EncodingBrotli, EncodingGzip:
+		default:
+			return nil, fmt.Errorf("unsupported compression %q (expected \"br\" or \"gzip\")", e)
+		}
+		if !slices.Contains(out, e) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// NegotiateEncoding picks the content coding to use for a response given the
+// request's Accept-Encoding header and the encodings the scenario offers in
+// preference order. The client's highest q-value wins; ties go to the
+// scenario's order. It returns "" when the client accepts none of them.
+func NegotiateEncoding(acceptEncoding string, offered []string) string {
+	if acceptEncoding == "" || len(offered) == 0 {
+		return ""
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if !ok || strings.ToLower(strings.TrimSpace(k)) != "q" {
+				continue
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		accepted[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, e := range offered {
+		q, ok := accepted[e]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+// Compress encodes body with the given content coding.
+func Compress(body []byte, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case EncodingBrotli:
+		w = brotli.NewWriter(&buf)
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", encoding)
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to compress body with %s: %w", encoding, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress body with %s: %w", encoding, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -37,3 +37,23 @@ func TestInferContentType(t *testing.T) {
 		})
 	}
 }
+
+func TestWithCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		want        string
+	}{
+		{"no params", "text/plain", "text/plain; charset=ISO-8859-1"},
+		{"replaces charset", "text/plain; charset=utf-8", "text/plain; charset=ISO-8859-1"},
+		{"keeps other params", "text/html; level=1", "text/html; charset=ISO-8859-1; level=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := services.WithCharset(tt.contentType, "ISO-8859-1"); got != tt.want {
+				t.Errorf("WithCharset(%q) = %q, want %q", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
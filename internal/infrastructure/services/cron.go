@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+)
+
+// cronParser parses five-field expressions: minute hour day-of-month month
+// day-of-week, optionally prefixed with CRON_TZ=<zone>.
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// cronStarBit is the bit robfig/cron sets on a day field written as "*". A
+// day field with it makes day-of-month and day-of-week combine with AND
+// instead of OR, as in classic cron.
+const cronStarBit = 1 << 63
+
+// maxCronWindow bounds how far ahead the end of an active window is searched.
+const maxCronWindow = 24 * time.Hour
+
+// cronSchedule is a parsed cron expression. A time is inside the schedule
+// when the minute it falls in is an activation of the expression.
+//
+// Without a CRON_TZ prefix the fields are read in the time zone of the time
+// being checked, which for the server clock is the server's local zone.
+type cronSchedule struct {
+	spec *cron.SpecSchedule
+	// window caches the last span found to be uniformly active or inactive,
+	// so requests within it skip recomputing the transitions.
+	window atomic.Pointer[cronWindow]
+}
+
+// cronWindow is the span [from, until) throughout which a schedule is
+// active, or inactive.
+type cronWindow struct {
+	from, until time.Time
+	active      bool
+}
+
+// parseCron parses a five-field cron expression with robfig/cron. Fields
+// accept *, numbers, ranges (a-b), lists (a,b) and steps (*/n, a-b/n); month
+// and day-of-week also accept three-letter names. Day-of-week 7 is Sunday,
+// like 0, and a day field starting with * (e.g. */2) counts as unrestricted
+// when combining day-of-month and day-of-week.
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	var tz []string
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "CRON_TZ=") || strings.HasPrefix(fields[0], "TZ=")) {
+		tz, fields = fields[:1], fields[1:]
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+	fields[4] = cronSunday(fields[4])
+
+	sched, err := cronParser.Parse(strings.Join(append(tz, fields...), " "))
+	if err != nil {
+		return nil, err
+	}
+	spec, ok := sched.(*cron.SpecSchedule)
+	if !ok {
+		return nil, fmt.Errorf("unsupported schedule %q", expr)
+	}
+	if strings.HasPrefix(fields[2], "*") {
+		spec.Dom |= cronStarBit
+	}
+	if strings.HasPrefix(fields[4], "*") {
+		spec.Dow |= cronStarBit
+	}
+	return &cronSchedule{spec: spec}, nil
+}
+
+// cronSunday rewrites day-of-week 7, which robfig/cron rejects, as 0: a
+// bare 7 becomes 0 and a range ending at 7 ends at 6 and adds 0.
+func cronSunday(field string) string {
+	parts := strings.Split(field, ",")
+	for i, part := range parts {
+		switch lo, hi, isRange := strings.Cut(part, "-"); {
+		case part == "7":
+			parts[i] = "0"
+		case isRange && hi == "7":
+			parts[i] = lo + "-6,0"
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// active reports whether t falls inside an active minute of the schedule.
+func (s *cronSchedule) active(t time.Time) bool {
+	if w := s.window.Load(); w != nil && !t.Before(w.from) && t.Before(w.until) {
+		return w.active
+	}
+	w := s.windowAt(t)
+	s.window.Store(w)
+	return w.active
+}
+
+// windowAt computes the window starting at t's minute from the schedule's
+// transitions: an inactive minute lasts until the next activation, and an
+// active one until the first minute that is not an activation, searched up
+// to maxCronWindow ahead.
+func (s *cronSchedule) windowAt(t time.Time) *cronWindow {
+	minute := t.Truncate(time.Minute)
+	limit := minute.Add(maxCronWindow)
+	if next := s.spec.Next(minute.Add(-time.Second)); !next.Equal(minute) {
+		// A zero next means no activation within five years.
+		if next.IsZero() || next.After(limit) {
+			next = limit
+		}
+		return &cronWindow{from: minute, until: next}
+	}
+	until := minute.Add(time.Minute)
+	for until.Before(limit) && s.spec.Next(until.Add(-time.Second)).Equal(until) {
+		until = until.Add(time.Minute)
+	}
+	return &cronWindow{from: minute, until: until, active: true}
+}
+
+// schedulePredicate creates a predicate over the request time (RFC 3339) that
+// holds while the time is inside the schedule.
+func schedulePredicate(s *cronSchedule) match.Predicate {
+	return func(v string) bool {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return false
+		}
+		return s.active(t)
+	}
+}
@@ -0,0 +1,107 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+	"github.com/sophialabs/proteusmock/internal/domain/scenario"
+)
+
+func compileSchedule(t *testing.T, schedule string) *match.CompiledScenario {
+	t.Helper()
+	cs, err := newTestCompiler(t).CompileScenario(&scenario.Scenario{
+		ID:       "scheduled",
+		When:     scenario.WhenClause{Method: "GET", Path: "/x", Schedule: schedule},
+		Response: scenario.Response{Status: 200},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+	return cs
+}
+
+func TestSchedule_Windows(t *testing.T) {
+	// 2025-06-02 is a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 6, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		schedule string
+		now      time.Time
+		want     bool
+	}{
+		{"business hours inside", "* 9-17 * * mon-fri", at(2, 10, 30), true},
+		{"business hours before open", "* 9-17 * * mon-fri", at(2, 8, 59), false},
+		{"business hours weekend", "* 9-17 * * mon-fri", at(7, 10, 0), false},
+		{"step minutes on", "*/15 * * * *", at(2, 3, 45), true},
+		{"step minutes off", "*/15 * * * *", at(2, 3, 46), false},
+		{"list", "0,30 12 * * *", at(2, 12, 30), true},
+		{"month name", "* * * jun *", at(2, 0, 0), true},
+		{"other month", "* * * jan-may *", at(2, 0, 0), false},
+		{"sunday as 7", "* * * * 7", at(8, 0, 0), true},
+		{"dom or dow when both restricted", "* * 15 * mon", at(2, 0, 0), true},
+		{"dom and dow when dow unrestricted", "* * 15 * *", at(2, 0, 0), false},
+		{"range with step", "0-30/10 * * * *", at(2, 0, 20), true},
+		{"dom step and dow both match", "* * */2 * mon", at(9, 0, 0), true},
+		{"dom step on other weekday", "* * */2 * mon", at(3, 0, 0), false},
+		{"dow step on other day of month", "* * 15 * */2", at(3, 0, 0), false},
+		{"range ending at sunday as 7", "* * * * fri-7", at(8, 0, 0), true},
+		{"time zone prefix inside", "CRON_TZ=Asia/Tokyo * 9-17 * * *", at(2, 1, 0), true},
+		{"time zone prefix outside", "CRON_TZ=Asia/Tokyo * 9-17 * * *", at(2, 10, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := compileSchedule(t, tt.schedule)
+			result := match.NewEvaluator().Evaluate(&match.IncomingRequest{Method: "GET", Now: tt.now}, []*match.CompiledScenario{cs})
+			if got := result.Matched != nil; got != tt.want {
+				t.Errorf("schedule %q at %v: got %v, want %v", tt.schedule, tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedule_Transitions(t *testing.T) {
+	cs := compileSchedule(t, "* 9-17 * * *")
+	at := func(hour, minute, second int) time.Time {
+		return time.Date(2025, 6, 2, hour, minute, second, 0, time.UTC)
+	}
+
+	// Times move forward across both transitions, then back into the
+	// cached active window and before it.
+	steps := []struct {
+		now  time.Time
+		want bool
+	}{
+		{at(8, 59, 59), false},
+		{at(9, 0, 0), true},
+		{at(12, 30, 0), true},
+		{at(17, 59, 59), true},
+		{at(18, 0, 0), false},
+		{at(10, 0, 0), true},
+		{at(8, 0, 0), false},
+	}
+	for _, step := range steps {
+		result := match.NewEvaluator().Evaluate(&match.IncomingRequest{Method: "GET", Now: step.now}, []*match.CompiledScenario{cs})
+		if got := result.Matched != nil; got != step.want {
+			t.Errorf("at %v: got %v, want %v", step.now, got, step.want)
+		}
+	}
+}
+
+func TestSchedule_InvalidFallsBackToAlwaysActive(t *testing.T) {
+	for _, schedule := range []string{"* * *", "61 * * * *", "* * * * funday", "*/0 * * * *", "5-1 * * * *", "CRON_TZ=Nowhere/Special * * * * *"} {
+		cs := compileSchedule(t, schedule)
+		if len(cs.Warnings) != 1 {
+			t.Errorf("schedule %q: expected a warning, got %v", schedule, cs.Warnings)
+		}
+		for _, p := range cs.Predicates {
+			if p.Field == "now" {
+				t.Errorf("schedule %q: expected no schedule predicate", schedule)
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sophialabs/proteusmock/internal/domain/scenario"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
+)
+
+// maxGenerateCount bounds generated lists, which are built at compile time.
+const maxGenerateCount = 10000
+
+// SetRandomSource replaces the source behind generated bodies, e.g. with a
+// seeded generator for reproducible data. Nil restores the global generator.
+func (c *Compiler) SetRandomSource(src ports.RandomSource) {
+	c.random = src
+}
+
+func (c *Compiler) randomSource() ports.RandomSource {
+	if c.random == nil {
+		return ports.RandomFunc(rand.IntN)
+	}
+	return c.random
+}
+
+// fakeGenerators produce one value of a fake type; i is the object's
+// zero-based position in the list.
+var fakeGenerators = map[string]func(r ports.RandomSource, i int) any{
+	"seq":        func(_ ports.RandomSource, i int) any { return i + 1 },
+	"uuid":       fakeUUID,
+	"int":        func(r ports.RandomSource, _ int) any { return r.IntN(1000) },
+	"float":      func(r ports.RandomSource, _ int) any { return float64(r.IntN(100000)) / 100 },
+	"bool":       func(r ports.RandomSource, _ int) any { return r.IntN(2) == 1 },
+	"first_name": func(r ports.RandomSource, _ int) any { return pick(r, fakeFirstNames) },
+	"last_name":  func(r ports.RandomSource, _ int) any { return pick(r, fakeLastNames) },
+	"name": func(r ports.RandomSource, _ int) any {
+		return pick(r, fakeFirstNames) + " " + pick(r, fakeLastNames)
+	},
+	"email": func(r ports.RandomSource, _ int) any {
+		return strings.ToLower(pick(r, fakeFirstNames)+"."+pick(r, fakeLastNames)) + "@example.com"
+	},
+	"word": func(r ports.RandomSource, _ int) any { return pick(r, fakeWords) },
+	"sentence": func(r ports.RandomSource, _ int) any {
+		words := make([]string, 4+r.IntN(5))
+		for j := range words {
+			words[j] = pick(r, fakeWords)
+		}
+		s := strings.Join(words, " ")
+		return strings.ToUpper(s[:1]) + s[1:] + "."
+	},
+	"city":  func(r ports.RandomSource, _ int) any { return pick(r, fakeCities) },
+	"phone": func(r ports.RandomSource, _ int) any { return fmt.Sprintf("+1-555-01%02d", r.IntN(100)) },
+	"date": func(r ports.RandomSource, _ int) any {
+		return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, r.IntN(2000)).Format(time.DateOnly)
+	},
+}
+
+var (
+	fakeFirstNames = []string{"Alice", "Bruno", "Chen", "Daria", "Emeka", "Fatima", "Gustavo", "Hana", "Ivan", "Julia", "Kofi", "Lena"}
+	fakeLastNames  = []string{"Almeida", "Brown", "Costa", "Dubois", "Ito", "Kowalski", "Mensah", "Novak", "Okafor", "Silva", "Smith", "Tanaka"}
+	fakeWords      = []string{"alpha", "bright", "cloud", "delta", "ember", "forest", "glass", "harbor", "island", "jade", "kite", "lunar", "meadow", "north", "orbit", "pixel", "quiet", "river"}
+	fakeCities     = []string{"Amsterdam", "Berlin", "Lagos", "Lisbon", "Montreal", "Osaka", "Porto", "Seoul", "Sydney", "Valencia"}
+)
+
+func pick(r ports.RandomSource, values []string) string {
+	return values[r.IntN(len(values))]
+}
+
+func fakeUUID(r ports.RandomSource, _ int) any {
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(r.IntN(256))
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// generateBody builds the JSON array described by g. The data is generated
+// once, at compile time, so every request (and every page of a paginated
+// response) sees the same list until the next reload.
+func generateBody(g *scenario.Generate, r ports.RandomSource) ([]byte, error) {
+	if g.Count < 0 || g.Count > maxGenerateCount {
+		return nil, fmt.Errorf("generate: count must be between 0 and %d", maxGenerateCount)
+	}
+	if len(g.Fields) == 0 {
+		return nil, fmt.Errorf("generate: fields must not be empty")
+	}
+	names := make([]string, 0, len(g.Fields))
+	for name, typ := range g.Fields {
+		if _, ok := fakeGenerators[typ]; !ok {
+			return nil, fmt.Errorf("generate: field %q has unsupported type %q (supported: %s)", name, typ, supportedFakeTypes())
+		}
+		names = append(names, name)
+	}
+	// Fixed field order keeps generation reproducible for a seeded source.
+	slices.Sort(names)
+
+	items := make([]map[string]any, g.Count)
+	for i := range items {
+		item := make(map[string]any, len(names))
+		for _, name := range names {
+			item[name] = fakeGenerators[g.Fields[name]](r, i)
+		}
+		items[i] = item
+	}
+	return json.Marshal(items)
+}
+
+func supportedFakeTypes() string {
+	types := make([]string, 0, len(fakeGenerators))
+	for t := range fakeGenerators {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return strings.Join(types, ", ")
+}
@@ -1,7 +1,10 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
 )
@@ -10,6 +13,10 @@ import (
 type ScenarioIndex struct {
 	entries map[string][]*match.CompiledScenario
 	paths   []string
+	methods []string
+	statics []*match.CompiledScenario
+	// pathMethods lists the methods registered for each path, sorted.
+	pathMethods map[string][]string
 }
 
 // NewScenarioIndex creates an empty index.
@@ -19,16 +26,27 @@ func NewScenarioIndex() *ScenarioIndex {
 	}
 }
 
-// Add inserts a compiled scenario into the index.
+// Add inserts a compiled scenario into the index, under one key per method.
 func (idx *ScenarioIndex) Add(cs *match.CompiledScenario) {
-	key := cs.PathKey
-	idx.entries[key] = append(idx.entries[key], cs)
+	if cs.Static != nil {
+		idx.statics = append(idx.statics, cs)
+		return
+	}
+	for _, key := range cs.PathKeys() {
+		idx.entries[key] = append(idx.entries[key], cs)
+	}
 }
 
 // Build sorts all entries by priority desc then ID asc, and collects unique paths.
-func (idx *ScenarioIndex) Build() {
+// It returns an error when two paths differ only in parameter names, since the
+// router would serve both from a single route and one set of scenarios would
+// never be reached.
+func (idx *ScenarioIndex) Build() error {
 	idx.paths = nil
+	idx.methods = nil
+	idx.pathMethods = make(map[string][]string)
 	seen := make(map[string]bool)
+	seenMethod := make(map[string]bool)
 
 	for key, candidates := range idx.entries {
 		sort.SliceStable(candidates, func(i, j int) bool {
@@ -44,17 +62,88 @@ func (idx *ScenarioIndex) Build() {
 		})
 		idx.entries[key] = candidates
 
-		// Extract path (strip METHOD: prefix).
-		for _, cs := range candidates {
-			path := cs.PathKey[len(cs.Method)+1:]
-			if !seen[path] {
-				seen[path] = true
-				idx.paths = append(idx.paths, path)
-			}
+		// Split METHOD:path; methods are HTTP tokens and never contain ':'.
+		method, path, _ := strings.Cut(key, ":")
+		idx.pathMethods[path] = append(idx.pathMethods[path], method)
+		if !seen[path] {
+			seen[path] = true
+			idx.paths = append(idx.paths, path)
+		}
+		if !seenMethod[method] {
+			seenMethod[method] = true
+			idx.methods = append(idx.methods, method)
 		}
 	}
 
 	sort.Strings(idx.paths)
+	sort.Strings(idx.methods)
+	for _, methods := range idx.pathMethods {
+		sort.Strings(methods)
+	}
+	sort.SliceStable(idx.statics, func(i, j int) bool {
+		return idx.statics[i].ID < idx.statics[j].ID
+	})
+
+	return idx.routeConflicts()
+}
+
+// routeConflicts reports paths that map to the same route once parameter
+// names are ignored, such as /users/{id} and /users/{userId}.
+func (idx *ScenarioIndex) routeConflicts() error {
+	byShape := make(map[string]string, len(idx.paths))
+	var errs []error
+	for _, path := range idx.paths {
+		shape := routeShape(path)
+		other, ok := byShape[shape]
+		if !ok {
+			byShape[shape] = path
+			continue
+		}
+		errs = append(errs, fmt.Errorf("route %q (scenario %q) conflicts with %q (scenario %q): paths differ only in parameter names; use the same names",
+			path, idx.pathScenarioID(path), other, idx.pathScenarioID(other)))
+	}
+	return errors.Join(errs...)
+}
+
+// pathScenarioID returns the ID of the first scenario registered for path.
+func (idx *ScenarioIndex) pathScenarioID(path string) string {
+	for _, method := range idx.pathMethods[path] {
+		if candidates := idx.entries[method+":"+path]; len(candidates) > 0 {
+			return candidates[0].ID
+		}
+	}
+	return ""
+}
+
+// routeShape strips parameter names from a chi pattern, keeping any regexp:
+// "/users/{id}/posts/{n:[0-9]+}" becomes "/users/{}/posts/{:[0-9]+}".
+func routeShape(path string) string {
+	var b strings.Builder
+	depth := 0
+	naming := false
+	for _, r := range path {
+		switch {
+		case r == '{':
+			if depth == 0 {
+				naming = true
+				b.WriteRune(r)
+				depth++
+				continue
+			}
+			depth++
+		case r == '}':
+			depth--
+			if depth == 0 {
+				naming = false
+			}
+		case r == ':' && depth == 1 && naming:
+			naming = false
+		}
+		if !naming {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
 }
 
 // Lookup returns the sorted candidates for a given METHOD:path key.
@@ -67,16 +156,39 @@ func (idx *ScenarioIndex) Paths() []string {
 	return idx.paths
 }
 
+// Methods returns all unique HTTP methods registered in the index.
+func (idx *ScenarioIndex) Methods() []string {
+	return idx.methods
+}
+
+// AllowedMethods returns the sorted methods with scenarios for a path pattern.
+func (idx *ScenarioIndex) AllowedMethods(path string) []string {
+	return idx.pathMethods[path]
+}
+
+// Statics returns the static directory mounts, sorted by ID.
+func (idx *ScenarioIndex) Statics() []*match.CompiledScenario {
+	return idx.statics
+}
+
 // All returns all compiled scenarios across all keys, sorted by priority desc then ID asc.
+// Scenarios registered under several methods appear once.
 func (idx *ScenarioIndex) All() []*match.CompiledScenario {
 	size := 0
 	for _, candidates := range idx.entries {
 		size += len(candidates)
 	}
-	all := make([]*match.CompiledScenario, 0, size)
+	all := make([]*match.CompiledScenario, 0, size+len(idx.statics))
+	seen := make(map[*match.CompiledScenario]bool, size)
 	for _, candidates := range idx.entries {
-		all = append(all, candidates...)
+		for _, cs := range candidates {
+			if !seen[cs] {
+				seen[cs] = true
+				all = append(all, cs)
+			}
+		}
 	}
+	all = append(all, idx.statics...)
 	sort.SliceStable(all, func(i, j int) bool {
 		if all[i].Priority != all[j].Priority {
 			return all[i].Priority > all[j].Priority
@@ -95,6 +207,11 @@ func (idx *ScenarioIndex) ByID(id string) (*match.CompiledScenario, bool) {
 			}
 		}
 	}
+	for _, cs := range idx.statics {
+		if cs.ID == id {
+			return cs, true
+		}
+	}
 	return nil, false
 }
 
@@ -1,9 +1,11 @@
 package services_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
+	"github.com/sophialabs/proteusmock/internal/domain/scenario"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/services"
 )
 
@@ -168,3 +170,105 @@ func TestScenarioIndex_AllAndKeys(t *testing.T) {
 		}
 	}
 }
+
+func TestScenarioIndex_Methods(t *testing.T) {
+	idx := services.NewScenarioIndex()
+	idx.Add(&match.CompiledScenario{ID: "a", Method: "GET", PathKey: "GET:/a"})
+	idx.Add(&match.CompiledScenario{ID: "b", Method: "PURGE", PathKey: "PURGE:/a"})
+	idx.Add(&match.CompiledScenario{ID: "c", Method: "GET", PathKey: "GET:/c"})
+	idx.Build()
+
+	methods := idx.Methods()
+	if len(methods) != 2 || methods[0] != "GET" || methods[1] != "PURGE" {
+		t.Errorf("expected [GET PURGE], got %v", methods)
+	}
+}
+
+func TestScenarioIndex_AllowedMethods(t *testing.T) {
+	idx := services.NewScenarioIndex()
+	idx.Add(&match.CompiledScenario{ID: "a", Method: "POST", PathKey: "POST:/api/items"})
+	idx.Add(&match.CompiledScenario{ID: "b", Method: "GET", PathKey: "GET:/api/items"})
+	idx.Add(&match.CompiledScenario{ID: "c", Method: "GET", PathKey: "GET:/api/items"})
+	idx.Add(&match.CompiledScenario{ID: "d", Method: "GET", PathKey: "GET:/api/other"})
+	idx.Build()
+
+	got := idx.AllowedMethods("/api/items")
+	if len(got) != 2 || got[0] != "GET" || got[1] != "POST" {
+		t.Errorf("expected [GET POST], got %v", got)
+	}
+	if got := idx.AllowedMethods("/api/missing"); len(got) != 0 {
+		t.Errorf("expected no methods for unknown path, got %v", got)
+	}
+}
+
+func TestScenarioIndex_MultipleMethods(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:   "health",
+		When: scenario.WhenClause{Methods: []string{"GET", "HEAD", "GET"}, Path: "/health"},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	idx := services.NewScenarioIndex()
+	idx.Add(cs)
+	idx.Build()
+
+	for _, key := range []string{"GET:/health", "HEAD:/health"} {
+		candidates := idx.Lookup(key)
+		if len(candidates) != 1 || candidates[0].ID != "health" {
+			t.Errorf("expected scenario under %s, got %v", key, candidates)
+		}
+	}
+	if got := idx.AllowedMethods("/health"); len(got) != 2 || got[0] != "GET" || got[1] != "HEAD" {
+		t.Errorf("expected allowed methods [GET HEAD], got %v", got)
+	}
+	if all := idx.All(); len(all) != 1 {
+		t.Errorf("expected scenario listed once, got %d", len(all))
+	}
+
+	evaluator := match.NewEvaluator()
+	for method, want := range map[string]bool{"GET": true, "HEAD": true, "POST": false} {
+		req := &match.IncomingRequest{Method: method, Path: "/health"}
+		if got := evaluator.Evaluate(req, []*match.CompiledScenario{cs}).Matched != nil; got != want {
+			t.Errorf("%s: expected match %v, got %v", method, want, got)
+		}
+	}
+}
+
+func TestScenarioIndex_RouteConflict(t *testing.T) {
+	idx := services.NewScenarioIndex()
+	idx.Add(&match.CompiledScenario{ID: "get-user", Method: "GET", PathKey: "GET:/users/{id}"})
+	idx.Add(&match.CompiledScenario{ID: "delete-user", Method: "DELETE", PathKey: "DELETE:/users/{userId}"})
+	idx.Add(&match.CompiledScenario{ID: "by-number", Method: "GET", PathKey: "GET:/orders/{id:[0-9]+}"})
+	idx.Add(&match.CompiledScenario{ID: "by-slug", Method: "GET", PathKey: "GET:/orders/{slug:[a-z]+}"})
+
+	err := idx.Build()
+	if err == nil {
+		t.Fatal("expected route conflict error")
+	}
+	msg := err.Error()
+	for _, want := range []string{`"/users/{userId}"`, `"/users/{id}"`, `"get-user"`, `"delete-user"`, "parameter names"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected error to mention %s, got: %s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "/orders/") {
+		t.Errorf("params with different regexps should not conflict, got: %s", msg)
+	}
+}
+
+func TestScenarioIndex_SameParamNamesDoNotConflict(t *testing.T) {
+	idx := services.NewScenarioIndex()
+	idx.Add(&match.CompiledScenario{ID: "get-user", Method: "GET", PathKey: "GET:/users/{id}"})
+	idx.Add(&match.CompiledScenario{ID: "delete-user", Method: "DELETE", PathKey: "DELETE:/users/{id}"})
+	idx.Add(&match.CompiledScenario{ID: "posts", Method: "GET", PathKey: "GET:/users/{userId}/posts"})
+
+	if err := idx.Build(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+)
+
+// bodyInterner de-duplicates static response bodies by content so scenarios
+// sharing the same fixture reference one backing array. Interned slices are
+// shared and must never be mutated.
+type bodyInterner struct {
+	mu     sync.Mutex
+	bodies map[[sha256.Size]byte][]byte
+}
+
+func newBodyInterner() *bodyInterner {
+	return &bodyInterner{bodies: make(map[[sha256.Size]byte][]byte)}
+}
+
+// intern returns the canonical slice for b's content, storing b if unseen.
+func (in *bodyInterner) intern(b []byte) []byte {
+	if len(b) == 0 {
+		return b
+	}
+	sum := sha256.Sum256(b)
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if existing, ok := in.bodies[sum]; ok && bytes.Equal(existing, b) {
+		return existing
+	}
+	in.bodies[sum] = b
+	return b
+}
+
+// reset drops all interned bodies. Slices already handed out stay valid.
+func (in *bodyInterner) reset() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	clear(in.bodies)
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+)
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// parseJSONPointer splits an RFC 6901 JSON Pointer into unescaped reference
+// tokens. A leading "#" selects the URI fragment form, whose tokens are
+// percent-decoded. The empty pointer refers to the whole document.
+func parseJSONPointer(ptr string) ([]string, error) {
+	if frag, ok := strings.CutPrefix(ptr, "#"); ok {
+		decoded, err := url.PathUnescape(frag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON pointer %q: %w", ptr, err)
+		}
+		ptr = decoded
+	}
+	if ptr == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must be empty or start with \"/\"", ptr)
+	}
+
+	tokens := strings.Split(ptr[1:], "/")
+	for i, tok := range tokens {
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || (tok[j+1] != '0' && tok[j+1] != '1')) {
+				return nil, fmt.Errorf("invalid JSON pointer %q: \"~\" must be followed by 0 or 1", ptr)
+			}
+		}
+		tokens[i] = pointerUnescaper.Replace(tok)
+	}
+	return tokens, nil
+}
+
+// jsonPointerPredicate creates a predicate that resolves a JSON Pointer
+// against the body and matches the value it refers to.
+func jsonPointerPredicate(tokens []string, valueMatcher match.Predicate) match.Predicate {
+	return func(body string) bool {
+		var data any
+		if err := parseJSON(body, &data); err != nil {
+			return false
+		}
+
+		v, ok := resolveJSONPointer(data, tokens)
+		if !ok {
+			return false
+		}
+		return valueMatcher(fmt.Sprintf("%v", v))
+	}
+}
+
+func resolveJSONPointer(data any, tokens []string) (any, bool) {
+	for _, tok := range tokens {
+		switch node := data.(type) {
+		case map[string]any:
+			v, ok := node[tok]
+			if !ok {
+				return nil, false
+			}
+			data = v
+		case []any:
+			// Array indices are decimal without leading zeros; "-" (past the
+			// end) never refers to an existing element.
+			if tok == "" || (len(tok) > 1 && tok[0] == '0') {
+				return nil, false
+			}
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			data = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return data, true
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// IsJSONContentType reports whether the media type is application/json or a
+// structured +json suffix type such as application/problem+json.
+func IsJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// OmitNulls recursively removes object keys whose value is null.
+// Null array elements are kept so positional semantics are preserved. Keys
+// keep their order and scalars are copied verbatim, so the result differs
+// from body only by the removed keys and insignificant whitespace.
+func OmitNulls(body []byte) ([]byte, error) {
+	var data json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to parse response body as JSON: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pruneNulls(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to parse response body as JSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// pruneNulls writes the compacted value v to buf without null-valued object
+// keys, walking objects token by token to keep their key order.
+func pruneNulls(buf *bytes.Buffer, v json.RawMessage) error {
+	v = bytes.TrimSpace(v)
+	switch {
+	case len(v) > 0 && v[0] == '{':
+		dec := json.NewDecoder(bytes.NewReader(v))
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		buf.WriteByte('{')
+		first := true
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			var child json.RawMessage
+			if err := dec.Decode(&child); err != nil {
+				return err
+			}
+			if string(child) == "null" {
+				continue
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			if err := writeKey(buf, key); err != nil {
+				return err
+			}
+			if err := pruneNulls(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case len(v) > 0 && v[0] == '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(v, &items); err != nil {
+			return err
+		}
+		buf.WriteByte('[')
+		for i, item := range items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := pruneNulls(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	default:
+		return json.Compact(buf, v)
+	}
+}
+
+// writeKey writes key and the colon following it, leaving <, > and & as is.
+func writeKey(buf *bytes.Buffer, key string) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(key); err != nil {
+		return err
+	}
+	// Encode terminates the value with a newline.
+	buf.Truncate(buf.Len() - 1)
+	buf.WriteByte(':')
+	return nil
+}
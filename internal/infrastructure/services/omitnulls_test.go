@@ -0,0 +1,92 @@
+package services_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sophialabs/proteusmock/internal/infrastructure/services"
+)
+
+func TestOmitNulls_Nested(t *testing.T) {
+	body := []byte(`{
+		"id": 1,
+		"nickname": null,
+		"profile": {"bio": null, "age": 30, "tags": ["a", null]},
+		"items": [{"sku": "x", "note": null}],
+		"active": false,
+		"count": 0,
+		"label": ""
+	}`)
+
+	out, err := services.OmitNulls(body)
+	if err != nil {
+		t.Fatalf("OmitNulls failed: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+
+	want := map[string]any{
+		"id":      1.0,
+		"profile": map[string]any{"age": 30.0, "tags": []any{"a", nil}},
+		"items":   []any{map[string]any{"sku": "x"}},
+		"active":  false,
+		"count":   0.0,
+		"label":   "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected result:\n got  %v\n want %v", got, want)
+	}
+}
+
+func TestOmitNulls_PreservesLargeNumbers(t *testing.T) {
+	out, err := services.OmitNulls([]byte(`{"id":9007199254740993,"x":null}`))
+	if err != nil {
+		t.Fatalf("OmitNulls failed: %v", err)
+	}
+	if string(out) != `{"id":9007199254740993}` {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestOmitNulls_PreservesKeyOrderAndEscaping(t *testing.T) {
+	body := []byte(`{"zeta": 1, "alpha": {"y": null, "x": "<b>&</b>", "a": [null, {"c": 2, "b": null}]}, "a<b": true}`)
+
+	out, err := services.OmitNulls(body)
+	if err != nil {
+		t.Fatalf("OmitNulls failed: %v", err)
+	}
+	want := `{"zeta":1,"alpha":{"x":"<b>&</b>","a":[null,{"c":2}]},"a<b":true}`
+	if string(out) != want {
+		t.Errorf("unexpected output:\n got  %s\n want %s", out, want)
+	}
+}
+
+func TestOmitNulls_InvalidJSON(t *testing.T) {
+	if _, err := services.OmitNulls([]byte("not json")); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestIsJSONContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"application/problem+json", true},
+		{"text/plain", false},
+		{"application/xml", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := services.IsJSONContentType(tt.contentType); got != tt.want {
+			t.Errorf("IsJSONContentType(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
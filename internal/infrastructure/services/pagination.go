@@ -28,11 +28,22 @@ func Paginate(body []byte, cfg *match.CompiledPagination, queryParams map[string
 	totalItems := len(items)
 	offset, limit := resolveSliceBounds(cfg, queryParams)
 
-	// Clamp offset and end.
-	offset = min(offset, totalItems)
-	end := min(offset+limit, totalItems)
-
-	sliced := items[offset:end]
+	var sliced []any
+	var end int
+	if cfg.TotalItemsPath != "" || cfg.TotalItems != nil {
+		// The body is already the requested page of a larger dataset.
+		totalItems, err = knownTotalItems(fullData, cfg)
+		if err != nil {
+			return nil, err
+		}
+		sliced = items
+		end = offset + len(items)
+	} else {
+		// Clamp offset and end.
+		offset = min(offset, totalItems)
+		end = min(offset+limit, totalItems)
+		sliced = items[offset:end]
+	}
 
 	totalPages := int(math.Ceil(float64(totalItems) / float64(limit)))
 	if totalPages == 0 {
@@ -61,8 +72,31 @@ func Paginate(body []byte, cfg *match.CompiledPagination, queryParams map[string
 	return result, nil
 }
 
+// knownTotalItems resolves the dataset size declared by total_items_source.
+func knownTotalItems(data any, cfg *match.CompiledPagination) (int, error) {
+	if cfg.TotalItems != nil {
+		return *cfg.TotalItems, nil
+	}
+	v, err := jsonpath.Get(cfg.TotalItemsPath, data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to extract total items at %q: %w", cfg.TotalItemsPath, err)
+	}
+	switch n := v.(type) {
+	case float64:
+		if n >= 0 && n == math.Trunc(n) {
+			return int(n), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(n); err == nil && i >= 0 {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("total items at %q is not a non-negative integer: %v", cfg.TotalItemsPath, v)
+}
+
 // resolveSliceBounds extracts offset and limit from query parameters
-// according to the configured pagination style.
+// according to the configured pagination style. The limit is capped by the
+// smaller of the scenario's max size and the global max size.
 func resolveSliceBounds(cfg *match.CompiledPagination, qp map[string]string) (offset, limit int) {
 	limit = cfg.DefaultSize
 
@@ -96,6 +130,9 @@ func resolveSliceBounds(cfg *match.CompiledPagination, qp map[string]string) (of
 	if limit > cfg.MaxSize {
 		limit = cfg.MaxSize
 	}
+	if cfg.GlobalMaxSize > 0 && limit > cfg.GlobalMaxSize {
+		limit = cfg.GlobalMaxSize
+	}
 	if limit <= 0 {
 		limit = 10
 	}
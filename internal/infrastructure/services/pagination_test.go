@@ -359,3 +359,80 @@ func assertArrayLen(t *testing.T, m map[string]any, key string, expected int) {
 		t.Errorf("key %q: expected length %d, got %d", key, expected, len(arr))
 	}
 }
+
+func TestPaginate_GlobalMaxSizeOverridesScenarioMax(t *testing.T) {
+	body := []byte(`{"items": [1,2,3,4,5,6,7,8,9,10]}`)
+	cfg := defaultPaginationConfig()
+	cfg.MaxSize = 8
+	cfg.GlobalMaxSize = 3
+
+	result, err := Paginate(body, cfg, map[string]string{"size": "6"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var env map[string]any
+	if err := json.Unmarshal(result, &env); err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+
+	assertFloat(t, env, "size", 3)
+	assertArrayLen(t, env, "data", 3)
+	assertFloat(t, env, "total_pages", 4)
+}
+
+func TestPaginate_KnownTotalItems(t *testing.T) {
+	fixed := 50
+	tests := []struct {
+		name string
+		body string
+		set  func(cfg *match.CompiledPagination)
+	}{
+		{"fixed", `{"items": [11,12,13,14,15]}`, func(cfg *match.CompiledPagination) { cfg.TotalItems = &fixed }},
+		{"jsonpath", `{"items": [11,12,13,14,15], "meta": {"total": 50}}`, func(cfg *match.CompiledPagination) { cfg.TotalItemsPath = "$.meta.total" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultPaginationConfig()
+			tt.set(cfg)
+
+			result, err := Paginate([]byte(tt.body), cfg, map[string]string{"page": "3", "size": "5"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var env map[string]any
+			if err := json.Unmarshal(result, &env); err != nil {
+				t.Fatalf("failed to unmarshal result: %v", err)
+			}
+
+			assertFloat(t, env, "page", 3)
+			assertFloat(t, env, "total_items", 50)
+			assertFloat(t, env, "total_pages", 10)
+			assertBool(t, env, "has_next", true)
+			assertBool(t, env, "has_previous", true)
+			assertArrayLen(t, env, "data", 5)
+		})
+	}
+
+	t.Run("last page", func(t *testing.T) {
+		cfg := defaultPaginationConfig()
+		cfg.TotalItems = &fixed
+		result, err := Paginate([]byte(`{"items": [46,47,48,49,50]}`), cfg, map[string]string{"page": "10", "size": "5"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var env map[string]any
+		if err := json.Unmarshal(result, &env); err != nil {
+			t.Fatalf("failed to unmarshal result: %v", err)
+		}
+		assertBool(t, env, "has_next", false)
+	})
+
+	t.Run("non-numeric total", func(t *testing.T) {
+		cfg := defaultPaginationConfig()
+		cfg.TotalItemsPath = "$.meta.total"
+		if _, err := Paginate([]byte(`{"items": [], "meta": {"total": "many"}}`), cfg, nil); err == nil {
+			t.Error("expected error for non-numeric total")
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+)
+
+// Protobuf wire types (https://protobuf.dev/programming-guides/encoding/).
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// protoField is one decoded field from a protobuf message.
+type protoField struct {
+	number   uint64
+	wireType int
+	varint   uint64
+	data     []byte
+}
+
+// value renders the field for string matching: integers in decimal, and
+// length-delimited fields as their raw bytes.
+func (f protoField) value() string {
+	if f.wireType == wireBytes {
+		return string(f.data)
+	}
+	return strconv.FormatUint(f.varint, 10)
+}
+
+// parseProtoPath parses a dot-separated field number path such as "2.1".
+func parseProtoPath(path string) ([]uint64, error) {
+	parts := strings.Split(path, ".")
+	numbers := make([]uint64, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.ParseUint(strings.TrimSpace(p), 10, 29)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid protobuf field path %q: expected dot-separated field numbers", path)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// protobufPredicate creates a predicate that decodes the body as a protobuf
+// message without a schema and matches the values found at the field path.
+// Intermediate path elements must be length-delimited fields holding nested
+// messages. Repeated fields match when any occurrence matches.
+func protobufPredicate(path []uint64, valueMatcher match.Predicate) match.Predicate {
+	return func(body string) bool {
+		for _, f := range protoLookup(grpcUnframe([]byte(body)), path) {
+			if valueMatcher(f.value()) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// grpcUnframe strips a single gRPC length-prefixed frame (1-byte compression
+// flag plus 4-byte big-endian length) when the body carries one. A bare
+// protobuf message never starts with a zero byte, so the check is unambiguous.
+func grpcUnframe(b []byte) []byte {
+	if len(b) >= 5 && b[0] == 0 && int(binary.BigEndian.Uint32(b[1:5])) == len(b)-5 {
+		return b[5:]
+	}
+	return b
+}
+
+func protoLookup(msg []byte, path []uint64) []protoField {
+	fields, ok := parseProtoMessage(msg)
+	if !ok {
+		return nil
+	}
+
+	var found []protoField
+	for _, f := range fields {
+		if f.number != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			found = append(found, f)
+			continue
+		}
+		if f.wireType == wireBytes {
+			found = append(found, protoLookup(f.data, path[1:])...)
+		}
+	}
+	return found
+}
+
+// parseProtoMessage decodes the top-level fields of a wire-format message.
+// Groups (wire types 3 and 4) are not supported and make the message invalid.
+func parseProtoMessage(b []byte) ([]protoField, bool) {
+	var fields []protoField
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, false
+		}
+		b = b[n:]
+
+		f := protoField{number: tag >> 3, wireType: int(tag & 0x7)}
+		if f.number == 0 {
+			return nil, false
+		}
+
+		switch f.wireType {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return nil, false
+			}
+			f.varint = v
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, false
+			}
+			f.varint = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, false
+			}
+			f.varint = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return nil, false
+			}
+			f.data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			return nil, false
+		}
+		fields = append(fields, f)
+	}
+	return fields, true
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// MatchTracker remembers when each scenario was last used, by ID. It
+// outlives reloads, which rebuild compiled scenarios from scratch.
+type MatchTracker struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// NewMatchTracker creates an empty tracker.
+func NewMatchTracker() *MatchTracker {
+	return &MatchTracker{last: make(map[string]time.Time)}
+}
+
+// Touch records that the scenario matched at t.
+func (t *MatchTracker) Touch(id string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at.After(t.last[id]) {
+		t.last[id] = at
+	}
+}
+
+// LastUsed returns when the scenario last matched. A scenario never seen
+// before is recorded as used at now, so new scenarios start out fresh.
+func (t *MatchTracker) LastUsed(id string, now time.Time) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	at, ok := t.last[id]
+	if !ok {
+		t.last[id] = now
+		return now
+	}
+	return at
+}
+
+// Forget drops what is known about a scenario.
+func (t *MatchTracker) Forget(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.last, id)
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/expr-lang/expr"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+)
+
+// whenExprEnv is the environment of when.expr expressions. json returns the
+// typed value at a JSONPath of the request body (float64, string, bool, nil,
+// maps and slices), so fields can be compared with each other.
+type whenExprEnv struct {
+	JSON func(string) any `expr:"json"`
+	Body func() string    `expr:"body"`
+}
+
+// whenExprPredicate compiles a boolean Expr expression over the request body.
+// Evaluation errors, e.g. comparing a missing field, never match.
+func whenExprPredicate(source string) (match.Predicate, error) {
+	program, err := expr.Compile(source, expr.Env(whenExprEnv{}), expr.AsBool())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile expression %q: %w", source, err)
+	}
+	return func(body string) bool {
+		var data any
+		parsed := false
+		env := whenExprEnv{
+			JSON: func(path string) any {
+				if !parsed {
+					parsed = true
+					if err := parseJSON(body, &data); err != nil {
+						data = nil
+					}
+				}
+				if data == nil {
+					return nil
+				}
+				v, err := jsonpath.Get(path, data)
+				if err != nil {
+					return nil
+				}
+				return v
+			},
+			Body: func() string { return body },
+		}
+		out, err := expr.Run(program, env)
+		if err != nil {
+			return false
+		}
+		ok, _ := out.(bool)
+		return ok
+	}, nil
+}
@@ -2,7 +2,10 @@ package usecases
 
 import (
 	"context"
+	"maps"
 	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
@@ -13,11 +16,32 @@ import (
 
 // HandleRequestResult is the outcome of processing a mock request.
 type HandleRequestResult struct {
-	Matched     bool
+	Matched bool
+	// UnsupportedMediaType is set on unmatched requests when a candidate
+	// rejected the request Content-Type; the caller answers 415.
+	UnsupportedMediaType bool
+	// Response is the response to serve. When RateLimited it holds the
+	// scenario's custom rate-limit response, or nil for the default 429.
 	Response    *match.CompiledResponse
 	RateLimited bool
 	Pagination  *match.CompiledPagination
 	TraceEntry  trace.Entry
+	// HeaderDelay elapses before the status line and headers are written.
+	HeaderDelay time.Duration
+	// BodyDelay elapses between writing the headers and writing the body.
+	BodyDelay time.Duration
+	// HostParams holds segments captured by the matched scenario's host pattern.
+	HostParams map[string]string
+	// Deprecated is set when the matched scenario is deprecated.
+	Deprecated         bool
+	DeprecationMessage string
+	// Priority is the matched scenario's priority.
+	Priority int
+	// Quiet is set when the matched scenario suppresses per-request info logs.
+	Quiet bool
+	// Fault is the fault type the caller injects instead of writing the
+	// response ("" = none). HeaderDelay still applies before it.
+	Fault string
 }
 
 // HandleRequestUseCase processes incoming mock requests.
@@ -27,6 +51,10 @@ type HandleRequestUseCase struct {
 	rateLimiter ports.RateLimiter
 	logger      ports.Logger
 	traceBuf    *trace.RingBuffer
+	random      ports.RandomSource
+	tracker     *services.MatchTracker // nil = last matches not tracked
+	// globalLatency is an additive delay (nanoseconds) applied to every match.
+	globalLatency atomic.Int64
 }
 
 // NewHandleRequestUseCase creates a new use case.
@@ -43,18 +71,55 @@ func NewHandleRequestUseCase(
 		rateLimiter: rateLimiter,
 		logger:      logger,
 		traceBuf:    traceBuf,
+		random:      ports.RandomFunc(rand.IntN),
 	}
 }
 
+// SetRandomSource overrides the random source used for jitter, fault rates and
+// weighted selection.
+func (uc *HandleRequestUseCase) SetRandomSource(r ports.RandomSource) {
+	uc.random = r
+}
+
+// SetMatchTracker records the time of every match in t, for eviction of
+// least-recently-matched dynamic scenarios.
+func (uc *HandleRequestUseCase) SetMatchTracker(t *services.MatchTracker) {
+	uc.tracker = t
+}
+
+// SetGlobalLatency sets an additive latency applied to every matched request on
+// top of any per-scenario latency. A non-positive duration clears it.
+func (uc *HandleRequestUseCase) SetGlobalLatency(d time.Duration) {
+	uc.globalLatency.Store(int64(max(d, 0)))
+}
+
+// GlobalLatency returns the current global additive latency.
+func (uc *HandleRequestUseCase) GlobalLatency() time.Duration {
+	return time.Duration(uc.globalLatency.Load())
+}
+
 // Execute evaluates the request against candidates and returns the result.
 func (uc *HandleRequestUseCase) Execute(ctx context.Context, req *match.IncomingRequest, candidates []*match.CompiledScenario) HandleRequestResult {
+	if req.Now.IsZero() {
+		req.Now = uc.clock.Now()
+	}
 	evalResult := uc.evaluator.Evaluate(req, candidates)
 
 	entry := trace.Entry{
-		Timestamp:  uc.clock.Now(),
-		Method:     req.Method,
-		Path:       req.Path,
-		Candidates: evalResult.Candidates,
+		Timestamp:         uc.clock.Now(),
+		Method:            req.Method,
+		Path:              req.Path,
+		Candidates:        evalResult.Candidates,
+		CandidatesOmitted: evalResult.CandidatesOmitted,
+		Request: &trace.Request{
+			Host:          req.Host,
+			Headers:       req.Headers,
+			Query:         req.Query,
+			Cookies:       req.Cookies,
+			Body:          string(req.Body),
+			ContentLength: req.ContentLength,
+			Secure:        req.Secure,
+		},
 	}
 
 	result := HandleRequestResult{
@@ -63,13 +128,22 @@ func (uc *HandleRequestUseCase) Execute(ctx context.Context, req *match.Incoming
 
 	if evalResult.Matched == nil {
 		uc.logger.Debug("no match found", "method", req.Method, "path", req.Path)
+		result.UnsupportedMediaType = evalResult.UnsupportedMediaType
 		uc.traceBuf.Add(entry)
 		return result
 	}
 
-	matched := evalResult.Matched
+	matched := uc.selectMatch(evalResult)
 	entry.MatchedID = matched.ID
 	result.Matched = true
+	if uc.tracker != nil {
+		uc.tracker.Touch(matched.ID, req.Now)
+	}
+	result.HostParams = matched.HostParams(req.Host)
+	result.Deprecated = matched.Deprecated
+	result.DeprecationMessage = matched.DeprecationMessage
+	result.Priority = matched.Priority
+	result.Quiet = matched.Quiet
 
 	// Rate limiting check.
 	if matched.Policy != nil && matched.Policy.RateLimit != nil {
@@ -82,30 +156,56 @@ func (uc *HandleRequestUseCase) Execute(ctx context.Context, req *match.Incoming
 			uc.logger.Debug("rate limited", "scenario", matched.ID, "key", key)
 			entry.RateLimited = true
 			result.RateLimited = true
+			if rl.Response != nil {
+				resp := rl.Response.ResolveTime(req.Now).Resolve(req.Body)
+				if resp.ContentType == "" && !resp.Raw {
+					resp.ContentType = services.InferContentType("", resp.BodyFile, resp.Body)
+				}
+				result.Response = &resp
+			}
 			result.TraceEntry = entry
 			uc.traceBuf.Add(entry)
 			return result
 		}
 	}
 
-	// Latency simulation (respects context cancellation).
+	// Latency simulation: resolve the header and body phases. The caller applies
+	// them around writing the response via Wait.
 	if matched.Policy != nil && matched.Policy.Latency != nil {
 		lat := matched.Policy.Latency
-		delay := time.Duration(lat.FixedMs) * time.Millisecond
+		result.HeaderDelay = time.Duration(lat.HeaderDelayMs) * time.Millisecond
+		result.BodyDelay = time.Duration(lat.FixedMs) * time.Millisecond
 		if lat.JitterMs > 0 {
-			delay += time.Duration(rand.IntN(lat.JitterMs)) * time.Millisecond
-		}
-		if delay > 0 {
-			if err := uc.clock.SleepContext(ctx, delay); err != nil {
-				uc.logger.Debug("latency sleep cancelled", "scenario", matched.ID, "error", err)
-			}
+			result.BodyDelay += time.Duration(uc.random.IntN(lat.JitterMs)) * time.Millisecond
 		}
 	}
+	result.BodyDelay += uc.GlobalLatency()
 
-	resp := matched.Response
+	if matched.Policy != nil && matched.Policy.Fault != nil && uc.faultTriggered(matched.Policy.Fault.Rate) {
+		uc.logger.Debug("fault injected", "scenario", matched.ID, "fault", matched.Policy.Fault.Type)
+		entry.Fault = matched.Policy.Fault.Type
+		result.Fault = matched.Policy.Fault.Type
+		result.TraceEntry = entry
+		uc.traceBuf.Add(entry)
+		return result
+	}
+
+	resp := matched.NextResponse().ResolveTime(req.Now).Resolve(req.Body)
 	// Infer content type if not explicitly set.
-	if resp.ContentType == "" {
-		resp.ContentType = services.InferContentType("", "", resp.Body)
+	if resp.ContentType == "" && !resp.Raw {
+		resp.ContentType = services.InferContentType("", resp.BodyFile, resp.Body)
+	}
+	if resp.Charset != "" {
+		resp.ContentType = services.WithCharset(resp.ContentType, resp.Charset)
+	}
+	if resp.Cache != nil {
+		// Copy before adding headers: the compiled map is shared across requests.
+		resp.Headers = maps.Clone(resp.Headers)
+		if resp.Headers == nil {
+			resp.Headers = make(map[string]string, 2)
+		}
+		resp.Headers["Cache-Control"] = resp.Cache.CacheControl
+		resp.Headers["Expires"] = req.Now.Add(resp.Cache.MaxAge).UTC().Format(http.TimeFormat)
 	}
 	result.Response = &resp
 
@@ -114,7 +214,121 @@ func (uc *HandleRequestUseCase) Execute(ctx context.Context, req *match.Incoming
 	}
 
 	result.TraceEntry = entry
+	if resp.Proxy != nil {
+		// The upstream status is only known once the caller has proxied the
+		// request, so it adds the trace entry itself via TraceProxied.
+		return result
+	}
 	uc.traceBuf.Add(entry)
 
 	return result
 }
+
+// TraceProxied records the trace entry of a proxied match along with the
+// status returned by the upstream.
+func (uc *HandleRequestUseCase) TraceProxied(entry trace.Entry, upstreamStatus int) {
+	entry.UpstreamStatus = upstreamStatus
+	uc.traceBuf.Add(entry)
+}
+
+// faultResolution is the granularity of fault rates.
+const faultResolution = 1_000_000
+
+// faultTriggered reports whether a request faults given a rate in (0, 1].
+func (uc *HandleRequestUseCase) faultTriggered(rate float64) bool {
+	return rate >= 1 || uc.random.IntN(faultResolution) < int(rate*faultResolution)
+}
+
+// Wait blocks for d using the injected clock, returning early with ctx.Err()
+// if the context is cancelled. Non-positive durations return immediately.
+func (uc *HandleRequestUseCase) Wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	return uc.clock.SleepContext(ctx, d)
+}
+
+// ReplayResult is the outcome of re-evaluating a traced request.
+type ReplayResult struct {
+	MatchedID         string                  `json:"matched_id"`
+	Candidates        []trace.CandidateResult `json:"candidates"`
+	CandidatesOmitted int                     `json:"candidates_omitted,omitempty"`
+}
+
+// ReplayRequest rebuilds the request captured in a trace entry, evaluated
+// at its original time.
+func ReplayRequest(e trace.Entry) *match.IncomingRequest {
+	return &match.IncomingRequest{
+		Method:        e.Method,
+		Path:          e.Path,
+		Host:          e.Request.Host,
+		Headers:       e.Request.Headers,
+		Query:         e.Request.Query,
+		Cookies:       e.Request.Cookies,
+		Body:          []byte(e.Request.Body),
+		ContentLength: e.Request.ContentLength,
+		Now:           e.Timestamp,
+		Secure:        e.Request.Secure,
+	}
+}
+
+// Replay evaluates req against candidates without serving it: no rate
+// limiting, no response selection and no trace entry. MatchedID is the
+// first-ranked match.
+func (uc *HandleRequestUseCase) Replay(req *match.IncomingRequest, candidates []*match.CompiledScenario) ReplayResult {
+	if req.Now.IsZero() {
+		req.Now = uc.clock.Now()
+	}
+	evalResult := uc.evaluator.Evaluate(req, candidates)
+	result := ReplayResult{
+		Candidates:        evalResult.Candidates,
+		CandidatesOmitted: evalResult.CandidatesOmitted,
+	}
+	if result.Candidates == nil {
+		result.Candidates = []trace.CandidateResult{}
+	}
+	if evalResult.Matched != nil {
+		result.MatchedID = evalResult.Matched.ID
+	}
+	return result
+}
+
+// selectMatch returns the first match, unless it is load-balanced, in which case
+// a weighted random choice is made among all load-balanced matches that share
+// its priority. Deterministic first-match remains the default.
+func (uc *HandleRequestUseCase) selectMatch(evalResult match.EvalResult) *match.CompiledScenario {
+	first := evalResult.Matched
+	if loadBalanceWeight(first) == 0 {
+		return first
+	}
+
+	var pool []*match.CompiledScenario
+	total := 0
+	for _, cs := range evalResult.Matches {
+		w := loadBalanceWeight(cs)
+		if w == 0 || cs.Priority != first.Priority {
+			continue
+		}
+		pool = append(pool, cs)
+		total += w
+	}
+	if len(pool) < 2 {
+		return first
+	}
+
+	pick := uc.random.IntN(total)
+	for _, cs := range pool {
+		pick -= loadBalanceWeight(cs)
+		if pick < 0 {
+			return cs
+		}
+	}
+	return first
+}
+
+func loadBalanceWeight(cs *match.CompiledScenario) int {
+	if cs.Policy == nil || cs.Policy.LoadBalance == nil {
+		return 0
+	}
+	return cs.Policy.LoadBalance.Weight
+}
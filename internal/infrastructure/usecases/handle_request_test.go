@@ -2,11 +2,15 @@ package usecases_test
 
 import (
 	"context"
+	"math/rand/v2"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
+	"github.com/sophialabs/proteusmock/internal/domain/scenario"
 	"github.com/sophialabs/proteusmock/internal/domain/trace"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/services"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/usecases"
 	"github.com/sophialabs/proteusmock/internal/testutil"
 )
@@ -132,7 +136,7 @@ func TestHandleRequest_LatencyPolicy(t *testing.T) {
 			},
 			Response: match.CompiledResponse{Status: 200, Body: []byte("ok")},
 			Policy: &match.CompiledPolicy{
-				Latency: &match.CompiledLatency{FixedMs: 100, JitterMs: 50},
+				Latency: &match.CompiledLatency{FixedMs: 100, JitterMs: 50, HeaderDelayMs: 30},
 			},
 		},
 	}
@@ -148,6 +152,12 @@ func TestHandleRequest_LatencyPolicy(t *testing.T) {
 	if result.Response.Status != 200 {
 		t.Errorf("expected status 200, got %d", result.Response.Status)
 	}
+	if result.HeaderDelay != 30*time.Millisecond {
+		t.Errorf("expected header delay 30ms, got %v", result.HeaderDelay)
+	}
+	if result.BodyDelay < 100*time.Millisecond || result.BodyDelay >= 150*time.Millisecond {
+		t.Errorf("expected body delay in [100ms, 150ms), got %v", result.BodyDelay)
+	}
 }
 
 func TestHandleRequest_ContentTypeInference(t *testing.T) {
@@ -311,3 +321,392 @@ func TestHandleRequest_TraceEntryRecorded(t *testing.T) {
 		t.Errorf("expected path /api/traced, got %s", entries[0].Path)
 	}
 }
+
+func TestHandleRequest_LoadBalancedDistribution(t *testing.T) {
+	uc := newHandleRequestUC(true)
+	uc.SetRandomSource(rand.New(rand.NewPCG(1, 2)))
+
+	always := []match.FieldPredicate{{Field: "method", Predicate: func(string) bool { return true }}}
+	candidates := []*match.CompiledScenario{
+		{
+			ID: "backend-a", Priority: 10, Predicates: always,
+			Response: match.CompiledResponse{Status: 200, Body: []byte("a")},
+			Policy:   &match.CompiledPolicy{LoadBalance: &match.CompiledLoadBalance{Weight: 3}},
+		},
+		{
+			ID: "backend-b", Priority: 10, Predicates: always,
+			Response: match.CompiledResponse{Status: 200, Body: []byte("b")},
+			Policy:   &match.CompiledPolicy{LoadBalance: &match.CompiledLoadBalance{Weight: 1}},
+		},
+		{
+			// Lower priority: never part of the pool.
+			ID: "fallback", Priority: 1, Predicates: always,
+			Response: match.CompiledResponse{Status: 200, Body: []byte("fallback")},
+			Policy:   &match.CompiledPolicy{LoadBalance: &match.CompiledLoadBalance{Weight: 100}},
+		},
+	}
+
+	counts := map[string]int{}
+	const n = 4000
+	for range n {
+		result := uc.Execute(context.Background(), &match.IncomingRequest{Method: "GET", Path: "/lb"}, candidates)
+		counts[result.TraceEntry.MatchedID]++
+	}
+
+	if counts["fallback"] != 0 {
+		t.Errorf("lower-priority scenario should never be selected, got %d", counts["fallback"])
+	}
+	ratioA := float64(counts["backend-a"]) / n
+	if ratioA < 0.70 || ratioA > 0.80 {
+		t.Errorf("expected backend-a near 75%%, got %.2f (%v)", ratioA, counts)
+	}
+}
+
+func TestHandleRequest_FirstMatchWithoutLoadBalance(t *testing.T) {
+	uc := newHandleRequestUC(true)
+	uc.SetRandomSource(rand.New(rand.NewPCG(1, 2)))
+
+	always := []match.FieldPredicate{{Field: "method", Predicate: func(string) bool { return true }}}
+	candidates := []*match.CompiledScenario{
+		{ID: "first", Priority: 10, Predicates: always, Response: match.CompiledResponse{Status: 200}},
+		{
+			ID: "second", Priority: 10, Predicates: always,
+			Response: match.CompiledResponse{Status: 200},
+			Policy:   &match.CompiledPolicy{LoadBalance: &match.CompiledLoadBalance{Weight: 5}},
+		},
+	}
+
+	for range 50 {
+		result := uc.Execute(context.Background(), &match.IncomingRequest{Method: "GET", Path: "/lb"}, candidates)
+		if result.TraceEntry.MatchedID != "first" {
+			t.Fatalf("expected deterministic first match, got %q", result.TraceEntry.MatchedID)
+		}
+	}
+}
+
+func TestHandleRequest_GlobalLatency(t *testing.T) {
+	uc := newHandleRequestUC(true)
+	req := &match.IncomingRequest{Method: "GET", Path: "/api/slow"}
+	candidates := []*match.CompiledScenario{
+		{
+			ID:       "slow",
+			Method:   "GET",
+			PathKey:  "GET:/api/slow",
+			Response: match.CompiledResponse{Status: 200},
+			Policy: &match.CompiledPolicy{
+				Latency: &match.CompiledLatency{FixedMs: 100},
+			},
+		},
+		{
+			ID:       "fast",
+			Method:   "GET",
+			PathKey:  "GET:/api/fast",
+			Response: match.CompiledResponse{Status: 200},
+		},
+	}
+
+	uc.SetGlobalLatency(250 * time.Millisecond)
+	if uc.GlobalLatency() != 250*time.Millisecond {
+		t.Fatalf("expected global latency 250ms, got %v", uc.GlobalLatency())
+	}
+
+	// Composes with per-scenario latency.
+	result := uc.Execute(context.Background(), req, candidates[:1])
+	if result.BodyDelay != 350*time.Millisecond {
+		t.Errorf("expected body delay 350ms, got %v", result.BodyDelay)
+	}
+
+	// Applies to scenarios without a latency policy.
+	result = uc.Execute(context.Background(), &match.IncomingRequest{Method: "GET", Path: "/api/fast"}, candidates[1:])
+	if result.BodyDelay != 250*time.Millisecond {
+		t.Errorf("expected body delay 250ms, got %v", result.BodyDelay)
+	}
+
+	uc.SetGlobalLatency(0)
+	result = uc.Execute(context.Background(), req, candidates[:1])
+	if result.BodyDelay != 100*time.Millisecond {
+		t.Errorf("expected body delay 100ms after clearing, got %v", result.BodyDelay)
+	}
+}
+
+func TestHandleRequest_ScheduleUsesInjectedClock(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:       "night-maintenance",
+		When:     scenario.WhenClause{Method: "GET", Path: "/status", Schedule: "* 0-5 * * *"},
+		Response: scenario.Response{Status: 503},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"inside window", time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC), true},
+		{"outside window", time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := usecases.NewHandleRequestUseCase(
+				match.NewEvaluator(),
+				&testutil.FixedClock{T: tt.now},
+				&testutil.StubRateLimiter{AllowAll: true},
+				&testutil.NoopLogger{},
+				trace.NewRingBuffer(10),
+			)
+			result := uc.Execute(context.Background(), &match.IncomingRequest{Method: "GET", Path: "/status"}, []*match.CompiledScenario{cs})
+			if result.Matched != tt.want {
+				t.Errorf("expected matched=%v, got %v", tt.want, result.Matched)
+			}
+		})
+	}
+}
+
+func TestHandleRequest_SwitchDispatchesOnBodyType(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:   "dispatcher",
+		When: scenario.WhenClause{Method: "POST", Path: "/events"},
+		Response: scenario.Response{
+			Status: 202,
+			Body:   `{"handled":"fallback"}`,
+			Switch: &scenario.ResponseSwitch{
+				On: "$.type",
+				Cases: map[string]scenario.Response{
+					"created": {Status: 201, Body: `{"handled":"created"}`},
+					"deleted": {Status: 204},
+				},
+				Default: &scenario.Response{Status: 400, Body: `{"error":"unknown type"}`},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	uc := newHandleRequestUC(true)
+	tests := []struct {
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{`{"type":"created","id":1}`, 201, `{"handled":"created"}`},
+		{`{"type":"deleted"}`, 204, ""},
+		{`{"type":"renamed"}`, 400, `{"error":"unknown type"}`},
+		{`{"id":1}`, 400, `{"error":"unknown type"}`},
+		{`not json`, 400, `{"error":"unknown type"}`},
+	}
+	for _, tt := range tests {
+		req := &match.IncomingRequest{Method: "POST", Path: "/events", Body: []byte(tt.body)}
+		result := uc.Execute(context.Background(), req, []*match.CompiledScenario{cs})
+		if !result.Matched {
+			t.Fatalf("body %s: expected match", tt.body)
+		}
+		if result.Response.Status != tt.wantStatus || string(result.Response.Body) != tt.wantBody {
+			t.Errorf("body %s: expected %d %q, got %d %q", tt.body, tt.wantStatus, tt.wantBody, result.Response.Status, result.Response.Body)
+		}
+		if result.Response.Switch != nil {
+			t.Errorf("body %s: expected resolved response without switch", tt.body)
+		}
+	}
+
+	// Without a default, the enclosing response is served.
+	cs.Response.Switch.Default = nil
+	result := uc.Execute(context.Background(), &match.IncomingRequest{Method: "POST", Path: "/events", Body: []byte(`{"type":"x"}`)}, []*match.CompiledScenario{cs})
+	if result.Response.Status != 202 || string(result.Response.Body) != `{"handled":"fallback"}` {
+		t.Errorf("expected enclosing response, got %d %q", result.Response.Status, result.Response.Body)
+	}
+}
+
+func TestHandleRequest_TimeSwitchBusinessHours(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:   "store-status",
+		When: scenario.WhenClause{Method: "GET", Path: "/store/status"},
+		Response: scenario.Response{
+			Status: 200,
+			Body:   `{"status":"closed"}`,
+			TimeSwitch: &scenario.TimeSwitch{Cases: []scenario.TimeCase{
+				{Schedule: "* 9-17 * * mon-fri", Response: scenario.Response{Status: 200, Body: `{"status":"open"}`}},
+				{Schedule: "* 10-13 * * sat", Response: scenario.Response{Status: 200, Body: `{"status":"open","hours":"short"}`}},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"weekday morning", time.Date(2025, 6, 2, 9, 30, 0, 0, time.UTC), `{"status":"open"}`}, // Monday
+		{"weekday evening", time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC), `{"status":"closed"}`},
+		{"saturday", time.Date(2025, 6, 7, 11, 0, 0, 0, time.UTC), `{"status":"open","hours":"short"}`},
+		{"sunday", time.Date(2025, 6, 8, 11, 0, 0, 0, time.UTC), `{"status":"closed"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := usecases.NewHandleRequestUseCase(
+				match.NewEvaluator(),
+				&testutil.FixedClock{T: tt.now},
+				&testutil.StubRateLimiter{AllowAll: true},
+				&testutil.NoopLogger{},
+				trace.NewRingBuffer(10),
+			)
+			result := uc.Execute(context.Background(), &match.IncomingRequest{Method: "GET", Path: "/store/status"}, []*match.CompiledScenario{cs})
+			if !result.Matched {
+				t.Fatal("expected match")
+			}
+			if string(result.Response.Body) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, result.Response.Body)
+			}
+			if result.Response.TimeSwitch != nil {
+				t.Error("expected resolved response without time switch")
+			}
+		})
+	}
+
+	_, err = compiler.CompileScenario(&scenario.Scenario{
+		ID:   "bad-schedule",
+		When: scenario.WhenClause{Method: "GET", Path: "/x"},
+		Response: scenario.Response{TimeSwitch: &scenario.TimeSwitch{Cases: []scenario.TimeCase{
+			{Schedule: "9-17 * *", Response: scenario.Response{Status: 200}},
+		}}},
+	})
+	if err == nil {
+		t.Error("expected invalid schedule to fail compilation")
+	}
+}
+
+func TestHandleRequest_ResponseSequence(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	compile := func(id string, cycle bool) *match.CompiledScenario {
+		t.Helper()
+		cs, err := compiler.CompileScenario(&scenario.Scenario{
+			ID:   id,
+			When: scenario.WhenClause{Method: "GET", Path: "/flaky"},
+			Responses: []scenario.Response{
+				{Status: 503},
+				{Status: 503},
+				{Status: 200, Body: `{"ok":true}`},
+			},
+			Cycle: cycle,
+		})
+		if err != nil {
+			t.Fatalf("CompileScenario failed: %v", err)
+		}
+		return cs
+	}
+
+	tests := []struct {
+		name  string
+		cycle bool
+		want  []int
+	}{
+		{"stick on last", false, []int{503, 503, 200, 200, 200}},
+		{"cycle", true, []int{503, 503, 200, 503, 503}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := compile(tt.name, tt.cycle)
+			uc := newHandleRequestUC(true)
+			for i, want := range tt.want {
+				req := &match.IncomingRequest{Method: "GET", Path: "/flaky"}
+				result := uc.Execute(context.Background(), req, []*match.CompiledScenario{cs})
+				if result.Response.Status != want {
+					t.Errorf("call %d: expected %d, got %d", i+1, want, result.Response.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleRequest_ResponseSequenceConcurrent(t *testing.T) {
+	compiler, err := services.NewCompiler(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewCompiler failed: %v", err)
+	}
+	cs, err := compiler.CompileScenario(&scenario.Scenario{
+		ID:        "sequence",
+		When:      scenario.WhenClause{Method: "GET", Path: "/seq"},
+		Responses: []scenario.Response{{Status: 200}, {Status: 201}},
+		Cycle:     true,
+	})
+	if err != nil {
+		t.Fatalf("CompileScenario failed: %v", err)
+	}
+
+	uc := newHandleRequestUC(true)
+	const calls = 100
+	statuses := make(chan int, calls)
+	var wg sync.WaitGroup
+	for range calls {
+		wg.Go(func() {
+			req := &match.IncomingRequest{Method: "GET", Path: "/seq"}
+			statuses <- uc.Execute(context.Background(), req, []*match.CompiledScenario{cs}).Response.Status
+		})
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := make(map[int]int)
+	for s := range statuses {
+		counts[s]++
+	}
+	if counts[200] != calls/2 || counts[201] != calls/2 {
+		t.Errorf("expected an even split across the sequence, got %v", counts)
+	}
+}
+
+func TestHandleRequest_FaultRate(t *testing.T) {
+	uc := newHandleRequestUC(true)
+	uc.SetRandomSource(rand.New(rand.NewPCG(1, 2)))
+
+	candidates := []*match.CompiledScenario{{
+		ID:       "flaky",
+		Response: match.CompiledResponse{Status: 200},
+		Policy: &match.CompiledPolicy{
+			Fault: &match.CompiledFault{Type: match.FaultResetConnection, Rate: 0.25},
+		},
+	}}
+
+	faults := 0
+	const n = 4000
+	for range n {
+		result := uc.Execute(context.Background(), &match.IncomingRequest{Method: "GET", Path: "/flaky"}, candidates)
+		switch result.Fault {
+		case match.FaultResetConnection:
+			faults++
+			if result.TraceEntry.Fault != match.FaultResetConnection {
+				t.Fatalf("expected fault in trace entry, got %q", result.TraceEntry.Fault)
+			}
+		case "":
+			if result.Response == nil || result.Response.Status != 200 {
+				t.Fatalf("expected normal response when not faulting, got %+v", result.Response)
+			}
+		default:
+			t.Fatalf("unexpected fault %q", result.Fault)
+		}
+	}
+	if ratio := float64(faults) / n; ratio < 0.20 || ratio > 0.30 {
+		t.Errorf("expected about 25%% faults, got %.2f", ratio)
+	}
+}
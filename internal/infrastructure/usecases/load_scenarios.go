@@ -2,7 +2,11 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/sophialabs/proteusmock/internal/domain/scenario"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
@@ -11,10 +15,17 @@ import (
 
 // LoadScenariosUseCase loads all scenarios, compiles them, and builds an index.
 type LoadScenariosUseCase struct {
-	repo          scenario.Repository
-	compiler      *services.Compiler
-	logger        ports.Logger
-	defaultEngine string
+	repo           scenario.Repository
+	compiler       *services.Compiler
+	logger         ports.Logger
+	defaultEngine  string
+	activeProfiles map[string]bool
+	strict         bool
+
+	// maxDynamic caps dynamic scenarios kept across reloads (0 = unlimited).
+	maxDynamic int
+	tracker    *services.MatchTracker
+	clock      ports.Clock
 }
 
 // NewLoadScenariosUseCase creates a new use case.
@@ -31,6 +42,33 @@ func (uc *LoadScenariosUseCase) SetDefaultEngine(engine string) {
 	uc.defaultEngine = engine
 }
 
+// SetStrict makes Execute fail when any scenario fails to compile instead of
+// skipping it.
+func (uc *LoadScenariosUseCase) SetStrict(strict bool) {
+	uc.strict = strict
+}
+
+// SetDynamicEviction caps the number of dynamic scenarios. When a load finds
+// more, the least recently matched ones, per tracker, are deleted from the
+// repository, so with eviction enabled Execute removes files or entries of
+// multi-scenario files on disk. A scenario whose deletion fails stays loaded
+// until a later load deletes it. New scenarios count as used when first
+// loaded. Zero disables eviction.
+func (uc *LoadScenariosUseCase) SetDynamicEviction(maxDynamic int, tracker *services.MatchTracker, clock ports.Clock) {
+	uc.maxDynamic = maxDynamic
+	uc.tracker = tracker
+	uc.clock = clock
+}
+
+// SetActiveProfiles restricts loading to scenarios that declare no profiles or
+// at least one of the given profiles.
+func (uc *LoadScenariosUseCase) SetActiveProfiles(profiles []string) {
+	uc.activeProfiles = make(map[string]bool, len(profiles))
+	for _, p := range profiles {
+		uc.activeProfiles[p] = true
+	}
+}
+
 // Execute loads, compiles, validates, and returns the built index.
 func (uc *LoadScenariosUseCase) Execute(ctx context.Context) (*services.ScenarioIndex, error) {
 	scenarios, err := uc.repo.LoadAll(ctx)
@@ -40,11 +78,19 @@ func (uc *LoadScenariosUseCase) Execute(ctx context.Context) (*services.Scenario
 
 	uc.logger.Info("loaded scenarios from repository", "count", len(scenarios))
 
-	// Apply global default engine where not overridden.
+	scenarios = uc.evictDynamic(ctx, scenarios)
+	scenarios = uc.filterByProfile(scenarios)
+
+	// Apply global default engine where not overridden. Mirror scenarios
+	// have no body to template.
 	if uc.defaultEngine != "" {
 		for _, s := range scenarios {
-			if s.Response.Engine == "" {
-				s.Response.Engine = uc.defaultEngine
+			if s.Kind == scenario.KindMirror {
+				continue
+			}
+			applyDefaultEngine(&s.Response, uc.defaultEngine)
+			for i := range s.Responses {
+				applyDefaultEngine(&s.Responses[i], uc.defaultEngine)
 			}
 		}
 	}
@@ -58,28 +104,147 @@ func (uc *LoadScenariosUseCase) Execute(ctx context.Context) (*services.Scenario
 		ids[s.ID] = true
 	}
 
-	// Compile and build index.
+	// Compile and build index. Identical static bodies share storage within a load.
+	uc.compiler.ResetBodyCache()
 	index := services.NewScenarioIndex()
-	var compileErrors []string
+	var compileErrors []error
 
 	for _, s := range scenarios {
 		cs, err := uc.compiler.CompileScenario(s)
 		if err != nil {
-			compileErrors = append(compileErrors, err.Error())
+			compileErrors = append(compileErrors, err)
 			uc.logger.Warn("failed to compile scenario", "id", s.ID, "error", err)
 			continue
 		}
+		for _, w := range cs.Warnings {
+			uc.logger.Warn("scenario compiled with warning", "id", cs.ID, "warning", w)
+		}
 		index.Add(cs)
 		uc.logger.Debug("compiled scenario", "id", cs.ID, "key", cs.PathKey)
 	}
 
+	if len(compileErrors) > 0 && uc.strict {
+		return nil, fmt.Errorf("%d scenario(s) failed to compile: %w", len(compileErrors), errors.Join(compileErrors...))
+	}
 	if len(compileErrors) > 0 {
 		uc.logger.Warn("some scenarios failed to compile", "errors", len(compileErrors))
 	}
 
-	index.Build()
+	if err := index.Build(); err != nil {
+		return nil, fmt.Errorf("failed to build scenario index: %w", err)
+	}
 
 	uc.logger.Info("scenario index built", "keys", len(index.Keys()), "paths", len(index.Paths()))
 
 	return index, nil
 }
+
+// applyDefaultEngine sets engine on r and its switch and time switch
+// responses where unset. Raw and generated responses are never templated.
+func applyDefaultEngine(r *scenario.Response, engine string) {
+	if r.Engine == "" && !r.Raw && r.Generate == nil {
+		r.Engine = engine
+	}
+	if r.Switch != nil {
+		for value, c := range r.Switch.Cases {
+			applyDefaultEngine(&c, engine)
+			r.Switch.Cases[value] = c
+		}
+		if r.Switch.Default != nil {
+			applyDefaultEngine(r.Switch.Default, engine)
+		}
+	}
+	if r.TimeSwitch != nil {
+		for i := range r.TimeSwitch.Cases {
+			applyDefaultEngine(&r.TimeSwitch.Cases[i].Response, engine)
+		}
+		if r.TimeSwitch.Default != nil {
+			applyDefaultEngine(r.TimeSwitch.Default, engine)
+		}
+	}
+}
+
+// evictDynamic removes the least recently matched dynamic scenarios beyond
+// the cap, in every profile, and returns the scenarios that remain.
+func (uc *LoadScenariosUseCase) evictDynamic(ctx context.Context, scenarios []*scenario.Scenario) []*scenario.Scenario {
+	if uc.maxDynamic <= 0 || uc.tracker == nil {
+		return scenarios
+	}
+
+	var dynamic []*scenario.Scenario
+	for _, s := range scenarios {
+		if s.Dynamic {
+			dynamic = append(dynamic, s)
+		}
+	}
+	if len(dynamic) <= uc.maxDynamic {
+		return scenarios
+	}
+
+	now := uc.clock.Now()
+	lastUsed := make(map[*scenario.Scenario]time.Time, len(dynamic))
+	for _, s := range dynamic {
+		lastUsed[s] = uc.tracker.LastUsed(s.ID, now)
+	}
+	slices.SortStableFunc(dynamic, func(a, b *scenario.Scenario) int {
+		if c := lastUsed[a].Compare(lastUsed[b]); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	// Delete from the end of multi-scenario files first so the remaining
+	// source indexes stay valid.
+	victims := dynamic[:len(dynamic)-uc.maxDynamic]
+	slices.SortFunc(victims, func(a, b *scenario.Scenario) int {
+		if c := strings.Compare(a.SourceFile, b.SourceFile); c != 0 {
+			return c
+		}
+		return b.SourceIndex - a.SourceIndex
+	})
+
+	evicted := make(map[*scenario.Scenario]bool)
+	for _, s := range victims {
+		if err := uc.repo.DeleteScenario(ctx, s.SourceFile, s.SourceIndex); err != nil {
+			// Keep the scenario loaded and tracked; the next load retries.
+			uc.logger.Warn("failed to delete evicted scenario", "id", s.ID, "error", err)
+			continue
+		}
+		uc.tracker.Forget(s.ID)
+		evicted[s] = true
+		uc.logger.Info("evicted dynamic scenario", "id", s.ID, "last_matched", lastUsed[s])
+	}
+
+	kept := make([]*scenario.Scenario, 0, len(scenarios)-len(evicted))
+	for _, s := range scenarios {
+		if !evicted[s] {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// filterByProfile drops scenarios whose profiles don't intersect the active set.
+func (uc *LoadScenariosUseCase) filterByProfile(scenarios []*scenario.Scenario) []*scenario.Scenario {
+	kept := make([]*scenario.Scenario, 0, len(scenarios))
+	for _, s := range scenarios {
+		if uc.profileActive(s.Profiles) {
+			kept = append(kept, s)
+			continue
+		}
+		uc.logger.Debug("skipping scenario for inactive profile", "id", s.ID, "profiles", s.Profiles)
+	}
+	return kept
+}
+
+func (uc *LoadScenariosUseCase) profileActive(profiles []string) bool {
+	if len(profiles) == 0 {
+		return true
+	}
+	for _, p := range profiles {
+		if uc.activeProfiles[p] {
+			return true
+		}
+	}
+	return false
+}
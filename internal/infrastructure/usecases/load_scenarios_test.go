@@ -2,8 +2,12 @@ package usecases_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sophialabs/proteusmock/internal/domain/scenario"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/services"
@@ -14,6 +18,8 @@ import (
 type mockRepo struct {
 	scenarios []*scenario.Scenario
 	err       error
+	deleted   []string // source files passed to DeleteScenario
+	deleteErr error
 }
 
 func (r *mockRepo) LoadAll(_ context.Context) ([]*scenario.Scenario, error) {
@@ -33,7 +39,11 @@ func (r *mockRepo) SaveScenario(_ context.Context, _ *scenario.Scenario, _ []byt
 	return nil
 }
 
-func (r *mockRepo) DeleteScenario(_ context.Context, _ string, _ int) error {
+func (r *mockRepo) DeleteScenario(_ context.Context, sourceFile string, _ int) error {
+	if r.deleteErr != nil {
+		return r.deleteErr
+	}
+	r.deleted = append(r.deleted, sourceFile)
 	return nil
 }
 
@@ -140,6 +150,74 @@ func TestLoadScenariosUseCase_SetDefaultEngine(t *testing.T) {
 	_ = idx
 }
 
+func TestLoadScenariosUseCase_DefaultEngineSkipsGenerate(t *testing.T) {
+	repo := &mockRepo{
+		scenarios: []*scenario.Scenario{
+			{
+				ID:   "generated",
+				When: scenario.WhenClause{Method: "GET", Path: "/api/users"},
+				Response: scenario.Response{
+					Status:   200,
+					Generate: &scenario.Generate{Count: 2, Fields: map[string]string{"id": "uuid"}},
+				},
+			},
+		},
+	}
+
+	uc := usecases.NewLoadScenariosUseCase(repo, newTestCompiler(t), &testutil.NoopLogger{})
+	uc.SetDefaultEngine("expr")
+	uc.SetStrict(true)
+	idx, err := uc.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if _, ok := idx.ByID("generated"); !ok {
+		t.Error("expected the generate scenario to compile under a default engine")
+	}
+	if got := repo.scenarios[0].Response.Engine; got != "" {
+		t.Errorf("expected no engine on a generate response, got %q", got)
+	}
+}
+
+func TestLoadScenariosUseCase_DefaultEngineTimeSwitch(t *testing.T) {
+	repo := &mockRepo{
+		scenarios: []*scenario.Scenario{
+			{
+				ID:   "hours",
+				When: scenario.WhenClause{Method: "GET", Path: "/api/status"},
+				Response: scenario.Response{
+					Status: 200,
+					Body:   "open",
+					TimeSwitch: &scenario.TimeSwitch{
+						Cases: []scenario.TimeCase{
+							{Schedule: "* 0-8 * * *", Response: scenario.Response{Status: 503, Body: "closed until ${now()}"}},
+							{Schedule: "* * * * 0", Response: scenario.Response{Status: 503, Body: "weekend", Raw: true}},
+						},
+						Default: &scenario.Response{Status: 200, Body: "open at ${now()}"},
+					},
+				},
+			},
+		},
+	}
+
+	uc := usecases.NewLoadScenariosUseCase(repo, newTestCompiler(t), &testutil.NoopLogger{})
+	uc.SetDefaultEngine("expr")
+	if _, err := uc.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	ts := repo.scenarios[0].Response.TimeSwitch
+	if got := ts.Cases[0].Response.Engine; got != "expr" {
+		t.Errorf("expected the default engine on a time case, got %q", got)
+	}
+	if got := ts.Cases[1].Response.Engine; got != "" {
+		t.Errorf("expected no engine on a raw time case, got %q", got)
+	}
+	if got := ts.Default.Engine; got != "expr" {
+		t.Errorf("expected the default engine on the time switch default, got %q", got)
+	}
+}
+
 func TestLoadScenariosUseCase_PartialCompileFailure(t *testing.T) {
 	repo := &mockRepo{
 		scenarios: []*scenario.Scenario{
@@ -172,3 +250,176 @@ func TestLoadScenariosUseCase_PartialCompileFailure(t *testing.T) {
 		t.Errorf("expected 1 compiled scenario (partial failure), got %d", len(idx.All()))
 	}
 }
+
+func TestLoadScenariosUseCase_StrictCompileFailure(t *testing.T) {
+	repo := &mockRepo{
+		scenarios: []*scenario.Scenario{
+			{
+				ID: "good", Priority: 10,
+				When:     scenario.WhenClause{Method: "GET", Path: "/ok"},
+				Response: scenario.Response{Status: 200, Body: "ok"},
+			},
+			{
+				ID:       "bad-regex",
+				When:     scenario.WhenClause{Method: "GET", Path: "/a", Headers: map[string]scenario.StringMatcher{"X-Bad": {Pattern: "[invalid"}}},
+				Response: scenario.Response{Status: 200},
+			},
+			{
+				ID:       "bad-method",
+				When:     scenario.WhenClause{Method: "NOT A METHOD", Path: "/b"},
+				Response: scenario.Response{Status: 200},
+			},
+		},
+	}
+
+	uc := usecases.NewLoadScenariosUseCase(repo, newTestCompiler(t), &testutil.NoopLogger{})
+	uc.SetStrict(true)
+
+	idx, err := uc.Execute(context.Background())
+	if err == nil {
+		t.Fatal("expected error in strict mode")
+	}
+	if idx != nil {
+		t.Error("expected nil index on strict failure")
+	}
+	for _, id := range []string{"bad-regex", "bad-method"} {
+		if !strings.Contains(err.Error(), id) {
+			t.Errorf("expected aggregated error to mention %q, got %v", id, err)
+		}
+	}
+}
+
+func TestLoadScenariosUseCase_Profiles(t *testing.T) {
+	newRepo := func() *mockRepo {
+		return &mockRepo{
+			scenarios: []*scenario.Scenario{
+				{ID: "always", When: scenario.WhenClause{Method: "GET", Path: "/a"}, Response: scenario.Response{Status: 200}},
+				{ID: "dev-only", Profiles: []string{"dev"}, When: scenario.WhenClause{Method: "GET", Path: "/debug"}, Response: scenario.Response{Status: 200}},
+				{ID: "qa-or-dev", Profiles: []string{"qa", "dev"}, When: scenario.WhenClause{Method: "GET", Path: "/fixtures"}, Response: scenario.Response{Status: 200}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		profiles []string
+		want     []string
+	}{
+		{"no active profiles", nil, []string{"always"}},
+		{"matching profile", []string{"dev"}, []string{"always", "dev-only", "qa-or-dev"}},
+		{"partial intersection", []string{"qa"}, []string{"always", "qa-or-dev"}},
+		{"non-matching profile", []string{"prod"}, []string{"always"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := usecases.NewLoadScenariosUseCase(newRepo(), newTestCompiler(t), &testutil.NoopLogger{})
+			uc.SetActiveProfiles(tt.profiles)
+
+			idx, err := uc.Execute(context.Background())
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			for _, id := range tt.want {
+				if _, ok := idx.ByID(id); !ok {
+					t.Errorf("expected scenario %q to be loaded", id)
+				}
+			}
+			if len(idx.All()) != len(tt.want) {
+				t.Errorf("expected %d scenarios, got %d", len(tt.want), len(idx.All()))
+			}
+		})
+	}
+}
+
+func TestLoadScenariosUseCase_ProfileVariantsShareID(t *testing.T) {
+	repo := &mockRepo{
+		scenarios: []*scenario.Scenario{
+			{ID: "payments", Profiles: []string{"dev"}, When: scenario.WhenClause{Method: "GET", Path: "/pay"}, Response: scenario.Response{Status: 200, Body: "dev"}},
+			{ID: "payments", Profiles: []string{"prod"}, When: scenario.WhenClause{Method: "GET", Path: "/pay"}, Response: scenario.Response{Status: 200, Body: "prod"}},
+		},
+	}
+
+	uc := usecases.NewLoadScenariosUseCase(repo, newTestCompiler(t), &testutil.NoopLogger{})
+	uc.SetActiveProfiles([]string{"prod"})
+
+	idx, err := uc.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	cs, ok := idx.ByID("payments")
+	if !ok || string(cs.Response.Body) != "prod" {
+		t.Errorf("expected the prod variant to load, got %+v", cs)
+	}
+}
+
+func TestLoadScenariosUseCase_DynamicEviction(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	scn := func(id string, dynamic bool) *scenario.Scenario {
+		return &scenario.Scenario{
+			ID:          id,
+			When:        scenario.WhenClause{Method: "GET", Path: "/" + id},
+			Response:    scenario.Response{Status: 200},
+			Dynamic:     dynamic,
+			SourceFile:  "/mock/" + id + ".yaml",
+			SourceIndex: -1,
+		}
+	}
+	repo := &mockRepo{scenarios: []*scenario.Scenario{
+		scn("authored", false), // never matched, but not dynamic
+		scn("rec-a", true),
+		scn("rec-b", true),
+		scn("rec-c", true),
+	}}
+
+	tracker := services.NewMatchTracker()
+	tracker.Touch("rec-a", t0.Add(time.Hour))
+	tracker.Touch("rec-b", t0.Add(2*time.Hour))
+	// rec-c has never been seen, so it counts as used when first loaded.
+	clk := &testutil.FixedClock{T: t0.Add(3 * time.Hour)}
+
+	uc := usecases.NewLoadScenariosUseCase(repo, newTestCompiler(t), &testutil.NoopLogger{})
+	uc.SetDynamicEviction(2, tracker, clk)
+
+	// A failed delete keeps the scenario loaded and tracked.
+	repo.deleteErr = errors.New("read-only file system")
+	idx, err := uc.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if _, ok := idx.ByID("rec-a"); !ok {
+		t.Error("expected rec-a to stay loaded when its deletion fails")
+	}
+	if got := tracker.LastUsed("rec-a", clk.T); !got.Equal(t0.Add(time.Hour)) {
+		t.Errorf("expected rec-a to stay tracked, got last use %v", got)
+	}
+
+	repo.deleteErr = nil
+	idx, err = uc.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if !slices.Equal(repo.deleted, []string{"/mock/rec-a.yaml"}) {
+		t.Errorf("expected only rec-a to be deleted, got %v", repo.deleted)
+	}
+	if _, ok := idx.ByID("rec-a"); ok {
+		t.Error("expected rec-a to be evicted from the index")
+	}
+	for _, id := range []string{"authored", "rec-b", "rec-c"} {
+		if _, ok := idx.ByID(id); !ok {
+			t.Errorf("expected %s to be kept", id)
+		}
+	}
+
+	// Within the cap, nothing more is evicted.
+	repo.scenarios = slices.DeleteFunc(repo.scenarios, func(s *scenario.Scenario) bool { return s.ID == "rec-a" })
+	repo.deleted = nil
+	if _, err := uc.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(repo.deleted) != 0 {
+		t.Errorf("expected no eviction within the cap, got %v", repo.deleted)
+	}
+}
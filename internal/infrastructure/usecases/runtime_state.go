@@ -0,0 +1,58 @@
+package usecases
+
+import (
+	"fmt"
+	"time"
+)
+
+// RuntimeStateVersion is the snapshot format written by Snapshot.
+const RuntimeStateVersion = 1
+
+// RuntimeState is a serializable snapshot of the mutable runtime settings
+// changed through the admin API. Every section is optional: Restore applies
+// the sections present and leaves the rest untouched, and unknown fields are
+// ignored, so snapshots survive moving between versions.
+//
+// Disabled and Sequences depend on the loaded scenarios, so the HTTP server
+// fills and applies them. An empty list or map resets that state, while an
+// absent or null one leaves it untouched.
+type RuntimeState struct {
+	Version int           `json:"version"`
+	Latency *LatencyState `json:"latency,omitempty"`
+	// Disabled lists the scenarios disabled through the admin API.
+	Disabled []string `json:"disabled"`
+	// Sequences maps the ID of each scenario with a `responses` sequence to
+	// the number of matches it has served.
+	Sequences map[string]uint64 `json:"sequences"`
+}
+
+// LatencyState captures the global latency added to every response.
+type LatencyState struct {
+	Global string `json:"global"` // Go duration, e.g. "250ms"
+}
+
+// Snapshot captures the current runtime state.
+func (uc *HandleRequestUseCase) Snapshot() RuntimeState {
+	return RuntimeState{
+		Version: RuntimeStateVersion,
+		Latency: &LatencyState{Global: uc.GlobalLatency().String()},
+	}
+}
+
+// Restore applies a snapshot. All sections are validated before any is
+// applied, so an invalid snapshot changes nothing.
+func (uc *HandleRequestUseCase) Restore(state RuntimeState) error {
+	var latency *time.Duration
+	if state.Latency != nil {
+		d, err := time.ParseDuration(state.Latency.Global)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid latency.global %q", state.Latency.Global)
+		}
+		latency = &d
+	}
+
+	if latency != nil {
+		uc.SetGlobalLatency(*latency)
+	}
+	return nil
+}
@@ -2,7 +2,12 @@ package usecases
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"time"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 
@@ -12,8 +17,9 @@ import (
 
 // SaveScenarioUseCase saves a scenario's YAML content to disk.
 type SaveScenarioUseCase struct {
-	repo   scenario.Repository
-	logger ports.Logger
+	repo    scenario.Repository
+	history scenario.History
+	logger  ports.Logger
 }
 
 // NewSaveScenarioUseCase creates a new use case.
@@ -24,6 +30,12 @@ func NewSaveScenarioUseCase(repo scenario.Repository, logger ports.Logger) *Save
 	}
 }
 
+// SetHistory makes Execute snapshot a scenario's previous YAML before
+// overwriting it.
+func (uc *SaveScenarioUseCase) SetHistory(history scenario.History) {
+	uc.history = history
+}
+
 // Execute saves the YAML content for a scenario identified by id.
 // For existing scenarios, it updates the file in place.
 // For new scenarios (id == ""), it creates a new file.
@@ -58,9 +70,111 @@ func (uc *SaveScenarioUseCase) Execute(ctx context.Context, id string, yamlConte
 		return fmt.Errorf("failed to find scenario %q: %w", id, err)
 	}
 
+	uc.snapshot(ctx, existing)
+
 	if err := uc.repo.SaveScenario(ctx, existing, yamlContent); err != nil {
 		return fmt.Errorf("failed to save scenario %q: %w", id, err)
 	}
 	uc.logger.Info("scenario updated", "id", id)
 	return nil
 }
+
+// RecordedProfile is the profile given to scenarios recorded from proxied
+// traffic, so they only replay when it is active.
+const RecordedProfile = "recorded"
+
+// Recording is an upstream exchange captured by a proxy scenario.
+type Recording struct {
+	// SourceID and Priority identify the proxy scenario the exchange came from.
+	SourceID    string
+	Priority    int
+	Method      string
+	Path        string
+	Status      int
+	ContentType string
+	Body        []byte
+	At          time.Time
+}
+
+type recordedScenario struct {
+	ID       string   `yaml:"id"`
+	Name     string   `yaml:"name"`
+	Priority int      `yaml:"priority"`
+	Profiles []string `yaml:"profiles"`
+	Dynamic  bool     `yaml:"dynamic"`
+	When     struct {
+		Method string `yaml:"method"`
+		Path   string `yaml:"path"`
+	} `yaml:"when"`
+	Response struct {
+		Status      int    `yaml:"status"`
+		ContentType string `yaml:"content_type,omitempty"`
+		Body        string `yaml:"body,omitempty"`
+	} `yaml:"response"`
+}
+
+// ErrAlreadyRecorded is returned by Record when the method and path already
+// have a recording.
+var ErrAlreadyRecorded = errors.New("exchange already recorded")
+
+// RecordingID returns the ID of the recording of method and path made by the
+// proxy scenario sourceID. Each method and path is recorded once.
+func RecordingID(sourceID, method, path string) string {
+	sum := sha256.Sum256([]byte(method + " " + path))
+	return sourceID + "-recorded-" + hex.EncodeToString(sum[:6])
+}
+
+// Record saves a recording as a new scenario in the "recorded" profile. It
+// outranks the proxy scenario it came from, so activating the profile
+// replays the upstream response instead of proxying again. Recordings are
+// dynamic, so they count towards the eviction cap. Only the first exchange
+// of a method and path is kept; later ones return ErrAlreadyRecorded
+// without writing. It returns the scenario's ID.
+func (uc *SaveScenarioUseCase) Record(ctx context.Context, rec Recording) (string, error) {
+	id := RecordingID(rec.SourceID, rec.Method, rec.Path)
+	if _, err := uc.repo.LoadByID(ctx, id); err == nil {
+		return id, ErrAlreadyRecorded
+	} else if !errors.Is(err, scenario.ErrNotFound) {
+		return "", fmt.Errorf("failed to look up recording %q: %w", id, err)
+	}
+	if !utf8.Valid(rec.Body) {
+		return "", fmt.Errorf("response body of %s %s is not UTF-8 text", rec.Method, rec.Path)
+	}
+
+	var doc recordedScenario
+	doc.ID = id
+	doc.Name = "Recorded " + rec.Method + " " + rec.Path + " at " + rec.At.UTC().Format(time.RFC3339)
+	doc.Priority = rec.Priority + 1
+	doc.Profiles = []string{RecordedProfile}
+	doc.Dynamic = true
+	doc.When.Method = rec.Method
+	doc.When.Path = rec.Path
+	doc.Response.Status = rec.Status
+	doc.Response.ContentType = rec.ContentType
+	doc.Response.Body = string(rec.Body)
+
+	content, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode recorded scenario: %w", err)
+	}
+	if err := uc.Execute(ctx, "", content); err != nil {
+		return "", err
+	}
+	return doc.ID, nil
+}
+
+// snapshot records the scenario's current YAML in the history, if configured.
+// Failures are logged rather than blocking the save.
+func (uc *SaveScenarioUseCase) snapshot(ctx context.Context, existing *scenario.Scenario) {
+	if uc.history == nil {
+		return
+	}
+	previous, err := uc.repo.ReadSourceYAML(ctx, existing)
+	if err != nil {
+		uc.logger.Warn("failed to read scenario for history", "id", existing.ID, "error", err)
+		return
+	}
+	if err := uc.history.Record(ctx, existing.ID, previous); err != nil {
+		uc.logger.Warn("failed to record scenario history", "id", existing.ID, "error", err)
+	}
+}
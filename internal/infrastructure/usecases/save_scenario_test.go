@@ -0,0 +1,68 @@
+package usecases_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/filesystem"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/usecases"
+	"github.com/sophialabs/proteusmock/internal/testutil"
+)
+
+func TestSaveScenario_RecordsHistory(t *testing.T) {
+	dir := t.TempDir()
+	v1 := "id: health\nwhen:\n  method: GET\n  path: /health\nresponse:\n  status: 200\n"
+	if err := os.WriteFile(filepath.Join(dir, "health.yaml"), []byte(v1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := filesystem.NewYAMLRepository(dir)
+	if err != nil {
+		t.Fatalf("NewYAMLRepository failed: %v", err)
+	}
+	history, err := filesystem.NewHistoryStore(dir, 10)
+	if err != nil {
+		t.Fatalf("NewHistoryStore failed: %v", err)
+	}
+	uc := usecases.NewSaveScenarioUseCase(repo, &testutil.NoopLogger{})
+	uc.SetHistory(history)
+
+	ctx := context.Background()
+	v2 := "id: health\nwhen:\n  method: GET\n  path: /health\nresponse:\n  status: 503\n"
+	v3 := "id: health\nwhen:\n  method: GET\n  path: /health\nresponse:\n  status: 204\n"
+	for _, content := range []string{v2, v3} {
+		if err := uc.Execute(ctx, "health", []byte(content)); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+
+	versions, err := history.List(ctx, "health")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 history entries, got %d", len(versions))
+	}
+
+	// Newest first: the second save backed up v2, the first backed up v1.
+	for i, want := range []string{v2, v1} {
+		got, err := history.Get(ctx, "health", versions[i].ID)
+		if err != nil {
+			t.Fatalf("Get(%s) failed: %v", versions[i].ID, err)
+		}
+		if string(got) != want {
+			t.Errorf("version %d: expected %q, got %q", i, want, got)
+		}
+	}
+
+	// Snapshots must not be loaded as scenarios.
+	all, err := repo.LoadAll(ctx)
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if len(all) != 1 {
+		t.Errorf("expected history snapshots to be skipped, got %d scenarios", len(all))
+	}
+}
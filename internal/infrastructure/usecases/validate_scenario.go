@@ -0,0 +1,74 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/sophialabs/proteusmock/internal/domain/match"
+	"github.com/sophialabs/proteusmock/internal/domain/scenario"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/services"
+)
+
+// ScenarioDecoder parses raw scenario YAML into domain scenarios.
+type ScenarioDecoder interface {
+	DecodeScenarios(yamlContent []byte) ([]*scenario.Scenario, error)
+}
+
+// ValidationError describes a single failure found while validating scenario YAML.
+type ValidationError struct {
+	Stage      string `json:"stage"` // "decode" or "compile"
+	ScenarioID string `json:"scenario_id,omitempty"`
+	Message    string `json:"message"`
+}
+
+// ValidateScenarioResult is the outcome of validating scenario YAML.
+type ValidateScenarioResult struct {
+	Valid    bool
+	Errors   []ValidationError
+	Compiled []*match.CompiledScenario
+}
+
+// ValidateScenarioUseCase runs raw YAML through the decode and compile pipeline
+// in memory, without writing to disk or touching the live index.
+type ValidateScenarioUseCase struct {
+	decoder  ScenarioDecoder
+	compiler *services.Compiler
+	logger   ports.Logger
+}
+
+// NewValidateScenarioUseCase creates a new use case.
+func NewValidateScenarioUseCase(decoder ScenarioDecoder, compiler *services.Compiler, logger ports.Logger) *ValidateScenarioUseCase {
+	return &ValidateScenarioUseCase{
+		decoder:  decoder,
+		compiler: compiler,
+		logger:   logger,
+	}
+}
+
+// Execute validates the YAML content and returns the compiled scenarios on success.
+func (uc *ValidateScenarioUseCase) Execute(_ context.Context, yamlContent []byte) ValidateScenarioResult {
+	scenarios, err := uc.decoder.DecodeScenarios(yamlContent)
+	if err != nil {
+		return ValidateScenarioResult{
+			Errors: []ValidationError{{Stage: "decode", Message: err.Error()}},
+		}
+	}
+
+	var result ValidateScenarioResult
+	for _, s := range scenarios {
+		if s.ID == "" {
+			result.Errors = append(result.Errors, ValidationError{Stage: "decode", Message: "scenario must contain an 'id' field"})
+			continue
+		}
+		cs, err := uc.compiler.CompileScenario(s)
+		if err != nil {
+			result.Errors = append(result.Errors, ValidationError{Stage: "compile", ScenarioID: s.ID, Message: err.Error()})
+			continue
+		}
+		result.Compiled = append(result.Compiled, cs)
+	}
+
+	result.Valid = len(result.Errors) == 0
+	uc.logger.Debug("scenario YAML validated", "valid", result.Valid, "errors", len(result.Errors))
+	return result
+}
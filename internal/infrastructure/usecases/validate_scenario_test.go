@@ -0,0 +1,74 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sophialabs/proteusmock/internal/domain/scenario"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/usecases"
+	"github.com/sophialabs/proteusmock/internal/testutil"
+)
+
+type stubDecoder struct {
+	scenarios []*scenario.Scenario
+	err       error
+}
+
+func (d *stubDecoder) DecodeScenarios(_ []byte) ([]*scenario.Scenario, error) {
+	return d.scenarios, d.err
+}
+
+func TestValidateScenario_Valid(t *testing.T) {
+	decoder := &stubDecoder{scenarios: []*scenario.Scenario{
+		{
+			ID:       "ok",
+			When:     scenario.WhenClause{Method: "GET", Path: "/ok"},
+			Response: scenario.Response{Status: 200},
+		},
+	}}
+	uc := usecases.NewValidateScenarioUseCase(decoder, newTestCompiler(t), &testutil.NoopLogger{})
+
+	result := uc.Execute(context.Background(), []byte("ignored"))
+	if !result.Valid {
+		t.Fatalf("expected valid, got errors: %v", result.Errors)
+	}
+	if len(result.Compiled) != 1 || result.Compiled[0].PathKey != "GET:/ok" {
+		t.Errorf("unexpected compiled scenarios: %+v", result.Compiled)
+	}
+}
+
+func TestValidateScenario_DecodeError(t *testing.T) {
+	decoder := &stubDecoder{err: errors.New("bad yaml")}
+	uc := usecases.NewValidateScenarioUseCase(decoder, newTestCompiler(t), &testutil.NoopLogger{})
+
+	result := uc.Execute(context.Background(), []byte("ignored"))
+	if result.Valid {
+		t.Fatal("expected invalid")
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Stage != "decode" {
+		t.Errorf("expected a single decode error, got %+v", result.Errors)
+	}
+}
+
+func TestValidateScenario_CompileError(t *testing.T) {
+	decoder := &stubDecoder{scenarios: []*scenario.Scenario{
+		{
+			ID: "bad-regex",
+			When: scenario.WhenClause{
+				Method:  "GET",
+				Path:    "/bad",
+				Headers: map[string]scenario.StringMatcher{"X-Key": {Pattern: "[invalid"}},
+			},
+		},
+	}}
+	uc := usecases.NewValidateScenarioUseCase(decoder, newTestCompiler(t), &testutil.NoopLogger{})
+
+	result := uc.Execute(context.Background(), []byte("ignored"))
+	if result.Valid {
+		t.Fatal("expected invalid")
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Stage != "compile" || result.Errors[0].ScenarioID != "bad-regex" {
+		t.Errorf("expected a compile error for bad-regex, got %+v", result.Errors)
+	}
+}
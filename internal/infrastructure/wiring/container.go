@@ -11,6 +11,7 @@ import (
 	inboundhttp "github.com/sophialabs/proteusmock/internal/infrastructure/inbound/http"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/clock"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/filesystem"
+	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/random"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/ratelimit"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/outbound/template"
 	"github.com/sophialabs/proteusmock/internal/infrastructure/ports"
@@ -24,7 +25,46 @@ type Params struct {
 	TraceSize      int
 	RateLimiterTTL time.Duration
 	Logger         ports.Logger
-	DefaultEngine  string // "" = static, "expr", "jinja2"
+	DefaultEngine  string // "" = static, "expr", "jinja2", "go"
+	// MaxTraceCandidates caps candidate results recorded per trace entry (0 = unlimited).
+	MaxTraceCandidates int
+	// ActiveProfiles selects which profile-restricted scenarios load.
+	ActiveProfiles []string
+	// OverridesDir holds scenarios deep-merged onto base scenarios by ID.
+	OverridesDir string
+	// HistoryLimit caps snapshots kept per scenario saved via the admin API (0 = disabled).
+	HistoryLimit int
+	// GlobalMaxPageSize caps pagination page size for all scenarios (0 = unlimited).
+	GlobalMaxPageSize int
+	// StrictLoad fails loading when any scenario fails to compile or a .json
+	// file under the root doesn't parse.
+	StrictLoad bool
+	// TemplateFuncs are custom helpers registered with every template engine.
+	TemplateFuncs map[string]template.TemplateFunc
+	// MethodNotAllowed answers 405 with Allow for paths mocked only for other methods.
+	MethodNotAllowed bool
+	// CaptureEcho answers unmatched requests with 200 and an echo of the request.
+	CaptureEcho bool
+	// DevMode exposes template render errors in 500 response bodies.
+	DevMode bool
+	// StatusBodies maps a status to the default body template of bodiless responses.
+	StatusBodies map[int]string
+	// DisableAdmin and DisableDashboard leave the /__admin and /__ui routes out.
+	DisableAdmin     bool
+	DisableDashboard bool
+	// AdminUser and AdminPassword guard the admin and dashboard routes with basic auth.
+	AdminUser     string
+	AdminPassword string
+	// CORS is the cross-origin policy; no allowed origins disables it.
+	CORS inboundhttp.CORSPolicy
+	// MaxDynamicScenarios caps generated scenarios, evicting the least recently matched (0 = unlimited).
+	MaxDynamicScenarios int
+	// RandomSeed seeds the shared random source (0 = unseeded).
+	RandomSeed uint64
+	// TrailingSlash is the trailing slash mode for mock paths ("" = strict).
+	TrailingSlash string
+	// Middlewares is the global transformation pipeline for mock routes, outermost first.
+	Middlewares []inboundhttp.MiddlewareSpec
 }
 
 // Container owns the construction and lifecycle of all infrastructure components.
@@ -51,12 +91,45 @@ func New(p Params) (*Container, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create repository: %w", err)
 	}
+	repo.SetLogger(p.Logger)
+	repo.SetStrict(p.StrictLoad)
+	if p.OverridesDir != "" {
+		if _, err := os.Stat(p.OverridesDir); err != nil {
+			return nil, fmt.Errorf("failed to access overrides directory: %w", err)
+		}
+		if err := repo.SetOverridesDir(p.OverridesDir); err != nil {
+			return nil, fmt.Errorf("failed to create repository: %w", err)
+		}
+	}
+
+	var history *filesystem.HistoryStore
+	if p.HistoryLimit > 0 {
+		history, err = filesystem.NewHistoryStore(p.RootDir, p.HistoryLimit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create history store: %w", err)
+		}
+	}
 
+	rnd := random.New(p.RandomSeed)
 	registry := template.NewRegistry()
+	registry.SetDataRoot(p.RootDir)
+	registry.SetRandomSource(rnd)
+	for name, fn := range p.TemplateFuncs {
+		if err := registry.RegisterFunc(name, fn); err != nil {
+			return nil, err
+		}
+	}
 	compiler, err := services.NewCompiler(p.RootDir, registry)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create compiler: %w", err)
 	}
+	compiler.SetGlobalMaxPageSize(p.GlobalMaxPageSize)
+	compiler.SetRandomSource(rnd)
+
+	statusBodies, err := compileStatusBodies(registry, p.DefaultEngine, p.StatusBodies)
+	if err != nil {
+		return nil, err
+	}
 
 	// Start background goroutine only after all fallible ops succeed.
 	rateLimiterStore := ratelimit.NewTokenBucketStore(p.RateLimiterTTL)
@@ -64,17 +137,48 @@ func New(p Params) (*Container, error) {
 	clk := clock.New()
 	traceBuf := trace.NewRingBuffer(p.TraceSize)
 	evaluator := match.NewEvaluator()
+	evaluator.SetMaxCandidates(p.MaxTraceCandidates)
 
 	loadUC := usecases.NewLoadScenariosUseCase(repo, compiler, p.Logger)
 	if p.DefaultEngine != "" {
 		loadUC.SetDefaultEngine(p.DefaultEngine)
 	}
+	loadUC.SetActiveProfiles(p.ActiveProfiles)
+	loadUC.SetStrict(p.StrictLoad)
 	handleReqUC := usecases.NewHandleRequestUseCase(evaluator, clk, rateLimiterStore, p.Logger, traceBuf)
+	handleReqUC.SetRandomSource(rnd)
+	if p.MaxDynamicScenarios > 0 {
+		tracker := services.NewMatchTracker()
+		loadUC.SetDynamicEviction(p.MaxDynamicScenarios, tracker, clk)
+		handleReqUC.SetMatchTracker(tracker)
+	}
 	saveUC := usecases.NewSaveScenarioUseCase(repo, p.Logger)
 	deleteUC := usecases.NewDeleteScenarioUseCase(repo, p.Logger)
+	validateUC := usecases.NewValidateScenarioUseCase(repo, compiler, p.Logger)
 
 	server := inboundhttp.NewServer(handleReqUC, loadUC, traceBuf, p.Logger)
 	server.SetCRUDDeps(saveUC, deleteUC, repo, p.RootDir)
+	server.SetValidateUseCase(validateUC)
+	if history != nil {
+		saveUC.SetHistory(history)
+		server.SetHistory(history)
+	}
+	server.SetMethodNotAllowed(p.MethodNotAllowed)
+	server.SetCaptureEcho(p.CaptureEcho)
+	server.SetDevMode(p.DevMode)
+	server.SetStatusBodies(statusBodies)
+	server.SetAdminDisabled(p.DisableAdmin)
+	server.SetDashboardDisabled(p.DisableDashboard)
+	server.SetAdminAuth(p.AdminUser, p.AdminPassword)
+	server.SetCORS(p.CORS)
+	if err := server.SetTrailingSlash(p.TrailingSlash); err != nil {
+		rateLimiterStore.Stop()
+		return nil, err
+	}
+	if err := server.SetMiddlewares(p.Middlewares); err != nil {
+		rateLimiterStore.Stop()
+		return nil, err
+	}
 
 	return &Container{
 		logger:           p.Logger,
@@ -87,9 +191,11 @@ func New(p Params) (*Container, error) {
 	}, nil
 }
 
-// Close releases resources held by the container. It is idempotent.
+// Close waits for background proxy recordings and releases resources held
+// by the container. It is idempotent.
 func (c *Container) Close() {
 	c.closeOnce.Do(func() {
+		c.server.WaitRecordings()
 		c.rateLimiterStore.Stop()
 	})
 }
@@ -118,3 +224,20 @@ func (c *Container) RateLimiterStore() *ratelimit.TokenBucketStore {
 func (c *Container) TraceBuf() *trace.RingBuffer {
 	return c.traceBuf
 }
+
+// compileStatusBodies compiles the default body template of each status with
+// engine, or Expr when no default engine is set.
+func compileStatusBodies(registry *template.Registry, engine string, sources map[int]string) (map[int]match.BodyRenderer, error) {
+	if engine == "" {
+		engine = "expr"
+	}
+	renderers := make(map[int]match.BodyRenderer, len(sources))
+	for status, source := range sources {
+		renderer, err := registry.Compile(engine, fmt.Sprintf("status_body_%d", status), source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile default body for status %d: %w", status, err)
+		}
+		renderers[status] = renderer
+	}
+	return renderers, nil
+}
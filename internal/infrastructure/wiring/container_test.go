@@ -2,6 +2,8 @@ package wiring_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -136,6 +138,48 @@ func TestNew_WithDefaultEngine(t *testing.T) {
 	}
 }
 
+func TestNew_RandomSeedIsReproducible(t *testing.T) {
+	serve := func(seed uint64) string {
+		p := validParams(t)
+		p.RandomSeed = seed
+		yaml := `id: random-id
+when:
+  method: GET
+  path: /api/id
+response:
+  status: 200
+  engine: expr
+  body: '${uuid()}'
+`
+		if err := os.WriteFile(filepath.Join(p.RootDir, "scenarios", "id.yaml"), []byte(yaml), 0o644); err != nil {
+			t.Fatalf("failed to write scenario file: %v", err)
+		}
+
+		c, err := wiring.New(p)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		defer c.Close()
+
+		idx, err := c.LoadScenariosUseCase().Execute(context.Background())
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		c.Server().Rebuild(idx)
+		w := httptest.NewRecorder()
+		c.Server().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/id", nil))
+		return w.Body.String()
+	}
+
+	first, second := serve(7), serve(7)
+	if first == "" || first != second {
+		t.Errorf("expected the same body for the same seed, got %q and %q", first, second)
+	}
+	if other := serve(8); other == first {
+		t.Errorf("expected a different body for another seed, got %q twice", other)
+	}
+}
+
 func TestClose_IsIdempotent(t *testing.T) {
 	p := validParams(t)
 	c, err := wiring.New(p)
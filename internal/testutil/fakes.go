@@ -2,6 +2,7 @@ package testutil
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/sophialabs/proteusmock/internal/domain/match"
@@ -18,6 +19,43 @@ func (l *NoopLogger) Warn(string, ...any)  {}
 func (l *NoopLogger) Error(string, ...any) {}
 func (l *NoopLogger) Debug(string, ...any) {}
 
+var _ ports.Logger = (*CapturingLogger)(nil)
+
+// CapturingLogger records the messages of Info and Warn logs and discards
+// the rest.
+type CapturingLogger struct {
+	mu    sync.Mutex
+	infos []string
+	warns []string
+}
+
+func (l *CapturingLogger) Info(msg string, _ ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.infos = append(l.infos, msg)
+}
+func (l *CapturingLogger) Warn(msg string, _ ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warns = append(l.warns, msg)
+}
+func (l *CapturingLogger) Error(string, ...any) {}
+func (l *CapturingLogger) Debug(string, ...any) {}
+
+// Infos returns the Info messages logged so far.
+func (l *CapturingLogger) Infos() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.infos...)
+}
+
+// Warns returns the Warn messages logged so far.
+func (l *CapturingLogger) Warns() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.warns...)
+}
+
 var _ ports.Clock = (*FixedClock)(nil)
 
 // FixedClock returns a fixed time and never sleeps.
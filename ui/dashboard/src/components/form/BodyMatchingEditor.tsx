@@ -47,7 +47,9 @@ export function BodyMatchingEditor({ body, onChange }: Props) {
               className="px-2.5 py-1.5 text-sm rounded-md border border-[hsl(var(--border))] bg-[hsl(var(--background))] focus:outline-none focus:ring-1 focus:ring-[hsl(var(--ring))]"
             >
               <option value="json">JSON (JSONPath)</option>
+              <option value="json-pointer">JSON (JSON Pointer)</option>
               <option value="xml">XML (XPath)</option>
+              <option value="protobuf">Protobuf (field numbers)</option>
             </select>
           </div>
 
@@ -203,7 +205,7 @@ function ConditionsEditor({
               type="text"
               value={c.extractor}
               onChange={e => updateCondition(i, 'extractor', e.target.value)}
-              placeholder={contentType === 'xml' ? '//xpath/expression' : '$.json.path'}
+              placeholder={contentType === 'xml' ? '//xpath/expression' : contentType === 'protobuf' ? '1.2' : contentType === 'json-pointer' ? '/json/pointer' : '$.json.path'}
               className="flex-1 px-2.5 py-1.5 text-sm font-mono rounded-md border border-[hsl(var(--border))] bg-[hsl(var(--background))] focus:outline-none focus:ring-1 focus:ring-[hsl(var(--ring))]"
             />
             <input
@@ -3,6 +3,7 @@ export interface ScenarioSummary {
   name: string
   priority: number
   method: string
+  methods?: string[]
   path_key: string
 }
 
@@ -15,6 +16,7 @@ export interface ScenarioDetail {
   source_yaml: string
   when: {
     method: string
+    methods?: string[]
     path: string
     headers?: Record<string, string>
     body?: Record<string, unknown>
@@ -49,6 +51,23 @@ export interface TraceEntry {
   }[]
 }
 
+export interface IndexGraph {
+  routes: {
+    key: string
+    method: string
+    path: string
+    candidates: {
+      position: number
+      id: string
+      name: string
+      priority: number
+      specificity: number
+      predicate_fields: string[]
+    }[]
+  }[]
+  statics: { id: string; name: string; path_prefix: string }[]
+}
+
 async function handleResponse<T>(res: Response): Promise<T> {
   if (!res.ok) {
     const text = await res.text()
@@ -93,6 +112,9 @@ export const api = {
   listFiles: (): Promise<string[]> =>
     fetch('/__admin/files').then(r => handleResponse<string[]>(r)),
 
+  getIndex: (): Promise<IndexGraph> =>
+    fetch('/__admin/index').then(r => handleResponse<IndexGraph>(r)),
+
   getTrace: (last = 50): Promise<TraceEntry[]> =>
     fetch(`/__admin/trace?last=${last}`).then(r => handleResponse<TraceEntry[]>(r)),
 
@@ -229,7 +229,7 @@ function ScenarioOverview({ scenario, includeFile }: { scenario: ScenarioDetail;
       </Section>
 
       <Section title="When (Request Matching)">
-        <Field label="Method" value={scenario.when.method} />
+        <Field label="Method" value={scenario.when.methods?.join(', ') ?? scenario.when.method} />
         <Field label="Path" value={scenario.when.path} mono />
         {scenario.when.headers && (
           <Field